@@ -38,6 +38,8 @@ var configFile = flag.String("config", "", "path to simulation config file")
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
 var memprofile = flag.String("memprofile", "", "write memory profile to `file`")
 var logfile = flag.String("log", "", "write log to `file`-nodeID.log")
+var scheduler = flag.Bool("scheduler", false,
+	"run with the seeded discrete-event scheduler")
 
 func main() {
 	flag.Parse()
@@ -73,7 +75,16 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	simulation.Run(cfg, *logfile)
+	if *scheduler {
+		digest, err := simulation.RunWithScheduler(cfg, *logfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("digest of message interleaving:", digest.String())
+	} else {
+		simulation.Run(cfg, *logfile)
+	}
 
 	if *memprofile != "" {
 		f, err := os.Create(*memprofile)
@@ -0,0 +1,81 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package common
+
+import "time"
+
+// Clock abstracts the source of time used by Consensus instance, thus a
+// simulated clock could be injected to drive all timers deterministically.
+type Clock interface {
+	// Now returns the current time of this clock.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// Sleep pauses the calling goroutine for at least the duration d.
+	Sleep(d time.Duration)
+	// NewTicker returns a ClockTicker which sends the current time
+	// periodically.
+	NewTicker(d time.Duration) ClockTicker
+}
+
+// ClockTicker is the ticker created by Clock.
+type ClockTicker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// SystemClock is a Clock based on wall-clock time.
+type SystemClock struct{}
+
+// Now implements Clock interface.
+func (c *SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements Clock interface.
+func (c *SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep implements Clock interface.
+func (c *SystemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// NewTicker implements Clock interface.
+func (c *SystemClock) NewTicker(d time.Duration) ClockTicker {
+	return &systemTicker{ticker: time.NewTicker(d)}
+}
+
+// systemTicker wraps time.Ticker to implement ClockTicker interface.
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+// C implements ClockTicker interface.
+func (t *systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+// Stop implements ClockTicker interface.
+func (t *systemTicker) Stop() {
+	t.ticker.Stop()
+}
@@ -0,0 +1,308 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics define the way to receive measurements from Consensus instance.
+// NOTE: parameter in 'labels' should be paired as key-value mapping, just like
+//       the 'ctx' parameter of Logger. For example:
+//           metrics.Histogram("dkg_phase_seconds", "phase", "2").Observe(1.5)
+type Metrics interface {
+	// Counter returns the counter identified by name and labels.
+	Counter(name string, labels ...string) Counter
+	// Gauge returns the gauge identified by name and labels.
+	Gauge(name string, labels ...string) Gauge
+	// Histogram returns the histogram identified by name and labels.
+	Histogram(name string, labels ...string) Histogram
+}
+
+// Counter is a monotonically increasing value.
+type Counter interface {
+	Inc()
+	Add(delta float64)
+}
+
+// Gauge is a value which could go up and down.
+type Gauge interface {
+	Set(value float64)
+	Add(delta float64)
+}
+
+// Histogram samples observations into buckets.
+type Histogram interface {
+	Observe(value float64)
+}
+
+// NullMetrics measures nothing.
+type NullMetrics struct{}
+
+type nullInstrument struct{}
+
+func (nullInstrument) Inc()            {}
+func (nullInstrument) Add(float64)     {}
+func (nullInstrument) Set(float64)     {}
+func (nullInstrument) Observe(float64) {}
+
+// Counter implements Metrics interface.
+func (m *NullMetrics) Counter(name string, labels ...string) Counter {
+	return nullInstrument{}
+}
+
+// Gauge implements Metrics interface.
+func (m *NullMetrics) Gauge(name string, labels ...string) Gauge {
+	return nullInstrument{}
+}
+
+// Histogram implements Metrics interface.
+func (m *NullMetrics) Histogram(name string, labels ...string) Histogram {
+	return nullInstrument{}
+}
+
+// DefaultHistogramBuckets are upper bounds of buckets used by TextMetrics when
+// no bucket is provided, they fit durations in seconds.
+var DefaultHistogramBuckets = []float64{
+	.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+const (
+	metricTypeCounter   = "counter"
+	metricTypeGauge     = "gauge"
+	metricTypeHistogram = "histogram"
+)
+
+type textSeries struct {
+	labels  string
+	value   float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+type textFamily struct {
+	typ    string
+	series map[string]*textSeries
+}
+
+// textInstrument implements Counter, Gauge and Histogram for one series of
+// TextMetrics.
+type textInstrument struct {
+	m      *TextMetrics
+	series *textSeries
+}
+
+func (i *textInstrument) Inc() {
+	i.Add(1)
+}
+
+func (i *textInstrument) Add(delta float64) {
+	i.m.lock.Lock()
+	defer i.m.lock.Unlock()
+	i.series.value += delta
+}
+
+func (i *textInstrument) Set(value float64) {
+	i.m.lock.Lock()
+	defer i.m.lock.Unlock()
+	i.series.value = value
+}
+
+func (i *textInstrument) Observe(value float64) {
+	i.m.lock.Lock()
+	defer i.m.lock.Unlock()
+	for idx, upper := range i.m.buckets {
+		if value <= upper {
+			i.series.buckets[idx]++
+		}
+	}
+	i.series.sum += value
+	i.series.count++
+}
+
+// TextMetrics keeps measurements in memory and dumps them in Prometheus text
+// exposition format. It implements http.Handler, thus could be served over
+// HTTP directly.
+type TextMetrics struct {
+	namespace string
+	buckets   []float64
+	lock      sync.Mutex
+	families  map[string]*textFamily
+}
+
+// NewTextMetrics creates a new TextMetrics instance, metric names would be
+// prefixed by namespace if it's not empty. DefaultHistogramBuckets would be
+// used if buckets is empty.
+func NewTextMetrics(namespace string, buckets []float64) *TextMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultHistogramBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+	return &TextMetrics{
+		namespace: namespace,
+		buckets:   sorted,
+		families:  make(map[string]*textFamily),
+	}
+}
+
+func (m *TextMetrics) get(
+	typ, name string, labels []string) *textInstrument {
+	if m.namespace != "" {
+		name = m.namespace + "_" + name
+	}
+	key := composeLabels(labels)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	family, exist := m.families[name]
+	if !exist {
+		family = &textFamily{
+			typ:    typ,
+			series: make(map[string]*textSeries),
+		}
+		m.families[name] = family
+	}
+	if family.typ != typ {
+		panic(fmt.Errorf("metric %s is already registered as %s, not %s",
+			name, family.typ, typ))
+	}
+	series, exist := family.series[key]
+	if !exist {
+		series = &textSeries{labels: key}
+		if typ == metricTypeHistogram {
+			series.buckets = make([]uint64, len(m.buckets))
+		}
+		family.series[key] = series
+	}
+	return &textInstrument{m: m, series: series}
+}
+
+// Counter implements Metrics interface.
+func (m *TextMetrics) Counter(name string, labels ...string) Counter {
+	return m.get(metricTypeCounter, name, labels)
+}
+
+// Gauge implements Metrics interface.
+func (m *TextMetrics) Gauge(name string, labels ...string) Gauge {
+	return m.get(metricTypeGauge, name, labels)
+}
+
+// Histogram implements Metrics interface.
+func (m *TextMetrics) Histogram(name string, labels ...string) Histogram {
+	return m.get(metricTypeHistogram, name, labels)
+}
+
+// WriteTo dumps all measurements to w in text exposition format.
+func (m *TextMetrics) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		names := make([]string, 0, len(m.families))
+		for name := range m.families {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			family := m.families[name]
+			fmt.Fprintf(buf, "# TYPE %s %s\n", name, family.typ)
+			keys := make([]string, 0, len(family.series))
+			for key := range family.series {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				m.writeSeries(buf, name, family.typ, family.series[key])
+			}
+		}
+	}()
+	return buf.WriteTo(w)
+}
+
+func (m *TextMetrics) writeSeries(
+	buf *bytes.Buffer, name, typ string, s *textSeries) {
+	if typ != metricTypeHistogram {
+		fmt.Fprintf(buf, "%s%s %s\n",
+			name, wrapLabels(s.labels), formatFloat(s.value))
+		return
+	}
+	for idx, upper := range m.buckets {
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name,
+			wrapLabels(appendLabel(s.labels, "le", formatFloat(upper))),
+			s.buckets[idx])
+	}
+	fmt.Fprintf(buf, "%s_bucket%s %d\n",
+		name, wrapLabels(appendLabel(s.labels, "le", "+Inf")), s.count)
+	fmt.Fprintf(buf, "%s_sum%s %s\n",
+		name, wrapLabels(s.labels), formatFloat(s.sum))
+	fmt.Fprintf(buf, "%s_count%s %d\n", name, wrapLabels(s.labels), s.count)
+}
+
+// ServeHTTP implements http.Handler interface.
+func (m *TextMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+// composeLabels makes key-value pairs in labels a stable string.
+func composeLabels(labels []string) string {
+	if len(labels)%2 != 0 {
+		panic(fmt.Errorf("labels should be paired: %v", labels))
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%s",
+			labels[i], strconv.Quote(labels[i+1])))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func appendLabel(labels, key, value string) string {
+	label := fmt.Sprintf("%s=%s", key, strconv.Quote(value))
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
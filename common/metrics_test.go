@@ -0,0 +1,80 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func (s *MetricsTestSuite) TestNullMetrics() {
+	m := &NullMetrics{}
+	m.Counter("counter").Inc()
+	m.Gauge("gauge", "key", "value").Set(1)
+	m.Histogram("histogram").Observe(1)
+}
+
+func (s *MetricsTestSuite) TestTextExposition() {
+	m := NewTextMetrics("test", []float64{1, 0.5})
+	m.Counter("confirmed_total").Inc()
+	m.Counter("confirmed_total").Add(2)
+	m.Gauge("backlog", "chan", "msg").Set(10)
+	m.Gauge("backlog", "chan", "priority").Set(3)
+	m.Gauge("backlog", "chan", "priority").Add(-1)
+	h := m.Histogram("latency_seconds", "phase", "1")
+	h.Observe(0.1)
+	h.Observe(0.7)
+	h.Observe(5)
+	buf := &bytes.Buffer{}
+	_, err := m.WriteTo(buf)
+	s.Require().NoError(err)
+	s.Equal(`# TYPE test_backlog gauge
+test_backlog{chan="msg"} 10
+test_backlog{chan="priority"} 2
+# TYPE test_confirmed_total counter
+test_confirmed_total 3
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{phase="1",le="0.5"} 1
+test_latency_seconds_bucket{phase="1",le="1"} 2
+test_latency_seconds_bucket{phase="1",le="+Inf"} 3
+test_latency_seconds_sum{phase="1"} 5.8
+test_latency_seconds_count{phase="1"} 3
+`, buf.String())
+	// The same content should be served over HTTP.
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	s.Equal(buf.String(), rec.Body.String())
+}
+
+func (s *MetricsTestSuite) TestTypeConflict() {
+	m := NewTextMetrics("", nil)
+	m.Counter("value")
+	s.Panics(func() { m.Gauge("value") })
+	s.Panics(func() { m.Counter("value", "unpaired") })
+}
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	cryptoDKG "github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+// Errors for agreement certificate.
+var (
+	ErrCertificateBeforeDKG = errors.New(
+		"agreement certificate is not available before DKGDelayRound")
+	ErrIncorrectSignerBitmap = errors.New(
+		"incorrect signer bitmap")
+	ErrIncorrectCertificateSignature = errors.New(
+		"incorrect agreement certificate signature")
+	ErrIncorrectCertificateProposer = errors.New(
+		"incorrect agreement certificate proposer")
+	ErrIncorrectCertificateProposerSignature = errors.New(
+		"incorrect agreement certificate proposer signature")
+	ErrCertificateMismatch = errors.New(
+		"agreement certificate mismatches agreement result")
+)
+
+// sortedNotaryIDs returns IDs in notary set in the order used by signer
+// bitmap.
+func sortedNotaryIDs(notarySet map[types.NodeID]struct{}) types.NodeIDs {
+	IDs := make(types.NodeIDs, 0, len(notarySet))
+	for nID := range notarySet {
+		IDs = append(IDs, nID)
+	}
+	sort.Sort(IDs)
+	return IDs
+}
+
+// NewAgreementCertificate aggregates commit votes for the block with hash
+// into an AgreementCertificate. For empty blocks, votes are voted on
+// types.NullBlockHash, and 'hash' should be the hash of that empty block.
+// The partial signatures of votes are not verified here, the caller should
+// make sure they are verified, or the certificate would fail the
+// verification. The returned certificate should be signed by
+// utils.Signer.SignAgreementCertificate before sending.
+func NewAgreementCertificate(hash common.Hash, votes []types.Vote,
+	notarySet map[types.NodeID]struct{},
+	npks *typesDKG.NodePublicKeys) (*types.AgreementCertificate, error) {
+	if len(votes) == 0 {
+		return nil, ErrNotEnoughVotes
+	}
+	cert := &types.AgreementCertificate{
+		BlockHash:    hash,
+		Position:     votes[0].Position,
+		IsEmptyBlock: votes[0].BlockHash == types.NullBlockHash,
+	}
+	if cert.Position.Round < DKGDelayRound {
+		return nil, ErrCertificateBeforeDKG
+	}
+	voteType := votes[0].Type
+	if voteType != types.VoteFastCom && voteType != types.VoteCom {
+		return nil, ErrIncorrectVoteType
+	}
+	notaryIDs := sortedNotaryIDs(notarySet)
+	index := make(map[types.NodeID]int, len(notaryIDs))
+	for idx, nID := range notaryIDs {
+		index[nID] = idx
+	}
+	cert.Signers = make([]byte, (len(notaryIDs)+7)/8)
+	var (
+		IDs   = make(cryptoDKG.IDs, 0, len(votes))
+		psigs = make([]cryptoDKG.PartialSignature, 0, len(votes))
+	)
+	for _, vote := range votes {
+		if vote.Type != voteType || vote.Period != votes[0].Period {
+			return nil, ErrIncorrectVoteType
+		}
+		if vote.Position != cert.Position {
+			return nil, ErrIncorrectVotePosition
+		}
+		if cert.IsEmptyBlock {
+			if vote.BlockHash != types.NullBlockHash {
+				return nil, ErrIncorrectVoteBlockHash
+			}
+		} else if vote.BlockHash != hash {
+			return nil, ErrIncorrectVoteBlockHash
+		}
+		idx, exist := index[vote.ProposerID]
+		if !exist {
+			return nil, ErrIncorrectVoteProposer
+		}
+		ID, exist := npks.IDMap[vote.ProposerID]
+		if !exist {
+			return nil, ErrIncorrectVoteProposer
+		}
+		if cert.Signed(idx) {
+			continue
+		}
+		cert.Signers[idx/8] |= 1 << uint(idx%8)
+		IDs = append(IDs, ID)
+		psigs = append(psigs, vote.PartialSignature)
+	}
+	if cert.SignerCount() < len(notarySet)*2/3+1 {
+		return nil, ErrNotEnoughVotes
+	}
+	sig, err := cryptoDKG.RecoverSignature(psigs, IDs)
+	if err != nil {
+		return nil, err
+	}
+	cert.Signature = sig.Signature[:]
+	return cert, nil
+}
+
+// VerifyAgreementCertificate verifies an AgreementCertificate against the
+// notary set and the TSigVerifier of its round.
+func VerifyAgreementCertificate(cert *types.AgreementCertificate,
+	cache *NodeSetCache, verifier TSigVerifier) error {
+	if err := verifyAgreementCertificateWithoutTSig(cert, cache); err != nil {
+		return err
+	}
+	if !verifier.VerifySignature(cert.BlockHash, crypto.Signature{
+		Type:      "bls",
+		Signature: cert.Signature,
+	}) {
+		return ErrIncorrectCertificateSignature
+	}
+	return nil
+}
+
+// VerifyAgreementCertificates verifies agreement certificates carried by a
+// types.BlockRange.
+func VerifyAgreementCertificates(certs []*types.AgreementCertificate,
+	cache *NodeSetCache, verifierCache *TSigVerifierCache) error {
+	for _, cert := range certs {
+		verifier, ok, err := verifierCache.UpdateAndGet(cert.Position.Round)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrCannotVerifyBlockRandomness
+		}
+		if err = VerifyAgreementCertificate(cert, cache, verifier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyAgreementCertificateWithoutTSig verifies everything except the
+// threshold signature of an AgreementCertificate, the signer bitmap is
+// trusted only when it's signed by a notary.
+func verifyAgreementCertificateWithoutTSig(
+	cert *types.AgreementCertificate, cache *NodeSetCache) error {
+	if cert.Position.Round < DKGDelayRound {
+		return ErrCertificateBeforeDKG
+	}
+	notarySet, err := cache.GetNotarySet(cert.Position.Round)
+	if err != nil {
+		return err
+	}
+	if len(cert.Signers) != (len(notarySet)+7)/8 {
+		return ErrIncorrectSignerBitmap
+	}
+	// Bits not mapped to any node should be empty.
+	for idx := len(notarySet); idx < len(cert.Signers)*8; idx++ {
+		if cert.Signed(idx) {
+			return ErrIncorrectSignerBitmap
+		}
+	}
+	if cert.SignerCount() < len(notarySet)*2/3+1 {
+		return ErrNotEnoughVotes
+	}
+	if _, exist := notarySet[cert.ProposerID]; !exist {
+		return ErrIncorrectCertificateProposer
+	}
+	ok, err := utils.VerifyAgreementCertificateSignature(cert)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIncorrectCertificateProposerSignature
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	cryptoDKG "github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/ecdsa"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+type AgreementCertificateTestSuite struct {
+	suite.Suite
+
+	nIDs         types.NodeIDs
+	signers      map[types.NodeID]*utils.Signer
+	cache        *NodeSetCache
+	npks         *typesDKG.NodePublicKeys
+	gpk          *typesDKG.GroupPublicKey
+	shareSecrets map[types.NodeID]*dkgShareSecret
+}
+
+// SetupTest runs DKG among all nodes by dealing private shares directly.
+func (s *AgreementCertificateTestSuite) SetupTest() {
+	var (
+		n     = 7
+		k     = n*2/3 + 1
+		round = DKGDelayRound
+	)
+	prvKeys, pubKeys, err := test.NewKeys(n)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, 100, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	s.cache = utils.NewNodeSetCache(gov)
+	s.nIDs = make(types.NodeIDs, 0, n)
+	s.signers = make(map[types.NodeID]*utils.Signer, n)
+	IDs := make(cryptoDKG.IDs, 0, n)
+	for i, pubKey := range pubKeys {
+		nID := types.NewNodeID(pubKey)
+		s.nIDs = append(s.nIDs, nID)
+		s.signers[nID] = utils.NewSigner(prvKeys[i])
+		IDs = append(IDs, cryptoDKG.NewID(nID.Hash[:]))
+	}
+	received := make([]*cryptoDKG.PrivateKeyShares, n)
+	for i := range received {
+		received[i] = cryptoDKG.NewEmptyPrivateKeyShares()
+	}
+	mpks := make([]*typesDKG.MasterPublicKey, 0, n)
+	for i, nID := range s.nIDs {
+		prvShares, pubShares := cryptoDKG.NewPrivateKeyShares(k)
+		prvShares.SetParticipants(IDs)
+		for j := range s.nIDs {
+			share, exist := prvShares.Share(IDs[j])
+			s.Require().True(exist)
+			s.Require().NoError(received[j].AddShare(IDs[i], share))
+		}
+		mpks = append(mpks, &typesDKG.MasterPublicKey{
+			ProposerID:      nID,
+			Round:           round,
+			DKGID:           IDs[i],
+			PublicKeyShares: *pubShares.Move(),
+		})
+	}
+	s.shareSecrets = make(map[types.NodeID]*dkgShareSecret, n)
+	for i, nID := range s.nIDs {
+		prv, err := received[i].RecoverPrivateKey(IDs)
+		s.Require().NoError(err)
+		s.shareSecrets[nID] = &dkgShareSecret{privateKey: prv}
+	}
+	s.npks, err = typesDKG.NewNodePublicKeys(round, mpks, nil, k)
+	s.Require().NoError(err)
+	s.gpk, err = typesDKG.NewGroupPublicKey(round, mpks, nil, k)
+	s.Require().NoError(err)
+}
+
+func (s *AgreementCertificateTestSuite) newVotes(
+	hash common.Hash, count int) []types.Vote {
+	votes := make([]types.Vote, 0, count)
+	for _, nID := range s.nIDs[:count] {
+		vote := types.NewVote(types.VoteCom, hash, 1)
+		vote.ProposerID = nID
+		vote.Position = types.Position{Round: DKGDelayRound, Height: 10}
+		vote.PartialSignature = s.shareSecrets[nID].sign(hash)
+		votes = append(votes, *vote)
+	}
+	return votes
+}
+
+func (s *AgreementCertificateTestSuite) TestCertificate() {
+	notarySet, err := s.cache.GetNotarySet(DKGDelayRound)
+	s.Require().NoError(err)
+	hash := common.NewRandomHash()
+	votes := s.newVotes(hash, len(s.nIDs)-1)
+	// Duplicated votes are counted once.
+	votes = append(votes, votes[0])
+	cert, err := NewAgreementCertificate(hash, votes, notarySet, s.npks)
+	s.Require().NoError(err)
+	s.Require().Equal(len(s.nIDs)-1, cert.SignerCount())
+	sortedIDs := sortedNotaryIDs(notarySet)
+	for idx, nID := range sortedIDs {
+		s.Require().Equal(nID != s.nIDs[len(s.nIDs)-1], cert.Signed(idx))
+	}
+	// Unsigned certificate is not acceptable.
+	s.Require().Equal(ErrIncorrectCertificateProposer,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	signer := s.signers[s.nIDs[0]]
+	s.Require().NoError(signer.SignAgreementCertificate(cert))
+	s.Require().NoError(VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	// The signature is the randomness of that block.
+	s.Require().True(s.gpk.VerifySignature(hash, crypto.Signature{
+		Type:      "bls",
+		Signature: cert.AgreementResult().Randomness,
+	}))
+	// Signature on other hash should fail.
+	cert.BlockHash = common.NewRandomHash()
+	s.Require().Equal(ErrIncorrectCertificateProposerSignature,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	s.Require().NoError(signer.SignAgreementCertificate(cert))
+	s.Require().Equal(ErrIncorrectCertificateSignature,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	cert.BlockHash = hash
+	s.Require().NoError(signer.SignAgreementCertificate(cert))
+	// A relay can't change signers in the bitmap.
+	signers := cert.Signers
+	cert.Signers = []byte{0x7f}
+	s.Require().Equal(len(s.nIDs), cert.SignerCount())
+	s.Require().Equal(ErrIncorrectCertificateProposerSignature,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	cert.Signers = signers
+	s.Require().NoError(VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	// Certificates signed by nodes not in notary set are not acceptable.
+	prvKey, err := ecdsa.NewPrivateKey()
+	s.Require().NoError(err)
+	s.Require().NoError(
+		utils.NewSigner(prvKey).SignAgreementCertificate(cert))
+	s.Require().Equal(ErrIncorrectCertificateProposer,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	s.Require().NoError(signer.SignAgreementCertificate(cert))
+	// Tampered bitmaps.
+	cert.Signers = append(cert.Signers, 0)
+	s.Require().Equal(ErrIncorrectSignerBitmap,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	cert.Signers = []byte{0x80}
+	s.Require().Equal(ErrIncorrectSignerBitmap,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	cert.Signers = []byte{0x01}
+	s.Require().Equal(ErrNotEnoughVotes,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+	// Certificate is not available before DKG.
+	cert.Position.Round = 0
+	s.Require().Equal(ErrCertificateBeforeDKG,
+		VerifyAgreementCertificate(cert, s.cache, s.gpk))
+}
+
+func (s *AgreementCertificateTestSuite) TestVerifyAgreementResult() {
+	notarySet, err := s.cache.GetNotarySet(DKGDelayRound)
+	s.Require().NoError(err)
+	hash := common.NewRandomHash()
+	cert, err := NewAgreementCertificate(
+		hash, s.newVotes(hash, len(s.nIDs)), notarySet, s.npks)
+	s.Require().NoError(err)
+	s.Require().NoError(s.signers[s.nIDs[1]].SignAgreementCertificate(cert))
+	result := cert.AgreementResult()
+	s.Require().NoError(VerifyAgreementResult(result, s.cache))
+	// The certificate should survive encoding.
+	b, err := rlp.EncodeToBytes(result)
+	s.Require().NoError(err)
+	decoded := &types.AgreementResult{}
+	s.Require().NoError(rlp.DecodeBytes(b, decoded))
+	s.Require().Equal(result.Certificate, decoded.Certificate)
+	s.Require().NoError(VerifyAgreementResult(decoded, s.cache))
+	// The certificate should match the result.
+	result.Randomness = common.GenerateRandomBytes()
+	s.Require().Equal(ErrCertificateMismatch,
+		VerifyAgreementResult(result, s.cache))
+	result.Randomness = cert.Signature
+	result.Position.Height++
+	s.Require().Equal(ErrCertificateMismatch,
+		VerifyAgreementResult(result, s.cache))
+	result.Position = cert.Position
+	// The bitmap in the certificate is verified.
+	cert.Signers = []byte{0x7e}
+	s.Require().Equal(ErrIncorrectCertificateProposerSignature,
+		VerifyAgreementResult(result, s.cache))
+	// Results without certificate are checked by randomness only.
+	result.Certificate = nil
+	s.Require().NoError(VerifyAgreementResult(result, s.cache))
+	b, err = rlp.EncodeToBytes(result)
+	s.Require().NoError(err)
+	decoded = &types.AgreementResult{}
+	s.Require().NoError(rlp.DecodeBytes(b, decoded))
+	s.Require().Nil(decoded.Certificate)
+}
+
+func (s *AgreementCertificateTestSuite) TestNewCertificateFailed() {
+	notarySet, err := s.cache.GetNotarySet(DKGDelayRound)
+	s.Require().NoError(err)
+	hash := common.NewRandomHash()
+	// Not enough votes.
+	_, err = NewAgreementCertificate(
+		hash, s.newVotes(hash, len(s.nIDs)*2/3), notarySet, s.npks)
+	s.Require().Equal(ErrNotEnoughVotes, err)
+	// Votes on different blocks.
+	votes := s.newVotes(hash, len(s.nIDs))
+	votes[1].BlockHash = common.NewRandomHash()
+	_, err = NewAgreementCertificate(hash, votes, notarySet, s.npks)
+	s.Require().Equal(ErrIncorrectVoteBlockHash, err)
+	// Votes not from notary set.
+	votes = s.newVotes(hash, len(s.nIDs))
+	votes[1].ProposerID = types.NodeID{Hash: common.NewRandomHash()}
+	_, err = NewAgreementCertificate(hash, votes, notarySet, s.npks)
+	s.Require().Equal(ErrIncorrectVoteProposer, err)
+	// Votes other than commit votes.
+	votes = s.newVotes(hash, len(s.nIDs))
+	for i := range votes {
+		votes[i].Type = types.VotePreCom
+	}
+	_, err = NewAgreementCertificate(hash, votes, notarySet, s.npks)
+	s.Require().Equal(ErrIncorrectVoteType, err)
+}
+
+func TestAgreementCertificate(t *testing.T) {
+	suite.Run(t, new(AgreementCertificateTestSuite))
+}
@@ -49,7 +49,7 @@ const settingLimit = 3
 func genValidLeader(
 	mgr *agreementMgr) validLeaderFn {
 	return func(block *types.Block, crs common.Hash) (bool, error) {
-		if block.Timestamp.After(time.Now()) {
+		if block.Timestamp.After(mgr.clock.Now()) {
 			return false, nil
 		}
 		if block.Position.Round >= DKGDelayRound {
@@ -111,7 +111,9 @@ func newAgreementMgrConfig(prev agreementMgrConfig, config *types.Config,
 type baRoundSetting struct {
 	round     uint64
 	dkgSet    map[types.NodeID]struct{}
-	threshold int
+	weights   map[types.NodeID]uint64
+	threshold uint64
+	minVoters int
 	ticker    Ticker
 	crs       common.Hash
 }
@@ -124,6 +126,7 @@ type agreementMgr struct {
 	gov               Governance
 	network           Network
 	logger            common.Logger
+	clock             common.Clock
 	cache             *utils.NodeSetCache
 	signer            *utils.Signer
 	bcModule          *blockChain
@@ -149,6 +152,7 @@ func newAgreementMgr(con *Consensus) (mgr *agreementMgr, err error) {
 		gov:               con.gov,
 		network:           con.network,
 		logger:            con.logger,
+		clock:             con.clock,
 		cache:             con.nodeSetCache,
 		signer:            con.signer,
 		bcModule:          con.bcModule,
@@ -171,7 +175,9 @@ func (mgr *agreementMgr) prepare() {
 		mgr.recv,
 		newLeaderSelector(genValidLeader(mgr), mgr.logger),
 		mgr.signer,
+		mgr.con.db,
 		mgr.logger)
+	agr.clock = mgr.clock
 	setting := mgr.generateSetting(round)
 	if setting == nil {
 		mgr.logger.Warn("Unable to prepare init setting", "round", round)
@@ -206,6 +212,7 @@ func (mgr *agreementMgr) run() {
 	}
 	mgr.isRunning = true
 	mgr.waitGroup.Add(1)
+	addWork(mgr.clock, 1)
 	go func() {
 		defer mgr.waitGroup.Done()
 		mgr.runBA(mgr.bcModule.tipRound())
@@ -302,6 +309,7 @@ func (mgr *agreementMgr) processVote(v *types.Vote) (err error) {
 	if err = mgr.baModule.processVote(v); err == nil {
 		mgr.baModule.updateFilter(mgr.voteFilter)
 		mgr.voteFilter.AddVote(v)
+		mgr.con.events.emitVote(v)
 	}
 	if err == ErrSkipButNoError {
 		err = nil
@@ -309,6 +317,43 @@ func (mgr *agreementMgr) processVote(v *types.Vote) (err error) {
 	return
 }
 
+// processVotes processes a batch of votes, the error of the i-th vote is
+// returned in the i-th element.
+func (mgr *agreementMgr) processVotes(votes []*types.Vote) []error {
+	errs := make([]error, len(votes))
+	if !mgr.recv.isNotary {
+		return errs
+	}
+	filtered := make([]*types.Vote, 0, len(votes))
+	indexes := make([]int, 0, len(votes))
+	for idx, v := range votes {
+		if mgr.voteFilter.Filter(v) {
+			continue
+		}
+		if err := mgr.checkProposer(v.Position.Round, v.ProposerID); err != nil {
+			errs[idx] = err
+			continue
+		}
+		filtered = append(filtered, v)
+		indexes = append(indexes, idx)
+	}
+	if len(filtered) == 0 {
+		return errs
+	}
+	for i, err := range mgr.baModule.processVotes(filtered) {
+		if err == nil {
+			mgr.baModule.updateFilter(mgr.voteFilter)
+			mgr.voteFilter.AddVote(filtered[i])
+			mgr.con.events.emitVote(filtered[i])
+		}
+		if err == ErrSkipButNoError {
+			err = nil
+		}
+		errs[indexes[i]] = err
+	}
+	return errs
+}
+
 func (mgr *agreementMgr) processBlock(b *types.Block) error {
 	if err := mgr.checkProposer(b.Position.Round, b.ProposerID); err != nil {
 		return err
@@ -379,8 +424,8 @@ func (mgr *agreementMgr) processAgreementResult(
 			return err
 		}
 		mgr.baModule.restart(
-			setting.dkgSet, setting.threshold,
-			result.Position, leader, setting.crs)
+			setting.dkgSet, setting.weights, setting.threshold,
+			setting.minVoters, result.Position, leader, setting.crs)
 		if result.Position.Round >= DKGDelayRound {
 			return mgr.baModule.processAgreementResult(result)
 		}
@@ -437,12 +482,29 @@ func (mgr *agreementMgr) generateSetting(round uint64) *baRoundSetting {
 			return nil
 		}
 	}
+	weights, err := mgr.cache.GetNotarySetWeights(round)
+	if err != nil {
+		mgr.logger.Error("Failed to get weights of notarySet",
+			"round", round,
+			"error", err)
+		return nil
+	}
+	var totalWeight uint64
+	for _, weight := range weights {
+		totalWeight += weight
+	}
 	setting := &baRoundSetting{
-		crs:    curConfig.crs,
-		dkgSet: dkgSet,
-		round:  round,
-		threshold: utils.GetBAThreshold(&types.Config{
-			NotarySetSize: curConfig.notarySetSize}),
+		crs:       curConfig.crs,
+		dkgSet:    dkgSet,
+		weights:   weights,
+		round:     round,
+		threshold: utils.GetBAWeightThreshold(totalWeight),
+	}
+	if round >= DKGDelayRound {
+		// Heavy-weight voters might reach the weight threshold with fewer
+		// partial signatures than required to recover randomness.
+		setting.minVoters = utils.GetDKGThreshold(&types.Config{
+			NotarySetSize: curConfig.notarySetSize})
 	}
 	mgr.settingCache.Add(round, setting)
 	return setting
@@ -457,7 +519,11 @@ func (mgr *agreementMgr) runBA(initRound uint64) {
 		setting      = &baRoundSetting{}
 		tickDuration time.Duration
 		ticker       Ticker
+		// Ticks, wake-ups and restart events are work in flight until
+		// handled, this routine is launched as one.
+		work = &workCounter{clock: mgr.clock, pending: 1}
 	)
+	defer work.done()
 
 	// Check if this routine needs to awake in this round and prepare essential
 	// variables when yes.
@@ -472,7 +538,7 @@ func (mgr *agreementMgr) runBA(initRound uint64) {
 				break
 			} else {
 				mgr.logger.Debug("Round is not ready", "round", nextRound)
-				time.Sleep(1 * time.Second)
+				work.sleep(1 * time.Second)
 			}
 		}
 		_, isDKG = setting.dkgSet[mgr.ID]
@@ -490,7 +556,15 @@ func (mgr *agreementMgr) runBA(initRound uint64) {
 			if ticker != nil {
 				ticker.Stop()
 			}
-			ticker = newTicker(mgr.gov, nextRound, TickerBA)
+			var err error
+			if ticker, err = newTicker(
+				mgr.gov, mgr.clock, nextRound, TickerBA); err != nil {
+				// The setting is ready, lambdaBA of this round is known.
+				mgr.logger.Warn("Failed to setup ticker, use default one",
+					"round", nextRound,
+					"error", err)
+				ticker = newDefaultTicker(mgr.clock, curConfig.lambdaBA)
+			}
 			tickDuration = curConfig.lambdaBA
 		}
 		setting.ticker = ticker
@@ -520,11 +594,12 @@ Loop:
 			mgr.recv.psigSigner = nil
 		}
 		// Run BA for this round.
+		addWork(mgr.clock, 1)
 		mgr.recv.restartNotary <- types.Position{
 			Round:  currentRound,
 			Height: math.MaxUint64,
 		}
-		if err := mgr.baRoutineForOneRound(setting); err != nil {
+		if err := mgr.baRoutineForOneRound(setting, work); err != nil {
 			mgr.logger.Error("BA routine failed",
 				"error", err,
 				"nodeID", mgr.ID)
@@ -534,10 +609,15 @@ Loop:
 }
 
 func (mgr *agreementMgr) baRoutineForOneRound(
-	setting *baRoundSetting) (err error) {
+	setting *baRoundSetting, work *workCounter) (err error) {
 	agr := mgr.baModule
 	recv := mgr.recv
 	oldPos := agr.agreementID()
+	// The confirmation of current position should only be measured once.
+	var (
+		beginTime time.Time
+		measured  = true
+	)
 	restart := func(restartPos types.Position) (breakLoop bool, err error) {
 		if !isStop(restartPos) {
 			if restartPos.Height+1 >= mgr.config(setting.round).RoundEndHeight() {
@@ -555,7 +635,7 @@ func (mgr *agreementMgr) baRoutineForOneRound(
 							"curRound", setting.round,
 							"tipRound", tipRound)
 					}
-					time.Sleep(100 * time.Millisecond)
+					work.sleep(100 * time.Millisecond)
 				}
 				// This round is finished.
 				breakLoop = true
@@ -588,7 +668,7 @@ func (mgr *agreementMgr) baRoutineForOneRound(
 			}
 			mgr.logger.Debug("BlockChain not ready!!!",
 				"old", oldPos, "restart", restartPos, "next", nextHeight)
-			time.Sleep(100 * time.Millisecond)
+			work.sleep(100 * time.Millisecond)
 		}
 		nextPos := types.Position{
 			Round:  setting.round,
@@ -600,9 +680,14 @@ func (mgr *agreementMgr) baRoutineForOneRound(
 		if err != nil {
 			return
 		}
-		time.Sleep(nextTime.Sub(time.Now()))
+		work.sleep(nextTime.Sub(mgr.clock.Now()))
 		setting.ticker.Restart()
-		agr.restart(setting.dkgSet, setting.threshold, nextPos, leader, setting.crs)
+		prevState := agr.stateType()
+		agr.restart(setting.dkgSet, setting.weights, setting.threshold,
+			setting.minVoters, nextPos, leader, setting.crs)
+		beginTime, measured = mgr.clock.Now(), false
+		mgr.con.events.emitBAState(nextPos, agr.period(), prevState,
+			agr.stateType())
 		return
 	}
 Loop:
@@ -613,9 +698,18 @@ Loop:
 		default:
 		}
 		if agr.confirmed() {
+			if !measured {
+				mgr.con.metrics.baPeriods.Observe(float64(agr.period()))
+				mgr.con.metrics.baConfirmLatency.Observe(
+					mgr.clock.Now().Sub(beginTime).Seconds())
+				mgr.con.metrics.baConfirmedBlocks.Inc()
+				measured = true
+			}
 			// Block until receive restartPos
+			work.done()
 			select {
 			case restartPos := <-recv.restartNotary:
+				work.add(1)
 				breakLoop, err := restart(restartPos)
 				if err != nil {
 					return err
@@ -629,6 +723,7 @@ Loop:
 		}
 		select {
 		case restartPos := <-recv.restartNotary:
+			work.add(1)
 			breakLoop, err := restart(restartPos)
 			if err != nil {
 				return err
@@ -639,19 +734,26 @@ Loop:
 		default:
 		}
 		if !mgr.recv.isNotary {
+			work.done()
 			select {
 			case <-setting.ticker.Tick():
+				work.addTick(setting.ticker)
 				continue Loop
 			case <-mgr.ctx.Done():
 				break Loop
 			}
 		}
+		prevState := agr.stateType()
 		if err = agr.nextState(); err != nil {
 			mgr.logger.Error("Failed to proceed to next state",
 				"nodeID", mgr.ID.String(),
 				"error", err)
 			break Loop
 		}
+		if curState := agr.stateType(); curState != prevState {
+			mgr.con.events.emitBAState(
+				agr.agreementID(), agr.period(), prevState, curState)
+		}
 		if agr.pullVotes() {
 			pos := agr.agreementID()
 			mgr.logger.Debug("Calling Network.PullVotes for syncing votes",
@@ -665,10 +767,12 @@ Loop:
 				continue Loop
 			default:
 			}
+			work.done()
 			select {
 			case <-agr.done():
 				continue Loop
 			case <-setting.ticker.Tick():
+				work.addTick(setting.ticker)
 			}
 		}
 	}
@@ -44,6 +44,28 @@ const (
 	stateSleep
 )
 
+func (s agreementStateType) String() string {
+	switch s {
+	case stateFast:
+		return "fast"
+	case stateFastVote:
+		return "fastVote"
+	case stateInitial:
+		return "initial"
+	case statePreCommit:
+		return "preCommit"
+	case stateCommit:
+		return "commit"
+	case stateForward:
+		return "forward"
+	case statePullVote:
+		return "pullVote"
+	case stateSleep:
+		return "sleep"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
 type agreementState interface {
 	state() agreementStateType
 	nextState() (agreementState, error)
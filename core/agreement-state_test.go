@@ -124,12 +124,13 @@ func (s *AgreementStateTestSuite) newAgreement(numNode int) *agreement {
 		},
 		leader,
 		s.signers[s.ID],
+		nil,
 		logger,
 	)
-	agreement.restart(notarySet,
-		utils.GetBAThreshold(&types.Config{
+	agreement.restart(notarySet, nil,
+		uint64(utils.GetBAThreshold(&types.Config{
 			NotarySetSize: uint32(len(notarySet)),
-		}),
+		})), 0,
 		types.Position{Height: types.GenesisHeight},
 		types.NodeID{}, common.NewRandomHash())
 	return agreement
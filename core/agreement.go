@@ -20,6 +20,7 @@ package core
 import (
 	"fmt"
 	"math"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -75,6 +76,21 @@ func newVoteListMap() []map[types.NodeID]*types.Vote {
 	return listMap
 }
 
+// batchPartialSignatureVerifier is an optional interface of
+// agreementReceiver to verify partial signatures of votes in batch. The
+// results of the i-th vote are returned in oks[i] and reports[i], which have
+// the same meanings as VerifyPartialSignature.
+type batchPartialSignatureVerifier interface {
+	VerifyPartialSignatures(votes []*types.Vote) (oks, reports []bool)
+}
+
+// voteJournal persists votes proposed by this node, thus the same votes would
+// be proposed again after crashing.
+type voteJournal interface {
+	GetVoteJournal(position types.Position) ([]types.Vote, error)
+	PutVoteJournal(vote types.Vote) error
+}
+
 // agreementReceiver is the interface receiving agreement event.
 type agreementReceiver interface {
 	ProposeVote(vote *types.Vote)
@@ -108,7 +124,9 @@ type agreementData struct {
 	lockValue    common.Hash
 	lockIter     uint64
 	period       uint64
-	requiredVote int
+	requiredVote uint64
+	minVoters    int
+	weights      map[types.NodeID]uint64
 	votes        map[uint64][]map[types.NodeID]*types.Vote
 	lock         sync.RWMutex
 	blocks       map[types.NodeID]*types.Block
@@ -131,6 +149,8 @@ type agreement struct {
 	candidateBlock         map[common.Hash]*types.Block
 	fastForward            chan uint64
 	signer                 *utils.Signer
+	journal                voteJournal
+	clock                  common.Clock
 	logger                 common.Logger
 }
 
@@ -140,6 +160,7 @@ func newAgreement(
 	recv agreementReceiver,
 	leader *leaderSelector,
 	signer *utils.Signer,
+	journal voteJournal,
 	logger common.Logger) *agreement {
 	agreement := &agreement{
 		data: &agreementData{
@@ -152,16 +173,21 @@ func newAgreement(
 		candidateBlock:         make(map[common.Hash]*types.Block),
 		fastForward:            make(chan uint64, 1),
 		signer:                 signer,
+		journal:                journal,
+		clock:                  &common.SystemClock{},
 		logger:                 logger,
 	}
 	agreement.stop()
 	return agreement
 }
 
-// restart the agreement
+// restart the agreement. Votes are counted by weights of their proposers,
+// nodes not found in weights are weighted by 1. Agreement is reached when the
+// accumulated weight of votes reaches threshold, and the count of voters
+// reaches minVoters.
 func (a *agreement) restart(
-	notarySet map[types.NodeID]struct{},
-	threshold int, aID types.Position, leader types.NodeID,
+	notarySet map[types.NodeID]struct{}, weights map[types.NodeID]uint64,
+	threshold uint64, minVoters int, aID types.Position, leader types.NodeID,
 	crs common.Hash) {
 	if !func() bool {
 		a.lock.Lock()
@@ -183,6 +209,8 @@ func (a *agreement) restart(
 		a.data.period = 2
 		a.data.blocks = make(map[types.NodeID]*types.Block)
 		a.data.requiredVote = threshold
+		a.data.minVoters = minVoters
+		a.data.weights = weights
 		a.data.leader.restart(crs)
 		a.data.lockValue = types.SkipBlockHash
 		a.data.lockIter = 0
@@ -226,7 +254,7 @@ func (a *agreement) restart(
 		a.pendingAgreementResult = newPendingAgreementResult
 	}()
 
-	expireTime := time.Now().Add(-10 * time.Second)
+	expireTime := a.clock.Now().Add(-10 * time.Second)
 	replayBlock := make([]*types.Block, 0)
 	func() {
 		a.lock.Lock()
@@ -249,6 +277,18 @@ func (a *agreement) restart(
 	}()
 
 	replayVote := make([]*types.Vote, 0)
+	if a.journal != nil {
+		// Replay votes proposed before crashing.
+		journaled, err := a.journal.GetVoteJournal(aID)
+		if err != nil {
+			a.logger.Error("Failed to get vote journal",
+				"position", aID,
+				"error", err)
+		}
+		for idx := range journaled {
+			replayVote = append(replayVote, &journaled[idx])
+		}
+	}
 	func() {
 		a.lock.Lock()
 		defer a.lock.Unlock()
@@ -290,7 +330,7 @@ func (a *agreement) restart(
 }
 
 func (a *agreement) stop() {
-	a.restart(make(map[types.NodeID]struct{}), int(math.MaxInt32),
+	a.restart(make(map[types.NodeID]struct{}), nil, math.MaxUint64, 0,
 		types.Position{
 			Height: math.MaxUint64,
 		},
@@ -345,6 +385,20 @@ func (a *agreement) leader() types.NodeID {
 	}).leader
 }
 
+// stateType returns the type of current state.
+func (a *agreement) stateType() agreementStateType {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.state.state()
+}
+
+// period returns the current period.
+func (a *agreement) period() uint64 {
+	a.data.lock.RLock()
+	defer a.data.lock.RUnlock()
+	return a.data.period
+}
+
 // nextState is called at the specific clock time.
 func (a *agreement) nextState() (err error) {
 	a.lock.Lock()
@@ -398,10 +452,29 @@ func (a *agreement) checkForkVote(vote *types.Vote) (
 	return
 }
 
-// prepareVote prepares a vote.
+// prepareVote prepares a vote. If a vote of the same period and type is
+// journaled, it would be reused instead, or this node might equivocate after
+// restarting.
 func (a *agreement) prepareVote(vote *types.Vote) (err error) {
 	vote.Position = a.agreementID()
-	err = a.signer.SignVote(vote)
+	if a.journal == nil {
+		err = a.signer.SignVote(vote)
+		return
+	}
+	journaled, err := a.journal.GetVoteJournal(vote.Position)
+	if err != nil {
+		return
+	}
+	for _, v := range journaled {
+		if v.Period == vote.Period && v.Type == vote.Type {
+			*vote = v
+			return
+		}
+	}
+	if err = a.signer.SignVote(vote); err != nil {
+		return
+	}
+	err = a.journal.PutVoteJournal(*vote)
 	return
 }
 
@@ -419,6 +492,75 @@ func (a *agreement) updateFilter(filter *utils.VoteFilter) {
 	filter.Position.Height = a.agreementID().Height
 }
 
+// batchSanityCheck performs the same checks as sanityCheck for a batch of
+// votes, the result of the i-th vote is stored in errs[i]. Vote signatures
+// are verified concurrently, and partial signatures are verified in batch if
+// the receiver supports it.
+func (a *agreement) batchSanityCheck(votes []*types.Vote, errs []error) {
+	indexes := make(chan int, len(votes))
+	for idx, vote := range votes {
+		if vote.Type >= types.MaxVoteType {
+			errs[idx] = ErrInvalidVote
+			continue
+		}
+		indexes <- idx
+	}
+	close(indexes)
+	workers := runtime.NumCPU()
+	if workers > len(indexes) {
+		workers = len(indexes)
+	}
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				ok, err := utils.VerifyVoteSignature(votes[idx])
+				if err != nil {
+					errs[idx] = err
+				} else if !ok {
+					errs[idx] = ErrIncorrectVoteSignature
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	round := a.agreementID().Round
+	psigVotes := make([]*types.Vote, 0, len(votes))
+	psigIndexes := make([]int, 0, len(votes))
+	for idx, vote := range votes {
+		if errs[idx] != nil || vote.Position.Round != round {
+			continue
+		}
+		psigVotes = append(psigVotes, vote)
+		psigIndexes = append(psigIndexes, idx)
+	}
+	if len(psigVotes) == 0 {
+		return
+	}
+	var oks, reports []bool
+	if verifier, ok := a.data.recv.(batchPartialSignatureVerifier); ok {
+		oks, reports = verifier.VerifyPartialSignatures(psigVotes)
+	} else {
+		oks = make([]bool, len(psigVotes))
+		reports = make([]bool, len(psigVotes))
+		for i, vote := range psigVotes {
+			oks[i], reports[i] = a.data.recv.VerifyPartialSignature(vote)
+		}
+	}
+	for i, idx := range psigIndexes {
+		if oks[i] {
+			continue
+		}
+		if reports[i] {
+			errs[idx] = ErrIncorrectVotePartialSignature
+		} else {
+			errs[idx] = ErrSkipButNoError
+		}
+	}
+}
+
 // processVote is the entry point for processing Vote.
 func (a *agreement) processVote(vote *types.Vote) error {
 	a.lock.Lock()
@@ -426,6 +568,29 @@ func (a *agreement) processVote(vote *types.Vote) error {
 	if err := a.sanityCheck(vote); err != nil {
 		return err
 	}
+	return a.processVerifiedVote(vote)
+}
+
+// processVotes processes a batch of votes, the error of the i-th vote is
+// returned in the i-th element. It's equivalent to calling processVote for
+// each vote, but signatures are verified in batch.
+func (a *agreement) processVotes(votes []*types.Vote) []error {
+	errs := make([]error, len(votes))
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.batchSanityCheck(votes, errs)
+	for idx, vote := range votes {
+		if errs[idx] != nil {
+			continue
+		}
+		errs[idx] = a.processVerifiedVote(vote)
+	}
+	return errs
+}
+
+// processVerifiedVote processes a vote passing sanity check, a.lock should
+// be held by the caller.
+func (a *agreement) processVerifiedVote(vote *types.Vote) error {
 	aID := a.agreementID()
 
 	// Agreement module has stopped.
@@ -434,7 +599,7 @@ func (a *agreement) processVote(vote *types.Vote) error {
 		if vote.Position.Round == aID.Round {
 			a.pendingVote = append(a.pendingVote, pendingVote{
 				vote:         vote,
-				receivedTime: time.Now().UTC(),
+				receivedTime: a.clock.Now().UTC(),
 			})
 			return nil
 		}
@@ -446,7 +611,7 @@ func (a *agreement) processVote(vote *types.Vote) error {
 		}
 		a.pendingVote = append(a.pendingVote, pendingVote{
 			vote:         vote,
-			receivedTime: time.Now().UTC(),
+			receivedTime: a.clock.Now().UTC(),
 		})
 		return nil
 	}
@@ -531,7 +696,7 @@ func (a *agreement) processVote(vote *types.Vote) error {
 	}
 	// Condition 3.
 	if vote.Type == types.VoteCom && vote.Period >= a.data.period &&
-		len(a.data.votes[vote.Period][types.VoteCom]) >= a.data.requiredVote {
+		a.data.reachThresholdNoLock(a.data.votes[vote.Period][types.VoteCom]) {
 		hashes := common.Hashes{}
 		addPullBlocks := func(voteType types.VoteType) {
 			for _, vote := range a.data.votes[vote.Period][voteType] {
@@ -671,7 +836,7 @@ func (a *agreement) processBlock(block *types.Block) error {
 	} else if aID != block.Position {
 		a.pendingBlock = append(a.pendingBlock, pendingBlock{
 			block:        block,
-			receivedTime: time.Now().UTC(),
+			receivedTime: a.clock.Now().UTC(),
 		})
 		return nil
 	} else if a.confirmedNoLock() {
@@ -692,7 +857,10 @@ func (a *agreement) processBlock(block *types.Block) error {
 	if block.ProposerID != a.data.ID &&
 		(a.state.state() == stateFast || a.state.state() == stateFastVote) &&
 		block.ProposerID == a.leader() {
+		addWork(a.clock, 1)
 		go func() {
+			work := &workCounter{clock: a.clock, pending: 1}
+			defer work.done()
 			for func() bool {
 				if aID != a.agreementID() {
 					return false
@@ -724,7 +892,7 @@ func (a *agreement) processBlock(block *types.Block) error {
 				return true
 			}() {
 				// TODO(jimmy): retry interval should be related to configurations.
-				time.Sleep(250 * time.Millisecond)
+				work.sleep(250 * time.Millisecond)
 			}
 		}()
 	}
@@ -770,15 +938,15 @@ func (a *agreementData) countVoteNoLock(
 	if !exist {
 		return
 	}
-	candidate := make(map[common.Hash]int)
+	candidate := make(map[common.Hash]uint64)
+	voters := make(map[common.Hash]int)
 	for _, vote := range votes[voteType] {
-		if _, exist := candidate[vote.BlockHash]; !exist {
-			candidate[vote.BlockHash] = 0
-		}
-		candidate[vote.BlockHash]++
+		candidate[vote.BlockHash] += a.weightNoLock(vote.ProposerID)
+		voters[vote.BlockHash]++
 	}
 	for candidateHash, votes := range candidate {
-		if votes >= a.requiredVote {
+		if votes >= a.requiredVote &&
+			voters[candidateHash] >= a.minVoters {
 			blockHash = candidateHash
 			ok = true
 			return
@@ -787,6 +955,28 @@ func (a *agreementData) countVoteNoLock(
 	return
 }
 
+// weightNoLock returns the voting power of a node.
+func (a *agreementData) weightNoLock(nID types.NodeID) uint64 {
+	if weight, exist := a.weights[nID]; exist {
+		return weight
+	}
+	return 1
+}
+
+// reachThresholdNoLock checks if the accumulated voting power and the count
+// of voters of votes reach the threshold.
+func (a *agreementData) reachThresholdNoLock(
+	votes map[types.NodeID]*types.Vote) bool {
+	if len(votes) < a.minVoters {
+		return false
+	}
+	var sum uint64
+	for nID := range votes {
+		sum += a.weightNoLock(nID)
+	}
+	return sum >= a.requiredVote
+}
+
 func (a *agreementData) setPeriod(period uint64) {
 	for i := a.period + 1; i <= period; i++ {
 		if _, exist := a.votes[i]; !exist {
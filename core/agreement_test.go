@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto/ecdsa"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 	"github.com/tangerine-network/tangerine-consensus/core/utils"
 )
@@ -109,6 +110,7 @@ type AgreementTestSuite struct {
 	agreement          []*agreement
 	agreementID        types.Position
 	defaultValidLeader validLeaderFn
+	journal            voteJournal
 }
 
 func (s *AgreementTestSuite) SetupTest() {
@@ -129,6 +131,7 @@ func (s *AgreementTestSuite) SetupTest() {
 	s.defaultValidLeader = func(*types.Block, common.Hash) (bool, error) {
 		return true, nil
 	}
+	s.journal = nil
 }
 
 func (s *AgreementTestSuite) newAgreement(
@@ -161,11 +164,12 @@ func (s *AgreementTestSuite) newAgreement(
 		},
 		leader,
 		s.signers[s.ID],
+		s.journal,
 		logger,
 	)
-	agreement.restart(notarySet, utils.GetBAThreshold(&types.Config{
-		NotarySetSize: uint32(len(notarySet)),
-	}), s.agreementID, leaderNode,
+	agreement.restart(notarySet, nil, uint64(utils.GetBAThreshold(
+		&types.Config{NotarySetSize: uint32(len(notarySet))})), 0,
+		s.agreementID, leaderNode,
 		common.NewRandomHash())
 	s.agreement = append(s.agreement, agreement)
 	return agreement, leaderNode
@@ -522,6 +526,58 @@ func (s *AgreementTestSuite) TestDecide() {
 	s.Equal(hash, confirmBlock)
 }
 
+func (s *AgreementTestSuite) TestWeightedDecide() {
+	a, _ := s.newAgreement(4, -1, s.defaultValidLeader)
+	a.data.period = 3
+	var heavy types.NodeID
+	for nID := range a.notarySet {
+		heavy = nID
+		break
+	}
+	// The total weight is 13, and the threshold is 9.
+	a.data.weights = map[types.NodeID]uint64{heavy: 10}
+	a.data.requiredVote = utils.GetBAWeightThreshold(13)
+	// Light nodes are not able to decide.
+	hash := common.NewRandomHash()
+	for nID := range a.notarySet {
+		if nID == heavy {
+			continue
+		}
+		vote := s.prepareVote(nID, types.VoteCom, hash, 3)
+		s.Require().NoError(a.processVote(vote))
+	}
+	s.Require().Len(s.confirmChan, 0)
+	// The heavy node is able to decide.
+	vote := s.prepareVote(heavy, types.VoteCom, hash, 3)
+	s.Require().NoError(a.processVote(vote))
+	s.Require().Len(s.confirmChan, 1)
+	s.Equal(hash, <-s.confirmChan)
+}
+
+func (s *AgreementTestSuite) TestWeightedDecideWithMinVoters() {
+	a, _ := s.newAgreement(7, -1, s.defaultValidLeader)
+	a.data.period = 3
+	nIDs := make(types.NodeIDs, 0, len(a.notarySet))
+	for nID := range a.notarySet {
+		nIDs = append(nIDs, nID)
+	}
+	// The total weight is 15, and the threshold is 11. Two heavy nodes reach
+	// the weight threshold, but not the count of voters required by TSig.
+	a.data.weights = map[types.NodeID]uint64{nIDs[0]: 5, nIDs[1]: 5}
+	a.data.requiredVote = utils.GetBAWeightThreshold(15)
+	a.data.minVoters = 5
+	hash := common.NewRandomHash()
+	for _, nID := range nIDs[:4] {
+		vote := s.prepareVote(nID, types.VoteCom, hash, 3)
+		s.Require().NoError(a.processVote(vote))
+	}
+	s.Require().Len(s.confirmChan, 0)
+	vote := s.prepareVote(nIDs[4], types.VoteCom, hash, 3)
+	s.Require().NoError(a.processVote(vote))
+	s.Require().Len(s.confirmChan, 1)
+	s.Equal(hash, <-s.confirmChan)
+}
+
 func (s *AgreementTestSuite) TestForkVote() {
 	a, _ := s.newAgreement(4, -1, s.defaultValidLeader)
 	a.data.period = 2
@@ -578,6 +634,142 @@ func (s *AgreementTestSuite) TestConfirmWithBlock() {
 	s.True(a.confirmed())
 }
 
+func (s *AgreementTestSuite) TestProcessVotes() {
+	a, _ := s.newAgreement(4, -1, s.defaultValidLeader)
+	a.data.period = 3
+	hash := common.NewRandomHash()
+	votes := []*types.Vote{}
+	for nID := range a.notarySet {
+		votes = append(votes, s.prepareVote(nID, types.VoteCom, hash, 3))
+	}
+	// Tamper one vote after signing.
+	tampered := votes[1].Clone()
+	tampered.BlockHash = common.NewRandomHash()
+	votes = append(votes, tampered)
+	// Vote with unknown type.
+	invalid := votes[0].Clone()
+	invalid.Type = types.MaxVoteType
+	votes = append(votes, invalid)
+	errs := a.processVotes(votes)
+	s.Require().Len(errs, len(votes))
+	for i := 0; i < 4; i++ {
+		s.NoError(errs[i])
+	}
+	s.Equal(ErrIncorrectVoteSignature, errs[4])
+	s.Equal(ErrInvalidVote, errs[5])
+	s.Require().Len(s.confirmChan, 1)
+	s.Equal(hash, <-s.confirmChan)
+}
+
+func (s *AgreementTestSuite) TestRestartWithVoteJournal() {
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	s.journal = dbInst
+	a, _ := s.newAgreement(4, -1, s.defaultValidLeader)
+	a.data.period = 3
+	hash := common.NewRandomHash()
+	vote := types.NewVote(types.VotePreCom, hash, 3)
+	s.Require().NoError(a.prepareVote(vote))
+	s.Require().NoError(a.processVote(vote))
+	// Crash in the middle of period 3 and restart with the same journal.
+	logger := &common.NullLogger{}
+	restarted := newAgreement(
+		s.ID,
+		&agreementTestReceiver{
+			s:              s,
+			agreementIndex: len(s.agreement),
+		},
+		newLeaderSelector(s.defaultValidLeader, logger),
+		s.signers[s.ID],
+		dbInst,
+		logger,
+	)
+	s.agreement = append(s.agreement, restarted)
+	restarted.restart(a.notarySet, nil, a.data.requiredVote, 0,
+		s.agreementID, types.NodeID{}, a.data.leader.hashCRS)
+	// The journaled vote should be replayed.
+	replayed, exist := restarted.data.votes[3][types.VotePreCom][s.ID]
+	s.Require().True(exist)
+	s.Equal(hash, replayed.BlockHash)
+	// Proposing a different vote in the same period and type would end up
+	// with the journaled one.
+	another := types.NewVote(types.VotePreCom, common.NewRandomHash(), 3)
+	s.Require().NoError(restarted.prepareVote(another))
+	s.Equal(vote.VoteHeader, another.VoteHeader)
+	s.Equal(vote.Signature, another.Signature)
+	// Votes of other types should be journaled.
+	com := types.NewVote(types.VoteCom, hash, 3)
+	s.Require().NoError(restarted.prepareVote(com))
+	journaled, err := dbInst.GetVoteJournal(s.agreementID)
+	s.Require().NoError(err)
+	s.Require().Len(journaled, 2)
+	s.Equal(com.VoteHeader, journaled[1].VoteHeader)
+}
+
 func TestAgreement(t *testing.T) {
 	suite.Run(t, new(AgreementTestSuite))
 }
+
+// agreementBenchReceiver implements core.agreementReceiver.
+type agreementBenchReceiver struct{}
+
+func (r *agreementBenchReceiver) VerifyPartialSignature(*types.Vote) (
+	bool, bool) {
+	return true, false
+}
+func (r *agreementBenchReceiver) ProposeVote(*types.Vote)           {}
+func (r *agreementBenchReceiver) ProposeBlock() common.Hash         { return common.Hash{} }
+func (r *agreementBenchReceiver) PullBlocks(common.Hashes)          {}
+func (r *agreementBenchReceiver) ReportForkVote(_, _ *types.Vote)   {}
+func (r *agreementBenchReceiver) ReportForkBlock(_, _ *types.Block) {}
+func (r *agreementBenchReceiver) ConfirmBlock(
+	common.Hash, map[types.NodeID]*types.Vote) {
+}
+
+func BenchmarkProcessVotes100(b *testing.B) { benchmarkProcessVotes(b, 100) }
+
+func benchmarkProcessVotes(b *testing.B, n int) {
+	notarySet := make(map[types.NodeID]struct{}, n)
+	votes := make([]*types.Vote, 0, n)
+	hash := common.NewRandomHash()
+	var signer *utils.Signer
+	for i := 0; i < n; i++ {
+		prvKey, err := ecdsa.NewPrivateKey()
+		if err != nil {
+			b.Fatal(err)
+		}
+		signer = utils.NewSigner(prvKey)
+		vote := types.NewVote(types.VotePreCom, hash, 1)
+		vote.Position = types.Position{Height: types.GenesisHeight}
+		if err = signer.SignVote(vote); err != nil {
+			b.Fatal(err)
+		}
+		votes = append(votes, vote)
+		notarySet[vote.ProposerID] = struct{}{}
+	}
+	a := newAgreement(votes[0].ProposerID, &agreementBenchReceiver{},
+		newLeaderSelector(nil, &common.NullLogger{}), signer, nil,
+		&common.NullLogger{})
+	a.restart(notarySet, nil, uint64(utils.GetBAThreshold(&types.Config{
+		NotarySetSize: uint32(n),
+	})), 0, types.Position{Height: types.GenesisHeight}, votes[0].ProposerID,
+		common.NewRandomHash())
+	b.Run("Single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, vote := range votes {
+				if err := a.processVote(vote); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, err := range a.processVotes(votes) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
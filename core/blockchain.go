@@ -140,6 +140,7 @@ type blockChain struct {
 	vGetter             tsigVerifierGetter
 	app                 Application
 	logger              common.Logger
+	metrics             *consensusMetrics
 	pendingRandomnesses map[types.Position][]byte
 	configs             []blockChainConfig
 	pendingBlocks       pendingBlockRecords
@@ -161,6 +162,7 @@ func newBlockChain(nID types.NodeID, dMoment time.Time, initBlock *types.Block,
 		vGetter:       vGetter,
 		app:           app,
 		logger:        logger,
+		metrics:       newConsensusMetrics(nil),
 		dMoment:       dMoment,
 		pendingRandomnesses: make(
 			map[types.Position][]byte),
@@ -454,6 +456,9 @@ func (bc *blockChain) findPendingBlock(p types.Position) *types.Block {
 }
 
 func (bc *blockChain) addPendingBlockRecord(p pendingBlockRecord) error {
+	defer func() {
+		bc.metrics.pendingBlocks.Set(float64(len(bc.pendingBlocks)))
+	}()
 	if err := bc.pendingBlocks.insert(p); err != nil {
 		if err == ErrDuplicatedPendingBlock {
 			// We need to ignore this error because BA might confirm duplicated
@@ -467,6 +472,9 @@ func (bc *blockChain) addPendingBlockRecord(p pendingBlockRecord) error {
 
 func (bc *blockChain) checkIfBlocksConfirmed() {
 	var err error
+	defer func() {
+		bc.metrics.pendingBlocks.Set(float64(len(bc.pendingBlocks)))
+	}()
 	for len(bc.pendingBlocks) > 0 {
 		if bc.pendingBlocks[0].position.Height <
 			bc.lastConfirmed.Position.Height+1 {
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -287,6 +288,29 @@ func (s *BlockChainTestSuite) TestBasicUsage() {
 	s.Require().Len(extracted, 0)
 }
 
+func (s *BlockChainTestSuite) TestPendingBlocksMetrics() {
+	var (
+		initBlock = s.newRoundOneInitBlock()
+		bc        = s.newBlockChain(initBlock, 10)
+		metrics   = common.NewTextMetrics("", nil)
+		blocks    = s.newBlocks(3, initBlock)
+	)
+	bc.metrics.setup(metrics)
+	dump := func() string {
+		buf := &bytes.Buffer{}
+		_, err := metrics.WriteTo(buf)
+		s.Require().NoError(err)
+		return buf.String()
+	}
+	// Blocks with newer positions would be kept in pending queue.
+	s.Require().NoError(bc.addBlock(blocks[2]))
+	s.Require().NoError(bc.addBlock(blocks[1]))
+	s.Require().Contains(dump(), MetricPendingBlocks+" 2\n")
+	// Once the gap is filled, all of them are confirmed.
+	s.Require().NoError(bc.addBlock(blocks[0]))
+	s.Require().Contains(dump(), MetricPendingBlocks+" 0\n")
+}
+
 func (s *BlockChainTestSuite) TestConcurrentAccess() {
 	// Raise one go routine for each block and randomness. And let them try to
 	// add to blockChain at the same time. Make sure we can delivered them all.
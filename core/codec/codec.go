@@ -0,0 +1,362 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+// Package codec implements the binary wire format for messages sent through
+// core.Network.
+//
+// Each encoded message is formed by:
+//   - 1 byte for the version of wire format.
+//   - 1 byte for the type of message.
+//   - the RLP encoded form of the message.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+)
+
+// Version is the version of wire format produced by this package.
+const Version byte = 1
+
+// headerSize is the size of version and message type.
+const headerSize = 2
+
+// Errors for codec.
+var (
+	// ErrMessageTooShort is reported when the message is shorter than the
+	// header.
+	ErrMessageTooShort = errors.New("message too short")
+	// ErrUnsupportedVersion is reported when the version of wire format is
+	// not supported.
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	// ErrUnknownMsgType is reported when decoding a message with an
+	// unregistered type.
+	ErrUnknownMsgType = errors.New("unknown message type")
+	// ErrUnregisteredMessage is reported when encoding a message whose go
+	// type is not registered.
+	ErrUnregisteredMessage = errors.New("unregistered message")
+	// ErrMessageTooLarge is reported when the size of message exceeds the
+	// limit of its type.
+	ErrMessageTooLarge = errors.New("message too large")
+	// ErrMsgTypeRegistered is reported when registering a type twice.
+	ErrMsgTypeRegistered = errors.New("message type already registered")
+	// ErrInvalidMessage is reported when the decoded message is malformed.
+	ErrInvalidMessage = errors.New("invalid message")
+)
+
+// MsgType is the type of message on wire.
+type MsgType byte
+
+// MsgType enum for messages sent through core.Network.
+const (
+	MsgTypeVote MsgType = iota + 1
+	MsgTypeBlock
+	MsgTypeAgreementResult
+	MsgTypeDKGPrivateShare
+	MsgTypeDKGPartialSignature
+	MsgTypePullBlocks
+	MsgTypePullVotes
+	MsgTypePullBlockRange
+	MsgTypeBlockRange
+)
+
+// MsgTypeCustom is the smallest type available for messages not defined in
+// this package, ex. the peer list of some network implementation.
+const MsgTypeCustom MsgType = 0x80
+
+// Size limits of RLP encoded messages, in bytes.
+const (
+	MaxVoteSize             = 4 * 1024
+	MaxBlockSize            = 16 * 1024 * 1024
+	MaxAgreementResultSize  = 1024 * 1024
+	MaxDKGPrivateShareSize  = 4 * 1024
+	MaxDKGPartialSigSize    = 4 * 1024
+	MaxPullBlocksSize       = 64 * 1024
+	MaxPullVotesSize        = 1024
+	MaxPullBlockRangeSize   = 1024
+	MaxBlockRangeSize       = MaxBlockSize
+	maxRegisteredNameLength = 64
+)
+
+// PullBlocksRequest asks peers for blocks by hashes.
+type PullBlocksRequest struct {
+	Requester types.NodeID
+	Hashes    common.Hashes
+}
+
+// PullVotesRequest asks peers for votes of one position.
+type PullVotesRequest struct {
+	Requester types.NodeID
+	Position  types.Position
+}
+
+// PullBlockRangeRequest asks peers for finalized blocks with height in
+// [From, To).
+type PullBlockRangeRequest struct {
+	Requester types.NodeID
+	From      uint64
+	To        uint64
+}
+
+// Validator is an optional interface for registered messages, Validate would
+// be called after decoding.
+type Validator interface {
+	Validate() error
+}
+
+type registration struct {
+	msgType  MsgType
+	name     string
+	goType   reflect.Type
+	maxSize  int
+	validate func(msg interface{}) error
+}
+
+// Codec encodes and decodes registered messages. It's safe for concurrent
+// use.
+type Codec struct {
+	lock     sync.RWMutex
+	byType   map[MsgType]*registration
+	byGoType map[reflect.Type]*registration
+}
+
+// NewCodec constructs a Codec instance with all messages sent through
+// core.Network registered.
+func NewCodec() *Codec {
+	c := &Codec{
+		byType:   make(map[MsgType]*registration),
+		byGoType: make(map[reflect.Type]*registration),
+	}
+	builtins := []struct {
+		msgType  MsgType
+		name     string
+		sample   interface{}
+		maxSize  int
+		validate func(msg interface{}) error
+	}{
+		{MsgTypeVote, "vote", &types.Vote{}, MaxVoteSize, validateVote},
+		{MsgTypeBlock, "block", &types.Block{}, MaxBlockSize, nil},
+		{MsgTypeAgreementResult, "agreement-result", &types.AgreementResult{},
+			MaxAgreementResultSize, validateAgreementResult},
+		{MsgTypeDKGPrivateShare, "dkg-private-share", &typesDKG.PrivateShare{},
+			MaxDKGPrivateShareSize, nil},
+		{MsgTypeDKGPartialSignature, "dkg-partial-signature",
+			&typesDKG.PartialSignature{}, MaxDKGPartialSigSize, nil},
+		{MsgTypePullBlocks, "pull-blocks", &PullBlocksRequest{},
+			MaxPullBlocksSize, validatePullBlocks},
+		{MsgTypePullVotes, "pull-votes", &PullVotesRequest{},
+			MaxPullVotesSize, nil},
+		{MsgTypePullBlockRange, "pull-block-range", &PullBlockRangeRequest{},
+			MaxPullBlockRangeSize, validatePullBlockRange},
+		{MsgTypeBlockRange, "block-range", &types.BlockRange{},
+			MaxBlockRangeSize, validateBlockRange},
+	}
+	for _, b := range builtins {
+		if err := c.register(
+			b.msgType, b.name, b.sample, b.maxSize, b.validate); err != nil {
+			panic(err)
+		}
+	}
+	return c
+}
+
+// Register registers a message type not defined in this package. 'sample'
+// should be a pointer to a zero value of that message, and 't' should not be
+// less than MsgTypeCustom.
+func (c *Codec) Register(
+	t MsgType, name string, sample interface{}, maxSize int) error {
+	if t < MsgTypeCustom {
+		return fmt.Errorf("message type %d is reserved", t)
+	}
+	return c.register(t, name, sample, maxSize, nil)
+}
+
+func (c *Codec) register(t MsgType, name string, sample interface{},
+	maxSize int, validate func(interface{}) error) error {
+	goType := reflect.TypeOf(sample)
+	if goType == nil || goType.Kind() != reflect.Ptr {
+		return fmt.Errorf("sample of %s should be a pointer: %T", name, sample)
+	}
+	if len(name) == 0 || len(name) > maxRegisteredNameLength {
+		return fmt.Errorf("invalid name of message type: %s", name)
+	}
+	if maxSize <= 0 {
+		return fmt.Errorf("invalid size limit of %s: %d", name, maxSize)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, exist := c.byType[t]; exist {
+		return ErrMsgTypeRegistered
+	}
+	if _, exist := c.byGoType[goType]; exist {
+		return ErrMsgTypeRegistered
+	}
+	reg := &registration{
+		msgType:  t,
+		name:     name,
+		goType:   goType,
+		maxSize:  maxSize,
+		validate: validate,
+	}
+	c.byType[t] = reg
+	c.byGoType[goType] = reg
+	return nil
+}
+
+// Name returns the registered name of a message type.
+func (c *Codec) Name(t MsgType) (string, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	reg, exist := c.byType[t]
+	if !exist {
+		return "", false
+	}
+	return reg.name, true
+}
+
+// MaxMessageSize returns the size of the largest message this codec would
+// accept, including the header.
+func (c *Codec) MaxMessageSize() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	max := 0
+	for _, reg := range c.byType {
+		if reg.maxSize > max {
+			max = reg.maxSize
+		}
+	}
+	return max + headerSize
+}
+
+// Encode encodes a registered message.
+func (c *Codec) Encode(msg interface{}) ([]byte, error) {
+	reg := func() *registration {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		return c.byGoType[reflect.TypeOf(msg)]
+	}()
+	if reg == nil {
+		return nil, ErrUnregisteredMessage
+	}
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > reg.maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	b := make([]byte, headerSize+len(payload))
+	b[0] = Version
+	b[1] = byte(reg.msgType)
+	copy(b[headerSize:], payload)
+	return b, nil
+}
+
+// Decode decodes a message encoded by Encode. The size limit is checked
+// before decoding the payload, and messages failed to pass validation are
+// rejected.
+func (c *Codec) Decode(b []byte) (interface{}, error) {
+	if len(b) < headerSize {
+		return nil, ErrMessageTooShort
+	}
+	if b[0] != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	reg := func() *registration {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		return c.byType[MsgType(b[1])]
+	}()
+	if reg == nil {
+		return nil, ErrUnknownMsgType
+	}
+	if len(b)-headerSize > reg.maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	msg := reflect.New(reg.goType.Elem()).Interface()
+	if err := rlp.DecodeBytes(b[headerSize:], msg); err != nil {
+		return nil, err
+	}
+	if reg.validate != nil {
+		if err := reg.validate(msg); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := msg.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+func validateVote(msg interface{}) error {
+	if msg.(*types.Vote).Type >= types.MaxVoteType {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+func validateAgreementResult(msg interface{}) error {
+	result := msg.(*types.AgreementResult)
+	for i := range result.Votes {
+		if err := validateVote(&result.Votes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePullBlocks(msg interface{}) error {
+	if len(msg.(*PullBlocksRequest).Hashes) == 0 {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+func validatePullBlockRange(msg interface{}) error {
+	if req := msg.(*PullBlockRangeRequest); req.From >= req.To {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+func validateBlockRange(msg interface{}) error {
+	r := msg.(*types.BlockRange)
+	if r.From >= r.To || len(r.Blocks) > int(r.To-r.From) ||
+		len(r.Certificates) > len(r.Blocks) {
+		return ErrInvalidMessage
+	}
+	for _, b := range r.Blocks {
+		if b == nil {
+			return ErrInvalidMessage
+		}
+	}
+	for _, cert := range r.Certificates {
+		if cert == nil {
+			return ErrInvalidMessage
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package codec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	cryptoDKG "github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+)
+
+type CodecTestSuite struct {
+	suite.Suite
+}
+
+func (s *CodecTestSuite) newVote() *types.Vote {
+	vote := types.NewVote(types.VoteCom, common.NewRandomHash(), 3)
+	vote.ProposerID = types.NodeID{Hash: common.NewRandomHash()}
+	vote.Position = types.Position{Round: 1, Height: 100}
+	vote.PartialSignature = cryptoDKG.PartialSignature{
+		Type:      "bls",
+		Signature: common.GenerateRandomBytes(),
+	}
+	vote.Signature = crypto.Signature{
+		Type:      "ecdsa",
+		Signature: common.GenerateRandomBytes(),
+	}
+	return vote
+}
+
+func (s *CodecTestSuite) TestRoundTrip() {
+	c := NewCodec()
+	payload := common.GenerateRandomBytes()
+	msgs := []interface{}{
+		s.newVote(),
+		&types.Block{
+			ProposerID: types.NodeID{Hash: common.NewRandomHash()},
+			ParentHash: common.NewRandomHash(),
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Round: 1, Height: 101},
+			Timestamp:  time.Now().UTC(),
+			Payload:    payload,
+			Witness: types.Witness{
+				Height: 99,
+				Data:   common.GenerateRandomBytes(),
+			},
+			PayloadHash: crypto.Keccak256Hash(payload),
+		},
+		&types.AgreementResult{
+			BlockHash:  common.NewRandomHash(),
+			Position:   types.Position{Round: 1, Height: 100},
+			Votes:      []types.Vote{*s.newVote(), *s.newVote()},
+			Randomness: common.GenerateRandomBytes(),
+		},
+		&typesDKG.PrivateShare{
+			ProposerID:   types.NodeID{Hash: common.NewRandomHash()},
+			ReceiverID:   types.NodeID{Hash: common.NewRandomHash()},
+			Round:        2,
+			Reset:        1,
+			PrivateShare: *cryptoDKG.NewPrivateKey(),
+		},
+		&typesDKG.PartialSignature{
+			ProposerID: types.NodeID{Hash: common.NewRandomHash()},
+			Round:      2,
+			Hash:       common.NewRandomHash(),
+		},
+		&PullBlocksRequest{
+			Requester: types.NodeID{Hash: common.NewRandomHash()},
+			Hashes:    common.Hashes{common.NewRandomHash()},
+		},
+		&PullVotesRequest{
+			Requester: types.NodeID{Hash: common.NewRandomHash()},
+			Position:  types.Position{Round: 1, Height: 100},
+		},
+		&PullBlockRangeRequest{
+			Requester: types.NodeID{Hash: common.NewRandomHash()},
+			From:      100,
+			To:        200,
+		},
+		&types.BlockRange{
+			From: 100,
+			To:   200,
+			Blocks: []*types.Block{{
+				ProposerID: types.NodeID{Hash: common.NewRandomHash()},
+				ParentHash: common.NewRandomHash(),
+				Hash:       common.NewRandomHash(),
+				Position:   types.Position{Round: 1, Height: 100},
+				Timestamp:  time.Now().UTC(),
+				Randomness: common.GenerateRandomBytes(),
+			}},
+			Certificates: []*types.AgreementCertificate{{
+				BlockHash: common.NewRandomHash(),
+				Position:  types.Position{Round: 1, Height: 100},
+				Signers:   []byte{0x7},
+				Signature: common.GenerateRandomBytes(),
+			}},
+		},
+	}
+	for _, msg := range msgs {
+		b, err := c.Encode(msg)
+		s.Require().NoError(err)
+		s.Require().Equal(Version, b[0])
+		decoded, err := c.Decode(b)
+		s.Require().NoError(err)
+		s.Require().IsType(msg, decoded)
+		// Compare the encoded form, some fields (ex. time.Time) are not
+		// comparable after decoding.
+		b2, err := c.Encode(decoded)
+		s.Require().NoError(err)
+		s.Require().Equal(b, b2)
+	}
+	// Messages not registered are not encodable.
+	_, err := c.Encode(&typesDKG.Complaint{})
+	s.Require().Equal(ErrUnregisteredMessage, err)
+}
+
+func (s *CodecTestSuite) TestMalformed() {
+	c := NewCodec()
+	b, err := c.Encode(s.newVote())
+	s.Require().NoError(err)
+	_, err = c.Decode(b[:1])
+	s.Require().Equal(ErrMessageTooShort, err)
+	// Unsupported version.
+	corrupted := append([]byte{}, b...)
+	corrupted[0] = Version + 1
+	_, err = c.Decode(corrupted)
+	s.Require().Equal(ErrUnsupportedVersion, err)
+	// Unknown type.
+	corrupted = append([]byte{}, b...)
+	corrupted[1] = byte(MsgTypeCustom)
+	_, err = c.Decode(corrupted)
+	s.Require().Equal(ErrUnknownMsgType, err)
+	// Trailing bytes.
+	_, err = c.Decode(append(append([]byte{}, b...), 0))
+	s.Require().Error(err)
+	// Truncated payload.
+	_, err = c.Decode(b[:len(b)-1])
+	s.Require().Error(err)
+	// Oversized message is rejected before decoding.
+	oversized := make([]byte, headerSize+MaxVoteSize+1)
+	oversized[0], oversized[1] = Version, byte(MsgTypeVote)
+	_, err = c.Decode(oversized)
+	s.Require().Equal(ErrMessageTooLarge, err)
+	// Vote with invalid type.
+	vote := s.newVote()
+	vote.Type = types.MaxVoteType
+	payload, err := rlp.EncodeToBytes(vote)
+	s.Require().NoError(err)
+	_, err = c.Decode(append([]byte{Version, byte(MsgTypeVote)}, payload...))
+	s.Require().Equal(ErrInvalidMessage, err)
+	// Pulling nothing.
+	b, err = c.Encode(&PullBlocksRequest{})
+	s.Require().NoError(err)
+	_, err = c.Decode(b)
+	s.Require().Equal(ErrInvalidMessage, err)
+	b, err = c.Encode(&PullBlockRangeRequest{From: 10, To: 10})
+	s.Require().NoError(err)
+	_, err = c.Decode(b)
+	s.Require().Equal(ErrInvalidMessage, err)
+	// More agreement certificates than blocks.
+	b, err = c.Encode(&types.BlockRange{
+		From:   10,
+		To:     20,
+		Blocks: []*types.Block{{Position: types.Position{Height: 10}}},
+		Certificates: []*types.AgreementCertificate{
+			{Position: types.Position{Height: 10}},
+			{Position: types.Position{Height: 11}},
+		},
+	})
+	s.Require().NoError(err)
+	_, err = c.Decode(b)
+	s.Require().Equal(ErrInvalidMessage, err)
+}
+
+type customMessage struct {
+	Data []byte
+}
+
+func (m *customMessage) Validate() error {
+	if len(m.Data) == 0 {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+func (s *CodecTestSuite) TestRegister() {
+	c := NewCodec()
+	s.Require().Error(c.Register(MsgTypeVote, "mine", &customMessage{}, 10))
+	s.Require().Error(c.Register(MsgTypeCustom, "mine", customMessage{}, 10))
+	s.Require().NoError(c.Register(MsgTypeCustom, "mine", &customMessage{}, 10))
+	s.Require().Equal(ErrMsgTypeRegistered,
+		c.Register(MsgTypeCustom, "mine", &customMessage{}, 10))
+	name, exist := c.Name(MsgTypeCustom)
+	s.Require().True(exist)
+	s.Require().Equal("mine", name)
+	b, err := c.Encode(&customMessage{Data: []byte{1, 2, 3}})
+	s.Require().NoError(err)
+	msg, err := c.Decode(b)
+	s.Require().NoError(err)
+	s.Require().Equal([]byte{1, 2, 3}, msg.(*customMessage).Data)
+	// Exceeds size limit.
+	_, err = c.Encode(&customMessage{Data: make([]byte, 10)})
+	s.Require().Equal(ErrMessageTooLarge, err)
+	// Custom validation.
+	b, err = c.Encode(&customMessage{})
+	s.Require().NoError(err)
+	_, err = c.Decode(b)
+	s.Require().Equal(ErrInvalidMessage, err)
+}
+
+func TestCodec(t *testing.T) {
+	suite.Run(t, new(CodecTestSuite))
+}
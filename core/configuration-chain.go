@@ -67,6 +67,9 @@ type configurationChain struct {
 	dkg             *dkgProtocol
 	dkgRunPhases    []dkgStepFn
 	logger          common.Logger
+	clock           common.Clock
+	metrics         *consensusMetrics
+	events          *eventHub
 	dkgLock         sync.RWMutex
 	dkgSigner       map[uint64]*dkgShareSecret
 	npks            map[uint64]*typesDKG.NodePublicKeys
@@ -100,6 +103,9 @@ func newConfigurationChain(
 		recv:        recv,
 		gov:         gov,
 		logger:      logger,
+		clock:       &common.SystemClock{},
+		metrics:     newConsensusMetrics(nil),
+		events:      newEventHub(),
 		dkgSigner:   make(map[uint64]*dkgShareSecret),
 		npks:        make(map[uint64]*typesDKG.NodePublicKeys),
 		tsig:        make(map[common.Hash]*tsigProtocol),
@@ -158,7 +164,7 @@ func (cc *configurationChain) abortDKGNoLock(
 		select {
 		case <-ctx.Done():
 			return false
-		case <-time.After(100 * time.Millisecond):
+		case <-cc.clock.After(100 * time.Millisecond):
 		}
 		cc.dkgLock.Unlock()
 	}
@@ -204,10 +210,16 @@ func (cc *configurationChain) registerDKG(
 	cc.pendingPrvShare = make(map[types.NodeID]*typesDKG.PrivateShare)
 	cc.mpkReady = false
 	cc.dkg, err = recoverDKGProtocol(cc.ID, cc.recv, round, reset, cc.db)
-	cc.dkgCtx, cc.dkgCtxCancel = context.WithCancel(parentCtx)
 	if err != nil {
-		panic(err)
+		// The DKG key material might be unavailable temporarily, ex. the
+		// keystore keeping it is locked, skip this DKG instead of crashing.
+		cc.logger.Error("Failed to recover DKG protocol",
+			"round", round,
+			"reset", reset,
+			"error", err)
+		return
 	}
+	cc.dkgCtx, cc.dkgCtxCancel = context.WithCancel(parentCtx)
 	if cc.dkg == nil {
 		cc.dkg = newDKGProtocol(
 			cc.ID,
@@ -224,16 +236,38 @@ func (cc *configurationChain) registerDKG(
 		}
 	}
 
-	go func() {
-		ticker := newTicker(cc.gov, round, TickerDKG)
+	go func(ctx context.Context) {
+		var (
+			ticker Ticker
+			err    error
+		)
+		// Governance might be slow, retry until this DKG is canceled.
+		for {
+			if ticker, err = newTicker(
+				cc.gov, cc.clock, round, TickerDKG); err == nil {
+				break
+			}
+			cc.logger.Warn("Failed to setup DKG ticker",
+				"round", round,
+				"reset", reset,
+				"error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-cc.clock.After(time.Second):
+			}
+		}
 		defer ticker.Stop()
 		<-ticker.Tick()
+		work := &workCounter{clock: cc.clock}
+		work.addTick(ticker)
+		defer work.done()
 		cc.dkgLock.Lock()
 		defer cc.dkgLock.Unlock()
 		if cc.dkg != nil && cc.dkg.round == round && cc.dkg.reset == reset {
 			cc.dkg.proposeMPKReady()
 		}
-	}()
+	}(cc.dkgCtx)
 }
 
 func (cc *configurationChain) runDKGPhaseOne(round uint64, reset uint64) error {
@@ -260,7 +294,7 @@ func (cc *configurationChain) runDKGPhaseOne(round uint64, reset uint64) error {
 		select {
 		case <-cc.dkgCtx.Done():
 			err = ErrDKGAborted
-		case <-time.After(500 * time.Millisecond):
+		case <-cc.clock.After(500 * time.Millisecond):
 		}
 		cc.dkgLock.Lock()
 	}
@@ -360,7 +394,7 @@ func (cc *configurationChain) runDKGPhaseNine(round uint64, reset uint64) error
 		select {
 		case <-cc.dkgCtx.Done():
 			err = ErrDKGAborted
-		case <-time.After(500 * time.Millisecond):
+		case <-cc.clock.After(500 * time.Millisecond):
 		}
 		cc.dkgLock.Lock()
 	}
@@ -446,7 +480,10 @@ func (cc *configurationChain) runDKG(
 	if _, _, err = cc.getDKGInfo(round, false); err == nil {
 		return ErrSkipButNoError
 	}
-	cfg := utils.GetConfigWithPanic(cc.gov, round, cc.logger)
+	cfg, err := utils.GetConfig(cc.gov, round, cc.logger)
+	if err != nil {
+		return
+	}
 	phaseHeight := uint64(
 		cfg.LambdaDKG.Nanoseconds() / cfg.MinBlockInterval.Nanoseconds())
 	skipPhase := int(dkgHeight / phaseHeight)
@@ -499,10 +536,14 @@ func (cc *configurationChain) runDKG(
 				default:
 				}
 
+				phaseBegin := cc.clock.Now()
 				err := cc.dkgRunPhases[cc.dkg.step](round, reset)
+				cc.metrics.dkgPhaseDuration(cc.dkg.step).Observe(
+					cc.clock.Now().Sub(phaseBegin).Seconds())
 				if err == nil || err == ErrSkipButNoError {
 					err = nil
 					cc.dkg.step++
+					cc.events.emitDKGStep(round, reset, cc.dkg.step)
 					err = cc.db.PutOrUpdateDKGProtocol(cc.dkg.toDKGProtocolInfo())
 					if err != nil {
 						cc.logger.Error("Failed to save DKG Protocol",
@@ -582,8 +623,11 @@ func (cc *configurationChain) recoverDKGInfo(
 		return ErrDKGNotReady
 	}
 
-	threshold := utils.GetDKGThreshold(
-		utils.GetConfigWithPanic(cc.gov, round, cc.logger))
+	cfg, err := utils.GetConfig(cc.gov, round, cc.logger)
+	if err != nil {
+		return err
+	}
+	threshold := utils.GetDKGThreshold(cfg)
 	cc.logger.Debug("Calling Governance.DKGMasterPublicKeys for recoverDKGInfo",
 		"round", round)
 	mpk := cc.gov.DKGMasterPublicKeys(round)
@@ -594,9 +638,7 @@ func (cc *configurationChain) recoverDKGInfo(
 	if err != nil {
 		return err
 	}
-	if len(qualifies) <
-		utils.GetDKGValidThreshold(utils.GetConfigWithPanic(
-			cc.gov, round, cc.logger)) {
+	if len(qualifies) < utils.GetDKGValidThreshold(cfg) {
 		return typesDKG.ErrNotReachThreshold
 	}
 
@@ -700,6 +742,7 @@ func (cc *configurationChain) runTSig(
 		return crypto.Signature{}, ErrTSigAlreadyRunning
 	}
 	cc.tsig[hash] = newTSigProtocol(npks, hash)
+	beginTime := cc.clock.Now()
 	pendingPsig := cc.pendingPsig[hash]
 	delete(cc.pendingPsig, hash)
 	go func() {
@@ -713,7 +756,7 @@ func (cc *configurationChain) runTSig(
 	}()
 	timeout := make(chan struct{}, 1)
 	go func() {
-		time.Sleep(wait)
+		cc.clock.Sleep(wait)
 		timeout <- struct{}{}
 		cc.tsigReady.Broadcast()
 	}()
@@ -734,6 +777,7 @@ func (cc *configurationChain) runTSig(
 	if err != nil {
 		return crypto.Signature{}, err
 	}
+	cc.metrics.tsigLatency.Observe(cc.clock.Now().Sub(beginTime).Seconds())
 	return signature, nil
 }
 
@@ -57,6 +57,14 @@ var (
 		"cannot verify block randomness")
 )
 
+// maxVoteBatchSize is the maximum count of votes drained from the message
+// channel to be verified in one batch.
+const maxVoteBatchSize = 64
+
+// maxPulledBlocks is the maximum count of blocks pulled by height range but
+// not yet consumed.
+const maxPulledBlocks = 1024
+
 type selfAgreementResult types.AgreementResult
 
 // consensusBAReceiver implements agreementReceiver.
@@ -89,47 +97,111 @@ func (recv *consensusBAReceiver) emptyBlockHash(pos types.Position) (
 	return hash, nil
 }
 
-func (recv *consensusBAReceiver) VerifyPartialSignature(vote *types.Vote) (
-	bool, bool) {
+// partialSignatureTarget returns the public key and the hash to verify the
+// partial signature of a vote. When the returned public key is nil, the
+// verification result is decided directly by ok and report.
+func (recv *consensusBAReceiver) partialSignatureTarget(vote *types.Vote) (
+	pubKey *cryptoDKG.PublicKey, hash common.Hash, ok bool, report bool) {
 	if vote.Position.Round >= DKGDelayRound && vote.BlockHash != types.SkipBlockHash {
 		if vote.Type == types.VoteCom || vote.Type == types.VoteFastCom {
 			if recv.npks == nil {
 				recv.consensus.logger.Debug(
 					"Unable to verify psig, npks is nil",
 					"vote", vote)
-				return false, false
+				return nil, hash, false, false
 			}
 			if vote.Position.Round != recv.npks.Round {
 				recv.consensus.logger.Debug(
 					"Unable to verify psig, round of npks mismatch",
 					"vote", vote,
 					"npksRound", recv.npks.Round)
-				return false, false
+				return nil, hash, false, false
 			}
-			pubKey, exist := recv.npks.PublicKeys[vote.ProposerID]
+			key, exist := recv.npks.PublicKeys[vote.ProposerID]
 			if !exist {
 				recv.consensus.logger.Debug(
 					"Unable to verify psig, proposer is not qualified",
 					"vote", vote)
-				return false, true
+				return nil, hash, false, true
 			}
-			blockHash := vote.BlockHash
-			if blockHash == types.NullBlockHash {
+			hash = vote.BlockHash
+			if hash == types.NullBlockHash {
 				var err error
-				blockHash, err = recv.emptyBlockHash(vote.Position)
+				hash, err = recv.emptyBlockHash(vote.Position)
 				if err != nil {
 					recv.consensus.logger.Error(
 						"Failed to verify vote for empty block",
 						"position", vote.Position,
 						"error", err)
-					return false, true
+					return nil, hash, false, true
 				}
 			}
-			return pubKey.VerifySignature(
-				blockHash, crypto.Signature(vote.PartialSignature)), true
+			return key, hash, false, true
+		}
+	}
+	return nil, hash, len(vote.PartialSignature.Signature) == 0, true
+}
+
+func (recv *consensusBAReceiver) VerifyPartialSignature(vote *types.Vote) (
+	bool, bool) {
+	pubKey, hash, ok, report := recv.partialSignatureTarget(vote)
+	if pubKey == nil {
+		return ok, report
+	}
+	return pubKey.VerifySignature(
+		hash, crypto.Signature(vote.PartialSignature)), true
+}
+
+// VerifyPartialSignatures verifies partial signatures of votes in batch, votes
+// signing the same hash are verified together.
+func (recv *consensusBAReceiver) VerifyPartialSignatures(votes []*types.Vote) (
+	oks, reports []bool) {
+	oks = make([]bool, len(votes))
+	reports = make([]bool, len(votes))
+	pubKeys := make([]*cryptoDKG.PublicKey, len(votes))
+	groups := make(map[common.Hash][]int)
+	for idx, vote := range votes {
+		pubKey, hash, ok, report := recv.partialSignatureTarget(vote)
+		if pubKey == nil {
+			oks[idx], reports[idx] = ok, report
+			continue
 		}
+		pubKeys[idx], reports[idx] = pubKey, true
+		groups[hash] = append(groups[hash], idx)
 	}
-	return len(vote.PartialSignature.Signature) == 0, true
+	for hash, indexes := range groups {
+		batchVerifyVotePartialSignatures(votes, pubKeys, hash, indexes, oks)
+	}
+	return
+}
+
+// batchVerifyVotePartialSignatures verifies partial signatures of votes
+// signing the same hash. When the batch fails, it's split into halves to
+// find out the incorrect ones.
+func batchVerifyVotePartialSignatures(votes []*types.Vote,
+	pubKeys []*cryptoDKG.PublicKey, hash common.Hash, indexes []int,
+	oks []bool) {
+	if len(indexes) == 1 {
+		idx := indexes[0]
+		oks[idx] = pubKeys[idx].VerifySignature(
+			hash, crypto.Signature(votes[idx].PartialSignature))
+		return
+	}
+	keys := make([]*cryptoDKG.PublicKey, 0, len(indexes))
+	sigs := make([]cryptoDKG.PartialSignature, 0, len(indexes))
+	for _, idx := range indexes {
+		keys = append(keys, pubKeys[idx])
+		sigs = append(sigs, votes[idx].PartialSignature)
+	}
+	if cryptoDKG.BatchVerifySignature(keys, hash, sigs) {
+		for _, idx := range indexes {
+			oks[idx] = true
+		}
+		return
+	}
+	mid := len(indexes) / 2
+	batchVerifyVotePartialSignatures(votes, pubKeys, hash, indexes[:mid], oks)
+	batchVerifyVotePartialSignatures(votes, pubKeys, hash, indexes[mid:], oks)
 }
 
 func (recv *consensusBAReceiver) ProposeVote(vote *types.Vote) {
@@ -192,6 +264,30 @@ func (recv *consensusBAReceiver) ProposeBlock() common.Hash {
 	return block.Hash
 }
 
+// newAgreementCertificate aggregates commit votes for a confirmed block into
+// a signed AgreementCertificate.
+func (recv *consensusBAReceiver) newAgreementCertificate(
+	block *types.Block, votes []types.Vote) (
+	*types.AgreementCertificate, error) {
+	notarySet, err := recv.consensus.nodeSetCache.GetNotarySet(
+		block.Position.Round)
+	if err != nil {
+		return nil, err
+	}
+	if recv.npks == nil || recv.npks.Round != block.Position.Round {
+		return nil, ErrDKGNotReady
+	}
+	cert, err := NewAgreementCertificate(block.Hash, votes, notarySet,
+		recv.npks)
+	if err != nil {
+		return nil, err
+	}
+	if err = recv.consensus.signer.SignAgreementCertificate(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
 func (recv *consensusBAReceiver) ConfirmBlock(
 	hash common.Hash, votes map[types.NodeID]*types.Vote) {
 	var (
@@ -242,7 +338,7 @@ func (recv *consensusBAReceiver) ConfirmBlock(
 					select {
 					case block = <-ch:
 						break PullBlockLoop
-					case <-time.After(1 * time.Second):
+					case <-recv.consensus.clock.After(1 * time.Second):
 					}
 				}
 				recv.consensus.logger.Debug("Receive unknown block",
@@ -262,32 +358,29 @@ func (recv *consensusBAReceiver) ConfirmBlock(
 			"block", block)
 	} else if votes != nil {
 		voteList := make([]types.Vote, 0, len(votes))
-		IDs := make(cryptoDKG.IDs, 0, len(votes))
-		psigs := make([]cryptoDKG.PartialSignature, 0, len(votes))
 		for _, vote := range votes {
 			if vote.BlockHash != hash {
 				continue
 			}
-			if block.Position.Round >= DKGDelayRound {
-				ID, exist := recv.npks.IDMap[vote.ProposerID]
-				if !exist {
-					continue
-				}
-				IDs = append(IDs, ID)
-				psigs = append(psigs, vote.PartialSignature)
-			} else {
-				voteList = append(voteList, *vote)
-			}
+			voteList = append(voteList, *vote)
 		}
+		var cert *types.AgreementCertificate
 		if block.Position.Round >= DKGDelayRound {
-			rand, err := cryptoDKG.RecoverSignature(psigs, IDs)
+			var err error
+			startTime := recv.consensus.clock.Now()
+			cert, err = recv.newAgreementCertificate(block, voteList)
+			recv.consensus.metrics.tsigBALatency.Observe(
+				recv.consensus.clock.Now().Sub(startTime).Seconds())
 			if err != nil {
 				recv.consensus.logger.Warn("Unable to recover randomness",
 					"block", block,
 					"error", err)
 			} else {
-				block.Randomness = rand.Signature[:]
+				block.Randomness = cert.Signature
+				recv.consensus.putAgreementCertificate(cert)
 			}
+			// Votes are compacted into the certificate.
+			voteList = nil
 		} else {
 			block.Randomness = NoRand
 		}
@@ -299,7 +392,9 @@ func (recv *consensusBAReceiver) ConfirmBlock(
 				Votes:        voteList,
 				IsEmptyBlock: isEmptyBlockConfirmed,
 				Randomness:   block.Randomness,
+				Certificate:  cert,
 			}
+			recv.consensus.events.emitAgreementResult(result)
 			// touchAgreementResult does not support concurrent access.
 			go func() {
 				recv.consensus.priorityMsgChan <- (*selfAgreementResult)(result)
@@ -323,33 +418,40 @@ func (recv *consensusBAReceiver) ConfirmBlock(
 	if !block.IsGenesis() &&
 		!recv.consensus.bcModule.confirmed(block.Position.Height-1) {
 		go func(hash common.Hash) {
+			recv.consensus.pullBlockRange(block.Position.Height)
 			parentHash := hash
 			for {
 				recv.consensus.logger.Warn("Parent block not confirmed",
 					"parent-hash", parentHash.String()[:6],
 					"cur-position", block.Position)
 				ch := make(chan *types.Block)
+				var block *types.Block
 				if !func() bool {
 					recv.consensus.lock.Lock()
 					defer recv.consensus.lock.Unlock()
 					if _, exist := recv.consensus.baConfirmedBlock[parentHash]; exist {
 						return false
 					}
+					// The parent might be pulled by height range already.
+					if b, exist := recv.consensus.pulledBlocks[parentHash]; exist {
+						delete(recv.consensus.pulledBlocks, parentHash)
+						block = b
+						return true
+					}
 					recv.consensus.baConfirmedBlock[parentHash] = ch
 					return true
 				}() {
 					return
 				}
-				var block *types.Block
 			PullBlockLoop:
-				for {
+				for block == nil {
 					recv.consensus.logger.Debug("Calling Network.PullBlock for parent",
 						"hash", parentHash)
 					recv.consensus.network.PullBlocks(common.Hashes{parentHash})
 					select {
 					case block = <-ch:
 						break PullBlockLoop
-					case <-time.After(1 * time.Second):
+					case <-recv.consensus.clock.After(1 * time.Second):
 					}
 				}
 				recv.consensus.logger.Info("Receive parent block",
@@ -360,6 +462,7 @@ func (recv *consensusBAReceiver) ConfirmBlock(
 					// block. Here, we pull it again as workaround.
 					continue
 				}
+				addWork(recv.consensus.clock, 1)
 				recv.consensus.processBlockChan <- block
 				parentHash = block.ParentHash
 				if block.IsGenesis() || recv.consensus.bcModule.confirmed(
@@ -370,17 +473,22 @@ func (recv *consensusBAReceiver) ConfirmBlock(
 		}(block.ParentHash)
 	}
 	if !block.IsEmpty() {
+		addWork(recv.consensus.clock, 1)
 		recv.consensus.processBlockChan <- block
 	}
 	// Clean the restartNotary channel so BA will not stuck by deadlock.
+	cleaned := &workCounter{clock: recv.consensus.clock}
 CleanChannelLoop:
 	for {
 		select {
 		case <-recv.restartNotary:
+			cleaned.add(1)
 		default:
 			break CleanChannelLoop
 		}
 	}
+	cleaned.done()
+	addWork(recv.consensus.clock, 1)
 	recv.restartNotary <- block.Position
 }
 
@@ -393,6 +501,7 @@ func (recv *consensusBAReceiver) PullBlocks(hashes common.Hashes) {
 }
 
 func (recv *consensusBAReceiver) ReportForkVote(v1, v2 *types.Vote) {
+	recv.consensus.events.emitForkVote(v1, v2)
 	recv.consensus.gov.ReportForkVote(v1, v2)
 }
 
@@ -401,6 +510,7 @@ func (recv *consensusBAReceiver) ReportForkBlock(b1, b2 *types.Block) {
 	b2Clone := b2.Clone()
 	b1Clone.Payload = []byte{}
 	b2Clone.Payload = []byte{}
+	recv.consensus.events.emitForkBlock(b1Clone, b2Clone)
 	recv.consensus.gov.ReportForkBlock(b1Clone, b2Clone)
 }
 
@@ -516,6 +626,7 @@ type Consensus struct {
 	// BA.
 	baMgr            *agreementMgr
 	baConfirmedBlock map[common.Hash]chan<- *types.Block
+	pulledBlocks     map[common.Hash]*types.Block
 
 	// DKG.
 	dkgRunning int32
@@ -540,6 +651,9 @@ type Consensus struct {
 	event                    *common.Event
 	roundEvent               *utils.RoundEvent
 	logger                   common.Logger
+	clock                    common.Clock
+	metrics                  *consensusMetrics
+	events                   *eventHub
 	resetDeliveryGuardTicker chan struct{}
 	msgChan                  chan types.Msg
 	priorityMsgChan          chan interface{}
@@ -561,12 +675,28 @@ func NewConsensus(
 	network Network,
 	prv crypto.PrivateKey,
 	logger common.Logger) *Consensus {
+	return newConsensusForRound(nil, dMoment, app, gov, db, network, prv,
+		&common.SystemClock{}, logger, true)
+}
+
+// NewConsensusWithClock constructs an Consensus instance driven by the given
+// clock, all timers of this instance, including tickers for BA and DKG, would
+// be measured by that clock.
+func NewConsensusWithClock(
+	dMoment time.Time,
+	app Application,
+	gov Governance,
+	db db.Database,
+	network Network,
+	prv crypto.PrivateKey,
+	clock common.Clock,
+	logger common.Logger) *Consensus {
 	return newConsensusForRound(
-		nil, dMoment, app, gov, db, network, prv, logger, true)
+		nil, dMoment, app, gov, db, network, prv, clock, logger, true)
 }
 
 // NewConsensusForSimulation creates an instance of Consensus for simulation,
-// the only difference with NewConsensus is nonblocking of app.
+// the differences with NewConsensusWithClock is nonblocking of app.
 func NewConsensusForSimulation(
 	dMoment time.Time,
 	app Application,
@@ -574,9 +704,10 @@ func NewConsensusForSimulation(
 	db db.Database,
 	network Network,
 	prv crypto.PrivateKey,
+	clock common.Clock,
 	logger common.Logger) *Consensus {
 	return newConsensusForRound(
-		nil, dMoment, app, gov, db, network, prv, logger, false)
+		nil, dMoment, app, gov, db, network, prv, clock, logger, false)
 }
 
 // NewConsensusFromSyncer constructs an Consensus instance from information
@@ -588,6 +719,8 @@ func NewConsensusForSimulation(
 //
 // NOTE: those confirmed blocks should be organized by chainID and sorted by
 //       their positions, in ascending order.
+//
+// The clock should be the one driving the syncer.
 func NewConsensusFromSyncer(
 	initBlock *types.Block,
 	startWithEmpty bool,
@@ -599,10 +732,11 @@ func NewConsensusFromSyncer(
 	prv crypto.PrivateKey,
 	confirmedBlocks []*types.Block,
 	cachedMessages []types.Msg,
+	clock common.Clock,
 	logger common.Logger) (*Consensus, error) {
 	// Setup Consensus instance.
 	con := newConsensusForRound(initBlock, dMoment, app, gov, db,
-		networkModule, prv, logger, true)
+		networkModule, prv, clock, logger, true)
 	// Launch a dummy receiver before we start receiving from network module.
 	con.dummyMsgBuffer = cachedMessages
 	con.dummyCancel, con.dummyFinished = utils.LaunchDummyReceiver(
@@ -646,6 +780,7 @@ func newConsensusForRound(
 	db db.Database,
 	network Network,
 	prv crypto.PrivateKey,
+	clock common.Clock,
 	logger common.Logger,
 	usingNonBlocking bool) *Consensus {
 	// TODO(w): load latest blockHeight from DB, and use config at that height.
@@ -685,13 +820,24 @@ func newConsensusForRound(
 			}
 			return crypto.Signature(signer.sign(hash)), nil
 		})
+	// All modules share the same metrics instance, thus SetMetrics could
+	// replace instruments for them at once.
+	metrics := newConsensusMetrics(nil)
+	cfgModule.metrics = metrics
+	cfgModule.clock = clock
+	events := newEventHub()
+	events.clock = clock
+	cfgModule.events = events
 	appModule := app
 	if usingNonBlocking {
-		appModule = newNonBlocking(app, debugApp)
+		nbModule := newNonBlocking(app, debugApp)
+		nbModule.metrics = metrics
+		appModule = nbModule
 	}
 	tsigVerifierCache := NewTSigVerifierCache(gov, 7)
 	bcModule := newBlockChain(ID, dMoment, initBlock, appModule,
 		tsigVerifierCache, signer, logger)
+	bcModule.metrics = metrics
 	// Construct Consensus instance.
 	con := &Consensus{
 		ID:                       ID,
@@ -701,6 +847,7 @@ func newConsensusForRound(
 		db:                       db,
 		network:                  network,
 		baConfirmedBlock:         make(map[common.Hash]chan<- *types.Block),
+		pulledBlocks:             make(map[common.Hash]*types.Block),
 		dkgReady:                 sync.NewCond(&sync.Mutex{}),
 		cfgModule:                cfgModule,
 		bcModule:                 bcModule,
@@ -710,6 +857,9 @@ func newConsensusForRound(
 		signer:                   signer,
 		event:                    common.NewEvent(),
 		logger:                   logger,
+		clock:                    clock,
+		metrics:                  metrics,
+		events:                   events,
 		resetDeliveryGuardTicker: make(chan struct{}),
 		msgChan:                  make(chan types.Msg, 1024),
 		priorityMsgChan:          make(chan interface{}, 1024),
@@ -728,6 +878,9 @@ func newConsensusForRound(
 	if err = con.prepare(initBlock); err != nil {
 		panic(err)
 	}
+	// Running this instance is work in flight until all routines are
+	// launched by Run.
+	addWork(clock, 1)
 	return con
 }
 
@@ -751,7 +904,7 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 	}
 	// Measure time elapse for each handler of round events.
 	elapse := func(what string, lastE utils.RoundEventParam) func() {
-		start := time.Now()
+		start := con.clock.Now()
 		con.logger.Info("Handle round event",
 			"what", what,
 			"event", lastE)
@@ -759,9 +912,11 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 			con.logger.Info("Finish round event",
 				"what", what,
 				"event", lastE,
-				"elapse", time.Since(start))
+				"elapse", con.clock.Now().Sub(start))
 		}
 	}
+	// Register round event handler to notify subscribers.
+	con.roundEvent.Register(con.events.emitRoundEvents)
 	// Register round event handler to purge cached node set. To make sure each
 	// modules see the up-to-date node set, we need to make sure this action
 	// should be taken as the first one.
@@ -891,8 +1046,14 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 			}
 		}()
 		go func() {
-			threshold := utils.GetDKGThreshold(
-				utils.GetConfigWithPanic(con.gov, e.Round, con.logger))
+			cfg, err := utils.GetConfig(con.gov, e.Round, con.logger)
+			if err != nil {
+				con.logger.Warn("Failed to get config to recover dkg set",
+					"round", e.Round,
+					"error", err)
+				return
+			}
+			threshold := utils.GetDKGThreshold(cfg)
 			// Restore group public key.
 			con.logger.Debug(
 				"Calling Governance.DKGMasterPublicKeys for recoverDKGInfo",
@@ -954,8 +1115,8 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 			go func() {
 				// Normally, gov.CRS would return non-nil. Use this for in case
 				// of unexpected network fluctuation and ensure the robustness.
-				if !checkWithCancel(
-					con.ctx, 500*time.Millisecond, checkCRS(nextRound)) {
+				if !checkWithCancel(con.ctx, con.clock, 500*time.Millisecond,
+					checkCRS(nextRound)) {
 					con.logger.Debug("unable to prepare CRS for notary set",
 						"round", nextRound,
 						"reset", e.Reset)
@@ -978,8 +1139,15 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 				con.logger.Info("Selected as notary set",
 					"round", nextRound,
 					"reset", e.Reset)
-				nextConfig := utils.GetConfigWithPanic(con.gov, nextRound,
+				nextConfig, err := utils.GetConfig(con.gov, nextRound,
 					con.logger)
+				if err != nil {
+					con.logger.Error("Failed to get config to register DKG",
+						"round", nextRound,
+						"reset", e.Reset,
+						"error", err)
+					return
+				}
 				con.cfgModule.registerDKG(con.ctx, nextRound, e.Reset,
 					utils.GetDKGThreshold(nextConfig))
 				con.event.RegisterHeight(e.NextDKGPreparationHeight(),
@@ -1006,8 +1174,22 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 	return
 }
 
+// SetMetrics replaces the metrics receiver of this Consensus instance and all
+// its modules. It should be called before Run.
+func (con *Consensus) SetMetrics(m common.Metrics) {
+	con.metrics.setup(m)
+}
+
+// Subscribe registers a subscription to events of this Consensus instance,
+// 'capacity' is the size of the buffered channel of that subscription. Refer
+// to Subscription for the policy to drop events.
+func (con *Consensus) Subscribe(capacity int) *Subscription {
+	return con.events.subscribe(capacity)
+}
+
 // Run starts running DEXON Consensus.
 func (con *Consensus) Run(stopChan chan<- struct{}) {
+	work := &workCounter{clock: con.clock, pending: 1}
 	// There may have emptys block in blockchain added by force sync.
 	blocksWithoutRandomness := con.bcModule.pendingBlocksWithoutRandomness()
 	// Launch BA routines.
@@ -1017,6 +1199,7 @@ func (con *Consensus) Run(stopChan chan<- struct{}) {
 	con.waitGroup.Add(1)
 	go con.deliverNetworkMsg()
 	con.waitGroup.Add(1)
+	addWork(con.clock, 1)
 	go con.processMsg()
 	go con.processBlockLoop()
 	// Stop dummy receiver if launched.
@@ -1033,7 +1216,7 @@ func (con *Consensus) Run(stopChan chan<- struct{}) {
 				select {
 				case con.msgChan <- msg:
 					break loop
-				case <-time.After(50 * time.Millisecond):
+				case <-con.clock.After(50 * time.Millisecond):
 					con.logger.Debug(
 						"internal message channel is full when syncing")
 				}
@@ -1043,11 +1226,12 @@ func (con *Consensus) Run(stopChan chan<- struct{}) {
 	}
 	con.generateBlockRandomness(blocksWithoutRandomness)
 	// Sleep until dMoment come.
-	time.Sleep(con.dMoment.Sub(time.Now().UTC()))
+	work.sleep(con.dMoment.Sub(con.clock.Now().UTC()))
 	// Take some time to bootstrap.
-	time.Sleep(3 * time.Second)
+	work.sleep(3 * time.Second)
 	con.waitGroup.Add(1)
 	go con.deliveryGuard(stopChan)
+	work.done()
 	// Block until done.
 	select {
 	case <-con.ctx.Done():
@@ -1177,11 +1361,13 @@ func (con *Consensus) runCRS(round uint64, hash common.Hash, reset bool) {
 					"round", round+1,
 					"crs", hex.EncodeToString(crs))
 				con.gov.ResetDKG(crs)
+				con.events.emitCRSProposal(round+1, crs, true)
 			} else {
 				con.logger.Debug("Calling Governance.ProposeCRS",
 					"round", round+1,
 					"crs", hex.EncodeToString(crs))
 				con.gov.ProposeCRS(round+1, crs)
+				con.events.emitCRSProposal(round+1, crs, false)
 			}
 		}
 	}
@@ -1214,7 +1400,7 @@ func (con *Consensus) deliverNetworkMsg() {
 				select {
 				case con.msgChan <- msg:
 					break innerLoop
-				case <-time.After(500 * time.Millisecond):
+				case <-con.clock.After(500 * time.Millisecond):
 					con.logger.Debug("internal message channel is full",
 						"pending", msg)
 				}
@@ -1227,8 +1413,16 @@ func (con *Consensus) deliverNetworkMsg() {
 
 func (con *Consensus) processMsg() {
 	defer con.waitGroup.Done()
+	// Messages from the network are work in flight until handled, this
+	// routine is launched as one.
+	work := &workCounter{clock: con.clock, pending: 1}
+	defer work.done()
+	// deferred is the message drained from msgChan while collecting a batch
+	// of votes, it would be processed in the next iteration.
+	var deferred *types.Msg
 MessageLoop:
 	for {
+		work.done()
 		select {
 		case <-con.ctx.Done():
 			return
@@ -1239,15 +1433,23 @@ MessageLoop:
 		case msg = <-con.priorityMsgChan:
 		default:
 		}
+		if msg == nil && deferred != nil {
+			msg, peer = deferred.Payload, deferred.PeerID
+			deferred = nil
+			work.add(1)
+		}
 		if msg == nil {
 			select {
 			case message := <-con.msgChan:
 				msg, peer = message.Payload, message.PeerID
+				work.add(1)
 			case msg = <-con.priorityMsgChan:
 			case <-con.ctx.Done():
 				return
 			}
 		}
+		con.metrics.msgChanBacklog.Set(float64(len(con.msgChan)))
+		con.metrics.priorityMsgBacklog.Set(float64(len(con.priorityMsgChan)))
 		switch val := msg.(type) {
 		case *selfAgreementResult:
 			con.baMgr.touchAgreementResult((*types.AgreementResult)(val))
@@ -1264,14 +1466,16 @@ MessageLoop:
 						con.logger.Error("Error verifying empty block hash",
 							"block", val,
 							"error, err")
-						con.network.ReportBadPeerChan() <- peer
+						con.reportBadPeer(peer,
+							types.OffenseInvalidEmptyBlockHash, err)
 						continue MessageLoop
 					}
 					if hash != val.Hash {
 						con.logger.Error("Incorrect confirmed empty block hash",
 							"block", val,
 							"hash", hash)
-						con.network.ReportBadPeerChan() <- peer
+						con.reportBadPeer(peer,
+							types.OffenseInvalidEmptyBlockHash, ErrIncorrectHash)
 						continue MessageLoop
 					}
 					if _, err := con.bcModule.proposeBlock(
@@ -1279,7 +1483,7 @@ MessageLoop:
 						con.logger.Error("Error adding empty block",
 							"block", val,
 							"error", err)
-						con.network.ReportBadPeerChan() <- peer
+						con.reportBadPeer(peer, offenseOf(err), err)
 						continue MessageLoop
 					}
 				} else {
@@ -1294,20 +1498,21 @@ MessageLoop:
 						con.logger.Error("Error verifying confirmed block randomness",
 							"block", val,
 							"error", err)
-						con.network.ReportBadPeerChan() <- peer
+						con.reportBadPeer(peer, offenseOf(err), err)
 						continue MessageLoop
 					}
 					if !ok {
 						con.logger.Error("Incorrect confirmed block randomness",
 							"block", val)
-						con.network.ReportBadPeerChan() <- peer
+						con.reportBadPeer(peer, types.OffenseWrongRandomness,
+							ErrIncorrectBlockRandomness)
 						continue MessageLoop
 					}
 					if err := utils.VerifyBlockSignature(val); err != nil {
 						con.logger.Error("VerifyBlockSignature failed",
 							"block", val,
 							"error", err)
-						con.network.ReportBadPeerChan() <- peer
+						con.reportBadPeer(peer, offenseOf(err), err)
 						continue MessageLoop
 					}
 				}
@@ -1326,53 +1531,205 @@ MessageLoop:
 					con.logger.Error("Failed to process finalized block",
 						"block", val,
 						"error", err)
-					con.network.ReportBadPeerChan() <- peer
+					con.reportBadPeer(peer, offenseOf(err), err)
 				}
 			} else {
 				if err := con.preProcessBlock(val); err != nil {
 					con.logger.Error("Failed to pre process block",
 						"block", val,
 						"error", err)
-					con.network.ReportBadPeerChan() <- peer
+					con.reportBadPeer(peer, offenseOf(err), err)
 				}
 			}
 		case *types.Vote:
-			if err := con.ProcessVote(val); err != nil {
-				con.logger.Error("Failed to process vote",
-					"vote", val,
-					"error", err)
-				con.network.ReportBadPeerChan() <- peer
+			votes, peers := []*types.Vote{val}, []interface{}{peer}
+		VoteLoop:
+			for len(votes) < maxVoteBatchSize {
+				select {
+				case message := <-con.msgChan:
+					if vote, ok := message.Payload.(*types.Vote); ok {
+						votes = append(votes, vote)
+						peers = append(peers, message.PeerID)
+						work.add(1)
+						continue VoteLoop
+					}
+					deferred = &message
+				default:
+				}
+				break
+			}
+			for idx, err := range con.ProcessVotes(votes) {
+				if err != nil {
+					con.logger.Error("Failed to process vote",
+						"vote", votes[idx],
+						"error", err)
+					con.reportBadPeer(peers[idx], offenseOf(err), err)
+				}
 			}
 		case *types.AgreementResult:
 			if err := con.ProcessAgreementResult(val); err != nil {
 				con.logger.Error("Failed to process agreement result",
 					"result", val,
 					"error", err)
-				con.network.ReportBadPeerChan() <- peer
+				con.reportBadPeer(peer, offenseOf(err), err)
+			}
+		case *types.BlockRange:
+			if err := con.processBlockRange(val); err != nil {
+				con.logger.Error("Failed to process block range",
+					"range", val,
+					"error", err)
+				if err != ErrCannotVerifyBlockRandomness {
+					con.reportBadPeer(peer, offenseOf(err), err)
+				}
 			}
 		case *typesDKG.PrivateShare:
 			if err := con.cfgModule.processPrivateShare(val); err != nil {
 				con.logger.Error("Failed to process private share",
 					"error", err)
-				con.network.ReportBadPeerChan() <- peer
+				con.reportBadPeer(peer, offenseOf(err), err)
 			}
 
 		case *typesDKG.PartialSignature:
 			if err := con.cfgModule.processPartialSignature(val); err != nil {
 				con.logger.Error("Failed to process partial signature",
 					"error", err)
-				con.network.ReportBadPeerChan() <- peer
+				con.reportBadPeer(peer, offenseOf(err), err)
 			}
 		}
 	}
 }
 
+// pullBlockRange pulls finalized blocks following the last delivered block
+// up to 'height', if the network module is able to pull blocks by height
+// range.
+func (con *Consensus) pullBlockRange(height uint64) {
+	puller, ok := con.network.(BlockRangePuller)
+	if !ok {
+		return
+	}
+	from := types.GenesisHeight
+	if b := con.bcModule.lastDeliveredBlock(); b != nil {
+		from = b.Position.Height + 1
+	}
+	if from >= height {
+		return
+	}
+	con.logger.Debug("Calling Network.PullBlockRange",
+		"from", from,
+		"to", height)
+	puller.PullBlockRange(from, height)
+}
+
+// processBlockRange verifies one page of blocks pulled by height range, and
+// hands them to routines waiting for those blocks.
+func (con *Consensus) processBlockRange(r *types.BlockRange) error {
+	if err := utils.VerifyBlockRange(r); err != nil {
+		return err
+	}
+	for _, b := range r.Blocks {
+		if err := VerifyFinalizedBlock(b, con.tsigVerifierCache); err != nil {
+			return err
+		}
+	}
+	if err := VerifyAgreementCertificates(
+		r.Certificates, con.nodeSetCache, con.tsigVerifierCache); err != nil {
+		return err
+	}
+	for _, cert := range r.Certificates {
+		con.putAgreementCertificate(cert)
+	}
+	func() {
+		con.lock.Lock()
+		defer con.lock.Unlock()
+		for h, b := range con.pulledBlocks {
+			if con.bcModule.confirmed(b.Position.Height) {
+				delete(con.pulledBlocks, h)
+			}
+		}
+		for _, b := range r.Blocks {
+			if ch, exist := con.baConfirmedBlock[b.Hash]; exist {
+				delete(con.baConfirmedBlock, b.Hash)
+				ch <- b
+				continue
+			}
+			if len(con.pulledBlocks) >= maxPulledBlocks ||
+				con.bcModule.confirmed(b.Position.Height) {
+				continue
+			}
+			con.pulledBlocks[b.Hash] = b
+		}
+	}()
+	if next, more := r.Next(); more {
+		if puller, ok := con.network.(BlockRangePuller); ok {
+			puller.PullBlockRange(next, r.To)
+		}
+	}
+	return nil
+}
+
+// putAgreementCertificate saves a verified agreement certificate, it would be
+// served to peers pulling blocks by height range.
+func (con *Consensus) putAgreementCertificate(
+	cert *types.AgreementCertificate) {
+	if err := con.db.PutAgreementCertificate(*cert); err != nil {
+		con.logger.Warn("Failed to save agreement certificate",
+			"certificate", cert,
+			"error", err)
+	}
+}
+
+// reportBadPeer reports a misbehaving peer, along with the offense it made,
+// to the network module. Networks not implementing BadPeerReporter only
+// receive the peer ID via ReportBadPeerChan.
+func (con *Consensus) reportBadPeer(
+	peer interface{}, offense types.Offense, err error) {
+	reporter, ok := con.network.(BadPeerReporter)
+	if !ok {
+		con.network.ReportBadPeerChan() <- peer
+		return
+	}
+	reporter.ReportBadPeer(&types.BadPeerReport{
+		PeerID:  peer,
+		Offense: offense,
+		Err:     err,
+	})
+}
+
+// offenseOf classifies the error returned when processing a message from
+// peers.
+func offenseOf(err error) types.Offense {
+	switch err {
+	case ErrIncorrectSignature,
+		ErrIncorrectVoteSignature,
+		ErrIncorrectVotePartialSignature,
+		ErrIncorrectPrivateShareSignature,
+		ErrIncorrectPartialSignatureSignature,
+		ErrIncorrectPartialSignature,
+		ErrIncorrectCRSSignature,
+		ErrIncorrectCertificateSignature,
+		utils.ErrIncorrectSignature:
+		return types.OffenseBadSignature
+	case ErrIncorrectBlockRandomness:
+		return types.OffenseWrongRandomness
+	case ErrNotDKGParticipant:
+		return types.OffenseUnsolicitedDKGShare
+	}
+	return types.OffenseInvalidMessage
+}
+
 // ProcessVote is the entry point to submit ont vote to a Consensus instance.
 func (con *Consensus) ProcessVote(vote *types.Vote) (err error) {
 	err = con.baMgr.processVote(vote)
 	return
 }
 
+// ProcessVotes submits a batch of votes to a Consensus instance, signatures
+// of these votes are verified in batch. The error of the i-th vote is
+// returned in the i-th element.
+func (con *Consensus) ProcessVotes(votes []*types.Vote) []error {
+	return con.baMgr.processVotes(votes)
+}
+
 // ProcessAgreementResult processes the randomness request.
 func (con *Consensus) ProcessAgreementResult(
 	rand *types.AgreementResult) error {
@@ -1396,7 +1753,12 @@ func (con *Consensus) ProcessAgreementResult(
 		con.baMgr.untouchAgreementResult(rand)
 		return err
 	}
+	// The certificate carries the randomness verified above.
+	if rand.Certificate != nil {
+		con.putAgreementCertificate(rand.Certificate)
+	}
 
+	con.events.emitAgreementResult(rand)
 	con.logger.Debug("Rebroadcast AgreementResult",
 		"result", rand)
 	con.network.BroadcastAgreementResult(rand)
@@ -1446,12 +1808,12 @@ func (con *Consensus) deliveryGuard(stopChan chan<- struct{}) {
 	defer con.waitGroup.Done()
 	select {
 	case <-con.ctx.Done():
-	case <-time.After(con.dMoment.Sub(time.Now())):
+	case <-con.clock.After(con.dMoment.Sub(con.clock.Now())):
 	}
 	// Node takes time to start.
 	select {
 	case <-con.ctx.Done():
-	case <-time.After(60 * time.Second):
+	case <-con.clock.After(60 * time.Second):
 	}
 	for {
 		select {
@@ -1463,7 +1825,7 @@ func (con *Consensus) deliveryGuard(stopChan chan<- struct{}) {
 		case <-con.ctx.Done():
 			return
 		case <-con.resetDeliveryGuardTicker:
-		case <-time.After(60 * time.Second):
+		case <-con.clock.After(60 * time.Second):
 			con.logger.Error("No blocks delivered for too long", "ID", con.ID)
 			stopChan <- struct{}{}
 			con.ctxCancel()
@@ -1485,6 +1847,13 @@ func (con *Consensus) deliverBlock(b *types.Block) {
 		b.Position.Height); err != nil {
 		panic(err)
 	}
+	// Votes journaled for positions before a delivered block are not needed
+	// to prevent equivocation anymore.
+	if err := con.db.PruneVoteJournal(b.Position); err != nil {
+		con.logger.Warn("Failed to prune vote journal",
+			"position", b.Position,
+			"error", err)
+	}
 	con.logger.Debug("Calling Application.BlockDelivered", "block", b)
 	con.app.BlockDelivered(b.Hash, b.Position, common.CopyBytes(b.Randomness))
 	if con.debugApp != nil {
@@ -1513,7 +1882,11 @@ func (con *Consensus) deliverFinalizedBlocksWithoutLock() (err error) {
 }
 
 func (con *Consensus) processBlockLoop() {
+	// Blocks passed to this routine are work in flight until processed.
+	work := &workCounter{clock: con.clock}
+	defer work.done()
 	for {
+		work.done()
 		select {
 		case <-con.ctx.Done():
 			return
@@ -1523,6 +1896,7 @@ func (con *Consensus) processBlockLoop() {
 		case <-con.ctx.Done():
 			return
 		case block := <-con.processBlockChan:
+			work.add(1)
 			if err := con.processBlock(block); err != nil {
 				con.logger.Error("Error processing block",
 					"block", block,
@@ -1551,7 +1925,7 @@ func (con *Consensus) processBlock(block *types.Block) (err error) {
 // PrepareBlock would setup header fields of block based on its ProposerID.
 func (con *Consensus) proposeBlock(position types.Position) (
 	*types.Block, error) {
-	b, err := con.bcModule.proposeBlock(position, time.Now().UTC(), false)
+	b, err := con.bcModule.proposeBlock(position, con.clock.Now().UTC(), false)
 	if err != nil {
 		return nil, err
 	}
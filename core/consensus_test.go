@@ -28,6 +28,7 @@ import (
 
 	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	cryptoDKG "github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
 	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/test"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
@@ -98,6 +99,17 @@ func (n *network) ReportBadPeerChan() chan<- interface{} {
 	return n.conn.s.sink
 }
 
+// reportingNetwork implements core.BadPeerReporter.
+type reportingNetwork struct {
+	network
+	reports []*types.BadPeerReport
+}
+
+// ReportBadPeer implements core.BadPeerReporter interface.
+func (n *reportingNetwork) ReportBadPeer(report *types.BadPeerReport) {
+	n.reports = append(n.reports, report)
+}
+
 func (nc *networkConnection) broadcast(from types.NodeID, msg interface{}) {
 	for nID := range nc.cons {
 		if nID == from {
@@ -424,6 +436,7 @@ func (s *ConsensusTestSuite) TestInitialHeightEventTriggered() {
 		prvKey,
 		[]*types.Block(nil),
 		[]types.Msg{},
+		&common.SystemClock{},
 		&common.NullLogger{},
 	)
 	s.Require().NoError(err)
@@ -433,6 +446,57 @@ func (s *ConsensusTestSuite) TestInitialHeightEventTriggered() {
 	s.Require().Equal(con.bcModule.configs[0].RoundEndHeight(), uint64(301))
 }
 
+func (s *ConsensusTestSuite) TestBatchVerifyVotePartialSignatures() {
+	var (
+		n       = 9
+		hash    = common.NewRandomHash()
+		votes   = make([]*types.Vote, n)
+		pubKeys = make([]*cryptoDKG.PublicKey, n)
+		indexes = make([]int, n)
+		bad     = map[int]struct{}{2: {}, 3: {}, 7: {}}
+	)
+	for i := range votes {
+		prvKey := cryptoDKG.NewPrivateKey()
+		pubKey := prvKey.PublicKey().(cryptoDKG.PublicKey)
+		pubKeys[i] = &pubKey
+		signHash := hash
+		if _, exist := bad[i]; exist {
+			signHash = common.NewRandomHash()
+		}
+		sig, err := prvKey.Sign(signHash)
+		s.Require().NoError(err)
+		votes[i] = types.NewVote(types.VoteCom, hash, 0)
+		votes[i].PartialSignature = cryptoDKG.PartialSignature(sig)
+		indexes[i] = i
+	}
+	oks := make([]bool, n)
+	batchVerifyVotePartialSignatures(votes, pubKeys, hash, indexes, oks)
+	for i, ok := range oks {
+		_, isBad := bad[i]
+		s.Equal(!isBad, ok)
+	}
+}
+
+func (s *ConsensusTestSuite) TestReportBadPeer() {
+	peer := types.NodeID{Hash: common.NewRandomHash()}
+	// Networks only implementing core.Network receive peer IDs.
+	sink := make(chan interface{}, 1)
+	con := &Consensus{network: &network{
+		conn: &networkConnection{s: &ConsensusTestSuite{sink: sink}},
+	}}
+	con.reportBadPeer(peer, types.OffenseBadSignature, ErrIncorrectSignature)
+	s.Require().Equal(peer, <-sink)
+	// Networks implementing BadPeerReporter receive the whole report.
+	reporter := &reportingNetwork{}
+	con.network = reporter
+	con.reportBadPeer(peer, types.OffenseBadSignature, ErrIncorrectSignature)
+	s.Require().Equal([]*types.BadPeerReport{&types.BadPeerReport{
+		PeerID:  peer,
+		Offense: types.OffenseBadSignature,
+		Err:     ErrIncorrectSignature,
+	}}, reporter.reports)
+}
+
 func TestConsensus(t *testing.T) {
 	suite.Run(t, new(ConsensusTestSuite))
 }
@@ -293,6 +293,29 @@ func (s *DKGTestSuite) TestSignature() {
 	s.False(pubKey.VerifySignature(hash, sig))
 }
 
+func (s *DKGTestSuite) TestBatchVerifySignature() {
+	hash := crypto.Keccak256Hash([]byte("🛬"))
+	pubKeys, sigs := genBatchSignatures(7, hash)
+	s.True(BatchVerifySignature(pubKeys, hash, sigs))
+	// Signatures signed over another hash.
+	s.False(BatchVerifySignature(
+		pubKeys, crypto.Keccak256Hash([]byte("🛫")), sigs))
+	// Mismatched public keys.
+	pubKeys[0], pubKeys[1] = pubKeys[1], pubKeys[0]
+	s.False(BatchVerifySignature(pubKeys, hash, sigs))
+	pubKeys[0], pubKeys[1] = pubKeys[1], pubKeys[0]
+	// Corrupted signature.
+	sigs[3].Signature[0]++
+	s.False(BatchVerifySignature(pubKeys, hash, sigs))
+	sigs[3].Signature[0]--
+	s.True(BatchVerifySignature(pubKeys, hash, sigs))
+	// Invalid inputs.
+	s.False(BatchVerifySignature(nil, hash, nil))
+	s.False(BatchVerifySignature(pubKeys[1:], hash, sigs))
+	sigs[2] = PartialSignature{}
+	s.False(BatchVerifySignature(pubKeys, hash, sigs))
+}
+
 func (s *DKGTestSuite) TestPrivateKeyRLPEncodeDecode() {
 	k := NewPrivateKey()
 	b, err := rlp.EncodeToBytes(k)
@@ -659,3 +682,46 @@ func benchmarkGPKAddShare(b *testing.B, t, n int) {
 		}
 	}
 }
+
+func genBatchSignatures(
+	n int, hash common.Hash) ([]*PublicKey, []PartialSignature) {
+	pubKeys := make([]*PublicKey, n)
+	sigs := make([]PartialSignature, n)
+	for i := range pubKeys {
+		prvKey := NewPrivateKey()
+		pubKey := prvKey.PublicKey().(PublicKey)
+		pubKeys[i] = &pubKey
+		sig, err := prvKey.Sign(hash)
+		if err != nil {
+			panic(err)
+		}
+		sigs[i] = PartialSignature(sig)
+	}
+	return pubKeys, sigs
+}
+
+func BenchmarkVerifySignature121(b *testing.B) {
+	benchmarkVerifySignature(b, 121)
+}
+
+func benchmarkVerifySignature(b *testing.B, n int) {
+	hash := common.NewRandomHash()
+	pubKeys, sigs := genBatchSignatures(n, hash)
+	b.Run("Single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for idx := range pubKeys {
+				if !pubKeys[idx].VerifySignature(
+					hash, crypto.Signature(sigs[idx])) {
+					b.Fatal("failed to verify signature")
+				}
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if !BatchVerifySignature(pubKeys, hash, sigs) {
+				b.Fatal("failed to verify signatures")
+			}
+		}
+	})
+}
@@ -18,12 +18,14 @@
 package dkg
 
 import (
+	cryptorand "crypto/rand"
 	"encoding/binary"
 	"fmt"
 	"math/rand"
 
 	"github.com/tangerine-network/bls/ffi/go/bls"
 
+	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 )
 
@@ -56,6 +58,70 @@ func RecoverSignature(sigs []PartialSignature, signerIDs IDs) (
 		Signature: recoverSig.Serialize()}, nil
 }
 
+// BatchVerifySignature verifies signatures over the same hash at once, the
+// i-th signature should be signed by the owner of the i-th public key. Each
+// signature and public key are multiplied by a random 64-bit coefficient
+// before being aggregated, thus invalid signatures can't cancel each other,
+// and only one pairing check is needed. When it fails, the caller has to
+// verify signatures one by one to find out invalid ones.
+func BatchVerifySignature(
+	pubKeys []*PublicKey, hash common.Hash, sigs []PartialSignature) bool {
+	if len(pubKeys) == 0 || len(pubKeys) != len(sigs) {
+		return false
+	}
+	var (
+		aggSig bls.Sign
+		aggPub bls.PublicKey
+		coef   = make([]byte, 8)
+	)
+	for i := range sigs {
+		if len(sigs[i].Signature) == 0 {
+			return false
+		}
+		var sig bls.Sign
+		if err := sig.Deserialize(sigs[i].Signature); err != nil {
+			return false
+		}
+		if _, err := cryptorand.Read(coef); err != nil {
+			return false
+		}
+		// The highest bit is always set, thus coefficients are non-zero.
+		c := binary.LittleEndian.Uint64(coef) | 1<<63
+		pub := pubKeys[i].publicKey
+		mulSign(&sig, c)
+		mulPublicKey(&pub, c)
+		if i == 0 {
+			aggSig, aggPub = sig, pub
+			continue
+		}
+		aggSig.Add(&sig)
+		aggPub.Add(&pub)
+	}
+	return aggSig.Verify(&aggPub, string(hash[:]))
+}
+
+// mulSign multiplies sig by c with double-and-add.
+func mulSign(sig *bls.Sign, c uint64) {
+	base := *sig
+	for bit := 62; bit >= 0; bit-- {
+		sig.Add(sig)
+		if c&(1<<uint(bit)) != 0 {
+			sig.Add(&base)
+		}
+	}
+}
+
+// mulPublicKey multiplies pub by c with double-and-add.
+func mulPublicKey(pub *bls.PublicKey, c uint64) {
+	base := *pub
+	for bit := 62; bit >= 0; bit-- {
+		pub.Add(pub)
+		if c&(1<<uint(bit)) != 0 {
+			pub.Add(&base)
+		}
+	}
+}
+
 // RecoverGroupPublicKey recovers group public key.
 func RecoverGroupPublicKey(pubShares []*PublicKeyShares) *PublicKey {
 	var pub *PublicKey
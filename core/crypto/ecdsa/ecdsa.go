@@ -61,6 +61,16 @@ func NewPrivateKeyFromECDSA(key *ecdsa.PrivateKey) *PrivateKey {
 	return &PrivateKey{privateKey: key}
 }
 
+// NewPrivateKeyFromBytes creates a new PrivateKey structure from the raw
+// bytes returned by PrivateKey.Bytes.
+func NewPrivateKeyFromBytes(b []byte) (*PrivateKey, error) {
+	key, err := dexCrypto.ToECDSA(b)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{privateKey: key}, nil
+}
+
 // NewPublicKeyFromECDSA creates a new PublicKey structure from
 // ecdsa.PublicKey.
 func NewPublicKeyFromECDSA(key *ecdsa.PublicKey) *PublicKey {
@@ -82,6 +92,11 @@ func (prv *PrivateKey) PublicKey() crypto.PublicKey {
 	return NewPublicKeyFromECDSA(&(prv.privateKey.PublicKey))
 }
 
+// Bytes returns the raw bytes of the private key.
+func (prv *PrivateKey) Bytes() []byte {
+	return dexCrypto.FromECDSA(prv.privateKey)
+}
+
 // Sign calculates an ECDSA signature.
 //
 // This function is susceptible to chosen plaintext attacks that can leak
@@ -68,6 +68,16 @@ func (s *ETHCryptoTestSuite) TestSignature() {
 	s.False(pub2.VerifySignature(hash1, sig11))
 }
 
+func (s *ETHCryptoTestSuite) TestPrivateKeyBytes() {
+	prv, err := NewPrivateKey()
+	s.Require().NoError(err)
+	restored, err := NewPrivateKeyFromBytes(prv.Bytes())
+	s.Require().NoError(err)
+	s.Equal(prv.PublicKey().Bytes(), restored.PublicKey().Bytes())
+	_, err = NewPrivateKeyFromBytes([]byte{1, 2, 3})
+	s.Error(err)
+}
+
 func (s *ETHCryptoTestSuite) TestSigToPub() {
 	prv, err := NewPrivateKey()
 	s.Require().Nil(err)
@@ -56,6 +56,14 @@ var (
 	// ErrDKGProtocolDoesNotExist raised when the DKG protocol of the
 	// requested round does not exists.
 	ErrDKGProtocolDoesNotExist = errors.New("dkg protocol does not exists")
+	// ErrVoteJournalConflict raised when attempting to journal a vote
+	// conflicting with the journaled one of the same position, period and
+	// type.
+	ErrVoteJournalConflict = errors.New("vote journal conflict")
+	// ErrAgreementCertificateDoesNotExist raised when the agreement
+	// certificate of the requested block does not exist.
+	ErrAgreementCertificateDoesNotExist = errors.New(
+		"agreement certificate does not exist")
 )
 
 // Database is the interface for a Database.
@@ -74,6 +82,12 @@ type Reader interface {
 	GetBlock(hash common.Hash) (types.Block, error)
 	GetAllBlocks() (BlockIterator, error)
 
+	// GetBlockByPosition returns the block at the position.
+	GetBlockByPosition(position types.Position) (types.Block, error)
+	// GetBlocksInRange returns blocks with height in [fromHeight, toHeight),
+	// sorted by height.
+	GetBlocksInRange(fromHeight, toHeight uint64) ([]types.Block, error)
+
 	// GetCompactionChainTipInfo returns the block hash and finalization height
 	// of the tip block of compaction chain. Empty hash and zero height means
 	// the compaction chain is empty.
@@ -82,6 +96,14 @@ type Reader interface {
 	// DKG Private Key related methods.
 	GetDKGPrivateKey(round, reset uint64) (dkg.PrivateKey, error)
 	GetDKGProtocol() (dkgProtocol DKGProtocolInfo, err error)
+
+	// GetVoteJournal returns journaled votes of one position, sorted by
+	// period and type.
+	GetVoteJournal(position types.Position) ([]types.Vote, error)
+
+	// GetAgreementCertificate returns the agreement certificate of the block.
+	GetAgreementCertificate(
+		hash common.Hash) (types.AgreementCertificate, error)
 }
 
 // Writer defines the interface for writing blocks into DB.
@@ -91,6 +113,24 @@ type Writer interface {
 	PutCompactionChainTipInfo(common.Hash, uint64) error
 	PutDKGPrivateKey(round, reset uint64, pk dkg.PrivateKey) error
 	PutOrUpdateDKGProtocol(dkgProtocol DKGProtocolInfo) error
+
+	// DeleteDKGPrivateKey deletes the DKG private key of one round, it's not
+	// an error when the key doesn't exist.
+	DeleteDKGPrivateKey(round uint64) error
+	// DeleteDKGProtocol deletes the DKG protocol info, it's not an error when
+	// the info doesn't exist.
+	DeleteDKGProtocol() error
+
+	// PutVoteJournal journals a vote proposed by this node, there should be
+	// at most one vote for each position, period and type.
+	PutVoteJournal(vote types.Vote) error
+	// PruneVoteJournal deletes journaled votes of positions older than
+	// 'before', which should be finalized.
+	PruneVoteJournal(before types.Position) error
+
+	// PutAgreementCertificate saves the agreement certificate of a block, the
+	// saved one of the same block would be replaced.
+	PutAgreementCertificate(cert types.AgreementCertificate) error
 }
 
 // BlockIterator defines an iterator on blocks hold
@@ -19,9 +19,13 @@ package db
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/tangerine-network/go-tangerine/rlp"
 	"github.com/tangerine-network/tangerine-consensus/common"
@@ -31,11 +35,71 @@ import (
 
 var (
 	blockKeyPrefix            = []byte("b-")
+	blockHeightKeyPrefix      = []byte("bh-")
 	compactionChainTipInfoKey = []byte("cc-tip")
 	dkgPrivateKeyKeyPrefix    = []byte("dkg-prvs")
 	dkgProtocolInfoKeyPrefix  = []byte("dkg-protocol-info")
+	voteJournalKeyPrefix      = []byte("vj-")
+	certificateKeyPrefix      = []byte("ac-")
+	dbVersionKey              = []byte("db-version")
 )
 
+// Versions of the layout of LevelDBBackedDB, databases are migrated to the
+// latest version when opened.
+const (
+	// dbVersionHeightIndex adds the height index of blocks.
+	dbVersionHeightIndex uint64 = 1
+	dbVersionLatest             = dbVersionHeightIndex
+	// Count of writes to flush in one batch when migrating.
+	migrationBatchSize = 1024
+)
+
+// ErrPruningStarted is the error when background pruning is started twice.
+var ErrPruningStarted = errors.New("pruning started")
+
+// RetentionPolicy decides which blocks and DKG private keys are kept in
+// LevelDBBackedDB, counted from the tip of compaction chain. A block is
+// pruned only when it's out of all windows, and the tip of compaction chain
+// is always kept. Zero means no window is applied, when both are zero,
+// nothing would be pruned.
+type RetentionPolicy struct {
+	KeepHeights uint64
+	KeepRounds  uint64
+}
+
+// blockHeightIndex is the value of height index of a block.
+type blockHeightIndex struct {
+	Hash  common.Hash
+	Round uint64
+}
+
+type blockHeightIterator struct {
+	lvl    *LevelDBBackedDB
+	height uint64
+}
+
+// NextBlock implements BlockIterator.NextBlock method.
+func (it *blockHeightIterator) NextBlock() (b types.Block, err error) {
+	iter := it.lvl.db.NewIterator(&util.Range{
+		Start: it.lvl.getBlockHeightKey(it.height),
+		Limit: blockHeightKeyLimit(),
+	}, nil)
+	defer iter.Release()
+	if !iter.Next() {
+		if err = iter.Error(); err == nil {
+			err = ErrIterationFinished
+		}
+		return
+	}
+	idx := blockHeightIndex{}
+	if err = rlp.DecodeBytes(iter.Value(), &idx); err != nil {
+		return
+	}
+	it.height = binary.BigEndian.Uint64(
+		iter.Key()[len(blockHeightKeyPrefix):]) + 1
+	return it.lvl.GetBlock(idx.Hash)
+}
+
 type compactionChainTipInfo struct {
 	Height uint64      `json:"height"`
 	Hash   common.Hash `json:"hash"`
@@ -343,7 +407,10 @@ func (m *NodeIDToDKGID) DecodeRLP(s *rlp.Stream) error {
 
 // LevelDBBackedDB is a leveldb backed DB implementation.
 type LevelDBBackedDB struct {
-	db *leveldb.DB
+	db          *leveldb.DB
+	pruningLock sync.Mutex
+	pruningStop chan struct{}
+	pruningWait sync.WaitGroup
 }
 
 // NewLevelDBBackedDB initialize a leveldb-backed database.
@@ -355,11 +422,82 @@ func NewLevelDBBackedDB(
 		return
 	}
 	lvl = &LevelDBBackedDB{db: dbInst}
+	if err = lvl.migrate(); err != nil {
+		dbInst.Close()
+		lvl = nil
+	}
 	return
 }
 
+// migrate upgrades the layout of this database to the latest version.
+func (lvl *LevelDBBackedDB) migrate() error {
+	version := uint64(0)
+	queried, err := lvl.db.Get(dbVersionKey, nil)
+	if err == nil {
+		if err = rlp.DecodeBytes(queried, &version); err != nil {
+			return err
+		}
+	} else if err != leveldb.ErrNotFound {
+		return err
+	}
+	if version < dbVersionHeightIndex {
+		if err = lvl.buildBlockHeightIndex(); err != nil {
+			return err
+		}
+	}
+	if version == dbVersionLatest {
+		return nil
+	}
+	marshaled, err := rlp.EncodeToBytes(dbVersionLatest)
+	if err != nil {
+		return err
+	}
+	return lvl.db.Put(dbVersionKey, marshaled, nil)
+}
+
+// buildBlockHeightIndex backfills the height index for blocks saved before
+// the index is introduced.
+func (lvl *LevelDBBackedDB) buildBlockHeightIndex() error {
+	iter := lvl.db.NewIterator(util.BytesPrefix(blockKeyPrefix), nil)
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		block := types.Block{}
+		if err := rlp.DecodeBytes(iter.Value(), &block); err != nil {
+			return err
+		}
+		index, err := rlp.EncodeToBytes(&blockHeightIndex{
+			Hash:  block.Hash,
+			Round: block.Position.Round,
+		})
+		if err != nil {
+			return err
+		}
+		batch.Put(lvl.getBlockHeightKey(block.Position.Height), index)
+		if batch.Len() >= migrationBatchSize {
+			if err = lvl.db.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return lvl.db.Write(batch, nil)
+}
+
 // Close implement Closer interface, which would release allocated resource.
 func (lvl *LevelDBBackedDB) Close() error {
+	func() {
+		lvl.pruningLock.Lock()
+		defer lvl.pruningLock.Unlock()
+		if lvl.pruningStop != nil {
+			close(lvl.pruningStop)
+			lvl.pruningStop = nil
+		}
+	}()
+	lvl.pruningWait.Wait()
 	return lvl.db.Close()
 }
 
@@ -426,14 +564,214 @@ func (lvl *LevelDBBackedDB) PutBlock(block types.Block) (err error) {
 		err = ErrBlockExists
 		return
 	}
-	err = lvl.db.Put(blockKey, marshaled, nil)
+	index, err := rlp.EncodeToBytes(&blockHeightIndex{
+		Hash:  block.Hash,
+		Round: block.Position.Round,
+	})
+	if err != nil {
+		return
+	}
+	batch := new(leveldb.Batch)
+	batch.Put(blockKey, marshaled)
+	batch.Put(lvl.getBlockHeightKey(block.Position.Height), index)
+	err = lvl.db.Write(batch, nil)
 	return
 }
 
 // GetAllBlocks implements Reader.GetAllBlocks method, which allows callers
-// to retrieve all blocks in DB.
+// to retrieve all blocks in DB. Blocks are iterated by height.
 func (lvl *LevelDBBackedDB) GetAllBlocks() (BlockIterator, error) {
-	return nil, ErrNotImplemented
+	return &blockHeightIterator{lvl: lvl}, nil
+}
+
+// GetBlockByPosition implements the Reader.GetBlockByPosition method.
+func (lvl *LevelDBBackedDB) GetBlockByPosition(
+	position types.Position) (block types.Block, err error) {
+	idx, err := lvl.getBlockHeightIndex(position.Height)
+	if err != nil {
+		return
+	}
+	if idx.Round != position.Round {
+		err = ErrBlockDoesNotExist
+		return
+	}
+	return lvl.GetBlock(idx.Hash)
+}
+
+// GetBlocksInRange implements the Reader.GetBlocksInRange method.
+func (lvl *LevelDBBackedDB) GetBlocksInRange(
+	fromHeight, toHeight uint64) (blocks []types.Block, err error) {
+	if fromHeight >= toHeight {
+		return
+	}
+	iter := lvl.db.NewIterator(&util.Range{
+		Start: lvl.getBlockHeightKey(fromHeight),
+		Limit: lvl.getBlockHeightKey(toHeight),
+	}, nil)
+	defer iter.Release()
+	for iter.Next() {
+		idx := blockHeightIndex{}
+		if err = rlp.DecodeBytes(iter.Value(), &idx); err != nil {
+			return
+		}
+		var block types.Block
+		if block, err = lvl.GetBlock(idx.Hash); err != nil {
+			return
+		}
+		blocks = append(blocks, block)
+	}
+	err = iter.Error()
+	return
+}
+
+// GetBlockHashByHeight returns the hash of the block at the height.
+func (lvl *LevelDBBackedDB) GetBlockHashByHeight(
+	height uint64) (common.Hash, error) {
+	idx, err := lvl.getBlockHeightIndex(height)
+	return idx.Hash, err
+}
+
+func (lvl *LevelDBBackedDB) getBlockHeightIndex(
+	height uint64) (idx blockHeightIndex, err error) {
+	queried, err := lvl.db.Get(lvl.getBlockHeightKey(height), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			err = ErrBlockDoesNotExist
+		}
+		return
+	}
+	err = rlp.DecodeBytes(queried, &idx)
+	return
+}
+
+// Prune deletes blocks along with their agreement certificates, DKG private
+// keys and journaled votes which are no longer needed under the retention
+// policy, and returns the count of pruned blocks.
+func (lvl *LevelDBBackedDB) Prune(
+	policy RetentionPolicy) (pruned int, err error) {
+	tipInfo, err := lvl.internalGetCompactionChainTipInfo()
+	if err != nil || tipInfo.Height == 0 {
+		return
+	}
+	tipIdx, err := lvl.getBlockHeightIndex(tipInfo.Height)
+	if err != nil {
+		return
+	}
+	batch := new(leveldb.Batch)
+	if policy.KeepHeights != 0 || policy.KeepRounds != 0 {
+		// Heights and rounds are both increasing, thus we can stop at the
+		// first block to keep.
+		iter := lvl.db.NewIterator(&util.Range{
+			Start: lvl.getBlockHeightKey(0),
+			Limit: lvl.getBlockHeightKey(tipInfo.Height),
+		}, nil)
+		for iter.Next() {
+			height := binary.BigEndian.Uint64(
+				iter.Key()[len(blockHeightKeyPrefix):])
+			idx := blockHeightIndex{}
+			if err = rlp.DecodeBytes(iter.Value(), &idx); err != nil {
+				break
+			}
+			if policy.KeepHeights != 0 &&
+				height+policy.KeepHeights > tipInfo.Height {
+				break
+			}
+			if policy.KeepRounds != 0 &&
+				idx.Round+policy.KeepRounds > tipIdx.Round {
+				break
+			}
+			batch.Delete(lvl.getBlockKey(idx.Hash))
+			batch.Delete(lvl.getAgreementCertificateKey(idx.Hash))
+			batch.Delete(append([]byte(nil), iter.Key()...))
+			pruned++
+		}
+		iter.Release()
+		if err == nil {
+			err = iter.Error()
+		}
+		if err != nil {
+			return
+		}
+	}
+	// DKG private keys of rounds before the previous round of the tip are
+	// never used to sign again.
+	iter := lvl.db.NewIterator(util.BytesPrefix(dkgPrivateKeyKeyPrefix), nil)
+	for iter.Next() {
+		round := binary.LittleEndian.Uint64(
+			iter.Key()[len(dkgPrivateKeyKeyPrefix):])
+		if round+1 >= tipIdx.Round {
+			continue
+		}
+		if policy.KeepRounds != 0 && round+policy.KeepRounds > tipIdx.Round {
+			continue
+		}
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return
+	}
+	// Agreements of positions before the tip are done.
+	if err = lvl.pruneVoteJournal(batch, types.Position{
+		Round:  tipIdx.Round,
+		Height: tipInfo.Height,
+	}); err != nil {
+		return
+	}
+	err = lvl.db.Write(batch, nil)
+	return
+}
+
+// PruneVoteJournal implements the Writer.PruneVoteJournal method.
+func (lvl *LevelDBBackedDB) PruneVoteJournal(before types.Position) error {
+	batch := new(leveldb.Batch)
+	if err := lvl.pruneVoteJournal(batch, before); err != nil {
+		return err
+	}
+	return lvl.db.Write(batch, nil)
+}
+
+// pruneVoteJournal adds deletions of journaled votes of positions older than
+// 'before' to the batch.
+func (lvl *LevelDBBackedDB) pruneVoteJournal(
+	batch *leveldb.Batch, before types.Position) error {
+	iter := lvl.db.NewIterator(&util.Range{
+		Start: voteJournalKeyPrefix,
+		Limit: lvl.getVoteJournalPositionKey(before),
+	}, nil)
+	defer iter.Release()
+	for iter.Next() {
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	return iter.Error()
+}
+
+// StartPruning prunes the database periodically in background until it's
+// closed. Failed pruning would be retried in the next period.
+func (lvl *LevelDBBackedDB) StartPruning(
+	policy RetentionPolicy, interval time.Duration) error {
+	lvl.pruningLock.Lock()
+	defer lvl.pruningLock.Unlock()
+	if lvl.pruningStop != nil {
+		return ErrPruningStarted
+	}
+	stop := make(chan struct{})
+	lvl.pruningStop = stop
+	lvl.pruningWait.Add(1)
+	go func() {
+		defer lvl.pruningWait.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			lvl.Prune(policy)
+		}
+	}()
+	return nil
 }
 
 // PutCompactionChainTipInfo saves tip of compaction chain into the database.
@@ -526,6 +864,11 @@ func (lvl *LevelDBBackedDB) PutDKGPrivateKey(
 		lvl.getDKGPrivateKeyKey(round), marshaled, nil)
 }
 
+// DeleteDKGPrivateKey deletes DKG private key of one round.
+func (lvl *LevelDBBackedDB) DeleteDKGPrivateKey(round uint64) error {
+	return lvl.db.Delete(lvl.getDKGPrivateKeyKey(round), nil)
+}
+
 // GetDKGProtocol get DKG protocol.
 func (lvl *LevelDBBackedDB) GetDKGProtocol() (
 	info DKGProtocolInfo, err error) {
@@ -550,6 +893,79 @@ func (lvl *LevelDBBackedDB) PutOrUpdateDKGProtocol(info DKGProtocolInfo) error {
 	return lvl.db.Put(lvl.getDKGProtocolInfoKey(), marshaled, nil)
 }
 
+// DeleteDKGProtocol deletes DKG protocol.
+func (lvl *LevelDBBackedDB) DeleteDKGProtocol() error {
+	return lvl.db.Delete(lvl.getDKGProtocolInfoKey(), nil)
+}
+
+// GetVoteJournal implements the Reader.GetVoteJournal method.
+func (lvl *LevelDBBackedDB) GetVoteJournal(
+	position types.Position) (votes []types.Vote, err error) {
+	iter := lvl.db.NewIterator(
+		util.BytesPrefix(lvl.getVoteJournalPositionKey(position)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		vote := types.Vote{}
+		if err = rlp.DecodeBytes(iter.Value(), &vote); err != nil {
+			return
+		}
+		votes = append(votes, vote)
+	}
+	err = iter.Error()
+	return
+}
+
+// PutVoteJournal implements the Writer.PutVoteJournal method.
+func (lvl *LevelDBBackedDB) PutVoteJournal(vote types.Vote) error {
+	key := lvl.getVoteJournalKey(&vote)
+	queried, err := lvl.db.Get(key, nil)
+	if err == nil {
+		journaled := types.Vote{}
+		if err = rlp.DecodeBytes(queried, &journaled); err != nil {
+			return err
+		}
+		if journaled.BlockHash != vote.BlockHash {
+			return ErrVoteJournalConflict
+		}
+		return nil
+	}
+	if err != leveldb.ErrNotFound {
+		return err
+	}
+	marshaled, err := rlp.EncodeToBytes(&vote)
+	if err != nil {
+		return err
+	}
+	return lvl.db.Put(key, marshaled, nil)
+}
+
+// GetAgreementCertificate implements the
+// Reader.GetAgreementCertificate method.
+func (lvl *LevelDBBackedDB) GetAgreementCertificate(
+	hash common.Hash) (cert types.AgreementCertificate, err error) {
+	queried, err := lvl.db.Get(lvl.getAgreementCertificateKey(hash), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			err = ErrAgreementCertificateDoesNotExist
+		}
+		return
+	}
+	err = rlp.DecodeBytes(queried, &cert)
+	return
+}
+
+// PutAgreementCertificate implements the
+// Writer.PutAgreementCertificate method.
+func (lvl *LevelDBBackedDB) PutAgreementCertificate(
+	cert types.AgreementCertificate) error {
+	marshaled, err := rlp.EncodeToBytes(&cert)
+	if err != nil {
+		return err
+	}
+	return lvl.db.Put(
+		lvl.getAgreementCertificateKey(cert.BlockHash), marshaled, nil)
+}
+
 func (lvl *LevelDBBackedDB) getBlockKey(hash common.Hash) (ret []byte) {
 	ret = make([]byte, len(blockKeyPrefix)+len(hash[:]))
 	copy(ret, blockKeyPrefix)
@@ -557,6 +973,18 @@ func (lvl *LevelDBBackedDB) getBlockKey(hash common.Hash) (ret []byte) {
 	return
 }
 
+func (lvl *LevelDBBackedDB) getBlockHeightKey(height uint64) (ret []byte) {
+	// Big endian is used to make keys sorted by height.
+	ret = make([]byte, len(blockHeightKeyPrefix)+8)
+	copy(ret, blockHeightKeyPrefix)
+	binary.BigEndian.PutUint64(ret[len(blockHeightKeyPrefix):], height)
+	return
+}
+
+func blockHeightKeyLimit() []byte {
+	return util.BytesPrefix(blockHeightKeyPrefix).Limit
+}
+
 func (lvl *LevelDBBackedDB) getDKGPrivateKeyKey(
 	round uint64) (ret []byte) {
 	ret = make([]byte, len(dkgPrivateKeyKeyPrefix)+8)
@@ -571,3 +999,32 @@ func (lvl *LevelDBBackedDB) getDKGProtocolInfoKey() (ret []byte) {
 	copy(ret, dkgProtocolInfoKeyPrefix)
 	return
 }
+
+func (lvl *LevelDBBackedDB) getVoteJournalPositionKey(
+	position types.Position) (ret []byte) {
+	ret = make([]byte, len(voteJournalKeyPrefix)+16)
+	copy(ret, voteJournalKeyPrefix)
+	binary.BigEndian.PutUint64(
+		ret[len(voteJournalKeyPrefix):], position.Round)
+	binary.BigEndian.PutUint64(
+		ret[len(voteJournalKeyPrefix)+8:], position.Height)
+	return
+}
+
+func (lvl *LevelDBBackedDB) getVoteJournalKey(vote *types.Vote) (ret []byte) {
+	// Big endian is used to make keys sorted by period and type.
+	prefix := lvl.getVoteJournalPositionKey(vote.Position)
+	ret = make([]byte, len(prefix)+9)
+	copy(ret, prefix)
+	binary.BigEndian.PutUint64(ret[len(prefix):], vote.Period)
+	ret[len(prefix)+8] = byte(vote.Type)
+	return
+}
+
+func (lvl *LevelDBBackedDB) getAgreementCertificateKey(
+	hash common.Hash) (ret []byte) {
+	ret = make([]byte, len(certificateKeyPrefix)+len(hash[:]))
+	copy(ret, certificateKeyPrefix)
+	copy(ret[len(certificateKeyPrefix):], hash[:])
+	return
+}
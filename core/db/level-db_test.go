@@ -30,6 +30,7 @@ import (
 
 	"github.com/tangerine-network/go-tangerine/rlp"
 	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 )
@@ -194,6 +195,11 @@ func (s *LevelDBTestSuite) TestDKGPrivateKey() {
 	s.Require().NoError(err)
 	s.Require().Equal(bytes.Compare(p2.Bytes(), tmpPrv.Bytes()), 0)
 	s.Require().NotEqual(bytes.Compare(p2.Bytes(), p.Bytes()), 0)
+	// Delete it, deleting it again is fine.
+	s.Require().NoError(dbInst.DeleteDKGPrivateKey(1))
+	_, err = dbInst.GetDKGPrivateKey(1, 1)
+	s.Require().Equal(ErrDKGPrivateKeyDoesNotExist, err)
+	s.Require().NoError(dbInst.DeleteDKGPrivateKey(1))
 }
 
 func (s *LevelDBTestSuite) TestDKGProtocol() {
@@ -211,6 +217,331 @@ func (s *LevelDBTestSuite) TestDKGProtocol() {
 	s.Require().Equal(err.Error(), ErrDKGProtocolDoesNotExist.Error())
 
 	s.Require().NoError(dbInst.PutOrUpdateDKGProtocol(DKGProtocolInfo{}))
+	_, err = dbInst.GetDKGProtocol()
+	s.Require().NoError(err)
+
+	s.Require().NoError(dbInst.DeleteDKGProtocol())
+	_, err = dbInst.GetDKGProtocol()
+	s.Require().Equal(ErrDKGProtocolDoesNotExist, err)
+}
+
+func (s *LevelDBTestSuite) TestVoteJournal() {
+	dbName := fmt.Sprintf("test-db-%v-vote-journal.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	pos := types.Position{Round: 1, Height: 10}
+	// Nothing journaled.
+	votes, err := dbInst.GetVoteJournal(pos)
+	s.Require().NoError(err)
+	s.Require().Empty(votes)
+	v1 := types.NewVote(types.VoteCom, common.NewRandomHash(), 3)
+	v1.Position = pos
+	v2 := types.NewVote(types.VotePreCom, common.NewRandomHash(), 3)
+	v2.Position = pos
+	v3 := types.NewVote(types.VoteInit, common.NewRandomHash(), 2)
+	v3.Position = pos
+	v4 := types.NewVote(types.VoteInit, common.NewRandomHash(), 2)
+	v4.Position = types.Position{Round: 1, Height: 11}
+	for _, v := range []*types.Vote{v1, v2, v3, v4} {
+		s.Require().NoError(dbInst.PutVoteJournal(*v))
+	}
+	// Journal the same vote again.
+	s.Require().NoError(dbInst.PutVoteJournal(*v1))
+	// Journal a conflicting vote.
+	conflict := v1.Clone()
+	conflict.BlockHash = common.NewRandomHash()
+	s.Require().Equal(ErrVoteJournalConflict, dbInst.PutVoteJournal(*conflict))
+	// Votes should survive after reopening the database.
+	s.Require().NoError(dbInst.Close())
+	dbInst, err = NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	votes, err = dbInst.GetVoteJournal(pos)
+	s.Require().NoError(err)
+	s.Require().Len(votes, 3)
+	s.Require().Equal(v3.VoteHeader, votes[0].VoteHeader)
+	s.Require().Equal(v2.VoteHeader, votes[1].VoteHeader)
+	s.Require().Equal(v1.VoteHeader, votes[2].VoteHeader)
+}
+
+func (s *LevelDBTestSuite) TestPruneVoteJournal() {
+	dbName := fmt.Sprintf("test-db-%v-prune-vote-journal.db",
+		time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	positions := []types.Position{
+		{Round: 0, Height: 9},
+		{Round: 1, Height: 10},
+		{Round: 1, Height: 11},
+	}
+	for _, pos := range positions {
+		v := types.NewVote(types.VoteCom, common.NewRandomHash(), 0)
+		v.Position = pos
+		s.Require().NoError(dbInst.PutVoteJournal(*v))
+	}
+	s.Require().NoError(dbInst.PruneVoteJournal(positions[1]))
+	votes, err := dbInst.GetVoteJournal(positions[0])
+	s.Require().NoError(err)
+	s.Require().Empty(votes)
+	for _, pos := range positions[1:] {
+		votes, err = dbInst.GetVoteJournal(pos)
+		s.Require().NoError(err)
+		s.Require().Len(votes, 1)
+	}
+}
+
+func (s *LevelDBTestSuite) TestAgreementCertificate() {
+	dbName := fmt.Sprintf("test-db-%v-agreement-certificate.db",
+		time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	cert := types.AgreementCertificate{
+		BlockHash: common.NewRandomHash(),
+		Position:  types.Position{Round: 1, Height: 10},
+		Signers:   []byte{0x7},
+		Signature: common.GenerateRandomBytes(),
+		ProposerSignature: crypto.Signature{
+			Type:      "bls",
+			Signature: common.GenerateRandomBytes(),
+		},
+	}
+	_, err = dbInst.GetAgreementCertificate(cert.BlockHash)
+	s.Require().Equal(ErrAgreementCertificateDoesNotExist, err)
+	s.Require().NoError(dbInst.PutAgreementCertificate(cert))
+	queried, err := dbInst.GetAgreementCertificate(cert.BlockHash)
+	s.Require().NoError(err)
+	s.Require().Equal(cert, queried)
+	// The saved one would be replaced.
+	cert.Signers = []byte{0xf}
+	s.Require().NoError(dbInst.PutAgreementCertificate(cert))
+	queried, err = dbInst.GetAgreementCertificate(cert.BlockHash)
+	s.Require().NoError(err)
+	s.Require().Equal(cert, queried)
+}
+
+func (s *LevelDBTestSuite) preparePruning(dbInst *LevelDBBackedDB) []*types.Block {
+	blocks := []*types.Block{}
+	for h := uint64(1); h <= 10; h++ {
+		b := &types.Block{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Round: (h - 1) / 3, Height: h},
+		}
+		s.Require().NoError(dbInst.PutBlock(*b))
+		s.Require().NoError(dbInst.PutCompactionChainTipInfo(b.Hash, h))
+		blocks = append(blocks, b)
+		vote := types.NewVote(types.VoteCom, b.Hash, 2)
+		vote.Position = b.Position
+		s.Require().NoError(dbInst.PutVoteJournal(*vote))
+		s.Require().NoError(dbInst.PutAgreementCertificate(
+			types.AgreementCertificate{
+				BlockHash: b.Hash,
+				Position:  b.Position,
+			}))
+	}
+	for round := uint64(0); round <= 3; round++ {
+		s.Require().NoError(
+			dbInst.PutDKGPrivateKey(round, 0, *dkg.NewPrivateKey()))
+	}
+	return blocks
+}
+
+func (s *LevelDBTestSuite) TestPrune() {
+	dbName := fmt.Sprintf("test-db-%v-prune.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	blocks := s.preparePruning(dbInst)
+	// Nothing is pruned without any window.
+	pruned, err := dbInst.Prune(RetentionPolicy{})
+	s.Require().NoError(err)
+	s.Require().Equal(0, pruned)
+	// Keep latest 4 heights.
+	pruned, err = dbInst.Prune(RetentionPolicy{KeepHeights: 4})
+	s.Require().NoError(err)
+	s.Require().Equal(6, pruned)
+	for _, b := range blocks {
+		s.Equal(b.Position.Height >= 7, dbInst.HasBlock(b.Hash))
+		hash, err := dbInst.GetBlockHashByHeight(b.Position.Height)
+		_, certErr := dbInst.GetAgreementCertificate(b.Hash)
+		if b.Position.Height >= 7 {
+			s.Require().NoError(err)
+			s.Equal(b.Hash, hash)
+			s.NoError(certErr)
+		} else {
+			s.Equal(ErrBlockDoesNotExist, err)
+			s.Equal(ErrAgreementCertificateDoesNotExist, certErr)
+		}
+	}
+	for round := uint64(0); round <= 3; round++ {
+		_, err = dbInst.GetDKGPrivateKey(round, 0)
+		if round >= 2 {
+			s.NoError(err)
+		} else {
+			s.Equal(ErrDKGPrivateKeyDoesNotExist, err)
+		}
+	}
+	// Journaled votes below the tip are pruned.
+	votes, err := dbInst.GetVoteJournal(blocks[8].Position)
+	s.Require().NoError(err)
+	s.Empty(votes)
+	votes, err = dbInst.GetVoteJournal(blocks[9].Position)
+	s.Require().NoError(err)
+	s.Len(votes, 1)
+	// Keep latest round, the tip should always be kept.
+	pruned, err = dbInst.Prune(RetentionPolicy{KeepRounds: 1})
+	s.Require().NoError(err)
+	s.Require().Equal(3, pruned)
+	iter, err := dbInst.GetAllBlocks()
+	s.Require().NoError(err)
+	b, err := iter.NextBlock()
+	s.Require().NoError(err)
+	s.Equal(blocks[9].Hash, b.Hash)
+	_, err = iter.NextBlock()
+	s.Equal(ErrIterationFinished, err)
+}
+
+func (s *LevelDBTestSuite) TestStartPruning() {
+	dbName := fmt.Sprintf("test-db-%v-start-pruning.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	blocks := s.preparePruning(dbInst)
+	policy := RetentionPolicy{KeepHeights: 1}
+	s.Require().NoError(dbInst.StartPruning(policy, 10*time.Millisecond))
+	s.Require().Equal(
+		ErrPruningStarted, dbInst.StartPruning(policy, 10*time.Millisecond))
+	deadline := time.Now().Add(time.Second)
+	for dbInst.HasBlock(blocks[8].Hash) {
+		s.Require().True(time.Now().Before(deadline))
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.True(dbInst.HasBlock(blocks[9].Hash))
+	s.Require().NoError(dbInst.Close())
+}
+
+func (s *LevelDBTestSuite) TestGetAllBlocks() {
+	dbName := fmt.Sprintf("test-db-%v-all-blocks.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	blocks := s.preparePruning(dbInst)
+	iter, err := dbInst.GetAllBlocks()
+	s.Require().NoError(err)
+	for _, b := range blocks {
+		queried, err := iter.NextBlock()
+		s.Require().NoError(err)
+		s.Require().Equal(b.Hash, queried.Hash)
+	}
+	_, err = iter.NextBlock()
+	s.Require().Equal(ErrIterationFinished, err)
+}
+
+func (s *LevelDBTestSuite) TestGetBlocksByHeight() {
+	dbName := fmt.Sprintf("test-db-%v-by-height.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	blocks := s.preparePruning(dbInst)
+	b, err := dbInst.GetBlockByPosition(blocks[4].Position)
+	s.Require().NoError(err)
+	s.Require().Equal(blocks[4].Hash, b.Hash)
+	_, err = dbInst.GetBlockByPosition(
+		types.Position{Round: 0, Height: blocks[4].Position.Height})
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	_, err = dbInst.GetBlockByPosition(types.Position{Round: 3, Height: 11})
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	queried, err := dbInst.GetBlocksInRange(3, 7)
+	s.Require().NoError(err)
+	s.Require().Len(queried, 4)
+	for i, b := range queried {
+		s.Require().Equal(blocks[i+2].Hash, b.Hash)
+	}
+	queried, err = dbInst.GetBlocksInRange(9, 100)
+	s.Require().NoError(err)
+	s.Require().Len(queried, 2)
+	queried, err = dbInst.GetBlocksInRange(7, 3)
+	s.Require().NoError(err)
+	s.Require().Empty(queried)
+}
+
+func (s *LevelDBTestSuite) TestMigrateHeightIndex() {
+	dbName := fmt.Sprintf("test-db-%v-migrate.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	// Mimic a database saved before the height index is introduced.
+	blocks := []*types.Block{}
+	for h := uint64(1); h <= 3; h++ {
+		b := &types.Block{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Round: 1, Height: h},
+		}
+		marshaled, err := rlp.EncodeToBytes(b)
+		s.Require().NoError(err)
+		s.Require().NoError(
+			dbInst.db.Put(dbInst.getBlockKey(b.Hash), marshaled, nil))
+		blocks = append(blocks, b)
+	}
+	s.Require().NoError(dbInst.db.Delete(dbVersionKey, nil))
+	s.Require().NoError(dbInst.Close())
+	// The height index is backfilled when reopened.
+	dbInst, err = NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(dbInst.Close())
+	}()
+	iter, err := dbInst.GetAllBlocks()
+	s.Require().NoError(err)
+	for _, b := range blocks {
+		queried, err := iter.NextBlock()
+		s.Require().NoError(err)
+		s.Require().Equal(b.Hash, queried.Hash)
+	}
+	_, err = iter.NextBlock()
+	s.Require().Equal(ErrIterationFinished, err)
+	queried, err := dbInst.GetBlocksInRange(2, 4)
+	s.Require().NoError(err)
+	s.Require().Len(queried, 2)
+	s.Require().Equal(blocks[1].Hash, queried[0].Hash)
+	s.Require().Equal(blocks[2].Hash, queried[1].Hash)
 }
 
 func (s *LevelDBTestSuite) TestDKGProtocolInfoRLPEncodeDecode() {
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"sort"
 	"sync"
 
 	"github.com/tangerine-network/tangerine-consensus/common"
@@ -45,6 +46,8 @@ type MemBackedDB struct {
 	blocksLock               sync.RWMutex
 	blockHashSequence        common.Hashes
 	blocksByHash             map[common.Hash]*types.Block
+	blockHashByHeight        map[uint64]common.Hash
+	blockHeights             []uint64
 	compactionChainTipLock   sync.RWMutex
 	compactionChainTipHash   common.Hash
 	compactionChainTipHeight uint64
@@ -52,6 +55,10 @@ type MemBackedDB struct {
 	dkgPrivateKeys           map[uint64]*dkgPrivateKey
 	dkgProtocolLock          sync.RWMutex
 	dkgProtocolInfo          *DKGProtocolInfo
+	voteJournalLock          sync.RWMutex
+	voteJournal              map[types.Position][]types.Vote
+	certificatesLock         sync.RWMutex
+	certificates             map[common.Hash]types.AgreementCertificate
 	persistantFilePath       string
 }
 
@@ -61,7 +68,11 @@ func NewMemBackedDB(persistantFilePath ...string) (
 	dbInst = &MemBackedDB{
 		blockHashSequence: common.Hashes{},
 		blocksByHash:      make(map[common.Hash]*types.Block),
+		blockHashByHeight: make(map[uint64]common.Hash),
 		dkgPrivateKeys:    make(map[uint64]*dkgPrivateKey),
+		voteJournal:       make(map[types.Position][]types.Vote),
+		certificates: make(
+			map[common.Hash]types.AgreementCertificate),
 	}
 	if len(persistantFilePath) == 0 || len(persistantFilePath[0]) == 0 {
 		return
@@ -82,8 +93,10 @@ func NewMemBackedDB(persistantFilePath ...string) (
 	// Init this instance by file content, it's a temporary way
 	// to export those private field for JSON encoding.
 	toLoad := struct {
-		Sequence common.Hashes
-		ByHash   map[common.Hash]*types.Block
+		Sequence     common.Hashes
+		ByHash       map[common.Hash]*types.Block
+		Votes        []types.Vote
+		Certificates []types.AgreementCertificate
 	}{}
 	err = json.Unmarshal(buf, &toLoad)
 	if err != nil {
@@ -91,6 +104,17 @@ func NewMemBackedDB(persistantFilePath ...string) (
 	}
 	dbInst.blockHashSequence = toLoad.Sequence
 	dbInst.blocksByHash = toLoad.ByHash
+	for _, hash := range dbInst.blockHashSequence {
+		dbInst.indexBlockHeight(dbInst.blocksByHash[hash])
+	}
+	// Journaled votes are dumped in order, grouped by position.
+	for _, v := range toLoad.Votes {
+		dbInst.voteJournal[v.Position] = append(
+			dbInst.voteJournal[v.Position], v)
+	}
+	for _, cert := range toLoad.Certificates {
+		dbInst.certificates[cert.BlockHash] = cert
+	}
 	return
 }
 
@@ -130,9 +154,66 @@ func (m *MemBackedDB) PutBlock(block types.Block) error {
 
 	m.blockHashSequence = append(m.blockHashSequence, block.Hash)
 	m.blocksByHash[block.Hash] = &block
+	m.indexBlockHeight(&block)
 	return nil
 }
 
+// indexBlockHeight adds a block to the height index, blocksLock should be
+// held by the caller.
+func (m *MemBackedDB) indexBlockHeight(block *types.Block) {
+	height := block.Position.Height
+	if _, exist := m.blockHashByHeight[height]; !exist {
+		idx := sort.Search(len(m.blockHeights), func(i int) bool {
+			return m.blockHeights[i] >= height
+		})
+		m.blockHeights = append(m.blockHeights, 0)
+		copy(m.blockHeights[idx+1:], m.blockHeights[idx:])
+		m.blockHeights[idx] = height
+	}
+	m.blockHashByHeight[height] = block.Hash
+}
+
+// GetBlockByPosition returns the block at the position.
+func (m *MemBackedDB) GetBlockByPosition(
+	position types.Position) (types.Block, error) {
+	m.blocksLock.RLock()
+	defer m.blocksLock.RUnlock()
+	hash, exist := m.blockHashByHeight[position.Height]
+	if !exist {
+		return types.Block{}, ErrBlockDoesNotExist
+	}
+	b, err := m.internalGetBlock(hash)
+	if err != nil {
+		return types.Block{}, err
+	}
+	if b.Position.Round != position.Round {
+		return types.Block{}, ErrBlockDoesNotExist
+	}
+	return b, nil
+}
+
+// GetBlocksInRange returns blocks with height in [fromHeight, toHeight).
+func (m *MemBackedDB) GetBlocksInRange(
+	fromHeight, toHeight uint64) ([]types.Block, error) {
+	m.blocksLock.RLock()
+	defer m.blocksLock.RUnlock()
+	begin := sort.Search(len(m.blockHeights), func(i int) bool {
+		return m.blockHeights[i] >= fromHeight
+	})
+	blocks := []types.Block{}
+	for _, height := range m.blockHeights[begin:] {
+		if height >= toHeight {
+			break
+		}
+		b, err := m.internalGetBlock(m.blockHashByHeight[height])
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
+
 // UpdateBlock updates a block in the database.
 func (m *MemBackedDB) UpdateBlock(block types.Block) error {
 	if !m.HasBlock(block.Hash) {
@@ -194,6 +275,14 @@ func (m *MemBackedDB) PutDKGPrivateKey(
 	return nil
 }
 
+// DeleteDKGPrivateKey deletes DKG private key of one round.
+func (m *MemBackedDB) DeleteDKGPrivateKey(round uint64) error {
+	m.dkgPrivateKeysLock.Lock()
+	defer m.dkgPrivateKeysLock.Unlock()
+	delete(m.dkgPrivateKeys, round)
+	return nil
+}
+
 // GetDKGProtocol get DKG protocol.
 func (m *MemBackedDB) GetDKGProtocol() (
 	DKGProtocolInfo, error) {
@@ -214,6 +303,82 @@ func (m *MemBackedDB) PutOrUpdateDKGProtocol(dkgProtocol DKGProtocolInfo) error
 	return nil
 }
 
+// DeleteDKGProtocol deletes DKG protocol.
+func (m *MemBackedDB) DeleteDKGProtocol() error {
+	m.dkgProtocolLock.Lock()
+	defer m.dkgProtocolLock.Unlock()
+	m.dkgProtocolInfo = nil
+	return nil
+}
+
+// GetVoteJournal gets journaled votes of one position.
+func (m *MemBackedDB) GetVoteJournal(
+	position types.Position) ([]types.Vote, error) {
+	m.voteJournalLock.RLock()
+	defer m.voteJournalLock.RUnlock()
+	votes := m.voteJournal[position]
+	return append(make([]types.Vote, 0, len(votes)), votes...), nil
+}
+
+// PutVoteJournal journals one vote.
+func (m *MemBackedDB) PutVoteJournal(vote types.Vote) error {
+	m.voteJournalLock.Lock()
+	defer m.voteJournalLock.Unlock()
+	votes := m.voteJournal[vote.Position]
+	for _, v := range votes {
+		if v.Period != vote.Period || v.Type != vote.Type {
+			continue
+		}
+		if v.BlockHash != vote.BlockHash {
+			return ErrVoteJournalConflict
+		}
+		return nil
+	}
+	votes = append(votes, vote)
+	sort.Slice(votes, func(i, j int) bool {
+		if votes[i].Period != votes[j].Period {
+			return votes[i].Period < votes[j].Period
+		}
+		return votes[i].Type < votes[j].Type
+	})
+	m.voteJournal[vote.Position] = votes
+	return nil
+}
+
+// PruneVoteJournal deletes journaled votes of positions older than 'before'.
+func (m *MemBackedDB) PruneVoteJournal(before types.Position) error {
+	m.voteJournalLock.Lock()
+	defer m.voteJournalLock.Unlock()
+	for pos := range m.voteJournal {
+		if pos.Older(before) {
+			delete(m.voteJournal, pos)
+		}
+	}
+	return nil
+}
+
+// GetAgreementCertificate gets the agreement certificate of one block.
+func (m *MemBackedDB) GetAgreementCertificate(
+	hash common.Hash) (types.AgreementCertificate, error) {
+	m.certificatesLock.RLock()
+	defer m.certificatesLock.RUnlock()
+	cert, exists := m.certificates[hash]
+	if !exists {
+		return types.AgreementCertificate{},
+			ErrAgreementCertificateDoesNotExist
+	}
+	return *cert.Clone(), nil
+}
+
+// PutAgreementCertificate saves the agreement certificate of one block.
+func (m *MemBackedDB) PutAgreementCertificate(
+	cert types.AgreementCertificate) error {
+	m.certificatesLock.Lock()
+	defer m.certificatesLock.Unlock()
+	m.certificates[cert.BlockHash] = *cert.Clone()
+	return nil
+}
+
 // Close implement Closer interface, which would release allocated resource.
 func (m *MemBackedDB) Close() (err error) {
 	// Save internal state to a pretty-print json file. It's a temporary way
@@ -224,14 +389,26 @@ func (m *MemBackedDB) Close() (err error) {
 
 	m.blocksLock.RLock()
 	defer m.blocksLock.RUnlock()
+	m.voteJournalLock.RLock()
+	defer m.voteJournalLock.RUnlock()
+	m.certificatesLock.RLock()
+	defer m.certificatesLock.RUnlock()
 
 	toDump := struct {
-		Sequence common.Hashes
-		ByHash   map[common.Hash]*types.Block
+		Sequence     common.Hashes
+		ByHash       map[common.Hash]*types.Block
+		Votes        []types.Vote
+		Certificates []types.AgreementCertificate
 	}{
 		Sequence: m.blockHashSequence,
 		ByHash:   m.blocksByHash,
 	}
+	for _, votes := range m.voteJournal {
+		toDump.Votes = append(toDump.Votes, votes...)
+	}
+	for _, cert := range m.certificates {
+		toDump.Certificates = append(toDump.Certificates, cert)
+	}
 
 	// Dump to JSON with 2-space indent.
 	buf, err := json.Marshal(&toDump)
@@ -128,6 +128,36 @@ func (s *MemBackedDBTestSuite) TestIteration() {
 	s.Contains(touched, s.b02.Hash)
 }
 
+func (s *MemBackedDBTestSuite) TestGetBlocksByHeight() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NotNil(dbInst)
+	// Put blocks out of order.
+	s.Require().NoError(dbInst.PutBlock(*s.b02))
+	s.Require().NoError(dbInst.PutBlock(*s.b00))
+	s.Require().NoError(dbInst.PutBlock(*s.b01))
+	b, err := dbInst.GetBlockByPosition(s.b01.Position)
+	s.Require().NoError(err)
+	s.Require().Equal(s.b01.Hash, b.Hash)
+	_, err = dbInst.GetBlockByPosition(types.Position{Round: 1, Height: 1})
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	_, err = dbInst.GetBlockByPosition(types.Position{Height: 3})
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	blocks, err := dbInst.GetBlocksInRange(1, 10)
+	s.Require().NoError(err)
+	s.Require().Len(blocks, 2)
+	s.Require().Equal(s.b01.Hash, blocks[0].Hash)
+	s.Require().Equal(s.b02.Hash, blocks[1].Hash)
+	blocks, err = dbInst.GetBlocksInRange(0, 2)
+	s.Require().NoError(err)
+	s.Require().Len(blocks, 2)
+	s.Require().Equal(s.b00.Hash, blocks[0].Hash)
+	s.Require().Equal(s.b01.Hash, blocks[1].Hash)
+	blocks, err = dbInst.GetBlocksInRange(2, 2)
+	s.Require().NoError(err)
+	s.Require().Empty(blocks)
+}
+
 func (s *MemBackedDBTestSuite) TestCompactionChainTipInfo() {
 	dbInst, err := NewMemBackedDB()
 	s.Require().NoError(err)
@@ -179,6 +209,134 @@ func (s *MemBackedDBTestSuite) TestDKGPrivateKey() {
 	s.Require().NoError(err)
 	s.Require().Equal(bytes.Compare(p2.Bytes(), tmpPrv.Bytes()), 0)
 	s.Require().NotEqual(bytes.Compare(p2.Bytes(), p.Bytes()), 0)
+	// Delete it, deleting it again is fine.
+	s.Require().NoError(dbInst.DeleteDKGPrivateKey(1))
+	_, err = dbInst.GetDKGPrivateKey(1, 1)
+	s.Require().Equal(ErrDKGPrivateKeyDoesNotExist, err)
+	s.Require().NoError(dbInst.DeleteDKGPrivateKey(1))
+}
+
+func (s *MemBackedDBTestSuite) TestVoteJournal() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NotNil(dbInst)
+	pos := types.Position{Round: 1, Height: 10}
+	v1 := types.NewVote(types.VoteCom, common.NewRandomHash(), 3)
+	v1.Position = pos
+	v2 := types.NewVote(types.VotePreCom, common.NewRandomHash(), 3)
+	v2.Position = pos
+	v3 := types.NewVote(types.VoteInit, common.NewRandomHash(), 2)
+	v3.Position = types.Position{Round: 1, Height: 11}
+	for _, v := range []*types.Vote{v1, v2, v3} {
+		s.Require().NoError(dbInst.PutVoteJournal(*v))
+	}
+	// Journal the same vote again.
+	s.Require().NoError(dbInst.PutVoteJournal(*v2))
+	// Journal a conflicting vote.
+	conflict := v2.Clone()
+	conflict.BlockHash = common.NewRandomHash()
+	s.Require().Equal(ErrVoteJournalConflict, dbInst.PutVoteJournal(*conflict))
+	votes, err := dbInst.GetVoteJournal(pos)
+	s.Require().NoError(err)
+	s.Require().Len(votes, 2)
+	s.Require().Equal(v2.VoteHeader, votes[0].VoteHeader)
+	s.Require().Equal(v1.VoteHeader, votes[1].VoteHeader)
+}
+
+func (s *MemBackedDBTestSuite) TestVoteJournalSaveAndLoad() {
+	dbPath := "test-vote-journal-save-and-load.db"
+	dbInst, err := NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(os.Remove(dbPath))
+	}()
+	pos := types.Position{Round: 1, Height: 10}
+	v1 := types.NewVote(types.VoteCom, common.NewRandomHash(), 3)
+	v1.Position = pos
+	v2 := types.NewVote(types.VotePreCom, common.NewRandomHash(), 3)
+	v2.Position = pos
+	v3 := types.NewVote(types.VoteInit, common.NewRandomHash(), 2)
+	v3.Position = types.Position{Round: 1, Height: 11}
+	for _, v := range []*types.Vote{v1, v2, v3} {
+		s.Require().NoError(dbInst.PutVoteJournal(*v))
+	}
+	s.Require().NoError(dbInst.Close())
+	// Journaled votes are kept after reopening.
+	dbInst, err = NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	votes, err := dbInst.GetVoteJournal(pos)
+	s.Require().NoError(err)
+	s.Require().Len(votes, 2)
+	s.Require().Equal(v2.VoteHeader, votes[0].VoteHeader)
+	s.Require().Equal(v1.VoteHeader, votes[1].VoteHeader)
+	votes, err = dbInst.GetVoteJournal(v3.Position)
+	s.Require().NoError(err)
+	s.Require().Len(votes, 1)
+	s.Require().Equal(v3.VoteHeader, votes[0].VoteHeader)
+	// Conflicting votes are still detected.
+	conflict := v1.Clone()
+	conflict.BlockHash = common.NewRandomHash()
+	s.Require().Equal(ErrVoteJournalConflict, dbInst.PutVoteJournal(*conflict))
+	s.Require().NoError(dbInst.Close())
+}
+
+func (s *MemBackedDBTestSuite) TestPruneVoteJournal() {
+	dbPath := "test-prune-vote-journal.db"
+	dbInst, err := NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(os.Remove(dbPath))
+	}()
+	positions := []types.Position{
+		{Round: 0, Height: 9},
+		{Round: 1, Height: 10},
+		{Round: 1, Height: 11},
+	}
+	for _, pos := range positions {
+		v := types.NewVote(types.VoteCom, common.NewRandomHash(), 0)
+		v.Position = pos
+		s.Require().NoError(dbInst.PutVoteJournal(*v))
+	}
+	s.Require().NoError(dbInst.PruneVoteJournal(positions[1]))
+	s.Require().NoError(dbInst.Close())
+	// Pruned votes should not be dumped.
+	dbInst, err = NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	votes, err := dbInst.GetVoteJournal(positions[0])
+	s.Require().NoError(err)
+	s.Require().Empty(votes)
+	for _, pos := range positions[1:] {
+		votes, err = dbInst.GetVoteJournal(pos)
+		s.Require().NoError(err)
+		s.Require().Len(votes, 1)
+	}
+	s.Require().NoError(dbInst.Close())
+}
+
+func (s *MemBackedDBTestSuite) TestAgreementCertificate() {
+	dbPath := "test-agreement-certificate.db"
+	dbInst, err := NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(os.Remove(dbPath))
+	}()
+	cert := types.AgreementCertificate{
+		BlockHash: common.NewRandomHash(),
+		Position:  types.Position{Round: 1, Height: 10},
+		Signers:   []byte{0x7},
+		Signature: common.GenerateRandomBytes(),
+	}
+	_, err = dbInst.GetAgreementCertificate(cert.BlockHash)
+	s.Require().Equal(ErrAgreementCertificateDoesNotExist, err)
+	s.Require().NoError(dbInst.PutAgreementCertificate(cert))
+	s.Require().NoError(dbInst.Close())
+	// Certificates should be dumped.
+	dbInst, err = NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	queried, err := dbInst.GetAgreementCertificate(cert.BlockHash)
+	s.Require().NoError(err)
+	s.Require().Equal(cert, queried)
+	s.Require().NoError(dbInst.Close())
 }
 
 func TestMemBackedDB(t *testing.T) {
@@ -607,10 +607,14 @@ func (tc *TSigVerifierCache) Update(round uint64) (bool, error) {
 	if !tc.intf.IsDKGFinal(round) {
 		return false, nil
 	}
+	cfg, err := utils.GetConfig(tc.intf, round, nil)
+	if err != nil {
+		return false, err
+	}
 	gpk, err := typesDKG.NewGroupPublicKey(round,
 		tc.intf.DKGMasterPublicKeys(round),
 		tc.intf.DKGComplaints(round),
-		utils.GetDKGThreshold(utils.GetConfigWithPanic(tc.intf, round, nil)))
+		utils.GetDKGThreshold(cfg))
 	if err != nil {
 		return false, err
 	}
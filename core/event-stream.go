@@ -0,0 +1,279 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+// ConsensusEvent is the interface of events emitted to subscribers of
+// Consensus.
+type ConsensusEvent interface {
+	// Timestamp returns the time when this event is emitted.
+	Timestamp() time.Time
+}
+
+// EventTime carries the emitting time of an event.
+type EventTime struct {
+	Time time.Time
+}
+
+// Timestamp implements ConsensusEvent interface.
+func (e EventTime) Timestamp() time.Time {
+	return e.Time
+}
+
+// BAStateEvent is emitted when BA module switches its state.
+type BAStateEvent struct {
+	EventTime
+	Position types.Position
+	Period   uint64
+	From     string
+	To       string
+}
+
+// VoteEvent is emitted when a vote is accepted by BA module.
+type VoteEvent struct {
+	EventTime
+	Vote types.Vote
+}
+
+// AgreementResultEvent is emitted when an agreement result is accepted.
+type AgreementResultEvent struct {
+	EventTime
+	Result types.AgreementResult
+}
+
+// DKGStepEvent is emitted when a DKG phase is done and DKG protocol moves to
+// next step.
+type DKGStepEvent struct {
+	EventTime
+	Round uint64
+	Reset uint64
+	Step  int
+}
+
+// CRSProposalEvent is emitted when CRS for next round is proposed to
+// governance, or a DKG reset is requested when Reset is true.
+type CRSProposalEvent struct {
+	EventTime
+	Round uint64
+	CRS   []byte
+	Reset bool
+}
+
+// ForkVoteEvent is emitted when a forked vote is reported.
+type ForkVoteEvent struct {
+	EventTime
+	Vote1 types.Vote
+	Vote2 types.Vote
+}
+
+// ForkBlockEvent is emitted when a forked block is reported.
+type ForkBlockEvent struct {
+	EventTime
+	Block1 *types.Block
+	Block2 *types.Block
+}
+
+// RoundTriggeredEvent is emitted when a round event is triggered by
+// utils.RoundEvent.
+type RoundTriggeredEvent struct {
+	EventTime
+	Param utils.RoundEventParam
+}
+
+// Subscription receives events from Consensus via a bounded channel.
+//
+// Events are delivered without blocking: when the channel of a subscription is
+// full, the newly emitted event is dropped for that subscription and counted
+// by Dropped. Subscribers which can't keep up would lose events, but would
+// never stall consensus.
+type Subscription struct {
+	hub     *eventHub
+	ch      chan ConsensusEvent
+	dropped uint64
+	once    sync.Once
+}
+
+// Events returns the channel to receive events. It's closed after calling
+// Unsubscribe.
+func (s *Subscription) Events() <-chan ConsensusEvent {
+	return s.ch
+}
+
+// Dropped returns the count of events dropped for this subscription.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe stops receiving events.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.hub.lock.Lock()
+		defer s.hub.lock.Unlock()
+		delete(s.hub.subscriptions, s)
+		close(s.ch)
+	})
+}
+
+// eventHub dispatches events to subscriptions.
+type eventHub struct {
+	lock          sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+	clock         common.Clock
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscriptions: make(map[*Subscription]struct{}),
+		clock:         &common.SystemClock{},
+	}
+}
+
+func (h *eventHub) subscribe(capacity int) *Subscription {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	s := &Subscription{
+		hub: h,
+		ch:  make(chan ConsensusEvent, capacity),
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.subscriptions[s] = struct{}{}
+	return s
+}
+
+// active checks if there is any subscription, it's used to skip preparing
+// events nobody cares.
+func (h *eventHub) active() bool {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return len(h.subscriptions) > 0
+}
+
+func (h *eventHub) emit(e ConsensusEvent) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	for s := range h.subscriptions {
+		select {
+		case s.ch <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+func (h *eventHub) emitBAState(
+	pos types.Position, period uint64, from, to agreementStateType) {
+	if !h.active() {
+		return
+	}
+	h.emit(&BAStateEvent{
+		EventTime: EventTime{h.clock.Now()},
+		Position:  pos,
+		Period:    period,
+		From:      from.String(),
+		To:        to.String(),
+	})
+}
+
+func (h *eventHub) emitVote(v *types.Vote) {
+	if !h.active() {
+		return
+	}
+	h.emit(&VoteEvent{
+		EventTime: EventTime{h.clock.Now()},
+		Vote:      *v.Clone(),
+	})
+}
+
+func (h *eventHub) emitAgreementResult(r *types.AgreementResult) {
+	if !h.active() {
+		return
+	}
+	h.emit(&AgreementResultEvent{
+		EventTime: EventTime{h.clock.Now()},
+		Result:    *r.Clone(),
+	})
+}
+
+func (h *eventHub) emitDKGStep(round, reset uint64, step int) {
+	if !h.active() {
+		return
+	}
+	h.emit(&DKGStepEvent{
+		EventTime: EventTime{h.clock.Now()},
+		Round:     round,
+		Reset:     reset,
+		Step:      step,
+	})
+}
+
+func (h *eventHub) emitCRSProposal(round uint64, crs []byte, reset bool) {
+	if !h.active() {
+		return
+	}
+	h.emit(&CRSProposalEvent{
+		EventTime: EventTime{h.clock.Now()},
+		Round:     round,
+		CRS:       common.CopyBytes(crs),
+		Reset:     reset,
+	})
+}
+
+func (h *eventHub) emitForkVote(v1, v2 *types.Vote) {
+	if !h.active() {
+		return
+	}
+	h.emit(&ForkVoteEvent{
+		EventTime: EventTime{h.clock.Now()},
+		Vote1:     *v1.Clone(),
+		Vote2:     *v2.Clone(),
+	})
+}
+
+func (h *eventHub) emitForkBlock(b1, b2 *types.Block) {
+	if !h.active() {
+		return
+	}
+	h.emit(&ForkBlockEvent{
+		EventTime: EventTime{h.clock.Now()},
+		Block1:    b1,
+		Block2:    b2,
+	})
+}
+
+func (h *eventHub) emitRoundEvents(evts []utils.RoundEventParam) {
+	if !h.active() {
+		return
+	}
+	for _, e := range evts {
+		h.emit(&RoundTriggeredEvent{
+			EventTime: EventTime{h.clock.Now()},
+			Param:     e,
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+type EventStreamTestSuite struct {
+	suite.Suite
+}
+
+func (s *EventStreamTestSuite) TestDropWhenFull() {
+	hub := newEventHub()
+	// Nothing should be blocked without subscriptions.
+	hub.emitDKGStep(1, 0, 1)
+	sub := hub.subscribe(2)
+	for i := 1; i <= 5; i++ {
+		hub.emitDKGStep(1, 0, i)
+	}
+	s.Require().Equal(uint64(3), sub.Dropped())
+	// The oldest events are kept.
+	for i := 1; i <= 2; i++ {
+		e := <-sub.Events()
+		step, ok := e.(*DKGStepEvent)
+		s.Require().True(ok)
+		s.Require().Equal(i, step.Step)
+	}
+	// Room is available again.
+	hub.emitBAState(types.Position{Height: 1}, 2, stateFast, stateFastVote)
+	e := <-sub.Events()
+	state, ok := e.(*BAStateEvent)
+	s.Require().True(ok)
+	s.Require().Equal("fast", state.From)
+	s.Require().Equal("fastVote", state.To)
+}
+
+func (s *EventStreamTestSuite) TestUnsubscribe() {
+	hub := newEventHub()
+	sub1 := hub.subscribe(1)
+	sub2 := hub.subscribe(1)
+	sub1.Unsubscribe()
+	// Calling Unsubscribe twice is safe.
+	sub1.Unsubscribe()
+	_, open := <-sub1.Events()
+	s.Require().False(open)
+	hub.emitCRSProposal(2, []byte{1, 2, 3}, false)
+	e := <-sub2.Events()
+	crs, ok := e.(*CRSProposalEvent)
+	s.Require().True(ok)
+	s.Require().Equal(uint64(2), crs.Round)
+	s.Require().False(crs.Reset)
+	s.Require().Zero(sub2.Dropped())
+}
+
+func (s *EventStreamTestSuite) TestAgreementResultCopied() {
+	hub := newEventHub()
+	sub := hub.subscribe(1)
+	vote := types.NewVote(types.VoteCom, common.NewRandomHash(), 1)
+	vote.Signature = crypto.Signature{Type: "bls", Signature: []byte{1}}
+	result := &types.AgreementResult{
+		BlockHash:  vote.BlockHash,
+		Votes:      []types.Vote{*vote},
+		Randomness: []byte{2},
+		Certificate: &types.AgreementCertificate{
+			BlockHash: vote.BlockHash,
+			Signers:   []byte{3},
+			Signature: []byte{2},
+		},
+	}
+	hub.emitAgreementResult(result)
+	// Modifying the emitted result should not affect the event.
+	result.Votes[0].Signature.Signature[0] = 0
+	result.Randomness[0] = 0
+	result.Certificate.Signers[0] = 0
+	result.Certificate.Signature[0] = 0
+	e := <-sub.Events()
+	evt, ok := e.(*AgreementResultEvent)
+	s.Require().True(ok)
+	s.Require().Equal([]byte{1}, evt.Result.Votes[0].Signature.Signature)
+	s.Require().Equal([]byte{2}, evt.Result.Randomness)
+	s.Require().Equal([]byte{3}, evt.Result.Certificate.Signers)
+	s.Require().Equal([]byte{2}, evt.Result.Certificate.Signature)
+}
+
+func TestEventStream(t *testing.T) {
+	suite.Run(t, new(EventStreamTestSuite))
+}
@@ -85,10 +85,27 @@ type Network interface {
 	// ReceiveChan returns a channel to receive messages from DEXON network.
 	ReceiveChan() <-chan types.Msg
 
-	// ReportBadPeerChan returns a channel to report bad peer.
+	// ReportBadPeerChan returns a channel to report bad peer by its ID.
 	ReportBadPeerChan() chan<- interface{}
 }
 
+// BadPeerReporter describes the network interface that is able to accept
+// reports of bad peers along with their offenses. When Network implements it,
+// bad peers are reported via ReportBadPeer instead of ReportBadPeerChan.
+type BadPeerReporter interface {
+	// ReportBadPeer reports a bad peer with the offense it made.
+	ReportBadPeer(report *types.BadPeerReport)
+}
+
+// BlockRangePuller describes the network interface that is able to pull
+// finalized blocks by height range.
+type BlockRangePuller interface {
+	// PullBlockRange tries to pull finalized blocks with height in [from, to)
+	// along with their agreement certificates, pages of *types.BlockRange
+	// would be received from Network.ReceiveChan.
+	PullBlockRange(from, to uint64)
+}
+
 // Governance interface specifies interface to control the governance contract.
 // Note that there are a lot more methods in the governance contract, that this
 // interface only define those that are required to run the consensus algorithm.
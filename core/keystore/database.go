@@ -0,0 +1,175 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"github.com/tangerine-network/go-tangerine/rlp"
+
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+)
+
+// Database wraps a db.Database, DKG private keys and DKG protocol info, which
+// carries private shares, are kept in a KeyStore instead, thus they are
+// encrypted at rest.
+//
+// DKG key material saved in the wrapped database before, ex. by a node
+// running without a keystore, is migrated when it's first read: it's moved
+// into the keystore and deleted from the wrapped database. Migration only
+// happens when the keystore is unlocked, and nothing is readable when it's
+// locked.
+type Database struct {
+	db.Database
+
+	ks *KeyStore
+}
+
+// NewDatabase constructs a Database instance wrapping 'dbInst'.
+func NewDatabase(dbInst db.Database, ks *KeyStore) *Database {
+	return &Database{
+		Database: dbInst,
+		ks:       ks,
+	}
+}
+
+// GetDKGPrivateKey implements the db.Reader.GetDKGPrivateKey method.
+func (d *Database) GetDKGPrivateKey(round, reset uint64) (
+	prv dkg.PrivateKey, err error) {
+	d.ks.lock.Lock()
+	defer d.ks.lock.Unlock()
+	if d.ks.aead == nil {
+		err = ErrKeyStoreLocked
+		return
+	}
+	exists, err := d.ks.exists(dkgPrivateKeyName(round))
+	if err != nil {
+		return
+	}
+	if !exists {
+		if err = d.migrateDKGPrivateKey(round, reset); err != nil {
+			return
+		}
+	}
+	return d.ks.getDKGPrivateKey(round, reset)
+}
+
+// migrateDKGPrivateKey moves the DKG private key of one round from the
+// wrapped database into the keystore, the caller should hold the write lock.
+func (d *Database) migrateDKGPrivateKey(round, reset uint64) error {
+	prv, err := d.Database.GetDKGPrivateKey(round, reset)
+	if err != nil {
+		if err == db.ErrDKGPrivateKeyDoesNotExist {
+			err = nil
+		}
+		return err
+	}
+	if err = d.ks.putDKGPrivateKey(round, reset, prv); err != nil {
+		return err
+	}
+	return d.Database.DeleteDKGPrivateKey(round)
+}
+
+// PutDKGPrivateKey implements the db.Writer.PutDKGPrivateKey method.
+func (d *Database) PutDKGPrivateKey(
+	round, reset uint64, prv dkg.PrivateKey) error {
+	return d.ks.PutDKGPrivateKey(round, reset, prv)
+}
+
+// DeleteDKGPrivateKey implements the db.Writer.DeleteDKGPrivateKey method,
+// the key is deleted from both the keystore and the wrapped database.
+func (d *Database) DeleteDKGPrivateKey(round uint64) error {
+	d.ks.lock.Lock()
+	defer d.ks.lock.Unlock()
+	if err := d.ks.remove(dkgPrivateKeyName(round)); err != nil {
+		return err
+	}
+	return d.Database.DeleteDKGPrivateKey(round)
+}
+
+// GetDKGProtocol implements the db.Reader.GetDKGProtocol method.
+func (d *Database) GetDKGProtocol() (info db.DKGProtocolInfo, err error) {
+	d.ks.lock.Lock()
+	defer d.ks.lock.Unlock()
+	if d.ks.aead == nil {
+		err = ErrKeyStoreLocked
+		return
+	}
+	exists, err := d.ks.exists(dkgProtocolName)
+	if err != nil {
+		return
+	}
+	if !exists {
+		if err = d.migrateDKGProtocol(); err != nil {
+			return
+		}
+	}
+	b, err := d.ks.get(dkgProtocolName)
+	if err != nil {
+		if err == errKeyFileDoesNotExist {
+			err = db.ErrDKGProtocolDoesNotExist
+		}
+		return
+	}
+	err = rlp.DecodeBytes(b, &info)
+	return
+}
+
+// migrateDKGProtocol moves DKG protocol info from the wrapped database into
+// the keystore, the caller should hold the write lock.
+func (d *Database) migrateDKGProtocol() error {
+	info, err := d.Database.GetDKGProtocol()
+	if err != nil {
+		if err == db.ErrDKGProtocolDoesNotExist {
+			err = nil
+		}
+		return err
+	}
+	if err = d.putDKGProtocol(info); err != nil {
+		return err
+	}
+	return d.Database.DeleteDKGProtocol()
+}
+
+// PutOrUpdateDKGProtocol implements the db.Writer.PutOrUpdateDKGProtocol
+// method.
+func (d *Database) PutOrUpdateDKGProtocol(info db.DKGProtocolInfo) error {
+	d.ks.lock.Lock()
+	defer d.ks.lock.Unlock()
+	return d.putDKGProtocol(info)
+}
+
+// DeleteDKGProtocol implements the db.Writer.DeleteDKGProtocol method, the
+// info is deleted from both the keystore and the wrapped database.
+func (d *Database) DeleteDKGProtocol() error {
+	d.ks.lock.Lock()
+	defer d.ks.lock.Unlock()
+	if err := d.ks.remove(dkgProtocolName); err != nil {
+		return err
+	}
+	return d.Database.DeleteDKGProtocol()
+}
+
+// putDKGProtocol saves DKG protocol info into the keystore, the caller should
+// hold the write lock.
+func (d *Database) putDKGProtocol(info db.DKGProtocolInfo) error {
+	b, err := rlp.EncodeToBytes(&info)
+	if err != nil {
+		return err
+	}
+	return d.ks.put(dkgProtocolName, b)
+}
@@ -0,0 +1,153 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+type DatabaseTestSuite struct {
+	suite.Suite
+}
+
+func (s *DatabaseTestSuite) TestDKGKeyMaterial() {
+	req := s.Require()
+	dir, err := ioutil.TempDir("", "keystore-db")
+	req.NoError(err)
+	defer func() {
+		req.NoError(os.RemoveAll(dir))
+	}()
+	ks, err := Create(dir, "passphrase", LightScryptParams)
+	req.NoError(err)
+	inner, err := db.NewMemBackedDB()
+	req.NoError(err)
+	dbInst := NewDatabase(inner, ks)
+	// DKG private keys are kept in keystore, instead of the wrapped one.
+	prv := dkg.NewPrivateKey()
+	req.NoError(dbInst.PutDKGPrivateKey(1, 0, *prv))
+	loaded, err := dbInst.GetDKGPrivateKey(1, 0)
+	req.NoError(err)
+	req.Equal(prv.Bytes(), loaded.Bytes())
+	_, err = inner.GetDKGPrivateKey(1, 0)
+	req.Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+	// So does DKG protocol info.
+	_, err = dbInst.GetDKGProtocol()
+	req.Equal(db.ErrDKGProtocolDoesNotExist, err)
+	prvShares, _ := dkg.NewPrivateKeyShares(3)
+	info := db.DKGProtocolInfo{
+		Round:              1,
+		Threshold:          3,
+		MasterPrivateShare: *prvShares,
+		IsPrvSharesEmpty:   true,
+		Step:               2,
+	}
+	req.NoError(dbInst.PutOrUpdateDKGProtocol(info))
+	loadedInfo, err := dbInst.GetDKGProtocol()
+	req.NoError(err)
+	req.True(info.Equal(&loadedInfo))
+	_, err = inner.GetDKGProtocol()
+	req.Equal(db.ErrDKGProtocolDoesNotExist, err)
+	// Other data goes to the wrapped one.
+	b := types.Block{Hash: common.NewRandomHash()}
+	req.NoError(dbInst.PutBlock(b))
+	req.True(inner.HasBlock(b.Hash))
+	// DKG key material can't be accessed when keystore is locked.
+	ks.Lock()
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	req.Equal(ErrKeyStoreLocked, err)
+	_, err = dbInst.GetDKGProtocol()
+	req.Equal(ErrKeyStoreLocked, err)
+	// Rounds not kept in keystore can't be accessed either.
+	_, err = dbInst.GetDKGPrivateKey(2, 0)
+	req.Equal(ErrKeyStoreLocked, err)
+}
+
+func (s *DatabaseTestSuite) TestMigrateDKGKeyMaterial() {
+	req := s.Require()
+	dir, err := ioutil.TempDir("", "keystore-db")
+	req.NoError(err)
+	defer func() {
+		req.NoError(os.RemoveAll(dir))
+	}()
+	ks, err := Create(dir, "passphrase", LightScryptParams)
+	req.NoError(err)
+	// Prepare DKG key material saved in plaintext before keystore is used.
+	inner, err := db.NewMemBackedDB()
+	req.NoError(err)
+	prv := dkg.NewPrivateKey()
+	req.NoError(inner.PutDKGPrivateKey(1, 0, *prv))
+	prvShares, _ := dkg.NewPrivateKeyShares(3)
+	info := db.DKGProtocolInfo{
+		Round:              1,
+		Threshold:          3,
+		MasterPrivateShare: *prvShares,
+		IsPrvSharesEmpty:   true,
+		Step:               1,
+	}
+	req.NoError(inner.PutOrUpdateDKGProtocol(info))
+	dbInst := NewDatabase(inner, ks)
+	// Plaintext key material is neither readable nor migrated when keystore
+	// is locked.
+	ks.Lock()
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	req.Equal(ErrKeyStoreLocked, err)
+	_, err = dbInst.GetDKGProtocol()
+	req.Equal(ErrKeyStoreLocked, err)
+	_, err = inner.GetDKGPrivateKey(1, 0)
+	req.NoError(err)
+	// Reading it with keystore unlocked moves it into keystore.
+	req.NoError(ks.Unlock("passphrase"))
+	loaded, err := dbInst.GetDKGPrivateKey(1, 0)
+	req.NoError(err)
+	req.Equal(prv.Bytes(), loaded.Bytes())
+	loaded, err = ks.GetDKGPrivateKey(1, 0)
+	req.NoError(err)
+	req.Equal(prv.Bytes(), loaded.Bytes())
+	loadedInfo, err := dbInst.GetDKGProtocol()
+	req.NoError(err)
+	req.True(info.Equal(&loadedInfo))
+	// The plaintext copies are gone.
+	_, err = inner.GetDKGPrivateKey(1, 0)
+	req.Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+	_, err = inner.GetDKGProtocol()
+	req.Equal(db.ErrDKGProtocolDoesNotExist, err)
+	// Migrated key material is protected by keystore.
+	ks.Lock()
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	req.Equal(ErrKeyStoreLocked, err)
+	_, err = dbInst.GetDKGProtocol()
+	req.Equal(ErrKeyStoreLocked, err)
+	// Deleting removes the key from keystore.
+	req.NoError(ks.Unlock("passphrase"))
+	req.NoError(dbInst.DeleteDKGPrivateKey(1))
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	req.Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+}
+
+func TestDatabase(t *testing.T) {
+	suite.Run(t, new(DatabaseTestSuite))
+}
@@ -0,0 +1,403 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/ecdsa"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+)
+
+// Errors for keystore.
+var (
+	// ErrKeyStoreExists is reported when creating a keystore in a directory
+	// already containing one.
+	ErrKeyStoreExists = errors.New("keystore exists")
+	// ErrKeyStoreLocked is reported when accessing keys in a locked keystore.
+	ErrKeyStoreLocked = errors.New("keystore locked")
+	// ErrInvalidPassphrase is reported when unlocking a keystore with a wrong
+	// passphrase.
+	ErrInvalidPassphrase = errors.New("invalid passphrase")
+	// ErrNodeKeyExists is reported when attempting to overwrite the node key.
+	ErrNodeKeyExists = errors.New("node key exists")
+	// ErrNodeKeyDoesNotExist is reported when the node key is not saved yet.
+	ErrNodeKeyDoesNotExist = errors.New("node key does not exist")
+	// ErrCorruptedKey is reported when a key file fails to be decrypted,
+	// which might be tampered or swapped with another one.
+	ErrCorruptedKey = errors.New("corrupted key")
+
+	errKeyFileDoesNotExist = errors.New("key file does not exist")
+)
+
+// ScryptParams are parameters of scrypt to derive the encryption key from a
+// passphrase.
+type ScryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+var (
+	// StandardScryptParams takes about 256MB memory and one second to derive
+	// a key on a modern processor.
+	StandardScryptParams = ScryptParams{N: 1 << 18, R: 8, P: 1}
+	// LightScryptParams takes about 4MB memory and 100ms to derive a key,
+	// it's for tests and resource-limited environments.
+	LightScryptParams = ScryptParams{N: 1 << 12, R: 8, P: 6}
+)
+
+const (
+	keyStoreVersion = 1
+	metaFileName    = "keystore.json"
+	nodeKeyName     = "node-key"
+	dkgProtocolName = "dkg-protocol"
+	keySize         = 32
+	saltSize        = 32
+	checkText       = "tangerine-consensus-keystore"
+)
+
+// sealedData is data encrypted and authenticated by AES-GCM.
+type sealedData struct {
+	Nonce      []byte `json:"nonce"`
+	CipherText []byte `json:"ciphertext"`
+}
+
+// keyStoreMeta is the metadata to derive the encryption key from passphrase,
+// and to check if the passphrase is correct.
+type keyStoreMeta struct {
+	Version int          `json:"version"`
+	Scrypt  ScryptParams `json:"scrypt"`
+	Salt    []byte       `json:"salt"`
+	Check   sealedData   `json:"check"`
+}
+
+type dkgPrivateKey struct {
+	PK    dkg.PrivateKey
+	Reset uint64
+}
+
+// KeyStore saves the node key and DKG private keys into files under a
+// directory, each of them is encrypted by a key derived from a passphrase.
+// A keystore is locked when loaded, keys can only be accessed after it's
+// unlocked.
+type KeyStore struct {
+	dir  string
+	meta keyStoreMeta
+	lock sync.RWMutex
+	aead cipher.AEAD
+}
+
+// Create initializes a keystore under 'dir', the returned keystore is
+// unlocked.
+func Create(dir, passphrase string, params ScryptParams) (
+	*KeyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	metaPath := filepath.Join(dir, metaFileName)
+	if _, err := os.Stat(metaPath); err == nil {
+		return nil, ErrKeyStoreExists
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	ks := &KeyStore{
+		dir: dir,
+		meta: keyStoreMeta{
+			Version: keyStoreVersion,
+			Scrypt:  params,
+			Salt:    make([]byte, saltSize),
+		},
+	}
+	if _, err := rand.Read(ks.meta.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := ks.deriveAEAD(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if ks.meta.Check, err = seal(
+		aead, []byte(checkText), []byte(metaFileName)); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(&ks.meta)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeFile(metaPath, b); err != nil {
+		return nil, err
+	}
+	ks.aead = aead
+	return ks, nil
+}
+
+// Load loads the keystore under 'dir', the returned keystore is locked.
+func Load(dir string) (*KeyStore, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, metaFileName))
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeyStore{dir: dir}
+	if err = json.Unmarshal(b, &ks.meta); err != nil {
+		return nil, err
+	}
+	if ks.meta.Version != keyStoreVersion {
+		return nil, fmt.Errorf(
+			"unknown keystore version: %d", ks.meta.Version)
+	}
+	return ks, nil
+}
+
+// Unlock derives the encryption key from the passphrase, keys in this
+// keystore can be accessed after unlocked.
+func (ks *KeyStore) Unlock(passphrase string) error {
+	aead, err := ks.deriveAEAD(passphrase)
+	if err != nil {
+		return err
+	}
+	check, err := open(aead, ks.meta.Check, []byte(metaFileName))
+	if err != nil || !bytes.Equal(check, []byte(checkText)) {
+		return ErrInvalidPassphrase
+	}
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.aead = aead
+	return nil
+}
+
+// Lock drops the encryption key, keys in this keystore can't be accessed
+// until unlocked again.
+func (ks *KeyStore) Lock() {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.aead = nil
+}
+
+// IsLocked checks if this keystore is locked.
+func (ks *KeyStore) IsLocked() bool {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	return ks.aead == nil
+}
+
+// PutNodeKey saves the private key of the node, it can't be overwritten.
+func (ks *KeyStore) PutNodeKey(prv *ecdsa.PrivateKey) error {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	if _, err := ks.get(nodeKeyName); err == nil {
+		return ErrNodeKeyExists
+	} else if err != errKeyFileDoesNotExist {
+		return err
+	}
+	return ks.put(nodeKeyName, prv.Bytes())
+}
+
+// GetNodeKey gets the private key of the node.
+func (ks *KeyStore) GetNodeKey() (*ecdsa.PrivateKey, error) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	b, err := ks.get(nodeKeyName)
+	if err != nil {
+		if err == errKeyFileDoesNotExist {
+			err = ErrNodeKeyDoesNotExist
+		}
+		return nil, err
+	}
+	return ecdsa.NewPrivateKeyFromBytes(b)
+}
+
+// PutDKGPrivateKey saves the DKG private key of one round. Like
+// db.Database, only the key of the latest reset in a round is kept.
+func (ks *KeyStore) PutDKGPrivateKey(
+	round, reset uint64, prv dkg.PrivateKey) error {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	return ks.putDKGPrivateKey(round, reset, prv)
+}
+
+// putDKGPrivateKey saves the DKG private key of one round, the caller should
+// hold the write lock.
+func (ks *KeyStore) putDKGPrivateKey(
+	round, reset uint64, prv dkg.PrivateKey) error {
+	if _, err := ks.getDKGPrivateKey(round, reset); err == nil {
+		return db.ErrDKGPrivateKeyExists
+	} else if err != db.ErrDKGPrivateKeyDoesNotExist {
+		return err
+	}
+	b, err := rlp.EncodeToBytes(&dkgPrivateKey{PK: prv, Reset: reset})
+	if err != nil {
+		return err
+	}
+	return ks.put(dkgPrivateKeyName(round), b)
+}
+
+// GetDKGPrivateKey gets the DKG private key of one round.
+func (ks *KeyStore) GetDKGPrivateKey(round, reset uint64) (
+	dkg.PrivateKey, error) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	return ks.getDKGPrivateKey(round, reset)
+}
+
+func (ks *KeyStore) getDKGPrivateKey(round, reset uint64) (
+	prv dkg.PrivateKey, err error) {
+	b, err := ks.get(dkgPrivateKeyName(round))
+	if err != nil {
+		if err == errKeyFileDoesNotExist {
+			err = db.ErrDKGPrivateKeyDoesNotExist
+		}
+		return
+	}
+	pk := dkgPrivateKey{}
+	if err = rlp.DecodeBytes(b, &pk); err != nil {
+		return
+	}
+	if pk.Reset != reset {
+		err = db.ErrDKGPrivateKeyDoesNotExist
+		return
+	}
+	prv = pk.PK
+	return
+}
+
+func dkgPrivateKeyName(round uint64) string {
+	return fmt.Sprintf("dkg-private-key-%d", round)
+}
+
+// deriveAEAD derives the encryption key from the passphrase.
+func (ks *KeyStore) deriveAEAD(passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), ks.meta.Salt,
+		ks.meta.Scrypt.N, ks.meta.Scrypt.R, ks.meta.Scrypt.P, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	// The key is expanded into the cipher, don't keep it around.
+	for i := range key {
+		key[i] = 0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// put encrypts and saves data as file 'name', the caller should hold the
+// write lock.
+func (ks *KeyStore) put(name string, data []byte) error {
+	if ks.aead == nil {
+		return ErrKeyStoreLocked
+	}
+	sealed, err := seal(ks.aead, data, []byte(name))
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(&sealed)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(ks.dir, name), b)
+}
+
+// remove deletes the file 'name', the caller should hold the write lock.
+func (ks *KeyStore) remove(name string) error {
+	err := os.Remove(filepath.Join(ks.dir, name))
+	if err != nil && os.IsNotExist(err) {
+		err = nil
+	}
+	return err
+}
+
+// exists checks if the file 'name' exists, it doesn't need the keystore to
+// be unlocked.
+func (ks *KeyStore) exists(name string) (bool, error) {
+	_, err := os.Stat(filepath.Join(ks.dir, name))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// get loads and decrypts the file 'name', the caller should hold the read
+// lock.
+func (ks *KeyStore) get(name string) ([]byte, error) {
+	if ks.aead == nil {
+		return nil, ErrKeyStoreLocked
+	}
+	b, err := ioutil.ReadFile(filepath.Join(ks.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = errKeyFileDoesNotExist
+		}
+		return nil, err
+	}
+	sealed := sealedData{}
+	if err = json.Unmarshal(b, &sealed); err != nil {
+		return nil, ErrCorruptedKey
+	}
+	data, err := open(ks.aead, sealed, []byte(name))
+	if err != nil {
+		return nil, ErrCorruptedKey
+	}
+	return data, nil
+}
+
+// seal encrypts data with a random nonce, the name of the file is
+// authenticated along with it, thus files can't be swapped with each other.
+func seal(aead cipher.AEAD, data, name []byte) (sealed sealedData, err error) {
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(sealed.Nonce); err != nil {
+		return
+	}
+	sealed.CipherText = aead.Seal(nil, sealed.Nonce, data, name)
+	return
+}
+
+func open(aead cipher.AEAD, sealed sealedData, name []byte) ([]byte, error) {
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, ErrCorruptedKey
+	}
+	return aead.Open(nil, sealed.Nonce, sealed.CipherText, name)
+}
+
+// writeFile writes to a temporary file then renames it, thus a file is
+// never partially written.
+func writeFile(path string, b []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
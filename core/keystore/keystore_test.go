@@ -0,0 +1,118 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/ecdsa"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+)
+
+type KeyStoreTestSuite struct {
+	suite.Suite
+
+	dir string
+}
+
+func (s *KeyStoreTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "keystore")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *KeyStoreTestSuite) TearDownTest() {
+	s.Require().NoError(os.RemoveAll(s.dir))
+}
+
+func (s *KeyStoreTestSuite) TestLockAndUnlock() {
+	req := s.Require()
+	ks, err := Create(s.dir, "passphrase", LightScryptParams)
+	req.NoError(err)
+	req.False(ks.IsLocked())
+	_, err = Create(s.dir, "passphrase", LightScryptParams)
+	req.Equal(ErrKeyStoreExists, err)
+	_, err = ks.GetNodeKey()
+	req.Equal(ErrNodeKeyDoesNotExist, err)
+	prv, err := ecdsa.NewPrivateKey()
+	req.NoError(err)
+	req.NoError(ks.PutNodeKey(prv))
+	req.Equal(ErrNodeKeyExists, ks.PutNodeKey(prv))
+	// The node key is encrypted on disk.
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, nodeKeyName))
+	req.NoError(err)
+	req.False(bytes.Contains(b, prv.Bytes()))
+	// A loaded keystore is locked.
+	ks, err = Load(s.dir)
+	req.NoError(err)
+	req.True(ks.IsLocked())
+	_, err = ks.GetNodeKey()
+	req.Equal(ErrKeyStoreLocked, err)
+	req.Equal(ErrInvalidPassphrase, ks.Unlock("wrong passphrase"))
+	req.True(ks.IsLocked())
+	req.NoError(ks.Unlock("passphrase"))
+	loaded, err := ks.GetNodeKey()
+	req.NoError(err)
+	req.Equal(prv.Bytes(), loaded.Bytes())
+	ks.Lock()
+	req.True(ks.IsLocked())
+	_, err = ks.GetNodeKey()
+	req.Equal(ErrKeyStoreLocked, err)
+}
+
+func (s *KeyStoreTestSuite) TestDKGPrivateKey() {
+	req := s.Require()
+	ks, err := Create(s.dir, "passphrase", LightScryptParams)
+	req.NoError(err)
+	_, err = ks.GetDKGPrivateKey(1, 0)
+	req.Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+	prv1 := dkg.NewPrivateKey()
+	req.NoError(ks.PutDKGPrivateKey(1, 0, *prv1))
+	req.Equal(db.ErrDKGPrivateKeyExists, ks.PutDKGPrivateKey(1, 0, *prv1))
+	loaded, err := ks.GetDKGPrivateKey(1, 0)
+	req.NoError(err)
+	req.Equal(prv1.Bytes(), loaded.Bytes())
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, dkgPrivateKeyName(1)))
+	req.NoError(err)
+	req.False(bytes.Contains(b, prv1.Bytes()))
+	// Only the key of the latest reset is kept.
+	_, err = ks.GetDKGPrivateKey(1, 1)
+	req.Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+	prv2 := dkg.NewPrivateKey()
+	req.NoError(ks.PutDKGPrivateKey(1, 1, *prv2))
+	_, err = ks.GetDKGPrivateKey(1, 0)
+	req.Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+	// Key files can't be swapped with each other.
+	req.NoError(ks.PutDKGPrivateKey(2, 0, *prv1))
+	req.NoError(os.Rename(
+		filepath.Join(s.dir, dkgPrivateKeyName(2)),
+		filepath.Join(s.dir, dkgPrivateKeyName(1))))
+	_, err = ks.GetDKGPrivateKey(1, 0)
+	req.Equal(ErrCorruptedKey, err)
+}
+
+func TestKeyStore(t *testing.T) {
+	suite.Run(t, new(KeyStoreTestSuite))
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"strconv"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+)
+
+// Names of metrics reported by Consensus.
+const (
+	// MetricBAPeriods is a histogram of BA periods spent for one position.
+	MetricBAPeriods = "ba_periods"
+	// MetricBAConfirmLatency is a histogram of seconds from the beginning of
+	// BA for one position to its confirmation.
+	MetricBAConfirmLatency = "ba_confirm_latency_seconds"
+	// MetricBAConfirmedBlocks is a counter of blocks confirmed by BA.
+	MetricBAConfirmedBlocks = "ba_confirmed_blocks_total"
+	// MetricPendingBlocks is a gauge of pending blocks in blockChain.
+	MetricPendingBlocks = "blockchain_pending_blocks"
+	// MetricDKGPhaseDuration is a histogram of seconds spent by each DKG
+	// phase, labeled by 'phase'.
+	MetricDKGPhaseDuration = "dkg_phase_duration_seconds"
+	// MetricTSigRecoveryLatency is a histogram of seconds to recover a
+	// threshold signature, labeled by 'source', which is either 'agreement'
+	// for randomness of BA confirmed blocks, or 'protocol' for TSig protocol
+	// run by DKG set.
+	MetricTSigRecoveryLatency = "tsig_recovery_latency_seconds"
+	// MetricMsgChanBacklog is a gauge of queued messages in the internal
+	// message channels, labeled by 'chan'.
+	MetricMsgChanBacklog = "msg_chan_backlog"
+	// MetricNonBlockingEvents is a gauge of queued events in the nonblocking
+	// decorator of Application.
+	MetricNonBlockingEvents = "nonblocking_pending_events"
+)
+
+// consensusMetrics groups instruments shared by Consensus and its modules.
+type consensusMetrics struct {
+	source             common.Metrics
+	baPeriods          common.Histogram
+	baConfirmLatency   common.Histogram
+	baConfirmedBlocks  common.Counter
+	pendingBlocks      common.Gauge
+	tsigBALatency      common.Histogram
+	tsigLatency        common.Histogram
+	msgChanBacklog     common.Gauge
+	priorityMsgBacklog common.Gauge
+	nonBlockingEvents  common.Gauge
+}
+
+func newConsensusMetrics(m common.Metrics) *consensusMetrics {
+	metrics := &consensusMetrics{}
+	metrics.setup(m)
+	return metrics
+}
+
+func (m *consensusMetrics) setup(source common.Metrics) {
+	if source == nil {
+		source = &common.NullMetrics{}
+	}
+	m.source = source
+	m.baPeriods = source.Histogram(MetricBAPeriods)
+	m.baConfirmLatency = source.Histogram(MetricBAConfirmLatency)
+	m.baConfirmedBlocks = source.Counter(MetricBAConfirmedBlocks)
+	m.pendingBlocks = source.Gauge(MetricPendingBlocks)
+	m.tsigBALatency = source.Histogram(
+		MetricTSigRecoveryLatency, "source", "agreement")
+	m.tsigLatency = source.Histogram(
+		MetricTSigRecoveryLatency, "source", "protocol")
+	m.msgChanBacklog = source.Gauge(MetricMsgChanBacklog, "chan", "msg")
+	m.priorityMsgBacklog = source.Gauge(
+		MetricMsgChanBacklog, "chan", "priority")
+	m.nonBlockingEvents = source.Gauge(MetricNonBlockingEvents)
+}
+
+func (m *consensusMetrics) dkgPhaseDuration(phase int) common.Histogram {
+	return m.source.Histogram(
+		MetricDKGPhaseDuration, "phase", strconv.Itoa(phase))
+}
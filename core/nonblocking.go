@@ -47,6 +47,7 @@ type nonBlocking struct {
 	events       []interface{}
 	eventsChange *sync.Cond
 	running      sync.WaitGroup
+	metrics      *consensusMetrics
 }
 
 func newNonBlocking(app Application, debug Debug) *nonBlocking {
@@ -56,6 +57,7 @@ func newNonBlocking(app Application, debug Debug) *nonBlocking {
 		eventChan:    make(chan interface{}, 6),
 		events:       make([]interface{}, 0, 100),
 		eventsChange: sync.NewCond(&sync.Mutex{}),
+		metrics:      newConsensusMetrics(nil),
 	}
 	go nonBlockingModule.run()
 	return nonBlockingModule
@@ -65,6 +67,7 @@ func (nb *nonBlocking) addEvent(event interface{}) {
 	nb.eventsChange.L.Lock()
 	defer nb.eventsChange.L.Unlock()
 	nb.events = append(nb.events, event)
+	nb.metrics.nonBlockingEvents.Set(float64(len(nb.events)))
 	nb.eventsChange.Broadcast()
 }
 
@@ -81,6 +84,7 @@ func (nb *nonBlocking) run() {
 			}
 			event = nb.events[0]
 			nb.events = nb.events[1:]
+			nb.metrics.nonBlockingEvents.Set(float64(len(nb.events)))
 			nb.running.Add(1)
 		}()
 		switch e := event.(type) {
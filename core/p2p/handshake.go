@@ -0,0 +1,155 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// Errors for handshake.
+var (
+	// ErrVersionMismatch is reported when remote peer speaks a different
+	// version of protocol.
+	ErrVersionMismatch = errors.New("protocol version mismatch")
+	// ErrInvalidHandshakeSignature is reported when remote peer fails to
+	// prove the possession of its private key.
+	ErrInvalidHandshakeSignature = errors.New("invalid handshake signature")
+	// ErrSelfConnection is reported when connecting to ourself.
+	ErrSelfConnection = errors.New("connect to self")
+	// ErrUnexpectedPeer is reported when the remote peer is not the one we
+	// expected.
+	ErrUnexpectedPeer = errors.New("unexpected peer")
+)
+
+const (
+	protocolVersion  byte = 1
+	nonceSize             = 32
+	handshakeDomain       = "tangerine-consensus-p2p-handshake"
+	maxHandshakeSize      = 1024
+)
+
+// handshakeAuth proves the possession of the private key of a node.
+type handshakeAuth struct {
+	PublicKey  []byte
+	Signature  crypto.Signature
+	ListenAddr string
+}
+
+// handshakeResult is the authenticated information of remote peer.
+type handshakeResult struct {
+	ID         types.NodeID
+	PublicKey  crypto.PublicKey
+	ListenAddr string
+}
+
+// hashHandshake calculates the hash to be signed by the owner of
+// 'signerNonce', which is bound to the nonce from the other side to prevent
+// replaying.
+func hashHandshake(
+	verifierNonce, signerNonce []byte, listenAddr string) common.Hash {
+	return crypto.Keccak256Hash(
+		[]byte(handshakeDomain), verifierNonce, signerNonce, []byte(listenAddr))
+}
+
+// handshake authenticates both sides of a connection. Both sides send a
+// random nonce, then sign the nonce from the other side with their private
+// keys. The identity of remote peer is derived from the public key recovered
+// from its signature, which should match the public key it claims.
+func handshake(conn net.Conn, prv crypto.PrivateKey, listenAddr string,
+	timeout time.Duration) (result *handshakeResult, err error) {
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return
+	}
+	defer func() {
+		if dErr := conn.SetDeadline(time.Time{}); err == nil {
+			err = dErr
+		}
+	}()
+	// Exchange version and nonce.
+	localHello := make([]byte, 1+nonceSize)
+	localHello[0] = protocolVersion
+	if _, err = rand.Read(localHello[1:]); err != nil {
+		return
+	}
+	if _, err = conn.Write(localHello); err != nil {
+		return
+	}
+	remoteHello := make([]byte, 1+nonceSize)
+	if _, err = io.ReadFull(conn, remoteHello); err != nil {
+		return
+	}
+	if remoteHello[0] != protocolVersion {
+		err = ErrVersionMismatch
+		return
+	}
+	localNonce, remoteNonce := localHello[1:], remoteHello[1:]
+	// Exchange signatures.
+	localAuth := &handshakeAuth{
+		PublicKey:  prv.PublicKey().Bytes(),
+		ListenAddr: listenAddr,
+	}
+	if localAuth.Signature, err = prv.Sign(
+		hashHandshake(remoteNonce, localNonce, listenAddr)); err != nil {
+		return
+	}
+	var b []byte
+	if b, err = rlp.EncodeToBytes(localAuth); err != nil {
+		return
+	}
+	if err = writeFrame(conn, b); err != nil {
+		return
+	}
+	if b, err = readFrame(conn, maxHandshakeSize); err != nil {
+		return
+	}
+	remoteAuth := &handshakeAuth{}
+	if err = rlp.DecodeBytes(b, remoteAuth); err != nil {
+		return
+	}
+	hash := hashHandshake(localNonce, remoteNonce, remoteAuth.ListenAddr)
+	pubKey, err := crypto.SigToPub(hash, remoteAuth.Signature)
+	if err != nil {
+		return
+	}
+	// The recovered public key should be identical to the claimed one,
+	// or the signature is not signed over our nonce.
+	if !bytes.Equal(pubKey.Bytes(), remoteAuth.PublicKey) ||
+		!pubKey.VerifySignature(hash, remoteAuth.Signature) {
+		err = ErrInvalidHandshakeSignature
+		return
+	}
+	result = &handshakeResult{
+		ID:         types.NewNodeID(pubKey),
+		PublicKey:  pubKey,
+		ListenAddr: remoteAuth.ListenAddr,
+	}
+	if result.ID == types.NewNodeID(prv.PublicKey()) {
+		result, err = nil, ErrSelfConnection
+	}
+	return
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/tangerine-network/tangerine-consensus/core/codec"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// ErrFrameTooLarge is reported when the size of a frame exceeds limit.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// msgTypePeers is the type of peerList on wire.
+const msgTypePeers = codec.MsgTypeCustom
+
+// maxPeerListSize is the size limit of an encoded peerList.
+const maxPeerListSize = 256 * 1024
+
+// peerInfo is the information to connect to a peer.
+type peerInfo struct {
+	ID   types.NodeID
+	Addr string
+}
+
+// peerList is exchanged between peers to discover each other.
+type peerList struct {
+	Peers []peerInfo
+}
+
+// newCodec constructs a codec.Codec which is able to encode messages
+// exchanged between Network instances.
+func newCodec() *codec.Codec {
+	c := codec.NewCodec()
+	if err := c.Register(
+		msgTypePeers, "peers", &peerList{}, maxPeerListSize); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// writeFrame writes a length-prefixed frame.
+func writeFrame(w io.Writer, body []byte) error {
+	frame := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	copy(frame[4:], body)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads a length-prefixed frame, frames larger than maxSize are
+// rejected before reading their bodies.
+func readFrame(r io.Reader, maxSize uint32) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header)
+	if size > maxSize {
+		return nil, ErrFrameTooLarge
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,746 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/codec"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+const (
+	// Count of maximum count of peers to pull blocks or votes from.
+	maxPullingPeerCount = 3
+	maxBlockCache       = 1000
+	maxVoteCache        = 128
+	maxSentAgreement    = 1000
+
+	defaultSendQueueSize    = 1024
+	defaultMaxFrameSize     = codec.MaxBlockSize + 1024
+	defaultHandshakeTimeout = 5 * time.Second
+	defaultMinBackoff       = 100 * time.Millisecond
+	defaultMaxBackoff       = 30 * time.Second
+)
+
+// ErrBannedPeer is reported when connecting with a peer which is banned for
+// its misbehavior.
+var ErrBannedPeer = errors.New("banned peer")
+
+// Config is the configuration for Network module.
+type Config struct {
+	// ListenAddr is the TCP address to accept connections, ex. "0.0.0.0:9000".
+	ListenAddr string
+	// AdvertiseAddr is the address told to other peers to connect to this
+	// node. The address of listener would be used if it's empty.
+	AdvertiseAddr string
+	// StaticPeers are addresses of peers to keep connected with.
+	StaticPeers []string
+	// BootstrapPeers are addresses of peers to connect with, peers known by
+	// them would be connected as well.
+	BootstrapPeers []string
+	// SendQueueSize is the count of pending messages for each peer, messages
+	// would be dropped when the queue is full.
+	SendQueueSize int
+	// MaxFrameSize is the size limit of one message in bytes.
+	MaxFrameSize uint32
+	// HandshakeTimeout is the timeout to dial and authenticate a peer.
+	HandshakeTimeout time.Duration
+	// MinBackoff and MaxBackoff limit the interval to reconnect a peer, the
+	// interval would be doubled after each failure.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Logger to log network events, logs nothing if it's nil.
+	Logger common.Logger
+	// PeerScore is the configuration to score peers reported via
+	// ReportBadPeerChan, utils.DefaultPeerScoreConfig would be used when
+	// it's nil.
+	PeerScore *utils.PeerScoreConfig
+}
+
+func (c *Config) setDefaults() {
+	if c.SendQueueSize <= 0 {
+		c.SendQueueSize = defaultSendQueueSize
+	}
+	if c.MaxFrameSize == 0 {
+		c.MaxFrameSize = defaultMaxFrameSize
+	}
+	if c.HandshakeTimeout <= 0 {
+		c.HandshakeTimeout = defaultHandshakeTimeout
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = defaultMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = defaultMaxBackoff
+		if c.MaxBackoff < c.MinBackoff {
+			c.MaxBackoff = c.MinBackoff
+		}
+	}
+	if c.Logger == nil {
+		c.Logger = &common.NullLogger{}
+	}
+}
+
+// Network implements core.Network interface over mutually authenticated TCP
+// connections.
+type Network struct {
+	ID                types.NodeID
+	prv               crypto.PrivateKey
+	config            Config
+	logger            common.Logger
+	codec             *codec.Codec
+	ctx               context.Context
+	ctxCancel         context.CancelFunc
+	listener          net.Listener
+	advertiseAddr     string
+	discovery         bool
+	peersLock         sync.RWMutex
+	peers             map[types.NodeID]*peer
+	dialingLock       sync.Mutex
+	dialing           map[string]struct{}
+	toConsensus       chan types.Msg
+	badPeerChan       chan interface{}
+	cache             *utils.NodeSetCache
+	blockCacheLock    sync.RWMutex
+	blockCache        map[common.Hash]*types.Block
+	blockHashes       []common.Hash
+	voteCacheLock     sync.RWMutex
+	voteCache         map[types.Position]map[types.VoteHeader]*types.Vote
+	votePositions     []types.Position
+	sentAgreementLock sync.Mutex
+	sentAgreement     map[common.Hash]struct{}
+	waitGroup         sync.WaitGroup
+	peerScorer        *utils.PeerScorer
+}
+
+// NewNetwork constructs a Network instance, call Start to make it
+// connect to peers.
+func NewNetwork(prv crypto.PrivateKey, config Config) *Network {
+	config.setDefaults()
+	n := &Network{
+		ID:            types.NewNodeID(prv.PublicKey()),
+		prv:           prv,
+		config:        config,
+		logger:        config.Logger,
+		codec:         newCodec(),
+		discovery:     len(config.BootstrapPeers) > 0,
+		peers:         make(map[types.NodeID]*peer),
+		dialing:       make(map[string]struct{}),
+		toConsensus:   make(chan types.Msg, 1000),
+		badPeerChan:   make(chan interface{}, 1000),
+		blockCache:    make(map[common.Hash]*types.Block, maxBlockCache),
+		sentAgreement: make(map[common.Hash]struct{}),
+		voteCache: make(
+			map[types.Position]map[types.VoteHeader]*types.Vote),
+	}
+	scoreConfig := utils.DefaultPeerScoreConfig()
+	if config.PeerScore != nil {
+		scoreConfig = *config.PeerScore
+	}
+	n.peerScorer = utils.NewPeerScorer(scoreConfig)
+	n.ctx, n.ctxCancel = context.WithCancel(context.Background())
+	return n
+}
+
+// Start listening and connecting to peers.
+func (n *Network) Start() (err error) {
+	if n.listener, err = net.Listen("tcp", n.config.ListenAddr); err != nil {
+		return
+	}
+	n.advertiseAddr = n.config.AdvertiseAddr
+	if n.advertiseAddr == "" {
+		n.advertiseAddr = n.listener.Addr().String()
+	}
+	n.waitGroup.Add(2)
+	go n.acceptLoop()
+	go n.badPeerLoop()
+	for _, addr := range n.config.StaticPeers {
+		n.maintain(addr, types.NodeID{})
+	}
+	for _, addr := range n.config.BootstrapPeers {
+		n.maintain(addr, types.NodeID{})
+	}
+	return
+}
+
+// Addr returns the address advertised to other peers.
+func (n *Network) Addr() string {
+	return n.advertiseAddr
+}
+
+// Close all connections and stop all routines.
+func (n *Network) Close() (err error) {
+	n.ctxCancel()
+	if n.listener != nil {
+		err = n.listener.Close()
+	}
+	func() {
+		n.peersLock.Lock()
+		defer n.peersLock.Unlock()
+		for _, p := range n.peers {
+			p.close()
+		}
+	}()
+	// Make sure no dialing routine would be raised after this line.
+	func() {
+		n.dialingLock.Lock()
+		defer n.dialingLock.Unlock()
+	}()
+	n.waitGroup.Wait()
+	return
+}
+
+// Peers returns IDs of connected peers.
+func (n *Network) Peers() (IDs types.NodeIDs) {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+	for ID := range n.peers {
+		IDs = append(IDs, ID)
+	}
+	return
+}
+
+// AttachNodeSetCache attaches an utils.NodeSetCache to this module. Once
+// attached, the behavior of Broadcast-X methods would be switched to broadcast
+// to notary set, instead of all peers.
+func (n *Network) AttachNodeSetCache(cache *utils.NodeSetCache) {
+	// This variable should be attached before start, no lock to protect it.
+	n.cache = cache
+}
+
+// PullBlocks implements core.Network interface.
+func (n *Network) PullBlocks(hashes common.Hashes) {
+	n.sendToRandomPeers(nil, &codec.PullBlocksRequest{
+		Requester: n.ID,
+		Hashes:    hashes,
+	})
+}
+
+// PullVotes implements core.Network interface.
+func (n *Network) PullVotes(pos types.Position) {
+	n.sendToRandomPeers(n.getNotarySet(pos.Round), &codec.PullVotesRequest{
+		Requester: n.ID,
+		Position:  pos,
+	})
+}
+
+// BroadcastVote implements core.Network interface.
+func (n *Network) BroadcastVote(vote *types.Vote) {
+	n.broadcast(n.getNotarySet(vote.Position.Round), vote)
+	n.addVoteToCache(vote)
+}
+
+// BroadcastBlock implements core.Network interface.
+func (n *Network) BroadcastBlock(block *types.Block) {
+	n.broadcast(nil, block)
+	n.addBlockToCache(block)
+}
+
+// BroadcastAgreementResult implements core.Network interface.
+func (n *Network) BroadcastAgreementResult(result *types.AgreementResult) {
+	if !n.markAgreementResultAsSent(result.BlockHash) {
+		return
+	}
+	n.broadcast(nil, result)
+}
+
+// SendDKGPrivateShare implements core.Network interface.
+func (n *Network) SendDKGPrivateShare(
+	pub crypto.PublicKey, prvShare *typesDKG.PrivateShare) {
+	n.send(types.NewNodeID(pub), prvShare)
+}
+
+// BroadcastDKGPrivateShare implements core.Network interface.
+func (n *Network) BroadcastDKGPrivateShare(prvShare *typesDKG.PrivateShare) {
+	n.broadcast(n.getNotarySet(prvShare.Round), prvShare)
+}
+
+// BroadcastDKGPartialSignature implements core.Network interface.
+func (n *Network) BroadcastDKGPartialSignature(
+	psig *typesDKG.PartialSignature) {
+	n.broadcast(n.getNotarySet(psig.Round), psig)
+}
+
+// ReceiveChan implements core.Network interface.
+func (n *Network) ReceiveChan() <-chan types.Msg {
+	return n.toConsensus
+}
+
+// ReportBadPeerChan implements core.Network interface, peers reported here
+// are scored as types.OffenseUnknown.
+func (n *Network) ReportBadPeerChan() chan<- interface{} {
+	return n.badPeerChan
+}
+
+// ReportBadPeer implements core.BadPeerReporter interface.
+func (n *Network) ReportBadPeer(report *types.BadPeerReport) {
+	select {
+	case n.badPeerChan <- report:
+	case <-n.ctx.Done():
+	}
+}
+
+// PeerScorer returns the utils.PeerScorer keeping scores of peers reported
+// via ReportBadPeerChan.
+func (n *Network) PeerScorer() *utils.PeerScorer {
+	return n.peerScorer
+}
+
+func (n *Network) acceptLoop() {
+	defer n.waitGroup.Done()
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			select {
+			case <-n.ctx.Done():
+				return
+			default:
+			}
+			n.logger.Error("Failed to accept connection", "error", err)
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-time.After(n.config.MinBackoff):
+			}
+			continue
+		}
+		go func() {
+			hs, err := handshake(
+				conn, n.prv, n.advertiseAddr, n.config.HandshakeTimeout)
+			if err != nil {
+				n.logger.Debug("Failed to authenticate incoming connection",
+					"remote", conn.RemoteAddr(),
+					"error", err)
+				conn.Close()
+				return
+			}
+			if n.peerScorer.Status(hs.ID) == utils.PeerBanned {
+				n.logger.Debug("Refuse banned peer", "peer", hs.ID)
+				conn.Close()
+				return
+			}
+			n.addPeer(conn, hs, hs.ID)
+		}()
+	}
+}
+
+func (n *Network) badPeerLoop() {
+	defer n.waitGroup.Done()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case v := <-n.badPeerChan:
+			peer, status := n.peerScorer.Report(v)
+			ID, ok := peer.(types.NodeID)
+			if !ok {
+				continue
+			}
+			// Only peers whose scores cross the ban threshold are
+			// disconnected, others are just deprioritized.
+			if status != utils.PeerBanned {
+				n.logger.Debug("Report bad peer", "peer", ID, "status", status)
+				continue
+			}
+			n.logger.Info("Disconnect banned peer", "peer", ID)
+			if p := n.getPeer(ID); p != nil {
+				p.close()
+			}
+		}
+	}
+}
+
+// maintain raises a routine to keep connected with the peer at addr, the
+// expected ID of that peer could be empty if unknown.
+func (n *Network) maintain(addr string, expected types.NodeID) {
+	n.dialingLock.Lock()
+	defer n.dialingLock.Unlock()
+	if n.ctx.Err() != nil {
+		return
+	}
+	if _, exist := n.dialing[addr]; exist {
+		return
+	}
+	n.dialing[addr] = struct{}{}
+	n.waitGroup.Add(1)
+	go n.dialLoop(addr, expected)
+}
+
+func (n *Network) dialLoop(addr string, expected types.NodeID) {
+	defer n.waitGroup.Done()
+	backoff := n.config.MinBackoff
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		default:
+		}
+		if expected != (types.NodeID{}) {
+			if p := n.getPeer(expected); p != nil {
+				select {
+				case <-p.closed:
+				case <-n.ctx.Done():
+					return
+				}
+				continue
+			}
+		}
+		p, err := n.dial(addr, expected)
+		if err == nil {
+			expected = p.ID
+			backoff = n.config.MinBackoff
+			continue
+		}
+		if err == ErrSelfConnection {
+			return
+		}
+		n.logger.Debug("Failed to connect to peer",
+			"addr", addr,
+			"backoff", backoff,
+			"error", err)
+		// Add some jitter to avoid all nodes reconnect at the same time.
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if backoff *= 2; backoff > n.config.MaxBackoff {
+			backoff = n.config.MaxBackoff
+		}
+	}
+}
+
+func (n *Network) dial(addr string, expected types.NodeID) (*peer, error) {
+	conn, err := net.DialTimeout("tcp", addr, n.config.HandshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	hs, err := handshake(
+		conn, n.prv, n.advertiseAddr, n.config.HandshakeTimeout)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if expected != (types.NodeID{}) && hs.ID != expected {
+		conn.Close()
+		return nil, ErrUnexpectedPeer
+	}
+	if n.peerScorer.Status(hs.ID) == utils.PeerBanned {
+		conn.Close()
+		return nil, ErrBannedPeer
+	}
+	p := n.addPeer(conn, hs, n.ID)
+	if p == nil {
+		return nil, context.Canceled
+	}
+	return p, nil
+}
+
+// addPeer registers an authenticated connection. When there is already a
+// connection to the same node, only one of them would be kept and returned.
+func (n *Network) addPeer(
+	conn net.Conn, hs *handshakeResult, dialer types.NodeID) *peer {
+	p := newPeer(conn, hs, dialer, n.config.SendQueueSize)
+	kept := func() *peer {
+		n.peersLock.Lock()
+		defer n.peersLock.Unlock()
+		if n.ctx.Err() != nil {
+			return nil
+		}
+		if existing, exist := n.peers[p.ID]; exist {
+			if !p.preferredTo(existing) {
+				return existing
+			}
+			existing.close()
+		}
+		n.peers[p.ID] = p
+		n.waitGroup.Add(2)
+		return p
+	}()
+	if kept != p {
+		p.close()
+		return kept
+	}
+	n.logger.Debug("Peer connected", "peer", p.ID, "addr", p.listenAddr)
+	go func() {
+		defer n.waitGroup.Done()
+		p.writeLoop()
+	}()
+	go func() {
+		defer n.waitGroup.Done()
+		err := p.readLoop(n.config.MaxFrameSize, func(body []byte) error {
+			return n.handleFrame(p, body)
+		})
+		n.removePeer(p)
+		n.logger.Debug("Peer disconnected", "peer", p.ID, "error", err)
+	}()
+	n.sendPeerList(p)
+	return p
+}
+
+func (n *Network) removePeer(p *peer) {
+	n.peersLock.Lock()
+	defer n.peersLock.Unlock()
+	if n.peers[p.ID] == p {
+		delete(n.peers, p.ID)
+	}
+}
+
+func (n *Network) getPeer(ID types.NodeID) *peer {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+	return n.peers[ID]
+}
+
+func (n *Network) handleFrame(p *peer, body []byte) error {
+	msg, err := n.codec.Decode(body)
+	if err != nil {
+		return err
+	}
+	switch v := msg.(type) {
+	case *types.Block:
+		n.addBlockToCache(v)
+		n.deliver(p.ID, v)
+	case *types.Vote:
+		n.addVoteToCache(v)
+		n.deliver(p.ID, v)
+	case *types.AgreementResult,
+		*typesDKG.PrivateShare, *typesDKG.PartialSignature:
+		n.deliver(p.ID, v)
+	case *codec.PullBlocksRequest:
+		if n.peerScorer.Status(p.ID) == utils.PeerNormal {
+			n.handlePullBlocks(p, v)
+		}
+	case *codec.PullVotesRequest:
+		if n.peerScorer.Status(p.ID) == utils.PeerNormal {
+			n.handlePullVotes(p, v)
+		}
+	case *peerList:
+		n.handlePeerList(v)
+	}
+	return nil
+}
+
+func (n *Network) deliver(from types.NodeID, msg interface{}) {
+	select {
+	case n.toConsensus <- types.Msg{PeerID: from, Payload: msg}:
+	case <-n.ctx.Done():
+	}
+}
+
+func (n *Network) handlePullBlocks(p *peer, req *codec.PullBlocksRequest) {
+	n.blockCacheLock.RLock()
+	defer n.blockCacheLock.RUnlock()
+	for _, h := range req.Hashes {
+		if b, exist := n.blockCache[h]; exist {
+			n.sendToPeer(p, b)
+		}
+	}
+}
+
+func (n *Network) handlePullVotes(p *peer, req *codec.PullVotesRequest) {
+	n.voteCacheLock.RLock()
+	defer n.voteCacheLock.RUnlock()
+	for _, v := range n.voteCache[req.Position] {
+		n.sendToPeer(p, v)
+	}
+}
+
+func (n *Network) sendPeerList(to *peer) {
+	list := &peerList{}
+	func() {
+		n.peersLock.RLock()
+		defer n.peersLock.RUnlock()
+		for ID, p := range n.peers {
+			if ID == to.ID || p.listenAddr == "" {
+				continue
+			}
+			list.Peers = append(list.Peers, peerInfo{ID: ID, Addr: p.listenAddr})
+		}
+	}()
+	n.sendToPeer(to, list)
+}
+
+func (n *Network) handlePeerList(list *peerList) {
+	if !n.discovery {
+		return
+	}
+	for _, info := range list.Peers {
+		if info.ID == n.ID || info.Addr == "" {
+			continue
+		}
+		n.maintain(info.Addr, info.ID)
+	}
+}
+
+// broadcast a message to peers in targets, or all peers if targets is nil.
+func (n *Network) broadcast(
+	targets map[types.NodeID]struct{}, msg interface{}) {
+	body, err := n.codec.Encode(msg)
+	if err != nil {
+		n.logger.Error("Failed to encode message", "error", err)
+		return
+	}
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+	for ID, p := range n.peers {
+		if targets != nil {
+			if _, exist := targets[ID]; !exist {
+				continue
+			}
+		}
+		if !p.send(body) {
+			n.logger.Warn("Send queue is full, drop message",
+				"peer", ID, "message", msg)
+		}
+	}
+}
+
+func (n *Network) send(ID types.NodeID, msg interface{}) {
+	p := n.getPeer(ID)
+	if p == nil {
+		n.logger.Warn("Peer not connected, drop message",
+			"peer", ID, "message", msg)
+		return
+	}
+	n.sendToPeer(p, msg)
+}
+
+func (n *Network) sendToPeer(p *peer, msg interface{}) {
+	body, err := n.codec.Encode(msg)
+	if err != nil {
+		n.logger.Error("Failed to encode message", "error", err)
+		return
+	}
+	if !p.send(body) {
+		n.logger.Warn("Send queue is full, drop message",
+			"peer", p.ID, "message", msg)
+	}
+}
+
+// sendToRandomPeers sends a message to at most maxPullingPeerCount peers in
+// candidates, or all connected peers if candidates is nil. Deprioritized
+// peers are picked only when there are not enough normal ones.
+func (n *Network) sendToRandomPeers(
+	candidates map[types.NodeID]struct{}, msg interface{}) {
+	var peers, deprioritized []*peer
+	func() {
+		n.peersLock.RLock()
+		defer n.peersLock.RUnlock()
+		for ID, p := range n.peers {
+			if candidates != nil {
+				if _, exist := candidates[ID]; !exist {
+					continue
+				}
+			}
+			peers = append(peers, p)
+		}
+	}()
+	rand.Shuffle(len(peers), func(i, j int) {
+		peers[i], peers[j] = peers[j], peers[i]
+	})
+	normal := peers[:0]
+	for _, p := range peers {
+		if n.peerScorer.Status(p.ID) == utils.PeerNormal {
+			normal = append(normal, p)
+		} else {
+			deprioritized = append(deprioritized, p)
+		}
+	}
+	peers = append(normal, deprioritized...)
+	for i, p := range peers {
+		if i >= maxPullingPeerCount {
+			break
+		}
+		n.sendToPeer(p, msg)
+	}
+}
+
+// getNotarySet returns the notary set of one round, nil is returned when
+// it's not available, which means all peers.
+func (n *Network) getNotarySet(round uint64) map[types.NodeID]struct{} {
+	if n.cache == nil {
+		return nil
+	}
+	set, err := n.cache.GetNotarySet(round)
+	if err != nil {
+		n.logger.Warn("Failed to get notary set", "round", round, "error", err)
+		return nil
+	}
+	return set
+}
+
+func (n *Network) addBlockToCache(b *types.Block) {
+	n.blockCacheLock.Lock()
+	defer n.blockCacheLock.Unlock()
+	if _, exist := n.blockCache[b.Hash]; exist {
+		// Keep the one with randomness.
+		if !b.IsFinalized() {
+			return
+		}
+	} else {
+		n.blockHashes = append(n.blockHashes, b.Hash)
+	}
+	n.blockCache[b.Hash] = b.Clone()
+	if len(n.blockHashes) > maxBlockCache {
+		delete(n.blockCache, n.blockHashes[0])
+		n.blockHashes = n.blockHashes[1:]
+	}
+}
+
+func (n *Network) addVoteToCache(v *types.Vote) {
+	n.voteCacheLock.Lock()
+	defer n.voteCacheLock.Unlock()
+	if _, exist := n.voteCache[v.Position]; !exist {
+		n.votePositions = append(n.votePositions, v.Position)
+		n.voteCache[v.Position] = make(map[types.VoteHeader]*types.Vote)
+		if len(n.votePositions) > maxVoteCache {
+			delete(n.voteCache, n.votePositions[0])
+			n.votePositions = n.votePositions[1:]
+		}
+	}
+	n.voteCache[v.Position][v.VoteHeader] = v
+}
+
+func (n *Network) markAgreementResultAsSent(blockHash common.Hash) bool {
+	n.sentAgreementLock.Lock()
+	defer n.sentAgreementLock.Unlock()
+	if _, exist := n.sentAgreement[blockHash]; exist {
+		return false
+	}
+	if len(n.sentAgreement) > maxSentAgreement {
+		// Randomly drop one entry.
+		for k := range n.sentAgreement {
+			delete(n.sentAgreement, k)
+			break
+		}
+	}
+	n.sentAgreement[blockHash] = struct{}{}
+	return true
+}
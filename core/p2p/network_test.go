@@ -0,0 +1,277 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+type NetworkTestSuite struct {
+	suite.Suite
+}
+
+func (s *NetworkTestSuite) newConfig(bootstrap ...string) Config {
+	return Config{
+		ListenAddr:     "127.0.0.1:0",
+		BootstrapPeers: bootstrap,
+		MinBackoff:     10 * time.Millisecond,
+		MaxBackoff:     100 * time.Millisecond,
+	}
+}
+
+// setupNetworks launches networks on loopback, all of them bootstrap from the
+// first one.
+func (s *NetworkTestSuite) setupNetworks(
+	prvKeys []crypto.PrivateKey) []*Network {
+	networks := make([]*Network, 0, len(prvKeys))
+	for i, prv := range prvKeys {
+		var n *Network
+		if i == 0 {
+			n = NewNetwork(prv, s.newConfig())
+		} else {
+			n = NewNetwork(prv, s.newConfig(networks[0].Addr()))
+		}
+		s.Require().NoError(n.Start())
+		networks = append(networks, n)
+	}
+	s.waitConnected(networks)
+	return networks
+}
+
+func (s *NetworkTestSuite) waitConnected(networks []*Network) {
+	s.Require().True(s.eventually(func() bool {
+		for _, n := range networks {
+			if len(n.Peers()) != len(networks)-1 {
+				return false
+			}
+		}
+		return true
+	}))
+}
+
+func (s *NetworkTestSuite) eventually(cond func() bool) bool {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func (s *NetworkTestSuite) receive(n *Network) types.Msg {
+	select {
+	case msg := <-n.ReceiveChan():
+		return msg
+	case <-time.After(5 * time.Second):
+		s.FailNow("timeout when receiving message")
+	}
+	return types.Msg{}
+}
+
+func (s *NetworkTestSuite) closeNetworks(networks []*Network) {
+	for _, n := range networks {
+		s.Require().NoError(n.Close())
+	}
+}
+
+func (s *NetworkTestSuite) TestBroadcastAndSend() {
+	prvKeys, pubKeys, err := test.NewKeys(7)
+	s.Require().NoError(err)
+	networks := s.setupNetworks(prvKeys)
+	defer s.closeNetworks(networks)
+	// Each vote should be received by all other peers.
+	for _, sender := range networks {
+		vote := types.NewVote(types.VoteInit, common.NewRandomHash(), 1)
+		vote.ProposerID = sender.ID
+		vote.Position = types.Position{Round: 1, Height: 10}
+		sender.BroadcastVote(vote)
+		for _, n := range networks {
+			if n == sender {
+				continue
+			}
+			msg := s.receive(n)
+			s.Require().Equal(sender.ID, msg.PeerID)
+			s.Require().Equal(vote.VoteHeader, msg.Payload.(*types.Vote).VoteHeader)
+		}
+	}
+	// Private shares should only be received by the receiver.
+	share := &typesDKG.PrivateShare{
+		ProposerID: networks[0].ID,
+		ReceiverID: networks[1].ID,
+		Round:      1,
+		Reset:      2,
+	}
+	networks[0].SendDKGPrivateShare(pubKeys[1], share)
+	msg := s.receive(networks[1])
+	s.Require().Equal(networks[0].ID, msg.PeerID)
+	recvShare := msg.Payload.(*typesDKG.PrivateShare)
+	s.Require().Equal(share.ProposerID, recvShare.ProposerID)
+	s.Require().Equal(share.Reset, recvShare.Reset)
+	for _, n := range networks[2:] {
+		s.Require().Len(n.ReceiveChan(), 0)
+	}
+}
+
+func (s *NetworkTestSuite) TestPullBlocksAndVotes() {
+	prvKeys, _, err := test.NewKeys(4)
+	s.Require().NoError(err)
+	networks := s.setupNetworks(prvKeys)
+	defer s.closeNetworks(networks)
+	block := &types.Block{
+		ProposerID: networks[0].ID,
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Round: 0, Height: 1},
+		Timestamp:  time.Now().UTC(),
+	}
+	networks[0].BroadcastBlock(block)
+	for _, n := range networks[1:] {
+		s.Require().Equal(block.Hash, s.receive(n).Payload.(*types.Block).Hash)
+	}
+	// All peers cache that block, the puller would receive at most
+	// maxPullingPeerCount copies.
+	networks[1].PullBlocks(common.Hashes{block.Hash})
+	s.Require().Equal(
+		block.Hash, s.receive(networks[1]).Payload.(*types.Block).Hash)
+	vote := types.NewVote(types.VoteCom, block.Hash, 2)
+	vote.ProposerID = networks[2].ID
+	vote.Position = block.Position
+	networks[2].BroadcastVote(vote)
+	s.receive(networks[3])
+	networks[3].PullVotes(block.Position)
+	s.Require().Equal(vote.VoteHeader,
+		s.receive(networks[3]).Payload.(*types.Vote).VoteHeader)
+}
+
+func (s *NetworkTestSuite) TestReconnect() {
+	prvKeys, _, err := test.NewKeys(3)
+	s.Require().NoError(err)
+	networks := s.setupNetworks(prvKeys)
+	defer s.closeNetworks(networks[:2])
+	// Restart the last node on the same address.
+	addr := networks[2].Addr()
+	s.Require().NoError(networks[2].Close())
+	s.Require().True(s.eventually(func() bool {
+		return len(networks[0].Peers()) == 1 && len(networks[1].Peers()) == 1
+	}))
+	config := s.newConfig()
+	config.ListenAddr = addr
+	restarted := NewNetwork(prvKeys[2], config)
+	s.Require().NoError(restarted.Start())
+	defer restarted.Close()
+	s.waitConnected([]*Network{networks[0], networks[1], restarted})
+}
+
+func (s *NetworkTestSuite) TestBadPeer() {
+	prvKeys, _, err := test.NewKeys(2)
+	s.Require().NoError(err)
+	networks := s.setupNetworks(prvKeys)
+	defer s.closeNetworks(networks)
+	networks[0].ReportBadPeerChan() <- networks[1].ID
+	// The bad peer is only deprioritized until its score crosses the ban
+	// threshold.
+	s.Require().True(s.eventually(func() bool {
+		return networks[0].PeerScorer().Score(networks[1].ID) < 0
+	}))
+	time.Sleep(100 * time.Millisecond)
+	s.Require().Len(networks[0].Peers(), 1)
+	s.Require().Equal(utils.PeerNormal,
+		networks[0].PeerScorer().Status(networks[1].ID))
+}
+
+func (s *NetworkTestSuite) TestBannedPeer() {
+	prvKeys, _, err := test.NewKeys(2)
+	s.Require().NoError(err)
+	networks := s.setupNetworks(prvKeys)
+	defer s.closeNetworks(networks)
+	for i := 0; i < 3; i++ {
+		networks[0].ReportBadPeer(&types.BadPeerReport{
+			PeerID:  networks[1].ID,
+			Offense: types.OffenseBadSignature,
+		})
+	}
+	s.Require().True(s.eventually(func() bool {
+		return networks[0].PeerScorer().Status(
+			networks[1].ID) == utils.PeerBanned
+	}))
+	// The banned peer is not able to reconnect until the ban is lifted.
+	s.Require().True(s.eventually(func() bool {
+		return len(networks[0].Peers()) == 0
+	}))
+	time.Sleep(500 * time.Millisecond)
+	s.Require().Len(networks[0].Peers(), 0)
+}
+
+func (s *NetworkTestSuite) TestImpersonation() {
+	prvKeys, _, err := test.NewKeys(3)
+	s.Require().NoError(err)
+	victim := NewNetwork(prvKeys[0], s.newConfig())
+	s.Require().NoError(victim.Start())
+	defer victim.Close()
+	// Dialing with an expected ID which is not the owner of the private key
+	// should fail.
+	attacker := NewNetwork(prvKeys[1], s.newConfig())
+	_, err = attacker.dial(
+		victim.Addr(), types.NewNodeID(prvKeys[2].PublicKey()))
+	s.Require().Equal(ErrUnexpectedPeer, err)
+	// Claiming the public key of others without a valid signature over the
+	// nonce from victim should fail.
+	conn, err := net.Dial("tcp", victim.Addr())
+	s.Require().NoError(err)
+	defer conn.Close()
+	s.Require().NoError(conn.SetDeadline(time.Now().Add(5 * time.Second)))
+	hello := make([]byte, 1+nonceSize)
+	hello[0] = protocolVersion
+	_, err = conn.Write(hello)
+	s.Require().NoError(err)
+	_, err = io.ReadFull(conn, make([]byte, 1+nonceSize))
+	s.Require().NoError(err)
+	auth := &handshakeAuth{
+		PublicKey:  prvKeys[2].PublicKey().Bytes(),
+		ListenAddr: "127.0.0.1:1",
+	}
+	auth.Signature, err = prvKeys[1].Sign(
+		hashHandshake(make([]byte, nonceSize), hello[1:], auth.ListenAddr))
+	s.Require().NoError(err)
+	b, err := rlp.EncodeToBytes(auth)
+	s.Require().NoError(err)
+	s.Require().NoError(writeFrame(conn, b))
+	// Receive the handshake from victim, then the connection is closed.
+	_, err = readFrame(conn, maxHandshakeSize)
+	s.Require().NoError(err)
+	_, err = readFrame(conn, maxHandshakeSize)
+	s.Require().Equal(io.EOF, err)
+	s.Require().Empty(victim.Peers())
+}
+
+func TestNetwork(t *testing.T) {
+	suite.Run(t, new(NetworkTestSuite))
+}
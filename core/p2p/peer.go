@@ -0,0 +1,112 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"net"
+	"sync"
+
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// peer is an authenticated connection to a remote node.
+type peer struct {
+	ID         types.NodeID
+	listenAddr string
+	conn       net.Conn
+	// dialer is the ID of the node initiating this connection, it's used to
+	// pick one connection when two nodes dial each other at the same time.
+	dialer    types.NodeID
+	sendQueue chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newPeer(conn net.Conn, hs *handshakeResult, dialer types.NodeID,
+	queueSize int) *peer {
+	return &peer{
+		ID:         hs.ID,
+		listenAddr: hs.ListenAddr,
+		conn:       conn,
+		dialer:     dialer,
+		sendQueue:  make(chan []byte, queueSize),
+		closed:     make(chan struct{}),
+	}
+}
+
+// preferredTo checks if this connection should be kept when another
+// connection to the same node exists. Both sides would make the same decision
+// by keeping the one dialed by the node with smaller ID.
+func (p *peer) preferredTo(other *peer) bool {
+	return bytes.Compare(p.dialer.Hash[:], other.dialer.Hash[:]) < 0
+}
+
+// send queues a frame body to this peer without blocking, false is returned
+// when the queue is full or the peer is closed.
+func (p *peer) send(body []byte) bool {
+	select {
+	case <-p.closed:
+		return false
+	default:
+	}
+	select {
+	case p.sendQueue <- body:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeLoop sends queued frames until the peer is closed.
+func (p *peer) writeLoop() {
+	defer p.close()
+	for {
+		select {
+		case <-p.closed:
+			return
+		case body := <-p.sendQueue:
+			if err := writeFrame(p.conn, body); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readLoop reads frames and passes them to handler until the peer is closed
+// or an error occurs.
+func (p *peer) readLoop(maxSize uint32, handler func([]byte) error) error {
+	defer p.close()
+	for {
+		body, err := readFrame(p.conn, maxSize)
+		if err != nil {
+			return err
+		}
+		if err = handler(body); err != nil {
+			return err
+		}
+	}
+}
+
+// close the connection, it's safe to be called more than once.
+func (p *peer) close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		p.conn.Close()
+	})
+}
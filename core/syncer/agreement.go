@@ -45,6 +45,7 @@ type agreement struct {
 	pendingAgrs       map[uint64]map[common.Hash]*types.AgreementResult
 	pendingBlocks     map[uint64]map[common.Hash]*types.Block
 	logger            common.Logger
+	clock             common.Clock
 	confirmedBlocks   map[common.Hash]struct{}
 	ctx               context.Context
 	ctxCancel         context.CancelFunc
@@ -54,7 +55,7 @@ type agreement struct {
 func newAgreement(chainTip uint64,
 	ch chan<- *types.Block, pullChan chan<- common.Hash,
 	cache *utils.NodeSetCache, verifier *core.TSigVerifierCache,
-	logger common.Logger) *agreement {
+	clock common.Clock, logger common.Logger) *agreement {
 	a := &agreement{
 		chainTip:          chainTip,
 		cache:             cache,
@@ -65,6 +66,7 @@ func newAgreement(chainTip uint64,
 		blocks:            make(map[types.Position]map[common.Hash]*types.Block),
 		agreementResults:  make(map[common.Hash][]byte),
 		logger:            logger,
+		clock:             clock,
 		pendingAgrs: make(
 			map[uint64]map[common.Hash]*types.AgreementResult),
 		pendingBlocks: make(
@@ -194,7 +196,16 @@ func (a *agreement) processAgreementResult(r *types.AgreementResult) {
 			a.logger.Error("cannot verify agreement result randomness", "result", r)
 			return
 		}
-		if !verifier.VerifySignature(r.BlockHash, crypto.Signature{
+		if r.Certificate != nil {
+			// The certificate carries the same signature as randomness.
+			if err := core.VerifyAgreementCertificate(
+				r.Certificate, a.cache, verifier); err != nil {
+				a.logger.Error("incorrect agreement result certificate",
+					"result", r,
+					"error", err)
+				return
+			}
+		} else if !verifier.VerifySignature(r.BlockHash, crypto.Signature{
 			Type:      "bls",
 			Signature: r.Randomness,
 		}) {
@@ -236,7 +247,7 @@ loop:
 				"position", &r.Position,
 				"hash", r.BlockHash.String()[:6])
 			return
-		case <-time.After(500 * time.Millisecond):
+		case <-a.clock.After(500 * time.Millisecond):
 			a.logger.Debug("Pull request is unable to send",
 				"position", &r.Position,
 				"hash", r.BlockHash.String()[:6])
@@ -287,7 +298,7 @@ func (a *agreement) confirm(b *types.Block) {
 			case <-a.ctx.Done():
 				a.logger.Error("Confirmed block is not sent", "block", b)
 				return
-			case <-time.After(500 * time.Millisecond):
+			case <-a.clock.After(500 * time.Millisecond):
 				a.logger.Debug("Agreement output channel is full", "block", b)
 			}
 		}
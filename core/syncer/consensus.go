@@ -32,6 +32,10 @@ import (
 	"github.com/tangerine-network/tangerine-consensus/core/utils"
 )
 
+// maxPulledBlocks is the maximum count of blocks pulled by height range but
+// not yet synced.
+const maxPulledBlocks = 1024
+
 var (
 	// ErrAlreadySynced is reported when syncer is synced.
 	ErrAlreadySynced = fmt.Errorf("already synced")
@@ -58,6 +62,7 @@ type Consensus struct {
 	network      core.Network
 	nodeSetCache *utils.NodeSetCache
 	tsigVerifier *core.TSigVerifierCache
+	clock        common.Clock
 
 	blocks            types.BlocksByPosition
 	agreementModule   *agreement
@@ -82,6 +87,8 @@ type Consensus struct {
 	dummyFinished      <-chan struct{}
 	dummyMsgBuffer     []types.Msg
 	initChainTipHeight uint64
+	rangePullEnabled   bool
+	pulledBlocks       map[uint64]*types.Block
 }
 
 // NewConsensus creates an instance for Consensus (syncer consensus).
@@ -94,6 +101,23 @@ func NewConsensus(
 	network core.Network,
 	prv crypto.PrivateKey,
 	logger common.Logger) *Consensus {
+	return NewConsensusWithClock(initHeight, dMoment, app, gov, db, network,
+		prv, &common.SystemClock{}, logger)
+}
+
+// NewConsensusWithClock creates an instance for Consensus (syncer consensus)
+// driven by the given clock, which is passed to the core.Consensus built
+// after synced.
+func NewConsensusWithClock(
+	initHeight uint64,
+	dMoment time.Time,
+	app core.Application,
+	gov core.Governance,
+	db db.Database,
+	network core.Network,
+	prv crypto.PrivateKey,
+	clock common.Clock,
+	logger common.Logger) *Consensus {
 
 	con := &Consensus{
 		dMoment:      dMoment,
@@ -104,10 +128,12 @@ func NewConsensus(
 		nodeSetCache: utils.NewNodeSetCache(gov),
 		tsigVerifier: core.NewTSigVerifierCache(gov, 7),
 		prv:          prv,
+		clock:        clock,
 		logger:       logger,
 		receiveChan:  make(chan *types.Block, 1000),
 		pullChan:     make(chan common.Hash, 1000),
 		heightEvt:    common.NewEvent(),
+		pulledBlocks: make(map[uint64]*types.Block),
 	}
 	con.ctx, con.ctxCancel = context.WithCancel(context.Background())
 	_, con.initChainTipHeight = db.GetCompactionChainTipInfo()
@@ -117,6 +143,7 @@ func NewConsensus(
 		con.pullChan,
 		con.nodeSetCache,
 		con.tsigVerifier,
+		con.clock,
 		con.logger)
 	con.agreementWaitGroup.Add(1)
 	go func() {
@@ -218,7 +245,7 @@ func (con *Consensus) assureBuffering() {
 						return false
 					case con.agreementModule.inputChan <- e.Round:
 						return false
-					case <-time.After(500 * time.Millisecond):
+					case <-con.clock.After(500 * time.Millisecond):
 						con.logger.Warn(
 							"Agreement input channel is full when notifying new round",
 							"round", e.Round,
@@ -336,6 +363,18 @@ func (con *Consensus) SyncBlocks(
 	// Make sure the first block is the next block of current compaction chain
 	// tip in DB.
 	_, tipHeight := con.db.GetCompactionChainTipInfo()
+	pullEnabled := con.isBlockRangePullEnabled()
+	if pullEnabled {
+		// Blocks pulled by height range might be synced before passed by
+		// the caller, skip those already in the compaction chain.
+		for len(blocks) > 0 && blocks[0].Position.Height <= tipHeight &&
+			con.db.HasBlock(blocks[0].Hash) {
+			blocks = blocks[1:]
+		}
+		if len(blocks) == 0 {
+			return
+		}
+	}
 	if blocks[0].Position.Height != tipHeight+1 {
 		con.logger.Error("Mismatched block height",
 			"now", blocks[0].Position.Height,
@@ -344,12 +383,16 @@ func (con *Consensus) SyncBlocks(
 		err = ErrInvalidSyncingHeight
 		return
 	}
+	provided := len(blocks)
+	if pullEnabled {
+		blocks = con.appendPulledBlocks(blocks)
+	}
 	con.logger.Trace("SyncBlocks",
 		"position", &blocks[0].Position,
 		"len", len(blocks),
 		"latest", latest,
 	)
-	for _, b := range blocks {
+	for i, b := range blocks {
 		if err = con.db.PutBlock(*b); err != nil {
 			// A block might be put into db when confirmed by BA, but not
 			// finalized yet.
@@ -364,6 +407,13 @@ func (con *Consensus) SyncBlocks(
 			b.Hash, b.Position.Height); err != nil {
 			return
 		}
+		if i >= provided {
+			// Blocks pulled by this module are not known by the caller.
+			con.logger.Debug("Syncer BlockConfirmed", "block", b)
+			con.app.BlockConfirmed(*b)
+			con.logger.Debug("Syncer BlockDelivered", "block", b)
+			con.app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+		}
 		con.heightEvt.NotifyHeight(b.Position.Height)
 	}
 	if latest {
@@ -376,11 +426,120 @@ func (con *Consensus) SyncBlocks(
 			con.stopBuffering()
 			con.syncedLastBlock = blocks[len(blocks)-1]
 			synced = true
+		} else if pullEnabled {
+			con.pullBlockRange(blocks[len(blocks)-1].Position.Height + 1)
 		}
 	}
 	return
 }
 
+// EnableBlockRangePull makes SyncBlocks pull finalized blocks between the
+// latest synced block and the oldest block confirmed by BA, when the network
+// module is able to pull blocks by height range. Those pulled blocks would be
+// synced in following calls to SyncBlocks, and delivered to the application
+// like blocks loaded from DB when constructing this module. Callers should
+// skip blocks already synced in that case.
+func (con *Consensus) EnableBlockRangePull() {
+	con.lock.Lock()
+	defer con.lock.Unlock()
+	con.rangePullEnabled = true
+}
+
+func (con *Consensus) isBlockRangePullEnabled() bool {
+	con.lock.RLock()
+	defer con.lock.RUnlock()
+	return con.rangePullEnabled
+}
+
+// appendPulledBlocks appends blocks pulled by height range which follow the
+// last one of 'blocks'.
+func (con *Consensus) appendPulledBlocks(
+	blocks []*types.Block) []*types.Block {
+	con.lock.Lock()
+	defer con.lock.Unlock()
+	last := blocks[len(blocks)-1]
+	for {
+		b, exist := con.pulledBlocks[last.Position.Height+1]
+		if !exist || b.ParentHash != last.Hash {
+			break
+		}
+		blocks = append(blocks, b)
+		last = b
+	}
+	for height := range con.pulledBlocks {
+		if height <= last.Position.Height {
+			delete(con.pulledBlocks, height)
+		}
+	}
+	return blocks
+}
+
+// pullBlockRange pulls finalized blocks from 'from' up to the oldest block
+// confirmed by BA, if the network module is able to pull blocks by height
+// range.
+func (con *Consensus) pullBlockRange(from uint64) {
+	puller, ok := con.network.(core.BlockRangePuller)
+	if !ok {
+		return
+	}
+	to := func() uint64 {
+		con.lock.RLock()
+		defer con.lock.RUnlock()
+		if len(con.blocks) == 0 {
+			return 0
+		}
+		return con.blocks[0].Position.Height
+	}()
+	if from >= to {
+		return
+	}
+	con.logger.Debug("Calling Network.PullBlockRange",
+		"from", from,
+		"to", to)
+	puller.PullBlockRange(from, to)
+}
+
+// processBlockRange verifies one page of blocks pulled by height range, and
+// keeps them to be synced along with blocks passed to SyncBlocks.
+func (con *Consensus) processBlockRange(r *types.BlockRange) error {
+	if err := utils.VerifyBlockRange(r); err != nil {
+		return err
+	}
+	for _, b := range r.Blocks {
+		if err := core.VerifyFinalizedBlock(b, con.tsigVerifier); err != nil {
+			return err
+		}
+	}
+	if err := core.VerifyAgreementCertificates(
+		r.Certificates, con.nodeSetCache, con.tsigVerifier); err != nil {
+		return err
+	}
+	// Certificates would be served to peers once blocks are synced.
+	for _, cert := range r.Certificates {
+		if err := con.db.PutAgreementCertificate(*cert); err != nil {
+			con.logger.Warn("Failed to save agreement certificate",
+				"certificate", cert,
+				"error", err)
+		}
+	}
+	func() {
+		con.lock.Lock()
+		defer con.lock.Unlock()
+		for _, b := range r.Blocks {
+			if len(con.pulledBlocks) >= maxPulledBlocks {
+				break
+			}
+			con.pulledBlocks[b.Position.Height] = b
+		}
+	}()
+	if next, more := r.Next(); more {
+		if puller, ok := con.network.(core.BlockRangePuller); ok {
+			puller.PullBlockRange(next, r.To)
+		}
+	}
+	return nil
+}
+
 // GetSyncedConsensus returns the core.Consensus instance after synced.
 func (con *Consensus) GetSyncedConsensus() (*core.Consensus, error) {
 	con.lock.Lock()
@@ -407,6 +566,7 @@ func (con *Consensus) GetSyncedConsensus() (*core.Consensus, error) {
 		con.prv,
 		con.blocks,
 		con.dummyMsgBuffer,
+		con.clock,
 		con.logger)
 	return con.syncedConsensus, err
 }
@@ -521,6 +681,13 @@ func (con *Consensus) startNetwork() {
 					if v.Position.Height <= con.initChainTipHeight {
 						continue loop
 					}
+				case *types.BlockRange:
+					if err := con.processBlockRange(v); err != nil {
+						con.logger.Error("Failed to process block range",
+							"range", v,
+							"error", err)
+					}
+					continue loop
 				default:
 					continue loop
 				}
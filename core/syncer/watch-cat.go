@@ -41,6 +41,7 @@ type WatchCat struct {
 	polling      time.Duration
 	ctx          context.Context
 	cancel       context.CancelFunc
+	clock        common.Clock
 	logger       common.Logger
 }
 
@@ -57,11 +58,18 @@ func NewWatchCat(
 		configReader: configReader,
 		feed:         make(chan types.Position),
 		polling:      polling,
+		clock:        &common.SystemClock{},
 		logger:       logger,
 	}
 	return wc
 }
 
+// SetClock replaces the clock to measure timeout and polling interval, it
+// should be called before Start.
+func (wc *WatchCat) SetClock(clock common.Clock) {
+	wc.clock = clock
+}
+
 // Feed the WatchCat so it won't produce the termination signal.
 func (wc *WatchCat) Feed(position types.Position) {
 	wc.feed <- position
@@ -91,7 +99,7 @@ func (wc *WatchCat) Start() {
 					continue
 				}
 				lastPos = pos
-			case <-time.After(wc.timeout):
+			case <-wc.clock.After(wc.timeout):
 				break MonitorLoop
 			}
 		}
@@ -131,7 +139,7 @@ func (wc *WatchCat) Start() {
 			select {
 			case <-wc.ctx.Done():
 				return
-			case <-time.After(wc.polling):
+			case <-wc.clock.After(wc.polling):
 			}
 		}
 	}()
@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 )
 
@@ -119,6 +120,61 @@ func (s *WatchCatTestSuite) TestBasicUsage() {
 	s.Equal(pos, watchCat.LastPosition())
 }
 
+func (s *WatchCatTestSuite) TestSimulatedClock() {
+	var (
+		req       = s.Require()
+		polling   = 10 * time.Second
+		timeout   = 30 * time.Second
+		notarySet = uint32(24)
+		clock     = test.NewSimulatedClock(time.Now().UTC())
+	)
+	watchCat, rec := s.newWatchCat(notarySet, polling, timeout)
+	watchCat.SetClock(clock)
+	watchCat.Start()
+	defer watchCat.Stop()
+	pos := types.Position{
+		Height: 10,
+	}
+	for i := 0; i < 10; i++ {
+		pending := clock.Pending()
+		pos.Height++
+		watchCat.Feed(pos)
+		// Make sure the timeout timer is reset before advancing the clock.
+		clock.BlockUntil(pending + 1)
+		clock.Advance(timeout / 2)
+		select {
+		case <-watchCat.Meow():
+			req.FailNow("unexpected terminated")
+		default:
+		}
+	}
+	clock.Advance(timeout)
+	waitFor := func(cond func() bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			req.True(time.Now().Before(deadline), "timeout")
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(func() bool {
+		votes, _ := rec.Votes(pos.Height)
+		return votes == 1
+	})
+	rec.lock.Lock()
+	rec.votes[pos.Height] = uint64((notarySet * 2 / 3) + 1)
+	rec.lock.Unlock()
+	waitFor(func() bool {
+		clock.Advance(polling)
+		select {
+		case <-watchCat.Meow():
+			return true
+		default:
+		}
+		return false
+	})
+	req.Equal(pos, watchCat.LastPosition())
+}
+
 func TestWatchCat(t *testing.T) {
 	suite.Run(t, new(WatchCatTestSuite))
 }
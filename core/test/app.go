@@ -81,6 +81,12 @@ type AppDeliveredRecord struct {
 	Pos  types.Position
 }
 
+// AppObserver is notified before App handles confirmed and delivered blocks.
+type AppObserver interface {
+	BlockConfirmed(b *types.Block)
+	BlockDelivered(blockHash common.Hash, pos types.Position, rand []byte)
+}
+
 // App implements Application interface for testing purpose.
 type App struct {
 	Confirmed           map[common.Hash]*types.Block
@@ -94,6 +100,8 @@ type App struct {
 	rEvt                *utils.RoundEvent
 	hEvt                *common.Event
 	roundToNotify       uint64
+	observers           []AppObserver
+	observersLock       sync.RWMutex
 }
 
 // NewApp constructs a TestApp instance.
@@ -185,8 +193,24 @@ func (app *App) VerifyBlock(block *types.Block) types.BlockVerifyStatus {
 	return types.VerifyOK
 }
 
+// AddObserver registers an observer to this App instance.
+func (app *App) AddObserver(o AppObserver) {
+	app.observersLock.Lock()
+	defer app.observersLock.Unlock()
+	app.observers = append(app.observers, o)
+}
+
+func (app *App) getObservers() []AppObserver {
+	app.observersLock.RLock()
+	defer app.observersLock.RUnlock()
+	return app.observers
+}
+
 // BlockConfirmed implements Application interface.
 func (app *App) BlockConfirmed(b types.Block) {
+	for _, o := range app.getObservers() {
+		o.BlockConfirmed(&b)
+	}
 	app.confirmedLock.Lock()
 	defer app.confirmedLock.Unlock()
 	app.Confirmed[b.Hash] = &b
@@ -208,6 +232,9 @@ func (app *App) ClearUndeliveredBlocks() {
 // BlockDelivered implements Application interface.
 func (app *App) BlockDelivered(blockHash common.Hash, pos types.Position,
 	rand []byte) {
+	for _, o := range app.getObservers() {
+		o.BlockDelivered(blockHash, pos, rand)
+	}
 	func() {
 		app.deliveredLock.Lock()
 		defer app.deliveredLock.Unlock()
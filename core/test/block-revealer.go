@@ -19,9 +19,8 @@ package test
 
 import (
 	"errors"
-	"sort"
+	"math"
 
-	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 )
@@ -31,49 +30,22 @@ var (
 	ErrNotValidCompactionChain = errors.New("not valid compaction chain")
 )
 
-// loadAllBlocks is a helper to load all blocks from db.BlockIterator.
-func loadAllBlocks(iter db.BlockIterator) (
-	blocks map[common.Hash]*types.Block, err error) {
-
-	blocks = make(map[common.Hash]*types.Block)
-	for {
-		block, err := iter.NextBlock()
-		if err != nil {
-			if err == db.ErrIterationFinished {
-				// It's safe to ignore iteraion-finished error.
-				err = nil
-			}
-			break
-		}
-		blocks[block.Hash] = &block
-	}
-	return
-}
-
 // BlockRevealerByPosition implements BlockRevealer interface, which would
-// load all blocks from db, reveal them in the order of compaction chain,
-// from the genesis block to the latest one.
+// load blocks from db by height, reveal them in the order of compaction
+// chain, from the genesis block to the latest one.
 type BlockRevealerByPosition struct {
-	blocks          types.BlocksByPosition
+	blocks          []types.Block
 	nextRevealIndex int
 }
 
 // NewBlockRevealerByPosition constructs a block revealer in the order of
 // compaction chain.
-func NewBlockRevealerByPosition(iter db.BlockIterator, startHeight uint64) (
+func NewBlockRevealerByPosition(dbInst db.Reader, startHeight uint64) (
 	r *BlockRevealerByPosition, err error) {
-	blocksByHash, err := loadAllBlocks(iter)
+	blocks, err := dbInst.GetBlocksInRange(startHeight, math.MaxUint64)
 	if err != nil {
 		return
 	}
-	blocks := types.BlocksByPosition{}
-	for _, b := range blocksByHash {
-		if b.Position.Height < startHeight {
-			continue
-		}
-		blocks = append(blocks, b)
-	}
-	sort.Sort(types.BlocksByPosition(blocks))
 	// Make sure the height of blocks are incremental with step 1.
 	for idx, b := range blocks {
 		if idx == 0 {
@@ -97,7 +69,7 @@ func (r *BlockRevealerByPosition) NextBlock() (types.Block, error) {
 	}
 	b := r.blocks[r.nextRevealIndex]
 	r.nextRevealIndex++
-	return *b, nil
+	return b, nil
 }
 
 // Reset implement Revealer.Reset method, which would reset revealing.
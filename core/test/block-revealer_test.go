@@ -48,19 +48,15 @@ func (s *BlockRevealerTestSuite) TestBlockRevealByPosition() {
 	}
 	s.Require().NoError(dbInst.PutBlock(*b1))
 	s.Require().NoError(dbInst.PutBlock(*b3))
-	iter, err := dbInst.GetAllBlocks()
-	s.Require().NoError(err)
 	// The compaction chain is not complete, we can't construct a revealer
 	// instance successfully.
-	r, err := NewBlockRevealerByPosition(iter, 0)
+	r, err := NewBlockRevealerByPosition(dbInst, 0)
 	s.Require().Nil(r)
 	s.Require().Equal(ErrNotValidCompactionChain.Error(), err.Error())
 	// Put a block to make the compaction chain complete.
 	s.Require().NoError(dbInst.PutBlock(*b2))
 	// We can construct that revealer now.
-	iter, err = dbInst.GetAllBlocks()
-	s.Require().NoError(err)
-	r, err = NewBlockRevealerByPosition(iter, 0)
+	r, err = NewBlockRevealerByPosition(dbInst, 0)
 	s.Require().NotNil(r)
 	s.Require().NoError(err)
 	// The revealing order should be ok.
@@ -76,9 +72,7 @@ func (s *BlockRevealerTestSuite) TestBlockRevealByPosition() {
 	_, err = r.NextBlock()
 	s.Require().Equal(db.ErrIterationFinished.Error(), err.Error())
 	// Test 'startHeight' parameter.
-	iter, err = dbInst.GetAllBlocks()
-	s.Require().NoError(err)
-	r, err = NewBlockRevealerByPosition(iter, 2)
+	r, err = NewBlockRevealerByPosition(dbInst, 2)
 	s.Require().NotNil(r)
 	s.Require().NoError(err)
 	chk(2)
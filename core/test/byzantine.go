@@ -0,0 +1,248 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	cryptoDKG "github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+const (
+	// Count of votes kept to be replayed.
+	maxReplayVotes = 128
+	// Count of duplicated pull requests for each one.
+	pullSpamCount = 10
+)
+
+// ByzantineBehavior is a kind of misbehavior of byzantine nodes.
+type ByzantineBehavior string
+
+// ByzantineBehavior enums.
+const (
+	// ByzantineEquivocateVote sends a conflicting vote along with each
+	// non-skip vote.
+	ByzantineEquivocateVote ByzantineBehavior = "equivocate-vote"
+	// ByzantineForkBlock proposes a conflicting block along with each
+	// proposed block.
+	ByzantineForkBlock ByzantineBehavior = "fork-block"
+	// ByzantineWithholdShare never sends DKG private shares to others.
+	ByzantineWithholdShare ByzantineBehavior = "withhold-share"
+	// ByzantineInvalidPartialSignature corrupts partial signatures in DKG
+	// partial signatures and votes, while keeping them signed properly.
+	ByzantineInvalidPartialSignature ByzantineBehavior = "invalid-psig"
+	// ByzantineReplayVote replays a vote of older positions along with each
+	// vote.
+	ByzantineReplayVote ByzantineBehavior = "replay-vote"
+	// ByzantineSpamPull duplicates each pull request, and pulls votes for
+	// each position it votes for.
+	ByzantineSpamPull ByzantineBehavior = "spam-pull"
+)
+
+// ParseByzantineBehaviors converts names to ByzantineBehavior enums.
+func ParseByzantineBehaviors(names []string) (
+	behaviors []ByzantineBehavior, err error) {
+	for _, name := range names {
+		b := ByzantineBehavior(name)
+		switch b {
+		case ByzantineEquivocateVote,
+			ByzantineForkBlock,
+			ByzantineWithholdShare,
+			ByzantineInvalidPartialSignature,
+			ByzantineReplayVote,
+			ByzantineSpamPull:
+			behaviors = append(behaviors, b)
+		default:
+			err = fmt.Errorf("unknown byzantine behavior: %s", name)
+			return
+		}
+	}
+	return
+}
+
+// ByzantineNetwork wraps a network for consensus core to make a node
+// misbehave. Forged messages are signed by the key of this node, thus they
+// could only be caught by their contents. Optional interfaces of the wrapped
+// network, ex. core.BadPeerReporter, are not exposed through it.
+type ByzantineNetwork struct {
+	ConsensusNetwork
+
+	id        types.NodeID
+	signer    *utils.Signer
+	behaviors map[ByzantineBehavior]struct{}
+	lock      sync.Mutex
+	sentVotes []*types.Vote
+}
+
+// NewByzantineNetwork constructs a ByzantineNetwork instance.
+func NewByzantineNetwork(n ConsensusNetwork, prvKey crypto.PrivateKey,
+	behaviors ...ByzantineBehavior) *ByzantineNetwork {
+	b := &ByzantineNetwork{
+		ConsensusNetwork: n,
+		id:               types.NewNodeID(prvKey.PublicKey()),
+		signer:           utils.NewSigner(prvKey),
+		behaviors:        make(map[ByzantineBehavior]struct{}),
+	}
+	for _, behavior := range behaviors {
+		b.behaviors[behavior] = struct{}{}
+	}
+	return b
+}
+
+func (b *ByzantineNetwork) misbehaves(behavior ByzantineBehavior) bool {
+	_, exists := b.behaviors[behavior]
+	return exists
+}
+
+// BroadcastVote implements core.Network interface.
+func (b *ByzantineNetwork) BroadcastVote(vote *types.Vote) {
+	if b.misbehaves(ByzantineInvalidPartialSignature) &&
+		len(vote.PartialSignature.Signature) > 0 {
+		vote = vote.Clone()
+		vote.PartialSignature = corruptPartialSignature(vote.PartialSignature)
+		if err := b.signer.SignVote(vote); err != nil {
+			panic(err)
+		}
+	}
+	b.ConsensusNetwork.BroadcastVote(vote)
+	if b.misbehaves(ByzantineEquivocateVote) &&
+		vote.BlockHash != types.SkipBlockHash {
+		forked := vote.Clone()
+		forked.BlockHash = common.NewRandomHash()
+		if err := b.signer.SignVote(forked); err != nil {
+			panic(err)
+		}
+		b.ConsensusNetwork.BroadcastVote(forked)
+	}
+	if b.misbehaves(ByzantineReplayVote) {
+		if replayed := b.pickVoteToReplay(vote); replayed != nil {
+			b.ConsensusNetwork.BroadcastVote(replayed)
+		}
+	}
+	if b.misbehaves(ByzantineSpamPull) {
+		b.PullVotes(vote.Position)
+	}
+}
+
+// BroadcastBlock implements core.Network interface.
+func (b *ByzantineNetwork) BroadcastBlock(block *types.Block) {
+	b.ConsensusNetwork.BroadcastBlock(block)
+	if !b.misbehaves(ByzantineForkBlock) || block.IsFinalized() ||
+		block.ProposerID != b.id {
+		return
+	}
+	forked := block.Clone()
+	forked.Payload = append(forked.Payload, byte(rand.Int()))
+	if err := b.signer.SignBlock(forked); err != nil {
+		panic(err)
+	}
+	b.ConsensusNetwork.BroadcastBlock(forked)
+}
+
+// SendDKGPrivateShare implements core.Network interface.
+func (b *ByzantineNetwork) SendDKGPrivateShare(
+	recv crypto.PublicKey, prvShare *typesDKG.PrivateShare) {
+	if b.misbehaves(ByzantineWithholdShare) {
+		return
+	}
+	b.ConsensusNetwork.SendDKGPrivateShare(recv, prvShare)
+}
+
+// BroadcastDKGPrivateShare implements core.Network interface.
+func (b *ByzantineNetwork) BroadcastDKGPrivateShare(
+	prvShare *typesDKG.PrivateShare) {
+	if b.misbehaves(ByzantineWithholdShare) {
+		return
+	}
+	b.ConsensusNetwork.BroadcastDKGPrivateShare(prvShare)
+}
+
+// BroadcastDKGPartialSignature implements core.Network interface.
+func (b *ByzantineNetwork) BroadcastDKGPartialSignature(
+	psig *typesDKG.PartialSignature) {
+	if b.misbehaves(ByzantineInvalidPartialSignature) {
+		psig = &typesDKG.PartialSignature{
+			Round:            psig.Round,
+			Hash:             psig.Hash,
+			PartialSignature: corruptPartialSignature(psig.PartialSignature),
+		}
+		if err := b.signer.SignDKGPartialSignature(psig); err != nil {
+			panic(err)
+		}
+	}
+	b.ConsensusNetwork.BroadcastDKGPartialSignature(psig)
+}
+
+// PullBlocks implements core.Network interface.
+func (b *ByzantineNetwork) PullBlocks(hashes common.Hashes) {
+	for i := 0; i < b.pullCount(); i++ {
+		b.ConsensusNetwork.PullBlocks(hashes)
+	}
+}
+
+// PullVotes implements core.Network interface.
+func (b *ByzantineNetwork) PullVotes(pos types.Position) {
+	for i := 0; i < b.pullCount(); i++ {
+		b.ConsensusNetwork.PullVotes(pos)
+	}
+}
+
+func (b *ByzantineNetwork) pullCount() int {
+	if b.misbehaves(ByzantineSpamPull) {
+		return pullSpamCount
+	}
+	return 1
+}
+
+// pickVoteToReplay records 'vote' and picks a vote of older positions sent
+// before.
+func (b *ByzantineNetwork) pickVoteToReplay(vote *types.Vote) *types.Vote {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var candidates []*types.Vote
+	for _, v := range b.sentVotes {
+		if v.Position.Older(vote.Position) {
+			candidates = append(candidates, v)
+		}
+	}
+	b.sentVotes = append(b.sentVotes, vote.Clone())
+	if len(b.sentVotes) > maxReplayVotes {
+		b.sentVotes = b.sentVotes[1:]
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.Intn(len(candidates))].Clone()
+}
+
+// corruptPartialSignature returns a copy of 'psig' with flipped bits.
+func corruptPartialSignature(
+	psig cryptoDKG.PartialSignature) cryptoDKG.PartialSignature {
+	corrupted := cryptoDKG.PartialSignature(crypto.Signature(psig).Clone())
+	for i := range corrupted.Signature {
+		corrupted.Signature[i] ^= 0xff
+	}
+	return corrupted
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	cryptoDKG "github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+func (s *NetworkTestSuite) receiveAll(
+	ch <-chan types.Msg) (msgs []interface{}) {
+	time.Sleep(50 * time.Millisecond)
+	for len(ch) > 0 {
+		msgs = append(msgs, (<-ch).Payload)
+	}
+	return
+}
+
+func (s *NetworkTestSuite) TestByzantineVotes() {
+	var (
+		req       = s.Require()
+		peerCount = 2
+	)
+	prvKeys, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	byzantine := NewByzantineNetwork(
+		networks[types.NewNodeID(pubKeys[0])], prvKeys[0],
+		ByzantineEquivocateVote,
+		ByzantineReplayVote,
+		ByzantineInvalidPartialSignature)
+	receiver := networks[types.NewNodeID(pubKeys[1])]
+	signer := utils.NewSigner(prvKeys[0])
+	newVote := func(height uint64) *types.Vote {
+		vote := types.NewVote(types.VoteCom, common.NewRandomHash(), 0)
+		vote.Position.Height = height
+		vote.PartialSignature = cryptoDKG.PartialSignature{
+			Type:      "bls",
+			Signature: []byte{1, 2, 3},
+		}
+		req.NoError(signer.SignVote(vote))
+		return vote
+	}
+	// Messages might be received in any order, thus votes are returned with
+	// their headers as keys.
+	checkVotes := func(msgs []interface{}) map[types.VoteHeader]*types.Vote {
+		votes := make(map[types.VoteHeader]*types.Vote)
+		for _, msg := range msgs {
+			vote := msg.(*types.Vote)
+			ok, err := utils.VerifyVoteSignature(vote)
+			req.NoError(err)
+			req.True(ok)
+			req.Equal(crypto.Signature{
+				Type:      "bls",
+				Signature: []byte{0xfe, 0xfd, 0xfc},
+			}, crypto.Signature(vote.PartialSignature))
+			votes[vote.VoteHeader] = vote
+		}
+		return votes
+	}
+	countForked := func(
+		votes map[types.VoteHeader]*types.Vote, vote *types.Vote) (count int) {
+		for header := range votes {
+			if header.Position == vote.Position &&
+				header.BlockHash != vote.BlockHash {
+				count++
+			}
+		}
+		return
+	}
+	// A conflicting vote is sent along with the vote.
+	vote1 := newVote(1)
+	byzantine.BroadcastVote(vote1)
+	votes := checkVotes(s.receiveAll(receiver.ReceiveChan()))
+	req.Len(votes, 2)
+	req.Contains(votes, vote1.VoteHeader)
+	req.Equal(1, countForked(votes, vote1))
+	// The vote of older position is replayed.
+	vote2 := newVote(2)
+	byzantine.BroadcastVote(vote2)
+	votes = checkVotes(s.receiveAll(receiver.ReceiveChan()))
+	req.Len(votes, 3)
+	req.Contains(votes, vote2.VoteHeader)
+	req.Contains(votes, vote1.VoteHeader)
+	req.Equal(1, countForked(votes, vote2))
+}
+
+func (s *NetworkTestSuite) TestByzantineBlocksAndDKG() {
+	var (
+		req       = s.Require()
+		peerCount = 2
+	)
+	prvKeys, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	byzantine := NewByzantineNetwork(
+		networks[types.NewNodeID(pubKeys[0])], prvKeys[0],
+		ByzantineForkBlock,
+		ByzantineWithholdShare,
+		ByzantineInvalidPartialSignature)
+	receiver := networks[types.NewNodeID(pubKeys[1])]
+	signer := utils.NewSigner(prvKeys[0])
+	// A conflicting block is proposed along with the block.
+	block := &types.Block{Position: types.Position{Height: 1}}
+	req.NoError(signer.SignBlock(block))
+	byzantine.BroadcastBlock(block)
+	msgs := s.receiveAll(receiver.ReceiveChan())
+	req.Len(msgs, 2)
+	original, forked := msgs[0].(*types.Block), msgs[1].(*types.Block)
+	if forked.Hash == block.Hash {
+		original, forked = forked, original
+	}
+	req.Equal(block.Hash, original.Hash)
+	req.Equal(block.Position, forked.Position)
+	req.NotEqual(block.Hash, forked.Hash)
+	req.NoError(utils.VerifyBlockSignature(forked))
+	// Blocks proposed by others are not forked.
+	block = &types.Block{Position: types.Position{Height: 2}}
+	req.NoError(utils.NewSigner(prvKeys[1]).SignBlock(block))
+	byzantine.BroadcastBlock(block)
+	req.Len(s.receiveAll(receiver.ReceiveChan()), 1)
+	// Private shares are withheld.
+	byzantine.SendDKGPrivateShare(pubKeys[1], &typesDKG.PrivateShare{})
+	byzantine.BroadcastDKGPrivateShare(&typesDKG.PrivateShare{})
+	req.Len(s.receiveAll(receiver.ReceiveChan()), 0)
+	// Partial signatures are corrupted, but signed properly.
+	psig := &typesDKG.PartialSignature{
+		Round: 1,
+		Hash:  common.NewRandomHash(),
+		PartialSignature: cryptoDKG.PartialSignature{
+			Type:      "bls",
+			Signature: []byte{1, 2, 3},
+		},
+	}
+	req.NoError(signer.SignDKGPartialSignature(psig))
+	byzantine.BroadcastDKGPartialSignature(psig)
+	msgs = s.receiveAll(receiver.ReceiveChan())
+	req.Len(msgs, 1)
+	received := msgs[0].(*typesDKG.PartialSignature)
+	req.Equal(psig.Hash, received.Hash)
+	req.NotEqual(psig.PartialSignature, received.PartialSignature)
+	ok, err := utils.VerifyDKGPartialSignatureSignature(received)
+	req.NoError(err)
+	req.True(ok)
+}
@@ -0,0 +1,375 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+)
+
+// simulatedTimer is a pending timer registered to SimulatedClock.
+type simulatedTimer struct {
+	deadline time.Time
+	seq      uint64
+	period   time.Duration
+	ch       chan time.Time
+	stopped  bool
+	tracked  bool
+	index    int
+}
+
+// simulatedTimers is a min-heap of timers ordered by their deadlines, timers
+// with identical deadlines are ordered by their registration order.
+type simulatedTimers []*simulatedTimer
+
+func (t simulatedTimers) Len() int { return len(t) }
+
+func (t simulatedTimers) Less(i, j int) bool {
+	if t[i].deadline.Equal(t[j].deadline) {
+		return t[i].seq < t[j].seq
+	}
+	return t[i].deadline.Before(t[j].deadline)
+}
+
+func (t simulatedTimers) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+	t[i].index = i
+	t[j].index = j
+}
+
+func (t *simulatedTimers) Push(x interface{}) {
+	timer := x.(*simulatedTimer)
+	timer.index = len(*t)
+	*t = append(*t, timer)
+}
+
+func (t *simulatedTimers) Pop() interface{} {
+	old := *t
+	n := len(old)
+	timer := old[n-1]
+	old[n-1] = nil
+	timer.index = -1
+	*t = old[:n-1]
+	return timer
+}
+
+// SimulatedClock implements common.Clock interface with virtual time. The
+// time only moves when the test driver calls Advance or AdvanceToNext, thus
+// timers of all modules sharing this clock fire in a deterministic order.
+//
+// It could also track work in flight for discrete-event schedulers: ticks of
+// tickers and wake-ups of timers from AfterWork are work in flight until
+// their receivers call WorkDone, others could be added by AddWork. The
+// scheduler waits by WaitIdle before moving the time forward.
+type SimulatedClock struct {
+	lock     sync.Mutex
+	cond     *sync.Cond
+	now      time.Time
+	seq      uint64
+	timers   simulatedTimers
+	pending  int
+	tracking bool
+	inflight int
+}
+
+// NewSimulatedClock constructs a SimulatedClock starting from 'start'.
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	c := &SimulatedClock{now: start}
+	c.cond = sync.NewCond(&c.lock)
+	return c
+}
+
+// Now implements common.Clock interface.
+func (c *SimulatedClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// After implements common.Clock interface.
+func (c *SimulatedClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.addTimerNoLock(d, 0, false).ch
+}
+
+// AfterWork is like After, the wake-up is work in flight when work is
+// tracked.
+func (c *SimulatedClock) AfterWork(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.addTimerNoLock(d, 0, true).ch
+}
+
+// Sleep implements common.Clock interface.
+func (c *SimulatedClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// NewTicker implements common.Clock interface.
+func (c *SimulatedClock) NewTicker(d time.Duration) common.ClockTicker {
+	if d <= 0 {
+		panic("non-positive interval for SimulatedClock.NewTicker")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return &simulatedTicker{clock: c, timer: c.addTimerNoLock(d, d, true)}
+}
+
+// TrackWork enables or disables tracking of work in flight, work in flight
+// is dropped when disabled.
+func (c *SimulatedClock) TrackWork(enabled bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.tracking = enabled
+	if !enabled {
+		c.inflight = 0
+		c.cond.Broadcast()
+	}
+}
+
+// AddWork marks 'n' pieces of work in flight, ex. messages delivered to nodes
+// sharing this clock. It does nothing when work is not tracked.
+func (c *SimulatedClock) AddWork(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.tracking {
+		c.inflight += n
+	}
+}
+
+// WorkDone marks a piece of work in flight as done.
+func (c *SimulatedClock) WorkDone() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.tracking {
+		return
+	}
+	if c.inflight <= 0 {
+		panic("no work in flight for SimulatedClock")
+	}
+	c.inflight--
+	if c.inflight == 0 {
+		c.cond.Broadcast()
+	}
+}
+
+// WaitIdle blocks until there is no work in flight.
+func (c *SimulatedClock) WaitIdle() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for c.inflight > 0 {
+		c.cond.Wait()
+	}
+}
+
+// Advance moves the clock forward by 'd', all timers expired in this period
+// fire in the order of their deadlines.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	target := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(target) {
+		c.fireNoLock()
+	}
+	c.now = target
+}
+
+// AdvanceToNext moves the clock forward to the deadline of the nearest
+// pending timer and fires all timers expiring at that moment. It returns
+// false when there is no pending timer.
+func (c *SimulatedClock) AdvanceToNext() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.purgeStoppedNoLock()
+	if len(c.timers) == 0 {
+		return false
+	}
+	deadline := c.timers[0].deadline
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(deadline) {
+		c.fireNoLock()
+	}
+	return true
+}
+
+// Next returns the deadline of the nearest pending timer, false is returned
+// when there is no pending timer.
+func (c *SimulatedClock) Next() (time.Time, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.purgeStoppedNoLock()
+	if len(c.timers) == 0 {
+		return time.Time{}, false
+	}
+	return c.timers[0].deadline, true
+}
+
+// Pending returns the count of timers waiting to be fired.
+func (c *SimulatedClock) Pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.pending
+}
+
+// BlockUntil blocks until there are at least 'n' pending timers, it's useful
+// to make sure the goroutines under test are waiting on this clock before
+// advancing it.
+func (c *SimulatedClock) BlockUntil(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for c.pending < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *SimulatedClock) addTimerNoLock(
+	d, period time.Duration, tracked bool) *simulatedTimer {
+	timer := &simulatedTimer{
+		deadline: c.now.Add(d),
+		seq:      c.seq,
+		period:   period,
+		ch:       make(chan time.Time, 1),
+		tracked:  tracked,
+	}
+	c.seq++
+	if d <= 0 && period == 0 {
+		timer.ch <- c.now
+		c.trackFiredNoLock(timer)
+		return timer
+	}
+	heap.Push(&c.timers, timer)
+	c.pending++
+	c.cond.Broadcast()
+	return timer
+}
+
+// fireNoLock fires the nearest timer, periodic timers would be scheduled
+// again.
+func (c *SimulatedClock) fireNoLock() {
+	timer := c.timers[0]
+	if timer.stopped {
+		heap.Pop(&c.timers)
+		return
+	}
+	c.now = timer.deadline
+	// Like time.Ticker, ticks are dropped for slow receivers.
+	select {
+	case timer.ch <- c.now:
+		c.trackFiredNoLock(timer)
+	default:
+	}
+	if timer.period > 0 {
+		timer.deadline = timer.deadline.Add(timer.period)
+		timer.seq = c.seq
+		c.seq++
+		heap.Fix(&c.timers, timer.index)
+		return
+	}
+	heap.Pop(&c.timers)
+	c.pending--
+}
+
+// trackFiredNoLock marks the wake-up of a tracked timer as work in flight.
+func (c *SimulatedClock) trackFiredNoLock(timer *simulatedTimer) {
+	if c.tracking && timer.tracked {
+		c.inflight++
+	}
+}
+
+func (c *SimulatedClock) purgeStoppedNoLock() {
+	for len(c.timers) > 0 && c.timers[0].stopped {
+		heap.Pop(&c.timers)
+	}
+}
+
+func (c *SimulatedClock) stopTimer(timer *simulatedTimer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if timer.stopped || timer.index < 0 {
+		return
+	}
+	timer.stopped = true
+	c.pending--
+}
+
+// workCounter counts work in flight received by a routine, which should be
+// reported as done before the routine blocks. It does nothing unless the clock
+// is a SimulatedClock.
+type workCounter struct {
+	clock   common.Clock
+	pending int
+}
+
+// newWorkCounter marks 'n' pieces of work in flight, which are received by
+// the returned workCounter. It's useful to launch a routine.
+func newWorkCounter(clock common.Clock, n int) *workCounter {
+	if c, ok := clock.(*SimulatedClock); ok {
+		c.AddWork(n)
+	}
+	return &workCounter{clock: clock, pending: n}
+}
+
+// after is like common.Clock.After, the wake-up would be received as work
+// once the returned channel is read.
+func (w *workCounter) after(d time.Duration) <-chan time.Time {
+	if c, ok := w.clock.(*SimulatedClock); ok {
+		return c.AfterWork(d)
+	}
+	return w.clock.After(d)
+}
+
+// add marks 'n' pieces of work received.
+func (w *workCounter) add(n int) {
+	w.pending += n
+}
+
+// handOver passes a piece of work received to another routine, which should
+// report it as done.
+func (w *workCounter) handOver() {
+	w.pending--
+}
+
+// done reports all work received as done.
+func (w *workCounter) done() {
+	if c, ok := w.clock.(*SimulatedClock); ok {
+		for ; w.pending > 0; w.pending-- {
+			c.WorkDone()
+		}
+	}
+	w.pending = 0
+}
+
+// simulatedTicker implements common.ClockTicker interface for
+// SimulatedClock.
+type simulatedTicker struct {
+	clock *SimulatedClock
+	timer *simulatedTimer
+}
+
+// C implements common.ClockTicker interface.
+func (t *simulatedTicker) C() <-chan time.Time {
+	return t.timer.ch
+}
+
+// Stop implements common.ClockTicker interface.
+func (t *simulatedTicker) Stop() {
+	t.clock.stopTimer(t.timer)
+}
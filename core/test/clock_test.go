@@ -0,0 +1,181 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SimulatedClockTestSuite struct {
+	suite.Suite
+}
+
+func (s *SimulatedClockTestSuite) TestAfter() {
+	var (
+		req   = s.Require()
+		start = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock = NewSimulatedClock(start)
+	)
+	req.Equal(start, clock.Now())
+	// Non-positive duration should be fired immediately.
+	select {
+	case t := <-clock.After(0):
+		req.Equal(start, t)
+	default:
+		req.FailNow("expect fired")
+	}
+	ch1 := clock.After(2 * time.Second)
+	ch2 := clock.After(time.Second)
+	req.Equal(2, clock.Pending())
+	clock.Advance(500 * time.Millisecond)
+	req.Equal(start.Add(500*time.Millisecond), clock.Now())
+	req.Len(ch1, 0)
+	req.Len(ch2, 0)
+	clock.Advance(500 * time.Millisecond)
+	req.Equal(start.Add(time.Second), <-ch2)
+	req.Len(ch1, 0)
+	req.Equal(1, clock.Pending())
+	// Jump to the deadline of the only pending timer.
+	req.True(clock.AdvanceToNext())
+	req.Equal(start.Add(2*time.Second), <-ch1)
+	req.Equal(start.Add(2*time.Second), clock.Now())
+	req.False(clock.AdvanceToNext())
+	req.Equal(0, clock.Pending())
+}
+
+func (s *SimulatedClockTestSuite) TestSleep() {
+	var (
+		req   = s.Require()
+		start = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock = NewSimulatedClock(start)
+		woken = make(chan time.Time, 1)
+	)
+	go func() {
+		clock.Sleep(3 * time.Second)
+		woken <- clock.Now()
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Second)
+	select {
+	case <-woken:
+		req.FailNow("woken too early")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	req.Equal(start.Add(3*time.Second), <-woken)
+}
+
+func (s *SimulatedClockTestSuite) TestTicker() {
+	var (
+		req   = s.Require()
+		start = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock = NewSimulatedClock(start)
+	)
+	ticker := clock.NewTicker(time.Second)
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		req.Equal(start.Add(time.Duration(i)*time.Second), <-ticker.C())
+	}
+	// Ticks are dropped when nobody receives them.
+	clock.Advance(5 * time.Second)
+	req.Equal(start.Add(4*time.Second), <-ticker.C())
+	req.Len(ticker.C(), 0)
+	req.Equal(1, clock.Pending())
+	ticker.Stop()
+	req.Equal(0, clock.Pending())
+	clock.Advance(time.Second)
+	req.Len(ticker.C(), 0)
+	req.False(clock.AdvanceToNext())
+}
+
+func (s *SimulatedClockTestSuite) TestOrdering() {
+	var (
+		req   = s.Require()
+		start = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock = NewSimulatedClock(start)
+		chs   []<-chan time.Time
+	)
+	// Timers with identical deadlines fire in their registration order, and
+	// all of them fire in one AdvanceToNext call.
+	for i := 0; i < 3; i++ {
+		chs = append(chs, clock.After(time.Second))
+	}
+	late := clock.After(2 * time.Second)
+	req.True(clock.AdvanceToNext())
+	for _, ch := range chs {
+		req.Equal(start.Add(time.Second), <-ch)
+	}
+	req.Len(late, 0)
+	req.Equal(1, clock.Pending())
+	next, exists := clock.Next()
+	req.True(exists)
+	req.Equal(start.Add(2*time.Second), next)
+}
+
+func (s *SimulatedClockTestSuite) TestTrackWork() {
+	var (
+		req   = s.Require()
+		start = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock = NewSimulatedClock(start)
+	)
+	// Work is not tracked by default.
+	clock.AddWork(1)
+	clock.WaitIdle()
+	clock.TrackWork(true)
+	clock.AddWork(2)
+	idle := make(chan struct{})
+	go func() {
+		defer close(idle)
+		clock.WaitIdle()
+	}()
+	clock.WorkDone()
+	select {
+	case <-idle:
+		req.FailNow("expect work in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.WorkDone()
+	<-idle
+	req.Panics(func() { clock.WorkDone() })
+	// Ticks of tickers and wake-ups from AfterWork are work in flight, those
+	// from After are not.
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+	chWork := clock.AfterWork(time.Second)
+	ch := clock.After(time.Second)
+	clock.Advance(time.Second)
+	<-ticker.C()
+	<-chWork
+	<-ch
+	clock.WorkDone()
+	clock.WorkDone()
+	clock.WaitIdle()
+	req.Panics(func() { clock.WorkDone() })
+	// Work in flight is dropped once disabled.
+	clock.AddWork(1)
+	clock.TrackWork(false)
+	clock.WaitIdle()
+	clock.WorkDone()
+}
+
+func TestSimulatedClock(t *testing.T) {
+	suite.Run(t, new(SimulatedClockTestSuite))
+}
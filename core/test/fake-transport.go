@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 )
@@ -45,6 +46,7 @@ type FakeTransport struct {
 	serverChannel chan<- *TransportEnvelope
 	peers         map[types.NodeID]fakePeerRecord
 	dMoment       time.Time
+	clock         common.Clock
 }
 
 // NewFakeTransportServer constructs FakeTransport instance for peer server.
@@ -52,6 +54,7 @@ func NewFakeTransportServer() TransportServer {
 	return &FakeTransport{
 		peerType:    TransportPeerServer,
 		recvChannel: make(chan *TransportEnvelope, 1000),
+		clock:       &common.SystemClock{},
 	}
 }
 
@@ -62,6 +65,7 @@ func NewFakeTransportClient(pubKey crypto.PublicKey) TransportClient {
 		recvChannel: make(chan *TransportEnvelope, 1000),
 		nID:         types.NewNodeID(pubKey),
 		pubKey:      pubKey,
+		clock:       &common.SystemClock{},
 	}
 }
 
@@ -108,7 +112,7 @@ func (t *FakeTransport) Broadcast(endpoints map[types.NodeID]struct{},
 			continue
 		}
 		go func(nID types.NodeID) {
-			time.Sleep(latency.Delay())
+			t.clock.Sleep(latency.Delay())
 			// #nosec G104
 			t.Send(nID, msg)
 		}(ID)
@@ -116,6 +120,11 @@ func (t *FakeTransport) Broadcast(endpoints map[types.NodeID]struct{},
 	return
 }
 
+// setClock replaces the clock to simulate latencies.
+func (t *FakeTransport) setClock(clock common.Clock) {
+	t.clock = clock
+}
+
 // Close implements Transport.Close method.
 func (t *FakeTransport) Close() (err error) {
 	close(t.recvChannel)
@@ -0,0 +1,285 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// Partition splits nodes into groups within a period, messages between nodes
+// in different groups are dropped. Nodes not belonging to any group are not
+// affected.
+type Partition struct {
+	Groups [][]types.NodeID
+	// Begin and End are offsets to dMoment. The partition is healed at End,
+	// or never healed when End is zero.
+	Begin time.Duration
+	End   time.Duration
+}
+
+// LinkFault degrades links from nodes in From to nodes in To within a
+// period, an empty From or To matches all nodes.
+type LinkFault struct {
+	From []types.NodeID
+	To   []types.NodeID
+	// Begin and End are offsets to dMoment. The fault is healed at End, or
+	// never healed when End is zero.
+	Begin time.Duration
+	End   time.Duration
+	// Delay is the extra latency added to each message, nil for none.
+	Delay LatencyModel
+	// Loss is the probability to drop a message.
+	Loss float64
+	// Duplicate is the probability to send a message twice.
+	Duplicate float64
+	// Reorder is the probability to hold a message for a random period
+	// shorter than ReorderWindow, messages sent later may overtake it.
+	Reorder       float64
+	ReorderWindow time.Duration
+}
+
+// FaultPlan describes faults injected to messages sent by a Network.
+type FaultPlan struct {
+	// Seed is used to draw randomness for losses, duplications and
+	// reorderings, mixed with the ID of the sender.
+	Seed       int64
+	Partitions []Partition
+	Links      []LinkFault
+}
+
+func isFaultActive(begin, end, elapsed time.Duration) bool {
+	return elapsed >= begin && (end == 0 || elapsed < end)
+}
+
+func newNodeIDSet(IDs []types.NodeID) map[types.NodeID]struct{} {
+	if len(IDs) == 0 {
+		return nil
+	}
+	set := make(map[types.NodeID]struct{}, len(IDs))
+	for _, ID := range IDs {
+		set[ID] = struct{}{}
+	}
+	return set
+}
+
+type partitionFault struct {
+	Partition
+
+	groups map[types.NodeID]int
+}
+
+func (p *partitionFault) separated(from, to types.NodeID) bool {
+	fromGroup, exists := p.groups[from]
+	if !exists {
+		return false
+	}
+	toGroup, exists := p.groups[to]
+	if !exists {
+		return false
+	}
+	return fromGroup != toGroup
+}
+
+type linkFault struct {
+	LinkFault
+
+	from map[types.NodeID]struct{}
+	to   map[types.NodeID]struct{}
+}
+
+func (l *linkFault) matches(from, to types.NodeID) bool {
+	if l.from != nil {
+		if _, exists := l.from[from]; !exists {
+			return false
+		}
+	}
+	if l.to != nil {
+		if _, exists := l.to[to]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// faultInjector decides the fate of each message sent by one node.
+type faultInjector struct {
+	partitions []*partitionFault
+	links      []*linkFault
+	lock       sync.Mutex
+	rand       *rand.Rand
+}
+
+func newFaultInjector(plan *FaultPlan, ID types.NodeID) *faultInjector {
+	f := &faultInjector{
+		rand: rand.New(rand.NewSource(
+			plan.Seed ^ int64(binary.LittleEndian.Uint64(ID.Hash[:8])))),
+	}
+	for _, p := range plan.Partitions {
+		pf := &partitionFault{
+			Partition: p,
+			groups:    make(map[types.NodeID]int),
+		}
+		for idx, group := range p.Groups {
+			for _, nID := range group {
+				pf.groups[nID] = idx
+			}
+		}
+		f.partitions = append(f.partitions, pf)
+	}
+	for _, l := range plan.Links {
+		// Draw extra latencies from the seeded source of this injector, which
+		// is guarded by the lock, when possible.
+		if m, ok := l.Delay.(*NormalLatencyModel); ok {
+			seeded := *m
+			seeded.Rand = f.rand
+			l.Delay = &seeded
+		}
+		f.links = append(f.links, &linkFault{
+			LinkFault: l,
+			from:      newNodeIDSet(l.From),
+			to:        newNodeIDSet(l.To),
+		})
+	}
+	return f
+}
+
+// decide returns the extra delay of each copy of a message sent from 'from'
+// to 'to' at 'elapsed' after dMoment. When the link is not affected by any
+// fault, 'affected' would be false. An affected message without any copy is
+// dropped.
+func (f *faultInjector) decide(elapsed time.Duration, from, to types.NodeID) (
+	copies []time.Duration, affected bool) {
+	for _, p := range f.partitions {
+		if isFaultActive(p.Begin, p.End, elapsed) && p.separated(from, to) {
+			return nil, true
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	copies = []time.Duration{0}
+	for _, l := range f.links {
+		if !isFaultActive(l.Begin, l.End, elapsed) || !l.matches(from, to) {
+			continue
+		}
+		affected = true
+		if f.rand.Float64() < l.Loss {
+			return nil, true
+		}
+		if l.Delay != nil {
+			delay := l.Delay.Delay()
+			for idx := range copies {
+				copies[idx] += delay
+			}
+		}
+		if l.ReorderWindow > 0 && f.rand.Float64() < l.Reorder {
+			for idx := range copies {
+				copies[idx] += time.Duration(f.rand.Int63n(int64(l.ReorderWindow)))
+			}
+		}
+		if f.rand.Float64() < l.Duplicate {
+			copies = append(copies, copies[len(copies)-1])
+		}
+	}
+	return
+}
+
+// faultClient injects faults to messages sent via TransportClient.
+type faultClient struct {
+	TransportClient
+
+	ID       types.NodeID
+	clock    common.Clock
+	injector *faultInjector
+	lock     sync.RWMutex
+}
+
+func (fc *faultClient) setPlan(plan *FaultPlan) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	if plan == nil {
+		fc.injector = nil
+		return
+	}
+	fc.injector = newFaultInjector(plan, fc.ID)
+}
+
+func (fc *faultClient) decide(to types.NodeID) (
+	copies []time.Duration, affected bool) {
+	fc.lock.RLock()
+	defer fc.lock.RUnlock()
+	if fc.injector == nil {
+		return
+	}
+	return fc.injector.decide(
+		fc.clock.Now().Sub(fc.TransportClient.DMoment()), fc.ID, to)
+}
+
+func (fc *faultClient) sendAfter(
+	ID types.NodeID, delay time.Duration, msg interface{}) {
+	work := &workCounter{clock: fc.clock}
+	wake := work.after(delay)
+	go func() {
+		<-wake
+		work.add(1)
+		defer work.done()
+		// #nosec G104
+		fc.TransportClient.Send(ID, msg)
+	}()
+}
+
+func (fc *faultClient) Send(ID types.NodeID, msg interface{}) error {
+	copies, affected := fc.decide(ID)
+	if !affected {
+		return fc.TransportClient.Send(ID, msg)
+	}
+	for _, delay := range copies {
+		if delay > 0 {
+			fc.sendAfter(ID, delay, msg)
+			continue
+		}
+		if err := fc.TransportClient.Send(ID, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fc *faultClient) Broadcast(
+	IDs map[types.NodeID]struct{}, latency LatencyModel, msg interface{}) error {
+	unaffected := make(map[types.NodeID]struct{}, len(IDs))
+	for ID := range IDs {
+		if ID == fc.ID {
+			continue
+		}
+		copies, affected := fc.decide(ID)
+		if !affected {
+			unaffected[ID] = struct{}{}
+			continue
+		}
+		for _, delay := range copies {
+			fc.sendAfter(ID, latency.Delay()+delay, msg)
+		}
+	}
+	return fc.TransportClient.Broadcast(unaffected, latency, msg)
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+type FaultTestSuite struct {
+	suite.Suite
+}
+
+func (s *FaultTestSuite) newNodeIDs(count int) (nIDs []types.NodeID) {
+	_, pubKeys, err := NewKeys(count)
+	s.Require().NoError(err)
+	for _, k := range pubKeys {
+		nIDs = append(nIDs, types.NewNodeID(k))
+	}
+	return
+}
+
+func (s *FaultTestSuite) TestPartition() {
+	var (
+		req  = s.Require()
+		nIDs = s.newNodeIDs(5)
+		f    = newFaultInjector(&FaultPlan{
+			Partitions: []Partition{{
+				Groups: [][]types.NodeID{nIDs[:2], nIDs[2:4]},
+				Begin:  time.Second,
+				End:    2 * time.Second,
+			}},
+		}, nIDs[0])
+	)
+	checkDropped := func(elapsed time.Duration, to types.NodeID, dropped bool) {
+		copies, affected := f.decide(elapsed, nIDs[0], to)
+		req.Equal(dropped, affected)
+		if dropped {
+			req.Empty(copies)
+		}
+	}
+	// Before the partition begins.
+	checkDropped(0, nIDs[2], false)
+	// Messages within the same group, or to nodes not in any group.
+	checkDropped(time.Second, nIDs[1], false)
+	checkDropped(time.Second, nIDs[4], false)
+	// Messages across groups.
+	checkDropped(time.Second, nIDs[2], true)
+	checkDropped(2*time.Second-1, nIDs[3], true)
+	// The partition is healed.
+	checkDropped(2*time.Second, nIDs[2], false)
+}
+
+func (s *FaultTestSuite) TestLinkFault() {
+	var (
+		req  = s.Require()
+		nIDs = s.newNodeIDs(3)
+	)
+	f := newFaultInjector(&FaultPlan{
+		Links: []LinkFault{{
+			From:      nIDs[:1],
+			To:        nIDs[1:2],
+			Delay:     &FixedLatencyModel{Latency: 100},
+			Duplicate: 1,
+		}},
+	}, nIDs[0])
+	copies, affected := f.decide(0, nIDs[0], nIDs[1])
+	req.True(affected)
+	req.Equal([]time.Duration{
+		100 * time.Millisecond, 100 * time.Millisecond}, copies)
+	// Links not matched.
+	_, affected = f.decide(0, nIDs[0], nIDs[2])
+	req.False(affected)
+	_, affected = f.decide(0, nIDs[1], nIDs[0])
+	req.False(affected)
+	// Reordered messages are held within the window.
+	f = newFaultInjector(&FaultPlan{
+		Links: []LinkFault{{
+			Reorder:       1,
+			ReorderWindow: time.Second,
+		}},
+	}, nIDs[0])
+	for i := 0; i < 100; i++ {
+		copies, affected = f.decide(0, nIDs[0], nIDs[1])
+		req.True(affected)
+		req.Len(copies, 1)
+		req.True(copies[0] >= 0 && copies[0] < time.Second)
+	}
+}
+
+func (s *FaultTestSuite) TestLossWithSeed() {
+	var (
+		req  = s.Require()
+		nIDs = s.newNodeIDs(2)
+		plan = &FaultPlan{
+			Seed:  1,
+			Links: []LinkFault{{Loss: 0.3}},
+		}
+		count = 1000
+	)
+	decideAll := func() (dropped []bool) {
+		f := newFaultInjector(plan, nIDs[0])
+		for i := 0; i < count; i++ {
+			copies, affected := f.decide(0, nIDs[0], nIDs[1])
+			req.True(affected)
+			dropped = append(dropped, len(copies) == 0)
+		}
+		return
+	}
+	dropped := decideAll()
+	// The same seed leads to the same decisions.
+	req.Equal(dropped, decideAll())
+	droppedCount := 0
+	for _, d := range dropped {
+		if d {
+			droppedCount++
+		}
+	}
+	req.InDelta(0.3, float64(droppedCount)/float64(count), 0.05)
+}
+
+func TestFault(t *testing.T) {
+	suite.Run(t, new(FaultTestSuite))
+}
@@ -0,0 +1,226 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+const (
+	defaultGossipTTL = 8
+	maxGossipSeen    = 10000
+)
+
+// gossipMessage wraps a message being gossiped between peers.
+type gossipMessage struct {
+	Hash    common.Hash
+	TTL     int
+	Payload interface{}
+}
+
+// MarshalJSON implements json.Marshaller.
+func (msg *gossipMessage) MarshalJSON() (b []byte, err error) {
+	var (
+		payloadType  string
+		payloadBytes []byte
+	)
+	switch msg.Payload.(type) {
+	case *types.Vote:
+		payloadType = "vote"
+	case *types.Block:
+		payloadType = "block"
+	case *types.AgreementResult:
+		payloadType = "agreement-result"
+	default:
+		err = fmt.Errorf("unknown payload type for gossip: %T", msg.Payload)
+		return
+	}
+	if payloadBytes, err = json.Marshal(msg.Payload); err != nil {
+		return
+	}
+	b, err = json.Marshal(&struct {
+		Hash        common.Hash `json:"hash"`
+		TTL         int         `json:"ttl"`
+		PayloadType string      `json:"type"`
+		Payload     []byte      `json:"payload"`
+	}{msg.Hash, msg.TTL, payloadType, payloadBytes})
+	return
+}
+
+// UnmarshalJSON implements json.Unmarshaller.
+func (msg *gossipMessage) UnmarshalJSON(data []byte) (err error) {
+	rawMsg := &struct {
+		Hash        common.Hash `json:"hash"`
+		TTL         int         `json:"ttl"`
+		PayloadType string      `json:"type"`
+		Payload     []byte      `json:"payload"`
+	}{}
+	if err = json.Unmarshal(data, rawMsg); err != nil {
+		return
+	}
+	var payload interface{}
+	switch rawMsg.PayloadType {
+	case "vote":
+		payload = &types.Vote{}
+	case "block":
+		payload = &types.Block{}
+	case "agreement-result":
+		payload = &types.AgreementResult{}
+	default:
+		err = fmt.Errorf("unknown payload type for gossip: %v",
+			rawMsg.PayloadType)
+		return
+	}
+	if err = json.Unmarshal(rawMsg.Payload, payload); err != nil {
+		return
+	}
+	msg.Hash = rawMsg.Hash
+	msg.TTL = rawMsg.TTL
+	msg.Payload = payload
+	return
+}
+
+// unwrapGossip returns the payload of gossipMessage, or the message itself if
+// it's not gossiped.
+func unwrapGossip(msg interface{}) interface{} {
+	if g, ok := msg.(*gossipMessage); ok {
+		return g.Payload
+	}
+	return msg
+}
+
+// hashGossip generates the identity of a gossiped message for deduplication.
+func hashGossip(msg interface{}) common.Hash {
+	switch v := msg.(type) {
+	case *types.Vote:
+		h := utils.HashVote(v)
+		return crypto.Keccak256Hash([]byte("vote"), h[:])
+	case *types.Block:
+		// A block would be broadcasted again once finalized.
+		finalized := byte(0)
+		if v.IsFinalized() {
+			finalized = 1
+		}
+		return crypto.Keccak256Hash(
+			[]byte("block"), v.Hash[:], []byte{finalized})
+	case *types.AgreementResult:
+		return crypto.Keccak256Hash([]byte("agreement-result"), v.BlockHash[:])
+	}
+	panic(fmt.Errorf("unknown message type for gossip: %T", msg))
+}
+
+// gossipSeen keeps hashes of recently seen gossiped messages.
+type gossipSeen struct {
+	lock   sync.Mutex
+	hashes map[common.Hash]struct{}
+	order  []common.Hash
+}
+
+func newGossipSeen() *gossipSeen {
+	return &gossipSeen{hashes: make(map[common.Hash]struct{})}
+}
+
+// mark the hash as seen, returns false if it's already seen.
+func (s *gossipSeen) mark(h common.Hash) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, exist := s.hashes[h]; exist {
+		return false
+	}
+	if len(s.order) >= maxGossipSeen {
+		delete(s.hashes, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.hashes[h] = struct{}{}
+	s.order = append(s.order, h)
+	return true
+}
+
+// gossipEnabled checks if broadcasting is done by gossiping.
+func (n *Network) gossipEnabled() bool {
+	return n.config.GossipFanout > 0
+}
+
+// gossipScope returns the set of peers that should receive the message.
+func (n *Network) gossipScope(msg interface{}) map[types.NodeID]struct{} {
+	if vote, ok := msg.(*types.Vote); ok {
+		return n.getNotarySet(vote.Position.Round)
+	}
+	return n.peers
+}
+
+// gossip starts gossiping a message originated from this node.
+func (n *Network) gossip(msg interface{}) {
+	ttl := n.config.GossipTTL
+	if ttl <= 0 {
+		ttl = defaultGossipTTL
+	}
+	g := &gossipMessage{Hash: hashGossip(msg), TTL: ttl, Payload: msg}
+	if !n.gossipSeen.mark(g.Hash) {
+		// It's relayed when received.
+		return
+	}
+	n.pushGossip(g, n.ID)
+}
+
+// pushGossip sends a gossiped message to at most GossipFanout random peers in
+// its scope.
+func (n *Network) pushGossip(g *gossipMessage, from types.NodeID) {
+	candidates := []types.NodeID{}
+	for nID := range n.gossipScope(g.Payload) {
+		if nID == n.ID || nID == from {
+			continue
+		}
+		candidates = append(candidates, nID)
+	}
+	targets := make(map[types.NodeID]struct{})
+	for _, idx := range rand.Perm(len(candidates)) {
+		if len(targets) >= n.config.GossipFanout {
+			break
+		}
+		targets[candidates[idx]] = struct{}{}
+	}
+	if err := n.trans.Broadcast(
+		targets, n.config.GossipLatency, g); err != nil {
+		panic(err)
+	}
+}
+
+// handleGossip relays a received gossiped message, returns false if this
+// message is seen before.
+func (n *Network) handleGossip(from types.NodeID, g *gossipMessage) bool {
+	if hashGossip(g.Payload) != g.Hash || !n.gossipSeen.mark(g.Hash) {
+		return false
+	}
+	if g.TTL > 1 {
+		n.pushGossip(&gossipMessage{
+			Hash:    g.Hash,
+			TTL:     g.TTL - 1,
+			Payload: n.cloneForFake(g.Payload),
+		}, from)
+	}
+	return true
+}
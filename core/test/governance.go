@@ -45,6 +45,9 @@ type Governance struct {
 	networkModule        *Network
 	pendingConfigChanges map[uint64]map[StateChangeType]interface{}
 	prohibitedTypes      map[StateChangeType]struct{}
+	nodeWeights          map[types.NodeID]uint64
+	forkVoteReports      map[types.NodeID]int
+	forkBlockReports     map[types.NodeID]int
 	lock                 sync.RWMutex
 }
 
@@ -77,6 +80,31 @@ func (g *Governance) NodeSet(round uint64) []crypto.PublicKey {
 	return g.nodeSets[round]
 }
 
+// NodeWeights implements utils.NodeWeightInterface to return weights of
+// nodes, the same weights are used for all rounds.
+func (g *Governance) NodeWeights(round uint64) map[types.NodeID]uint64 {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	if g.nodeWeights == nil {
+		return nil
+	}
+	weights := make(map[types.NodeID]uint64, len(g.nodeWeights))
+	for nID, weight := range g.nodeWeights {
+		weights[nID] = weight
+	}
+	return weights
+}
+
+// SetNodeWeights sets weights of nodes, nodes not found are weighted by 1.
+func (g *Governance) SetNodeWeights(weights map[types.NodeID]uint64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.nodeWeights = make(map[types.NodeID]uint64, len(weights))
+	for nID, weight := range weights {
+		g.nodeWeights[nID] = weight
+	}
+}
+
 // Configuration returns the configuration at a given block height.
 func (g *Governance) Configuration(round uint64) *types.Config {
 	if round == 0 || round == 1 {
@@ -132,6 +160,15 @@ func (g *Governance) NotifyRound(round, beginHeight uint64) {
 			}
 		}
 		for t, v := range g.pendingConfigChanges[shiftedRound+1] {
+			if isNodeChange(t) {
+				for _, change := range v.([]interface{}) {
+					if err := g.stateModule.RequestChange(
+						t, change); err != nil {
+						panic(err)
+					}
+				}
+				continue
+			}
 			if err := g.stateModule.RequestChange(t, v); err != nil {
 				panic(err)
 			}
@@ -297,10 +334,38 @@ func (g *Governance) IsDKGSuccess(round uint64) bool {
 
 // ReportForkVote reports a node for forking votes.
 func (g *Governance) ReportForkVote(vote1, vote2 *types.Vote) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.forkVoteReports == nil {
+		g.forkVoteReports = make(map[types.NodeID]int)
+	}
+	g.forkVoteReports[vote1.ProposerID]++
 }
 
 // ReportForkBlock reports a node for forking blocks.
 func (g *Governance) ReportForkBlock(block1, block2 *types.Block) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.forkBlockReports == nil {
+		g.forkBlockReports = make(map[types.NodeID]int)
+	}
+	g.forkBlockReports[block1.ProposerID]++
+}
+
+// ForkReports returns the count of reports for forking votes and blocks of
+// each node.
+func (g *Governance) ForkReports() (votes, blocks map[types.NodeID]int) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	votes = make(map[types.NodeID]int, len(g.forkVoteReports))
+	for nID, count := range g.forkVoteReports {
+		votes[nID] = count
+	}
+	blocks = make(map[types.NodeID]int, len(g.forkBlockReports))
+	for nID, count := range g.forkBlockReports {
+		blocks[nID] = count
+	}
+	return
 }
 
 // ResetDKG resets latest DKG data and propose new CRS.
@@ -391,6 +456,9 @@ func (g *Governance) Clone() *Governance {
 	for round, forRound := range g.pendingConfigChanges {
 		copiedForRound := make(map[StateChangeType]interface{})
 		for k, v := range forRound {
+			if isNodeChange(k) {
+				v = append([]interface{}{}, v.([]interface{})...)
+			}
 			copiedForRound[k] = v
 		}
 		copiedPendingChanges[round] = copiedForRound
@@ -413,6 +481,14 @@ func (g *Governance) Clone() *Governance {
 	for t := range g.prohibitedTypes {
 		copiedProhibitedTypes[t] = struct{}{}
 	}
+	// Clone node weights.
+	var copiedNodeWeights map[types.NodeID]uint64
+	if g.nodeWeights != nil {
+		copiedNodeWeights = make(map[types.NodeID]uint64, len(g.nodeWeights))
+		for nID, weight := range g.nodeWeights {
+			copiedNodeWeights[nID] = weight
+		}
+	}
 	// Clone pending changes.
 	return &Governance{
 		roundShift:           g.roundShift,
@@ -421,6 +497,7 @@ func (g *Governance) Clone() *Governance {
 		nodeSets:             copiedNodeSets,
 		pendingConfigChanges: copiedPendingChanges,
 		prohibitedTypes:      copiedProhibitedTypes,
+		nodeWeights:          copiedNodeWeights,
 	}
 }
 
@@ -476,13 +553,14 @@ func (g *Governance) Equal(other *Governance, checkState bool) bool {
 }
 
 // RegisterConfigChange tells this governance instance to request some
-// configuration change at some round.
+// configuration change at some round. Node set changes registered for one
+// round are accumulated, while other changes overwrite the previous one.
 // NOTE: you can't request config change for round 0, 1, they are genesis
 //       rounds.
 // NOTE: this function should be called before running.
 func (g *Governance) RegisterConfigChange(
 	round uint64, t StateChangeType, v interface{}) (err error) {
-	if t < StateAddCRS || t > StateChangeNotarySetSize {
+	if (t < StateAddCRS || t > StateChangeNotarySetSize) && !isNodeChange(t) {
 		return fmt.Errorf("state changes to register is not supported: %v", t)
 	}
 	if round < 2 {
@@ -500,10 +578,22 @@ func (g *Governance) RegisterConfigChange(
 		pendingChangesForRound = make(map[StateChangeType]interface{})
 		g.pendingConfigChanges[round] = pendingChangesForRound
 	}
+	if isNodeChange(t) {
+		changes, _ := pendingChangesForRound[t].([]interface{})
+		v = append(changes, v)
+	}
 	pendingChangesForRound[t] = v
 	return nil
 }
 
+func isNodeChange(t StateChangeType) bool {
+	switch t {
+	case StateAddNode, StateRemoveNode, StateRotateNode:
+		return true
+	}
+	return false
+}
+
 // SwitchToRemoteMode would switch this governance instance to remote mode,
 // which means: it will broadcast all changes from its underlying state
 // instance.
@@ -115,6 +115,46 @@ func (s *GovernanceTestSuite) TestRegisterChange() {
 	req.Equal(g.Configuration(7).NotarySetSize, uint32(40))
 }
 
+func (s *GovernanceTestSuite) TestRegisterNodeChange() {
+	var (
+		req                = s.Require()
+		roundLength uint64 = 100
+	)
+	_, keys, err := NewKeys(6)
+	req.NoError(err)
+	genesisNodes := keys[:4]
+	g, err := NewGovernance(NewState(
+		1, genesisNodes, 100*time.Millisecond, &common.NullLogger{}, true), 2)
+	req.NoError(err)
+	req.NoError(g.State().RequestChange(StateChangeRoundLength,
+		uint64(roundLength)))
+	// Node set changes for one round are accumulated.
+	req.NoError(g.RegisterConfigChange(5, StateRemoveNode, genesisNodes[0]))
+	req.NoError(g.RegisterConfigChange(5, StateRemoveNode, genesisNodes[1]))
+	req.NoError(g.RegisterConfigChange(5, StateAddNode, keys[4]))
+	req.NoError(g.RegisterConfigChange(6, StateRotateNode, NodeRotation{
+		OldKey: genesisNodes[2],
+		NewKey: keys[5],
+	}))
+	for r := uint64(2); r <= 4; r++ {
+		g.NotifyRound(r, roundLength*r)
+	}
+	checkNodeSet := func(round uint64, expected ...crypto.PublicKey) {
+		nodeSet := g.NodeSet(round)
+		req.Len(nodeSet, len(expected))
+		nIDs := make(map[types.NodeID]struct{})
+		for _, k := range nodeSet {
+			nIDs[types.NewNodeID(k)] = struct{}{}
+		}
+		for _, k := range expected {
+			req.Contains(nIDs, types.NewNodeID(k))
+		}
+	}
+	checkNodeSet(4, genesisNodes...)
+	checkNodeSet(5, genesisNodes[2], genesisNodes[3], keys[4])
+	checkNodeSet(6, keys[5], genesisNodes[3], keys[4])
+}
+
 func (s *GovernanceTestSuite) TestProhibit() {
 	round := uint64(1)
 	prvKeys, genesisNodes, err := NewKeys(4)
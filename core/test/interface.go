@@ -20,9 +20,11 @@ package test
 import (
 	"time"
 
+	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
 )
 
 // BlockRevealer defines the interface to reveal a group
@@ -34,6 +36,22 @@ type BlockRevealer interface {
 	Reset()
 }
 
+// ConsensusNetwork is the network interface required by consensus core, it
+// has the same methods as core.Network, which can't be referred in this
+// package to avoid import cycle.
+type ConsensusNetwork interface {
+	PullBlocks(hashes common.Hashes)
+	PullVotes(position types.Position)
+	BroadcastVote(vote *types.Vote)
+	BroadcastBlock(block *types.Block)
+	BroadcastAgreementResult(randRequest *types.AgreementResult)
+	SendDKGPrivateShare(pub crypto.PublicKey, prvShare *typesDKG.PrivateShare)
+	BroadcastDKGPrivateShare(prvShare *typesDKG.PrivateShare)
+	BroadcastDKGPartialSignature(psig *typesDKG.PartialSignature)
+	ReceiveChan() <-chan types.Msg
+	ReportBadPeerChan() chan<- interface{}
+}
+
 // TransportPeerType defines the type of peer, either 'peer' or 'server'.
 type TransportPeerType string
 
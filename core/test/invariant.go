@@ -0,0 +1,321 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// InvariantViolationType is the type of violated invariants.
+type InvariantViolationType string
+
+// InvariantViolationType enums.
+const (
+	// ViolationConflictingBlocks means different blocks are confirmed or
+	// delivered at the same position.
+	ViolationConflictingBlocks InvariantViolationType = "conflicting-blocks"
+	// ViolationRandomnessMismatch means the same block is delivered with
+	// different randomness.
+	ViolationRandomnessMismatch InvariantViolationType = "randomness-mismatch"
+	// ViolationNonMonotonicHeight means a node confirms or delivers a block
+	// not following the previous one.
+	ViolationNonMonotonicHeight InvariantViolationType = "non-monotonic-height"
+	// ViolationRoundBoundary means a node skips or goes back rounds, or begins
+	// a round at a height different from others.
+	ViolationRoundBoundary InvariantViolationType = "round-boundary"
+	// ViolationStall means a node doesn't deliver any block within the stall
+	// timeout.
+	ViolationStall InvariantViolationType = "stall"
+)
+
+// InvariantViolation describes a violated invariant.
+type InvariantViolation struct {
+	Type     InvariantViolationType
+	Position types.Position
+	// Nodes are involved nodes, the first one is the node which violates the
+	// invariant when there is only one.
+	Nodes  []types.NodeID
+	Detail string
+	When   time.Time
+}
+
+func (v *InvariantViolation) Error() string {
+	nodes := make([]string, 0, len(v.Nodes))
+	for _, nID := range v.Nodes {
+		nodes = append(nodes, nID.String())
+	}
+	return fmt.Sprintf("[%s] at %s on nodes [%s] when %s: %s",
+		v.Type, &v.Position, strings.Join(nodes, ", "),
+		v.When.Format(time.RFC3339Nano), v.Detail)
+}
+
+type invariantBlockRecord struct {
+	hash   common.Hash
+	rand   []byte
+	nodeID types.NodeID
+}
+
+type invariantRoundRecord struct {
+	beginHeight uint64
+	nodeID      types.NodeID
+}
+
+type invariantNodeRecord struct {
+	confirmed     bool
+	lastConfirmed types.Position
+	delivered     bool
+	lastDelivered types.Position
+	lastProgress  time.Time
+	stalled       bool
+}
+
+// InvariantChecker monitors blocks confirmed and delivered by each node, and
+// reports violations of safety and liveness invariants once they happen.
+type InvariantChecker struct {
+	clock        common.Clock
+	stallTimeout time.Duration
+	lock         sync.Mutex
+	nodes        map[types.NodeID]*invariantNodeRecord
+	confirmed    map[types.Position]*invariantBlockRecord
+	delivered    map[types.Position]*invariantBlockRecord
+	roundBegins  map[uint64]*invariantRoundRecord
+	violations   []*InvariantViolation
+	failed       chan struct{}
+}
+
+// NewInvariantChecker constructs an InvariantChecker instance. Nodes not
+// delivering any block within 'stallTimeout' are reported as stalled, the
+// stall check is disabled when it's zero.
+func NewInvariantChecker(
+	clock common.Clock, stallTimeout time.Duration) *InvariantChecker {
+	if clock == nil {
+		clock = &common.SystemClock{}
+	}
+	return &InvariantChecker{
+		clock:        clock,
+		stallTimeout: stallTimeout,
+		nodes:        make(map[types.NodeID]*invariantNodeRecord),
+		confirmed:    make(map[types.Position]*invariantBlockRecord),
+		delivered:    make(map[types.Position]*invariantBlockRecord),
+		roundBegins:  make(map[uint64]*invariantRoundRecord),
+		failed:       make(chan struct{}),
+	}
+}
+
+// Watch starts monitoring blocks confirmed and delivered by an App instance.
+func (c *InvariantChecker) Watch(nID types.NodeID, app *App) {
+	func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.nodes[nID] = &invariantNodeRecord{lastProgress: c.clock.Now()}
+	}()
+	app.AddObserver(&invariantObserver{checker: c, nodeID: nID})
+}
+
+// Run checks stalled nodes periodically until the context is done.
+func (c *InvariantChecker) Run(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(interval):
+		}
+		c.CheckStall()
+	}
+}
+
+// CheckStall reports nodes not delivering any block within the stall
+// timeout, each node is reported once until it delivers blocks again.
+func (c *InvariantChecker) CheckStall() {
+	if c.stallTimeout == 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.clock.Now()
+	for nID, rec := range c.nodes {
+		if rec.stalled || now.Sub(rec.lastProgress) < c.stallTimeout {
+			continue
+		}
+		rec.stalled = true
+		c.addViolationNoLock(ViolationStall, rec.lastDelivered, fmt.Sprintf(
+			"no block delivered since %s",
+			rec.lastProgress.Format(time.RFC3339Nano)), nID)
+	}
+}
+
+// Failed returns a channel which would be closed once any violation found.
+func (c *InvariantChecker) Failed() <-chan struct{} {
+	return c.failed
+}
+
+// Violations returns all violations found in order.
+func (c *InvariantChecker) Violations() []*InvariantViolation {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*InvariantViolation{}, c.violations...)
+}
+
+// Err returns the first violation, or nil when there is none.
+func (c *InvariantChecker) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.violations) == 0 {
+		return nil
+	}
+	return c.violations[0]
+}
+
+// Report generates a human-readable report of violations, starting from the
+// first divergence.
+func (c *InvariantChecker) Report() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.violations) == 0 {
+		return fmt.Sprintf("no invariant violation among %d nodes",
+			len(c.nodes))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invariant violation(s) among %d nodes\n",
+		len(c.violations), len(c.nodes))
+	fmt.Fprintf(&b, "first divergence: %s\n", c.violations[0].Error())
+	for idx, v := range c.violations[1:] {
+		fmt.Fprintf(&b, "  #%d %s\n", idx+2, v.Error())
+	}
+	return b.String()
+}
+
+func (c *InvariantChecker) addViolationNoLock(t InvariantViolationType,
+	pos types.Position, detail string, nodes ...types.NodeID) {
+	c.violations = append(c.violations, &InvariantViolation{
+		Type:     t,
+		Position: pos,
+		Nodes:    nodes,
+		Detail:   detail,
+		When:     c.clock.Now(),
+	})
+	if len(c.violations) == 1 {
+		close(c.failed)
+	}
+}
+
+func (c *InvariantChecker) getNodeNoLock(
+	nID types.NodeID) *invariantNodeRecord {
+	rec, exists := c.nodes[nID]
+	if !exists {
+		rec = &invariantNodeRecord{lastProgress: c.clock.Now()}
+		c.nodes[nID] = rec
+	}
+	return rec
+}
+
+func (c *InvariantChecker) blockConfirmed(nID types.NodeID, b *types.Block) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	pos := b.Position
+	if prev, exists := c.confirmed[pos]; !exists {
+		c.confirmed[pos] = &invariantBlockRecord{hash: b.Hash, nodeID: nID}
+	} else if prev.hash != b.Hash {
+		c.addViolationNoLock(ViolationConflictingBlocks, pos, fmt.Sprintf(
+			"confirmed %s, while %s confirmed %s",
+			b.Hash.String()[:6], prev.nodeID, prev.hash.String()[:6]),
+			nID, prev.nodeID)
+	}
+	rec := c.getNodeNoLock(nID)
+	if rec.confirmed && rec.lastConfirmed.Height+1 != pos.Height {
+		c.addViolationNoLock(ViolationNonMonotonicHeight, pos, fmt.Sprintf(
+			"confirmed after %s", &rec.lastConfirmed), nID)
+	}
+	rec.confirmed = true
+	rec.lastConfirmed = pos
+}
+
+func (c *InvariantChecker) blockDelivered(nID types.NodeID,
+	hash common.Hash, pos types.Position, rand []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if prev, exists := c.delivered[pos]; !exists {
+		c.delivered[pos] = &invariantBlockRecord{
+			hash:   hash,
+			rand:   common.CopyBytes(rand),
+			nodeID: nID,
+		}
+	} else if prev.hash != hash {
+		c.addViolationNoLock(ViolationConflictingBlocks, pos, fmt.Sprintf(
+			"delivered %s, while %s delivered %s",
+			hash.String()[:6], prev.nodeID, prev.hash.String()[:6]),
+			nID, prev.nodeID)
+	} else if !bytes.Equal(prev.rand, rand) {
+		c.addViolationNoLock(ViolationRandomnessMismatch, pos, fmt.Sprintf(
+			"delivered %s with randomness %x, while %s delivered with %x",
+			hash.String()[:6], rand, prev.nodeID, prev.rand),
+			nID, prev.nodeID)
+	}
+	rec := c.getNodeNoLock(nID)
+	if rec.delivered {
+		last := rec.lastDelivered
+		if last.Height+1 != pos.Height {
+			c.addViolationNoLock(ViolationNonMonotonicHeight, pos,
+				fmt.Sprintf("delivered after %s", &last), nID)
+		}
+		switch {
+		case pos.Round < last.Round || pos.Round > last.Round+1:
+			c.addViolationNoLock(ViolationRoundBoundary, pos,
+				fmt.Sprintf("delivered after %s", &last), nID)
+		case pos.Round == last.Round+1:
+			if begin, exists := c.roundBegins[pos.Round]; !exists {
+				c.roundBegins[pos.Round] = &invariantRoundRecord{
+					beginHeight: pos.Height,
+					nodeID:      nID,
+				}
+			} else if begin.beginHeight != pos.Height {
+				c.addViolationNoLock(ViolationRoundBoundary, pos, fmt.Sprintf(
+					"round %d begins, while it begins at height %d on %s",
+					pos.Round, begin.beginHeight, begin.nodeID),
+					nID, begin.nodeID)
+			}
+		}
+	}
+	rec.delivered = true
+	rec.lastDelivered = pos
+	rec.lastProgress = c.clock.Now()
+	rec.stalled = false
+}
+
+// invariantObserver forwards blocks from an App instance to the checker.
+type invariantObserver struct {
+	checker *InvariantChecker
+	nodeID  types.NodeID
+}
+
+func (o *invariantObserver) BlockConfirmed(b *types.Block) {
+	o.checker.blockConfirmed(o.nodeID, b)
+}
+
+func (o *invariantObserver) BlockDelivered(
+	blockHash common.Hash, pos types.Position, rand []byte) {
+	o.checker.blockDelivered(o.nodeID, blockHash, pos, rand)
+}
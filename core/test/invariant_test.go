@@ -0,0 +1,169 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+type InvariantCheckerTestSuite struct {
+	suite.Suite
+}
+
+func (s *InvariantCheckerTestSuite) newBlock(
+	round, height uint64) *types.Block {
+	return &types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Round: round, Height: height},
+	}
+}
+
+func (s *InvariantCheckerTestSuite) setup(count int) (
+	c *InvariantChecker, clock *SimulatedClock, nIDs []types.NodeID,
+	apps []*App) {
+	clock = NewSimulatedClock(time.Now().UTC())
+	c = NewInvariantChecker(clock, time.Second)
+	for i := 0; i < count; i++ {
+		nID := types.NodeID{Hash: common.NewRandomHash()}
+		app := NewApp(0, nil, nil)
+		c.Watch(nID, app)
+		nIDs = append(nIDs, nID)
+		apps = append(apps, app)
+	}
+	return
+}
+
+func (s *InvariantCheckerTestSuite) checkViolations(
+	c *InvariantChecker, expected ...InvariantViolationType) {
+	violations := c.Violations()
+	s.Require().Len(violations, len(expected))
+	for idx, t := range expected {
+		s.Require().Equal(t, violations[idx].Type)
+	}
+}
+
+func (s *InvariantCheckerTestSuite) TestNoViolation() {
+	c, _, _, apps := s.setup(2)
+	for _, app := range apps {
+		for h := uint64(1); h <= 3; h++ {
+			b := &types.Block{
+				Hash:     common.Hash{byte(h)},
+				Position: types.Position{Height: h},
+			}
+			app.BlockConfirmed(*b)
+			app.BlockDelivered(b.Hash, b.Position, []byte{byte(h)})
+		}
+	}
+	s.Require().NoError(c.Err())
+	s.checkViolations(c)
+	s.Require().Equal("no invariant violation among 2 nodes", c.Report())
+	select {
+	case <-c.Failed():
+		s.FailNow("should not fail")
+	default:
+	}
+}
+
+func (s *InvariantCheckerTestSuite) TestConflictingBlocks() {
+	c, _, nIDs, apps := s.setup(2)
+	b1, b2 := s.newBlock(0, 1), s.newBlock(0, 1)
+	apps[0].BlockConfirmed(*b1)
+	apps[1].BlockConfirmed(*b2)
+	s.checkViolations(c, ViolationConflictingBlocks)
+	<-c.Failed()
+	v := c.Err().(*InvariantViolation)
+	s.Require().Equal(b2.Position, v.Position)
+	s.Require().Equal([]types.NodeID{nIDs[1], nIDs[0]}, v.Nodes)
+	apps[0].BlockDelivered(b1.Hash, b1.Position, []byte{1})
+	apps[1].BlockDelivered(b2.Hash, b2.Position, []byte{1})
+	s.checkViolations(c,
+		ViolationConflictingBlocks, ViolationConflictingBlocks)
+	report := c.Report()
+	s.Require().True(strings.HasPrefix(report,
+		"2 invariant violation(s) among 2 nodes\n"+
+			"first divergence: [conflicting-blocks]"))
+	s.Require().Contains(report, "#2 [conflicting-blocks]")
+}
+
+func (s *InvariantCheckerTestSuite) TestRandomnessMismatch() {
+	c, _, _, apps := s.setup(2)
+	b := s.newBlock(0, 1)
+	for idx, app := range apps {
+		app.BlockConfirmed(*b)
+		app.BlockDelivered(b.Hash, b.Position, []byte{byte(idx)})
+	}
+	s.checkViolations(c, ViolationRandomnessMismatch)
+}
+
+func (s *InvariantCheckerTestSuite) TestHeightAndRound() {
+	c, _, nIDs, _ := s.setup(2)
+	deliver := func(nID types.NodeID, b *types.Block) {
+		c.blockDelivered(nID, b.Hash, b.Position, []byte{1})
+	}
+	blocks := []*types.Block{
+		s.newBlock(0, 1),
+		s.newBlock(0, 2),
+		s.newBlock(1, 3),
+		s.newBlock(1, 4),
+		s.newBlock(3, 5),
+	}
+	for _, b := range blocks[:3] {
+		deliver(nIDs[0], b)
+	}
+	s.checkViolations(c)
+	// Skip a height.
+	deliver(nIDs[0], blocks[4])
+	s.checkViolations(c,
+		ViolationNonMonotonicHeight, ViolationRoundBoundary)
+	// Round 1 begins at a different height.
+	deliver(nIDs[1], blocks[0])
+	deliver(nIDs[1], s.newBlock(1, 2))
+	s.checkViolations(c,
+		ViolationNonMonotonicHeight,
+		ViolationRoundBoundary,
+		ViolationRoundBoundary)
+}
+
+func (s *InvariantCheckerTestSuite) TestStall() {
+	c, clock, nIDs, apps := s.setup(2)
+	clock.Advance(500 * time.Millisecond)
+	b := s.newBlock(0, 1)
+	apps[0].BlockConfirmed(*b)
+	apps[0].BlockDelivered(b.Hash, b.Position, []byte{1})
+	clock.Advance(600 * time.Millisecond)
+	c.CheckStall()
+	s.checkViolations(c, ViolationStall)
+	s.Require().Equal(nIDs[1], c.Violations()[0].Nodes[0])
+	// Stalled nodes are reported once.
+	c.CheckStall()
+	s.checkViolations(c, ViolationStall)
+	clock.Advance(time.Second)
+	c.CheckStall()
+	s.checkViolations(c, ViolationStall, ViolationStall)
+	s.Require().Equal(nIDs[0], c.Violations()[1].Nodes[0])
+}
+
+func TestInvariantChecker(t *testing.T) {
+	suite.Run(t, new(InvariantCheckerTestSuite))
+}
@@ -32,11 +32,20 @@ type LatencyModel interface {
 type NormalLatencyModel struct {
 	Sigma float64
 	Mean  float64
+	// Rand is the source of randomness, the global source from math/rand
+	// would be used when it's nil. Note that rand.Rand is not safe for
+	// concurrent use.
+	Rand *rand.Rand
 }
 
 // Delay implements LatencyModel interface.
 func (m *NormalLatencyModel) Delay() time.Duration {
-	delay := rand.NormFloat64()*m.Sigma + m.Mean
+	var delay float64
+	if m.Rand != nil {
+		delay = m.Rand.NormFloat64()*m.Sigma + m.Mean
+	} else {
+		delay = rand.NormFloat64()*m.Sigma + m.Mean
+	}
 	if delay < 0 {
 		delay = m.Sigma / 2
 	}
@@ -21,6 +21,8 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/codec"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
 )
@@ -59,6 +61,12 @@ func (m *DefaultMarshaller) Unmarshal(
 			break
 		}
 		msg = result
+	case "block-range":
+		r := &types.BlockRange{}
+		if err = json.Unmarshal(payload, r); err != nil {
+			break
+		}
+		msg = r
 	case "dkg-private-share":
 		privateShare := &typesDKG.PrivateShare{}
 		if err = json.Unmarshal(payload, privateShare); err != nil {
@@ -101,6 +109,12 @@ func (m *DefaultMarshaller) Unmarshal(
 			break
 		}
 		msg = req
+	case "gossip":
+		g := &gossipMessage{}
+		if err = json.Unmarshal(payload, g); err != nil {
+			break
+		}
+		msg = g
 	default:
 		if m.fallback == nil {
 			err = fmt.Errorf("unknown msg type: %v", msgType)
@@ -124,6 +138,9 @@ func (m *DefaultMarshaller) Marshal(
 	case *types.AgreementResult:
 		msgType = "agreement-result"
 		payload, err = json.Marshal(msg)
+	case *types.BlockRange:
+		msgType = "block-range"
+		payload, err = json.Marshal(msg)
 	case *typesDKG.PrivateShare:
 		msgType = "dkg-private-share"
 		payload, err = json.Marshal(msg)
@@ -145,6 +162,9 @@ func (m *DefaultMarshaller) Marshal(
 	case *PullRequest:
 		msgType = "pull-request"
 		payload, err = json.Marshal(msg)
+	case *gossipMessage:
+		msgType = "gossip"
+		payload, err = json.Marshal(msg)
 	default:
 		if m.fallback == nil {
 			err = fmt.Errorf("unknwon message type: %v", msg)
@@ -154,3 +174,92 @@ func (m *DefaultMarshaller) Marshal(
 	}
 	return
 }
+
+// BinaryMarshaller marshals messages sent through core.Network by
+// codec.Codec, other messages are handled by the fallback marshaller.
+type BinaryMarshaller struct {
+	codec    *codec.Codec
+	fallback Marshaller
+}
+
+// NewBinaryMarshaller constructs an BinaryMarshaller instance.
+func NewBinaryMarshaller(fallback Marshaller) *BinaryMarshaller {
+	return &BinaryMarshaller{
+		codec:    codec.NewCodec(),
+		fallback: fallback,
+	}
+}
+
+// Unmarshal implements Marshaller interface.
+func (m *BinaryMarshaller) Unmarshal(
+	msgType string, payload []byte) (msg interface{}, err error) {
+	if msgType != "binary" {
+		if m.fallback == nil {
+			err = fmt.Errorf("unknown msg type: %v", msgType)
+			return
+		}
+		return m.fallback.Unmarshal(msgType, payload)
+	}
+	if msg, err = m.codec.Decode(payload); err != nil {
+		return
+	}
+	switch v := msg.(type) {
+	case *codec.PullBlocksRequest:
+		msg = &PullRequest{
+			Requester: v.Requester,
+			Type:      "block",
+			Identity:  v.Hashes,
+		}
+	case *codec.PullVotesRequest:
+		msg = &PullRequest{
+			Requester: v.Requester,
+			Type:      "vote",
+			Identity:  v.Position,
+		}
+	case *codec.PullBlockRangeRequest:
+		msg = &PullRequest{
+			Requester: v.Requester,
+			Type:      "block-range",
+			Identity:  HeightRange{From: v.From, To: v.To},
+		}
+	}
+	return
+}
+
+// Marshal implements Marshaller interface.
+func (m *BinaryMarshaller) Marshal(
+	msg interface{}) (msgType string, payload []byte, err error) {
+	if req, ok := msg.(*PullRequest); ok {
+		switch req.Type {
+		case "block":
+			msg = &codec.PullBlocksRequest{
+				Requester: req.Requester,
+				Hashes:    req.Identity.(common.Hashes),
+			}
+		case "vote":
+			msg = &codec.PullVotesRequest{
+				Requester: req.Requester,
+				Position:  req.Identity.(types.Position),
+			}
+		case "block-range":
+			heights := req.Identity.(HeightRange)
+			msg = &codec.PullBlockRangeRequest{
+				Requester: req.Requester,
+				From:      heights.From,
+				To:        heights.To,
+			}
+		}
+	}
+	payload, err = m.codec.Encode(msg)
+	switch err {
+	case nil:
+		msgType = "binary"
+	case codec.ErrUnregisteredMessage:
+		if m.fallback == nil {
+			err = fmt.Errorf("unknwon message type: %v", msg)
+			break
+		}
+		msgType, payload, err = m.fallback.Marshal(msg)
+	}
+	return
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+)
+
+// MessageKey generates a key identifying the content of a message routed
+// between nodes, messages with different content have different keys. Only
+// fields independent of random materials like signatures and DKG shares are
+// used, thus the same message has the same key between runs.
+func MessageKey(msg interface{}) string {
+	switch v := msg.(type) {
+	case *types.Vote:
+		return fmt.Sprintf("vote-%s-%d-%d-%s",
+			v.Position, v.Period, v.Type, v.BlockHash.String())
+	case *types.Block:
+		return fmt.Sprintf("block-%s-%s-%t",
+			v.Position, v.Hash.String(), v.IsFinalized())
+	case *types.AgreementResult:
+		return fmt.Sprintf("result-%s-%s", v.Position, v.BlockHash.String())
+	case *types.BlockRange:
+		return fmt.Sprintf("range-%d-%d", v.From, v.To)
+	case *typesDKG.PrivateShare:
+		return fmt.Sprintf("dkg-share-%d-%d-%s",
+			v.Round, v.Reset, v.ReceiverID.String())
+	case *typesDKG.PartialSignature:
+		// The signed hash differs between resets of DKG.
+		return fmt.Sprintf("dkg-psig-%d-%s", v.Round, v.Hash.String())
+	case *PullRequest:
+		return fmt.Sprintf("pull-%s-%v", v.Type, v.Identity)
+	case *gossipMessage:
+		return fmt.Sprintf("gossip-%d-%s", v.TTL, MessageKey(v.Payload))
+	case packedStateChanges:
+		return stateChangesKey(v)
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%T-%s", msg, crypto.Keccak256Hash(b).String())
+}
+
+// stateChangesKey generates a key from types and payloads of packed state
+// change requests. Payloads of DKG requests are identified by their proposer,
+// round and reset, since they're generated from random materials.
+func stateChangesKey(packed packedStateChanges) string {
+	raws := []*rawStateChangeRequest{}
+	if err := rlp.DecodeBytes(packed, &raws); err != nil {
+		panic(err)
+	}
+	keys := make([]string, 0, len(raws))
+	for _, raw := range raws {
+		payload, err := (&State{}).unpackPayload(raw)
+		if err != nil {
+			panic(err)
+		}
+		var id string
+		switch v := payload.(type) {
+		case *typesDKG.Complaint:
+			id = fmt.Sprintf("%s-%d-%d-%s", v.ProposerID.String(), v.Round,
+				v.Reset, v.PrivateShare.ProposerID.String())
+		case *typesDKG.MasterPublicKey:
+			id = fmt.Sprintf("%s-%d-%d", v.ProposerID.String(), v.Round, v.Reset)
+		case *typesDKG.MPKReady:
+			id = fmt.Sprintf("%s-%d-%d", v.ProposerID.String(), v.Round, v.Reset)
+		case *typesDKG.Finalize:
+			id = fmt.Sprintf("%s-%d-%d", v.ProposerID.String(), v.Round, v.Reset)
+		case *typesDKG.Success:
+			id = fmt.Sprintf("%s-%d-%d", v.ProposerID.String(), v.Round, v.Reset)
+		default:
+			id = crypto.Keccak256Hash(raw.Payload).String()
+		}
+		keys = append(keys, fmt.Sprintf("%d-%s", raw.Type, id))
+	}
+	// Requests are packed from a map, their order is random.
+	sort.Strings(keys)
+	return fmt.Sprintf("state-%s", strings.Join(keys, ","))
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+)
+
+type MessageKeyTestSuite struct {
+	suite.Suite
+}
+
+func (s *MessageKeyTestSuite) packStateChanges(
+	round uint64) packedStateChanges {
+	st := NewState(1, nil, time.Second, &common.NullLogger{}, false)
+	s.Require().NoError(st.RequestChange(StateChangeLambdaBA, time.Second))
+	s.Require().NoError(st.RequestChange(StateAddDKGMPKReady,
+		&typesDKG.MPKReady{Round: round}))
+	b, err := st.PackOwnRequests()
+	s.Require().NoError(err)
+	return packedStateChanges(b)
+}
+
+func (s *MessageKeyTestSuite) TestUnique() {
+	var (
+		req   = s.Require()
+		hash1 = common.NewRandomHash()
+		hash2 = common.NewRandomHash()
+		pos   = types.Position{Round: 1, Height: 2}
+	)
+	msgs := []interface{}{
+		&types.Vote{VoteHeader: types.VoteHeader{Position: pos,
+			BlockHash: hash1}},
+		&types.Vote{VoteHeader: types.VoteHeader{Position: pos,
+			BlockHash: hash2}},
+		&types.Block{Position: pos, Hash: hash1},
+		&types.Block{Position: pos, Hash: hash2},
+		&types.AgreementResult{Position: pos, BlockHash: hash1},
+		&types.AgreementResult{Position: pos, BlockHash: hash2},
+		&typesDKG.PartialSignature{Round: 1, Hash: hash1},
+		&typesDKG.PartialSignature{Round: 1, Hash: hash2},
+		&typesDKG.PrivateShare{Round: 1, Reset: 0},
+		&typesDKG.PrivateShare{Round: 1, Reset: 1},
+		&types.BlockRange{From: 1, To: 2},
+		&types.BlockRange{From: 1, To: 3},
+		&gossipMessage{TTL: 1, Payload: &types.Block{Hash: hash1}},
+		&gossipMessage{TTL: 1, Payload: &types.Block{Hash: hash2}},
+		s.packStateChanges(1),
+		s.packStateChanges(2),
+	}
+	keys := make(map[string]struct{})
+	for _, msg := range msgs {
+		keys[MessageKey(msg)] = struct{}{}
+	}
+	req.Len(keys, len(msgs))
+}
+
+func (s *MessageKeyTestSuite) TestIndependentOfRandomMaterials() {
+	var (
+		req  = s.Require()
+		hash = common.NewRandomHash()
+	)
+	// Signatures are not part of keys.
+	req.Equal(
+		MessageKey(&typesDKG.PartialSignature{Round: 1, Hash: hash}),
+		MessageKey(&typesDKG.PartialSignature{Round: 1, Hash: hash,
+			Signature: crypto.Signature{Signature: hash[:]}}))
+	// Hashes and timestamps of state change requests are not part of keys.
+	packed := s.packStateChanges(1)
+	time.Sleep(time.Millisecond)
+	req.Equal(MessageKey(packed), MessageKey(s.packStateChanges(1)))
+}
+
+func TestMessageKey(t *testing.T) {
+	suite.Run(t, new(MessageKeyTestSuite))
+}
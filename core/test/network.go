@@ -29,6 +29,7 @@ import (
 
 	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
 	"github.com/tangerine-network/tangerine-consensus/core/utils"
@@ -40,6 +41,9 @@ const (
 	maxBlockCache       = 1000
 	maxVoteCache        = 128
 
+	// Count of maximum count of blocks in one page of block range.
+	maxBlockRangePage = 32
+
 	// Gossiping parameter.
 	maxAgreementResultBroadcast  = 3
 	gossipAgreementResultPercent = 33
@@ -53,6 +57,9 @@ const (
 	NetworkTypeTCP      NetworkType = "tcp"
 	NetworkTypeTCPLocal NetworkType = "tcp-local"
 	NetworkTypeFake     NetworkType = "fake"
+	// NetworkTypeRouted delivers messages via a TransportRouter, which
+	// decides when and in which order messages are delivered.
+	NetworkTypeRouted NetworkType = "routed"
 )
 
 // NetworkConfig is the configuration for Network module.
@@ -63,6 +70,39 @@ type NetworkConfig struct {
 	DirectLatency LatencyModel
 	GossipLatency LatencyModel
 	Marshaller    Marshaller
+	// GossipFanout is the count of peers to push to in each hop of gossiping.
+	// Once positive, votes, blocks and agreement results would be gossiped
+	// instead of being sent to all receivers directly.
+	GossipFanout int
+	// GossipTTL is the maximum count of hops for a gossiped message.
+	GossipTTL int
+	// Clock is used to simulate latencies, the wall-clock would be used when
+	// it's nil.
+	Clock common.Clock
+	// Faults is injected to messages sent by this network module, it can be
+	// replaced later via Network.SetFaultPlan.
+	Faults *FaultPlan
+	// PeerScore is the configuration to score peers reported via
+	// ReportBadPeerChan, utils.DefaultPeerScoreConfig would be used when
+	// it's nil.
+	PeerScore *utils.PeerScoreConfig
+	// PullLimit is the configuration to limit pull requests served by this
+	// network module, DefaultPullLimitConfig would be used when it's nil.
+	PullLimit *PullLimitConfig
+	// Logger is used to log requests dropped by this network module, nothing
+	// would be logged when it's nil.
+	Logger common.Logger
+	// SecureConnKey enables secure connections of TCP networks when it's not
+	// nil, see TCPTransport.EnableSecureConn. It should be the private key of
+	// this node, and the peer server should enable secure connections, too.
+	SecureConnKey crypto.PrivateKey
+}
+
+// HeightRange is the identity of pull requests for blocks by height range,
+// [From, To).
+type HeightRange struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
 }
 
 // PullRequest is a generic request to pull everything (ex. vote, block...).
@@ -81,6 +121,8 @@ func (req *PullRequest) MarshalJSON() (b []byte, err error) {
 		idAsBytes, err = json.Marshal(req.Identity.(common.Hashes))
 	case "vote":
 		idAsBytes, err = json.Marshal(req.Identity.(types.Position))
+	case "block-range":
+		idAsBytes, err = json.Marshal(req.Identity.(HeightRange))
 	default:
 		err = fmt.Errorf("unknown ID type for pull request: %v", req.Type)
 	}
@@ -119,6 +161,12 @@ func (req *PullRequest) UnmarshalJSON(data []byte) (err error) {
 			break
 		}
 		ID = pos
+	case "block-range":
+		heights := HeightRange{}
+		if err = json.Unmarshal(rawReq.Identity, &heights); err != nil {
+			break
+		}
+		ID = heights
 	default:
 		err = fmt.Errorf("unknown pull request type: %v", rawReq.Type)
 	}
@@ -147,7 +195,7 @@ func (cc *censorClient) Send(ID types.NodeID, msg interface{}) error {
 	if func() bool {
 		cc.lock.RLock()
 		defer cc.lock.RUnlock()
-		return cc.censor.Censor(msg)
+		return cc.censor.Censor(unwrapGossip(msg))
 	}() {
 		return nil
 	}
@@ -159,7 +207,7 @@ func (cc *censorClient) Broadcast(
 	if func() bool {
 		cc.lock.RLock()
 		defer cc.lock.RUnlock()
-		return cc.censor.Censor(msg)
+		return cc.censor.Censor(unwrapGossip(msg))
 	}() {
 		return nil
 	}
@@ -176,7 +224,9 @@ type Network struct {
 	config               NetworkConfig
 	ctx                  context.Context
 	ctxCancel            context.CancelFunc
+	clock                common.Clock
 	trans                *censorClient
+	faults               *faultClient
 	dMoment              time.Time
 	fromTransport        <-chan *TransportEnvelope
 	toConsensus          chan types.Msg
@@ -195,10 +245,23 @@ type Network struct {
 	unreceivedBlocksLock sync.RWMutex
 	unreceivedBlocks     map[common.Hash]chan<- common.Hash
 	cache                *utils.NodeSetCache
+	db                   db.Reader
 	notarySetCachesLock  sync.Mutex
 	notarySetCaches      map[uint64]map[types.NodeID]struct{}
 	censor               NetworkCensor
 	censorLock           sync.RWMutex
+	gossipSeen           *gossipSeen
+	peerScorer           *utils.PeerScorer
+	pullLimit            PullLimitConfig
+	pullLimiter          *pullLimiter
+	pullRequests         chan *pullTask
+	logger               common.Logger
+}
+
+// pullTask is an admitted pull request waiting to be served.
+type pullTask struct {
+	key pullKey
+	req *PullRequest
 }
 
 // NewNetwork setup network stuffs for nodes, which provides an
@@ -219,9 +282,18 @@ func NewNetwork(pubKey crypto.PublicKey, config NetworkConfig) (
 		notarySetCaches:  make(map[uint64]map[types.NodeID]struct{}),
 		voteCache: make(
 			map[types.Position]map[types.VoteHeader]*types.Vote),
-		censor: &dummyCensor{},
+		censor:     &dummyCensor{},
+		gossipSeen: newGossipSeen(),
 	}
 	n.ctx, n.ctxCancel = context.WithCancel(context.Background())
+	n.clock = config.Clock
+	if n.clock == nil {
+		n.clock = &common.SystemClock{}
+	}
+	n.logger = config.Logger
+	if n.logger == nil {
+		n.logger = &common.NullLogger{}
+	}
 	// Construct transport layer.
 	var trans TransportClient
 	switch config.Type {
@@ -231,11 +303,46 @@ func NewNetwork(pubKey crypto.PublicKey, config NetworkConfig) (
 		trans = NewTCPTransportClient(pubKey, config.Marshaller, false)
 	case NetworkTypeFake:
 		trans = NewFakeTransportClient(pubKey)
+	case NetworkTypeRouted:
+		trans = NewRoutedTransportClient(pubKey)
 	default:
 		panic(fmt.Errorf("unknown network type: %v", config.Type))
 	}
-	n.trans = &censorClient{
+	if config.SecureConnKey != nil {
+		t, ok := trans.(*TCPTransportClient)
+		if !ok {
+			panic(fmt.Errorf(
+				"secure connections not supported: %v", config.Type))
+		}
+		if err := t.EnableSecureConn(config.SecureConnKey); err != nil {
+			panic(err)
+		}
+	}
+	if t, ok := trans.(interface{ setClock(common.Clock) }); ok {
+		t.setClock(n.clock)
+	}
+	scoreConfig := utils.DefaultPeerScoreConfig()
+	if config.PeerScore != nil {
+		scoreConfig = *config.PeerScore
+	}
+	if scoreConfig.Clock == nil {
+		scoreConfig.Clock = n.clock
+	}
+	n.peerScorer = utils.NewPeerScorer(scoreConfig)
+	if config.PullLimit != nil {
+		n.pullLimit = *config.PullLimit
+	}
+	n.pullLimit.setDefaults()
+	n.pullLimiter = newPullLimiter(n.pullLimit, n.clock)
+	n.pullRequests = make(chan *pullTask, n.pullLimit.QueueSize)
+	n.faults = &faultClient{
 		TransportClient: trans,
+		ID:              n.ID,
+		clock:           n.clock,
+	}
+	n.faults.setPlan(config.Faults)
+	n.trans = &censorClient{
+		TransportClient: n.faults,
 		censor:          &dummyCensor{},
 	}
 	return
@@ -261,18 +368,56 @@ func (n *Network) SetCensor(censorIn, censorOut NetworkCensor) {
 	}()
 }
 
+// SetFaultPlan replaces faults injected to messages sent by this network
+// module, all faults are healed immediately when 'plan' is nil.
+func (n *Network) SetFaultPlan(plan *FaultPlan) {
+	n.faults.setPlan(plan)
+}
+
 // PullBlocks implements core.Network interface.
 func (n *Network) PullBlocks(hashes common.Hashes) {
-	go n.pullBlocksAsync(hashes)
+	// Split hashes to keep each pull request acceptable by peers.
+	for len(hashes) > n.pullLimit.MaxHashes {
+		go n.pullBlocksAsync(newWorkCounter(n.clock, 1),
+			hashes[:n.pullLimit.MaxHashes])
+		hashes = hashes[n.pullLimit.MaxHashes:]
+	}
+	go n.pullBlocksAsync(newWorkCounter(n.clock, 1), hashes)
+}
+
+// PullBlockRange implements core.BlockRangePuller interface.
+func (n *Network) PullBlockRange(from, to uint64) {
+	if from >= to {
+		return
+	}
+	req := &PullRequest{
+		Requester: n.ID,
+		Type:      "block-range",
+		Identity:  HeightRange{From: from, To: to},
+	}
+	// Pull from one random peer, following pages are pulled by the caller
+	// once a page is received.
+	for nID := range n.peers {
+		if nID == n.ID {
+			continue
+		}
+		n.send(nID, req)
+		break
+	}
 }
 
 // PullVotes implements core.Network interface.
 func (n *Network) PullVotes(pos types.Position) {
-	go n.pullVotesAsync(pos)
+	go n.pullVotesAsync(newWorkCounter(n.clock, 1), pos)
 }
 
 // BroadcastVote implements core.Network interface.
 func (n *Network) BroadcastVote(vote *types.Vote) {
+	if n.gossipEnabled() {
+		n.gossip(vote)
+		n.addVoteToCache(vote)
+		return
+	}
 	if err := n.trans.Broadcast(n.getNotarySet(vote.Position.Round),
 		n.config.DirectLatency, vote); err != nil {
 		panic(err)
@@ -284,17 +429,21 @@ func (n *Network) BroadcastVote(vote *types.Vote) {
 func (n *Network) BroadcastBlock(block *types.Block) {
 	// Avoid data race in fake transport.
 	block = n.cloneForFake(block).(*types.Block)
-	notarySet := n.getNotarySet(block.Position.Round)
-	if !block.IsFinalized() {
-		if err := n.trans.Broadcast(
-			notarySet, n.config.DirectLatency, block); err != nil {
+	if n.gossipEnabled() {
+		n.gossip(block)
+	} else {
+		notarySet := n.getNotarySet(block.Position.Round)
+		if !block.IsFinalized() {
+			if err := n.trans.Broadcast(
+				notarySet, n.config.DirectLatency, block); err != nil {
+				panic(err)
+			}
+		}
+		if err := n.trans.Broadcast(getComplementSet(n.peers, notarySet),
+			n.config.GossipLatency, block); err != nil {
 			panic(err)
 		}
 	}
-	if err := n.trans.Broadcast(getComplementSet(n.peers, notarySet),
-		n.config.GossipLatency, block); err != nil {
-		panic(err)
-	}
 	n.addBlockToCache(block)
 	if block.IsFinalized() {
 		n.addBlockRandomnessToCache(block.Hash, block.Randomness)
@@ -308,6 +457,10 @@ func (n *Network) BroadcastAgreementResult(
 		return
 	}
 	n.addBlockRandomnessToCache(result.BlockHash, result.Randomness)
+	if n.gossipEnabled() {
+		n.gossip(n.cloneForFake(result))
+		return
+	}
 	notarySet := n.getNotarySet(result.Position.Round)
 	count := maxAgreementResultBroadcast
 	for nID := range notarySet {
@@ -362,7 +515,7 @@ func (n *Network) Setup(serverEndpoint interface{}) (err error) {
 		addr := net.JoinHostPort(
 			n.config.PeerServer, strconv.Itoa(n.config.PeerPort))
 		n.fromTransport, err = n.trans.Join(addr)
-	case NetworkTypeFake:
+	case NetworkTypeFake, NetworkTypeRouted:
 		n.fromTransport, err = n.trans.Join(serverEndpoint)
 	default:
 		err = fmt.Errorf("unknown network type: %v", n.config.Type)
@@ -378,14 +531,32 @@ func (n *Network) Setup(serverEndpoint interface{}) (err error) {
 }
 
 func (n *Network) dispatchMsg(e *TransportEnvelope) {
+	// The delivered envelope is work in flight, which is done here or handed
+	// over to the routine handling it.
+	work := &workCounter{clock: n.clock, pending: 1}
+	defer work.done()
+	switch n.peerScorer.Status(e.From) {
+	case utils.PeerBanned:
+		return
+	case utils.PeerDeprioritized:
+		// Serving pull requests is costly, ignore them from deprioritized
+		// peers.
+		if _, ok := e.Msg.(*PullRequest); ok {
+			return
+		}
+	}
+	msg := unwrapGossip(e.Msg)
 	if func() bool {
 		n.censorLock.RLock()
 		defer n.censorLock.RUnlock()
-		return n.censor.Censor(e.Msg)
+		return n.censor.Censor(msg)
 	}() {
 		return
 	}
-	msg := n.cloneForFake(e.Msg)
+	if g, ok := e.Msg.(*gossipMessage); ok && !n.handleGossip(e.From, g) {
+		return
+	}
+	msg = n.cloneForFake(msg)
 	switch v := msg.(type) {
 	case *types.Block:
 		n.addBlockToCache(v)
@@ -398,6 +569,7 @@ func (n *Network) dispatchMsg(e *TransportEnvelope) {
 			}
 			delete(n.unreceivedBlocks, v.Hash)
 		}()
+		work.handOver()
 		n.toConsensus <- types.Msg{
 			PeerID:  e.From,
 			Payload: v,
@@ -405,12 +577,14 @@ func (n *Network) dispatchMsg(e *TransportEnvelope) {
 	case *types.Vote:
 		// Add this vote to cache.
 		n.addVoteToCache(v)
+		work.handOver()
 		n.toConsensus <- types.Msg{
 			PeerID:  e.From,
 			Payload: v,
 		}
-	case *types.AgreementResult,
+	case *types.AgreementResult, *types.BlockRange,
 		*typesDKG.PrivateShare, *typesDKG.PartialSignature:
+		work.handOver()
 		n.toConsensus <- types.Msg{
 			PeerID:  e.From,
 			Payload: v,
@@ -424,12 +598,59 @@ func (n *Network) dispatchMsg(e *TransportEnvelope) {
 			panic(err)
 		}
 	case *PullRequest:
-		go n.handlePullRequest(v)
+		if n.enqueuePullRequest(e.From, v) {
+			work.handOver()
+		}
 	default:
 		n.toNode <- v
 	}
 }
 
+// enqueuePullRequest queues a pull request to be served by workers if it's
+// admitted by the pull limiter, it returns true when queued.
+func (n *Network) enqueuePullRequest(
+	from types.NodeID, req *PullRequest) bool {
+	key, err := n.pullLimiter.admit(from, req)
+	switch err {
+	case nil:
+	case ErrInvalidPullRequest, ErrPullRequestTooLarge:
+		n.peerScorer.Report(&types.BadPeerReport{
+			PeerID:  from,
+			Offense: types.OffenseInvalidMessage,
+			Err:     err,
+		})
+		return false
+	default:
+		return false
+	}
+	select {
+	case n.pullRequests <- &pullTask{key: key, req: req}:
+		return true
+	default:
+		// Drop the request when workers are busy, the requester would
+		// retry with other peers.
+		n.pullLimiter.done(key)
+		return false
+	}
+}
+
+// servePullRequests is the routine of a worker serving pull requests.
+func (n *Network) servePullRequests() {
+	// Queued pull requests are work in flight until served.
+	work := &workCounter{clock: n.clock}
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case t := <-n.pullRequests:
+			work.add(1)
+			n.handlePullRequest(t.req)
+			n.pullLimiter.done(t.key)
+			work.done()
+		}
+	}
+}
+
 func (n *Network) handlePullRequest(req *PullRequest) {
 	switch req.Type {
 	case "block":
@@ -462,6 +683,23 @@ func (n *Network) handlePullRequest(req *PullRequest) {
 				}
 			}
 		}()
+	case "block-range":
+		if n.db == nil {
+			break
+		}
+		heights := req.Identity.(HeightRange)
+		r, err := utils.GetBlockRange(
+			n.db, heights.From, heights.To, maxBlockRangePage)
+		if err != nil {
+			n.logger.Warn("Failed to serve pull request",
+				"requester", req.Requester,
+				"type", req.Type,
+				"from", heights.From,
+				"to", heights.To,
+				"error", err)
+			break
+		}
+		n.send(req.Requester, r)
 	default:
 		panic(fmt.Errorf("unknown type of pull request: %v", req.Type))
 	}
@@ -469,6 +707,9 @@ func (n *Network) handlePullRequest(req *PullRequest) {
 
 // Run the main loop.
 func (n *Network) Run() {
+	for i := 0; i < n.pullLimit.Workers; i++ {
+		go n.servePullRequests()
+	}
 Loop:
 	for {
 		select {
@@ -477,7 +718,8 @@ Loop:
 		default:
 		}
 		select {
-		case peer := <-n.badPeerChan:
+		case report := <-n.badPeerChan:
+			peer, _ := n.peerScorer.Report(report)
 			if peer == nil {
 				continue Loop
 			}
@@ -546,6 +788,13 @@ func (n *Network) AttachNodeSetCache(cache *utils.NodeSetCache) {
 	n.cache = cache
 }
 
+// AttachDatabase attaches a db.Reader to this module, pull requests for
+// blocks by height range would be served from the compaction chain in it.
+func (n *Network) AttachDatabase(dbInst db.Reader) {
+	// This variable should be attached before run, no lock to protect it.
+	n.db = dbInst
+}
+
 // PurgeNodeSetCache purges cache of some round in attached utils.NodeSetCache.
 func (n *Network) PurgeNodeSetCache(round uint64) {
 	n.cache.Purge(round)
@@ -556,7 +805,21 @@ func (n *Network) ReportBadPeerChan() chan<- interface{} {
 	return n.badPeerChan
 }
 
-func (n *Network) pullBlocksAsync(hashes common.Hashes) {
+// ReportBadPeer implements core.BadPeerReporter interface.
+func (n *Network) ReportBadPeer(report *types.BadPeerReport) {
+	n.badPeerChan <- report
+}
+
+// PeerScorer returns the utils.PeerScorer keeping scores of peers reported
+// via ReportBadPeerChan.
+func (n *Network) PeerScorer() *utils.PeerScorer {
+	return n.peerScorer
+}
+
+// pullBlocksAsync is the routine to pull blocks, 'work' is the work in flight
+// received by this routine.
+func (n *Network) pullBlocksAsync(work *workCounter, hashes common.Hashes) {
+	defer work.done()
 	// Setup notification channels for each block hash.
 	notYetReceived := make(map[common.Hash]struct{})
 	ch := make(chan common.Hash, len(hashes))
@@ -583,10 +846,13 @@ Loop:
 			continue
 		}
 		n.send(nID, req)
+		wake := work.after(2 * n.config.DirectLatency.Delay())
+		work.done()
 		select {
 		case <-n.ctx.Done():
 			break Loop
-		case <-time.After(2 * n.config.DirectLatency.Delay()):
+		case <-wake:
+			work.add(1)
 			// Consume everything in the notification channel.
 			for {
 				select {
@@ -607,7 +873,10 @@ Loop:
 	}
 }
 
-func (n *Network) pullVotesAsync(pos types.Position) {
+// pullVotesAsync is the routine to pull votes, 'work' is the work in flight
+// received by this routine.
+func (n *Network) pullVotesAsync(work *workCounter, pos types.Position) {
+	defer work.done()
 	// Randomly pick several peers to pull votes from.
 	req := &PullRequest{
 		Requester: n.ID,
@@ -689,7 +958,7 @@ func (n *Network) markAgreementResultAsSent(blockHash common.Hash) bool {
 }
 
 func (n *Network) cloneForFake(v interface{}) interface{} {
-	if n.config.Type != NetworkTypeFake {
+	if n.config.Type != NetworkTypeFake && n.config.Type != NetworkTypeRouted {
 		return v
 	}
 	switch val := v.(type) {
@@ -698,6 +967,15 @@ func (n *Network) cloneForFake(v interface{}) interface{} {
 	case *types.AgreementResult:
 		// Perform deep copy for randomness result.
 		return cloneAgreementResult(val)
+	case *types.BlockRange:
+		r := &types.BlockRange{From: val.From, To: val.To}
+		for _, b := range val.Blocks {
+			r.Blocks = append(r.Blocks, b.Clone())
+		}
+		for _, cert := range val.Certificates {
+			r.Certificates = append(r.Certificates, cert.Clone())
+		}
+		return r
 	}
 	return v
 }
@@ -724,8 +1002,12 @@ func (n *Network) getNotarySet(round uint64) map[types.NodeID]struct{} {
 }
 
 func (n *Network) send(endpoint types.NodeID, msg interface{}) {
+	work := &workCounter{clock: n.clock}
+	wake := work.after(n.config.DirectLatency.Delay())
 	go func() {
-		time.Sleep(n.config.DirectLatency.Delay())
+		<-wake
+		work.add(1)
+		defer work.done()
 		if err := n.trans.Send(endpoint, msg); err != nil {
 			panic(err)
 		}
@@ -28,6 +28,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
 	"github.com/tangerine-network/tangerine-consensus/core/utils"
@@ -39,6 +40,11 @@ type NetworkTestSuite struct {
 
 func (s *NetworkTestSuite) setupNetworks(
 	pubKeys []crypto.PublicKey) map[types.NodeID]*Network {
+	return s.setupGossipNetworks(pubKeys, 0, 0)
+}
+
+func (s *NetworkTestSuite) setupGossipNetworks(pubKeys []crypto.PublicKey,
+	fanout, ttl int) map[types.NodeID]*Network {
 	var (
 		server = NewFakeTransportServer()
 		wg     sync.WaitGroup
@@ -52,6 +58,8 @@ func (s *NetworkTestSuite) setupNetworks(
 			Type:          NetworkTypeFake,
 			DirectLatency: &FixedLatencyModel{},
 			GossipLatency: &FixedLatencyModel{},
+			GossipFanout:  fanout,
+			GossipTTL:     ttl,
 			Marshaller:    NewDefaultMarshaller(nil)})
 		networks[n.ID] = n
 		wg.Add(1)
@@ -234,6 +242,132 @@ func (s *NetworkTestSuite) TestPullVotes() {
 	}
 }
 
+func (s *NetworkTestSuite) TestPullRequestFlood() {
+	var (
+		req       = s.Require()
+		peerCount = 2
+		burst     = 5
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	victim := networks[types.NewNodeID(pubKeys[0])]
+	attacker := networks[types.NewNodeID(pubKeys[1])]
+	victim.pullLimiter = newPullLimiter(PullLimitConfig{
+		Rate:      0.001,
+		Burst:     burst,
+		MaxHashes: 2,
+	}, victim.clock)
+	// Fill votes of several positions into the cache of victim.
+	for i := 0; i < 3*burst; i++ {
+		v := types.NewVote(types.VoteInit, common.NewRandomHash(), 0)
+		v.Position.Height = uint64(i)
+		req.NoError(attacker.trans.Send(victim.ID, v))
+	}
+	time.Sleep(100 * time.Millisecond)
+	for len(victim.ReceiveChan()) > 0 {
+		<-victim.ReceiveChan()
+	}
+	// Flood victim with pull requests, only a burst of them are served.
+	for i := 0; i < 3*burst; i++ {
+		req.NoError(attacker.trans.Send(victim.ID, &PullRequest{
+			Requester: attacker.ID,
+			Type:      "vote",
+			Identity:  types.Position{Height: uint64(i)},
+		}))
+	}
+	time.Sleep(100 * time.Millisecond)
+	req.Len(attacker.ReceiveChan(), burst)
+	// Requests carrying too many hashes make the requester punished.
+	req.NoError(attacker.trans.Send(victim.ID, &PullRequest{
+		Requester: attacker.ID,
+		Type:      "block",
+		Identity: common.Hashes{
+			common.NewRandomHash(),
+			common.NewRandomHash(),
+			common.NewRandomHash(),
+		},
+	}))
+	time.Sleep(100 * time.Millisecond)
+	req.True(victim.PeerScorer().Score(attacker.ID) < 0)
+}
+
+func (s *NetworkTestSuite) TestPullBlockRange() {
+	var (
+		req       = s.Require()
+		peerCount = 2
+		count     = maxBlockRangePage + 10
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	server := networks[types.NewNodeID(pubKeys[0])]
+	client := networks[types.NewNodeID(pubKeys[1])]
+	// Prepare the compaction chain of server.
+	dbInst, err := db.NewMemBackedDB()
+	req.NoError(err)
+	var parent common.Hash
+	for i := 0; i < count; i++ {
+		b := types.Block{
+			ProposerID: types.NodeID{Hash: common.NewRandomHash()},
+			ParentHash: parent,
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: types.GenesisHeight + uint64(i)},
+			Randomness: common.GenerateRandomBytes(),
+		}
+		req.NoError(dbInst.PutBlock(b))
+		req.NoError(dbInst.PutCompactionChainTipInfo(b.Hash, b.Position.Height))
+		req.NoError(dbInst.PutAgreementCertificate(types.AgreementCertificate{
+			BlockHash:    b.Hash,
+			Position:     b.Position,
+			IsEmptyBlock: b.IsEmpty(),
+			Signature:    b.Randomness,
+		}))
+		parent = b.Hash
+	}
+	server.AttachDatabase(dbInst)
+	receive := func() *types.BlockRange {
+		select {
+		case msg := <-client.ReceiveChan():
+			r, ok := msg.Payload.(*types.BlockRange)
+			req.True(ok)
+			req.NoError(utils.VerifyBlockRange(r))
+			return r
+		case <-time.After(time.Second):
+			s.FailNow("timeout when receiving block range")
+		}
+		return nil
+	}
+	// Pull all blocks page by page.
+	to := types.GenesisHeight + uint64(count)
+	client.PullBlockRange(types.GenesisHeight, to)
+	r := receive()
+	req.Len(r.Blocks, maxBlockRangePage)
+	req.Len(r.Certificates, maxBlockRangePage)
+	next, more := r.Next()
+	req.True(more)
+	client.PullBlockRange(next, to)
+	r = receive()
+	req.Len(r.Blocks, count-maxBlockRangePage)
+	_, more = r.Next()
+	req.False(more)
+	// Pulling beyond the compaction chain tip gets nothing.
+	client.PullBlockRange(to, to+10)
+	r = receive()
+	req.Len(r.Blocks, 0)
+	// Invalid ranges from peers are dropped instead of crashing the server.
+	server.handlePullRequest(&PullRequest{
+		Requester: client.ID,
+		Type:      "block-range",
+		Identity:  HeightRange{From: to, To: types.GenesisHeight},
+	})
+	select {
+	case msg := <-client.ReceiveChan():
+		s.FailNow("unexpected message", "%v", msg.Payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func (s *NetworkTestSuite) TestBroadcastToSet() {
 	// Make sure when a network module attached to a utils.NodeSetCache,
 	// These function would broadcast to correct nodes, not all peers.
@@ -374,6 +508,190 @@ func (s *NetworkTestSuite) TestCensor() {
 
 }
 
+func (s *NetworkTestSuite) TestFaultPlan() {
+	var (
+		req       = s.Require()
+		peerCount = 4
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	nIDs := make([]types.NodeID, 0, peerCount)
+	for _, k := range pubKeys {
+		nIDs = append(nIDs, types.NewNodeID(k))
+	}
+	sender := networks[nIDs[0]]
+	checkReceived := func(expected map[types.NodeID]int) {
+		time.Sleep(50 * time.Millisecond)
+		for _, nID := range nIDs {
+			req.Len(networks[nID].ReceiveChan(), expected[nID])
+			for i := 0; i < expected[nID]; i++ {
+				<-networks[nID].ReceiveChan()
+			}
+		}
+	}
+	// Split nodes into two groups.
+	sender.SetFaultPlan(&FaultPlan{
+		Partitions: []Partition{{
+			Groups: [][]types.NodeID{nIDs[:2], nIDs[2:]},
+		}},
+	})
+	sender.BroadcastVote(&types.Vote{})
+	checkReceived(map[types.NodeID]int{nIDs[1]: 1})
+	// Duplicate messages sent to one node.
+	sender.SetFaultPlan(&FaultPlan{
+		Links: []LinkFault{{
+			To:        nIDs[1:2],
+			Duplicate: 1,
+		}},
+	})
+	sender.BroadcastVote(&types.Vote{})
+	checkReceived(map[types.NodeID]int{nIDs[1]: 2, nIDs[2]: 1, nIDs[3]: 1})
+	// Drop all messages sent to some nodes, including direct ones.
+	sender.SetFaultPlan(&FaultPlan{
+		Links: []LinkFault{{
+			To:   nIDs[2:],
+			Loss: 1,
+		}},
+	})
+	sender.BroadcastVote(&types.Vote{})
+	sender.SendDKGPrivateShare(pubKeys[3], &typesDKG.PrivateShare{})
+	checkReceived(map[types.NodeID]int{nIDs[1]: 1})
+	// Heal all faults.
+	sender.SetFaultPlan(nil)
+	sender.BroadcastVote(&types.Vote{})
+	checkReceived(map[types.NodeID]int{nIDs[1]: 1, nIDs[2]: 1, nIDs[3]: 1})
+}
+
+func (s *NetworkTestSuite) TestSpammingPeerCutOff() {
+	var (
+		req       = s.Require()
+		peerCount = 3
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	nIDs := make([]types.NodeID, 0, peerCount)
+	for _, k := range pubKeys {
+		nIDs = append(nIDs, types.NewNodeID(k))
+	}
+	victim, spammer, honest := networks[nIDs[0]], nIDs[1], nIDs[2]
+	// Reports every vote from the spammer as a bad one, like what
+	// core.Consensus does.
+	drain := func() (fromSpammer, fromHonest int) {
+		time.Sleep(50 * time.Millisecond)
+		for len(victim.ReceiveChan()) > 0 {
+			msg := <-victim.ReceiveChan()
+			switch msg.PeerID {
+			case spammer:
+				fromSpammer++
+				victim.ReportBadPeer(&types.BadPeerReport{
+					PeerID:  msg.PeerID,
+					Offense: types.OffenseBadSignature,
+				})
+			case honest:
+				fromHonest++
+			}
+		}
+		return
+	}
+	for i := 0; i < 3; i++ {
+		networks[spammer].BroadcastVote(&types.Vote{})
+		fromSpammer, _ := drain()
+		req.Equal(1, fromSpammer)
+	}
+	time.Sleep(50 * time.Millisecond)
+	req.Equal(utils.PeerBanned, victim.PeerScorer().Status(spammer))
+	// Messages from the banned peer are dropped, while others still pass.
+	networks[spammer].BroadcastVote(&types.Vote{})
+	networks[honest].BroadcastVote(&types.Vote{})
+	fromSpammer, fromHonest := drain()
+	req.Equal(0, fromSpammer)
+	req.Equal(1, fromHonest)
+}
+
+func (s *NetworkTestSuite) TestGossip() {
+	var (
+		req       = s.Require()
+		peerCount = 10
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupGossipNetworks(pubKeys, peerCount-4, 0)
+	var master *Network
+	for _, master = range networks {
+		break
+	}
+	// Every other node should receive the block exactly once.
+	block := &types.Block{Hash: common.NewRandomHash()}
+	master.BroadcastBlock(block)
+	for _, n := range networks {
+		if n == master {
+			continue
+		}
+		select {
+		case msg := <-n.ReceiveChan():
+			req.Equal(block.Hash, msg.Payload.(*types.Block).Hash)
+		case <-time.After(5 * time.Second):
+			req.FailNow("timeout when receiving gossiped block")
+		}
+	}
+	// Broadcasting agreement result twice would not duplicate it.
+	result := &types.AgreementResult{BlockHash: block.Hash}
+	master.BroadcastAgreementResult(result)
+	master.BroadcastAgreementResult(result)
+	time.Sleep(500 * time.Millisecond)
+	for _, n := range networks {
+		if n == master {
+			req.Len(n.ReceiveChan(), 0)
+			continue
+		}
+		req.Len(n.ReceiveChan(), 1)
+		msg := <-n.ReceiveChan()
+		req.Equal(block.Hash, msg.Payload.(*types.AgreementResult).BlockHash)
+	}
+}
+
+func (s *NetworkTestSuite) TestGossipTTL() {
+	var (
+		req       = s.Require()
+		peerCount = 6
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupGossipNetworks(pubKeys, 2, 1)
+	var master *Network
+	for _, master = range networks {
+		break
+	}
+	// Only peers picked in the first hop would receive the vote.
+	master.BroadcastVote(&types.Vote{})
+	time.Sleep(500 * time.Millisecond)
+	received := 0
+	for _, n := range networks {
+		if len(n.ReceiveChan()) > 0 {
+			req.IsType(&types.Vote{}, (<-n.ReceiveChan()).Payload)
+			received++
+		}
+	}
+	req.Equal(2, received)
+}
+
+func (s *NetworkTestSuite) TestGossipMarshaling() {
+	m := NewDefaultMarshaller(nil)
+	block := &types.Block{Hash: common.NewRandomHash()}
+	g := &gossipMessage{Hash: hashGossip(block), TTL: 3, Payload: block}
+	msgType, payload, err := m.Marshal(g)
+	s.Require().NoError(err)
+	msg, err := m.Unmarshal(msgType, payload)
+	s.Require().NoError(err)
+	g2 := msg.(*gossipMessage)
+	s.Require().Equal(g.Hash, g2.Hash)
+	s.Require().Equal(g.TTL, g2.TTL)
+	s.Require().Equal(block.Hash, g2.Payload.(*types.Block).Hash)
+	s.Require().Equal(g.Hash, hashGossip(g2.Payload))
+}
+
 func TestNetwork(t *testing.T) {
 	suite.Run(t, new(NetworkTestSuite))
 }
@@ -0,0 +1,208 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// Errors for pull limiter.
+var (
+	// ErrInvalidPullRequest is reported when a pull request is malformed,
+	// or its requester is not the peer sending it.
+	ErrInvalidPullRequest = errors.New("invalid pull request")
+	// ErrPullRequestTooLarge is reported when a pull request carries more
+	// hashes than allowed.
+	ErrPullRequestTooLarge = errors.New("pull request too large")
+	// ErrPullRateExceeded is reported when a peer sends pull requests faster
+	// than allowed.
+	ErrPullRateExceeded = errors.New("pull rate exceeded")
+	// ErrDuplicatedPullRequest is reported when an identical pull request is
+	// still being served.
+	ErrDuplicatedPullRequest = errors.New("duplicated pull request")
+)
+
+// PullLimitConfig is the configuration to limit pull requests served by
+// Network, default values are used for fields not positive.
+type PullLimitConfig struct {
+	// Rate is the count of pull requests allowed per second for each peer.
+	Rate float64
+	// Burst is the maximum count of pull requests allowed at once for each
+	// peer.
+	Burst int
+	// MaxHashes is the maximum count of hashes in one pull request for
+	// blocks, larger requests are split by the requester.
+	MaxHashes int
+	// Workers is the count of routines serving pull requests.
+	Workers int
+	// QueueSize is the count of pending pull requests, requests are dropped
+	// when the queue is full.
+	QueueSize int
+}
+
+// DefaultPullLimitConfig returns the default configuration to limit pull
+// requests.
+func DefaultPullLimitConfig() PullLimitConfig {
+	return PullLimitConfig{
+		Rate:      32,
+		Burst:     128,
+		MaxHashes: 128,
+		Workers:   4,
+		QueueSize: 256,
+	}
+}
+
+func (c *PullLimitConfig) setDefaults() {
+	defaults := DefaultPullLimitConfig()
+	if c.Rate <= 0 {
+		c.Rate = defaults.Rate
+	}
+	if c.Burst <= 0 {
+		c.Burst = defaults.Burst
+	}
+	if c.MaxHashes <= 0 {
+		c.MaxHashes = defaults.MaxHashes
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaults.Workers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaults.QueueSize
+	}
+}
+
+// pullKey identifies identical pull requests.
+type pullKey struct {
+	Requester types.NodeID
+	Type      string
+	Identity  interface{}
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// pullLimiter decides which pull requests should be served.
+type pullLimiter struct {
+	config   PullLimitConfig
+	clock    common.Clock
+	lock     sync.Mutex
+	buckets  map[types.NodeID]*tokenBucket
+	inFlight map[pullKey]struct{}
+}
+
+func newPullLimiter(config PullLimitConfig, clock common.Clock) *pullLimiter {
+	return &pullLimiter{
+		config:   config,
+		clock:    clock,
+		buckets:  make(map[types.NodeID]*tokenBucket),
+		inFlight: make(map[pullKey]struct{}),
+	}
+}
+
+// admit checks a pull request sent by a peer, the returned key should be
+// released via done once the request is served.
+func (l *pullLimiter) admit(
+	from types.NodeID, req *PullRequest) (key pullKey, err error) {
+	if req.Requester != from {
+		err = ErrInvalidPullRequest
+		return
+	}
+	key = pullKey{Requester: req.Requester, Type: req.Type}
+	switch req.Type {
+	case "block":
+		hashes, ok := req.Identity.(common.Hashes)
+		if !ok {
+			err = ErrInvalidPullRequest
+			return
+		}
+		if len(hashes) > l.config.MaxHashes {
+			err = ErrPullRequestTooLarge
+			return
+		}
+		b := make([]byte, 0, len(hashes)*common.HashLength)
+		for _, h := range hashes {
+			b = append(b, h[:]...)
+		}
+		key.Identity = crypto.Keccak256Hash(b)
+	case "vote":
+		pos, ok := req.Identity.(types.Position)
+		if !ok {
+			err = ErrInvalidPullRequest
+			return
+		}
+		key.Identity = pos
+	case "block-range":
+		heights, ok := req.Identity.(HeightRange)
+		if !ok || heights.From >= heights.To {
+			err = ErrInvalidPullRequest
+			return
+		}
+		key.Identity = heights
+	default:
+		err = ErrInvalidPullRequest
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, exists := l.inFlight[key]; exists {
+		err = ErrDuplicatedPullRequest
+		return
+	}
+	if !l.takeTokenNoLock(from) {
+		err = ErrPullRateExceeded
+		return
+	}
+	l.inFlight[key] = struct{}{}
+	return
+}
+
+// done marks a pull request as served.
+func (l *pullLimiter) done(key pullKey) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.inFlight, key)
+}
+
+func (l *pullLimiter) takeTokenNoLock(from types.NodeID) bool {
+	now := l.clock.Now()
+	b, exists := l.buckets[from]
+	if !exists {
+		b = &tokenBucket{tokens: float64(l.config.Burst), updated: now}
+		l.buckets[from] = b
+	}
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * l.config.Rate
+		if b.tokens > float64(l.config.Burst) {
+			b.tokens = float64(l.config.Burst)
+		}
+		b.updated = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+type PullLimiterTestSuite struct {
+	suite.Suite
+}
+
+func (s *PullLimiterTestSuite) TestAdmit() {
+	var (
+		req     = s.Require()
+		nIDs    = GenerateRandomNodeIDs(2)
+		limiter = newPullLimiter(PullLimitConfig{
+			Rate:      1,
+			Burst:     10,
+			MaxHashes: 2,
+		}, NewSimulatedClock(time.Now()))
+	)
+	// Requests sent on behalf of others are rejected.
+	_, err := limiter.admit(nIDs[1], &PullRequest{
+		Requester: nIDs[0],
+		Type:      "vote",
+		Identity:  types.Position{Height: 1},
+	})
+	req.Equal(ErrInvalidPullRequest, err)
+	// Malformed requests are rejected.
+	_, err = limiter.admit(nIDs[0], &PullRequest{
+		Requester: nIDs[0],
+		Type:      "block",
+		Identity:  types.Position{Height: 1},
+	})
+	req.Equal(ErrInvalidPullRequest, err)
+	_, err = limiter.admit(nIDs[0], &PullRequest{
+		Requester: nIDs[0],
+		Type:      "unknown",
+	})
+	req.Equal(ErrInvalidPullRequest, err)
+	// Requests carrying too many hashes are rejected.
+	blockReq := &PullRequest{
+		Requester: nIDs[0],
+		Type:      "block",
+		Identity: common.Hashes{
+			common.NewRandomHash(),
+			common.NewRandomHash(),
+			common.NewRandomHash(),
+		},
+	}
+	_, err = limiter.admit(nIDs[0], blockReq)
+	req.Equal(ErrPullRequestTooLarge, err)
+	// Identical requests are rejected until the first one is served.
+	blockReq.Identity = blockReq.Identity.(common.Hashes)[:2]
+	key, err := limiter.admit(nIDs[0], blockReq)
+	req.NoError(err)
+	_, err = limiter.admit(nIDs[0], &PullRequest{
+		Requester: nIDs[0],
+		Type:      "block",
+		Identity:  append(common.Hashes{}, blockReq.Identity.(common.Hashes)...),
+	})
+	req.Equal(ErrDuplicatedPullRequest, err)
+	// The same request from another peer is not a duplicate.
+	_, err = limiter.admit(nIDs[1], &PullRequest{
+		Requester: nIDs[1],
+		Type:      "block",
+		Identity:  blockReq.Identity,
+	})
+	req.NoError(err)
+	limiter.done(key)
+	_, err = limiter.admit(nIDs[0], blockReq)
+	req.NoError(err)
+}
+
+func (s *PullLimiterTestSuite) TestRate() {
+	var (
+		req     = s.Require()
+		nIDs    = GenerateRandomNodeIDs(2)
+		clock   = NewSimulatedClock(time.Now())
+		limiter = newPullLimiter(PullLimitConfig{
+			Rate:      2,
+			Burst:     4,
+			MaxHashes: 1,
+		}, clock)
+		height uint64
+	)
+	pull := func(from types.NodeID) error {
+		height++
+		key, err := limiter.admit(from, &PullRequest{
+			Requester: from,
+			Type:      "vote",
+			Identity:  types.Position{Height: height},
+		})
+		if err == nil {
+			limiter.done(key)
+		}
+		return err
+	}
+	// Burst is allowed.
+	for i := 0; i < 4; i++ {
+		req.NoError(pull(nIDs[0]))
+	}
+	req.Equal(ErrPullRateExceeded, pull(nIDs[0]))
+	// Other peers have their own quota.
+	req.NoError(pull(nIDs[1]))
+	// Tokens are refilled over time.
+	clock.Advance(time.Second)
+	req.NoError(pull(nIDs[0]))
+	req.NoError(pull(nIDs[0]))
+	req.Equal(ErrPullRateExceeded, pull(nIDs[0]))
+	// Refilled tokens never exceed the burst.
+	clock.Advance(time.Hour)
+	for i := 0; i < 4; i++ {
+		req.NoError(pull(nIDs[0]))
+	}
+	req.Equal(ErrPullRateExceeded, pull(nIDs[0]))
+}
+
+func TestPullLimiter(t *testing.T) {
+	suite.Run(t, new(PullLimiterTestSuite))
+}
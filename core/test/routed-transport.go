@@ -0,0 +1,138 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// ErrNoPeerServer is reported when reporting to peer server in a network
+// without one.
+var ErrNoPeerServer = errors.New("no peer server")
+
+// TransportRouter routes messages among RoutedTransport instances. It's
+// expected to be implemented by a scheduler, which decides when and in which
+// order messages are delivered.
+type TransportRouter interface {
+	// Join registers a peer with the channel to deliver messages to it, and
+	// blocks until all peers joined.
+	Join(pubKey crypto.PublicKey, recv chan<- *TransportEnvelope) (
+		peers []crypto.PublicKey, dMoment time.Time, err error)
+	// Route asks the router to deliver a message to a peer.
+	Route(from, to types.NodeID, msg interface{})
+}
+
+// RoutedTransport implements TransportClient interface by passing all
+// messages to a TransportRouter. The latency of each message is decided by
+// the router, thus the latency model passed to Broadcast is ignored.
+type RoutedTransport struct {
+	nID         types.NodeID
+	pubKey      crypto.PublicKey
+	recvChannel chan *TransportEnvelope
+	router      TransportRouter
+	peers       map[types.NodeID]crypto.PublicKey
+	dMoment     time.Time
+}
+
+// NewRoutedTransportClient constructs RoutedTransport instance for peer.
+func NewRoutedTransportClient(pubKey crypto.PublicKey) TransportClient {
+	return &RoutedTransport{
+		nID:         types.NewNodeID(pubKey),
+		pubKey:      pubKey,
+		recvChannel: make(chan *TransportEnvelope, 1000),
+		peers:       make(map[types.NodeID]crypto.PublicKey),
+	}
+}
+
+// Disconnect implements Transport.Disconnect method.
+func (t *RoutedTransport) Disconnect(endpoint types.NodeID) {
+	delete(t.peers, endpoint)
+}
+
+// Send implements Transport.Send method.
+func (t *RoutedTransport) Send(
+	endpoint types.NodeID, msg interface{}) (err error) {
+	if _, exists := t.peers[endpoint]; !exists {
+		err = fmt.Errorf("the endpoint does not exists: %v", endpoint)
+		return
+	}
+	t.router.Route(t.nID, endpoint, msg)
+	return
+}
+
+// Report implements TransportClient.Report method.
+func (t *RoutedTransport) Report(msg interface{}) error {
+	return ErrNoPeerServer
+}
+
+// Broadcast implements Transport.Broadcast method.
+func (t *RoutedTransport) Broadcast(endpoints map[types.NodeID]struct{},
+	latency LatencyModel, msg interface{}) (err error) {
+	for ID := range endpoints {
+		if ID == t.nID {
+			continue
+		}
+		if _, exists := t.peers[ID]; !exists {
+			continue
+		}
+		t.router.Route(t.nID, ID, msg)
+	}
+	return
+}
+
+// Close implements Transport.Close method. The receiving channel is not
+// closed because the router might still deliver messages to it.
+func (t *RoutedTransport) Close() (err error) {
+	return
+}
+
+// Peers implements Transport.Peers method.
+func (t *RoutedTransport) Peers() (peers []crypto.PublicKey) {
+	for _, pubKey := range t.peers {
+		peers = append(peers, pubKey)
+	}
+	return
+}
+
+// Join implements TransportClient.Join method.
+func (t *RoutedTransport) Join(
+	serverEndpoint interface{}) (<-chan *TransportEnvelope, error) {
+	var ok bool
+	if t.router, ok = serverEndpoint.(TransportRouter); !ok {
+		return nil, fmt.Errorf("accept TransportRouter when join")
+	}
+	peers, dMoment, err := t.router.Join(t.pubKey, t.recvChannel)
+	if err != nil {
+		return nil, err
+	}
+	for _, pubKey := range peers {
+		t.peers[types.NewNodeID(pubKey)] = pubKey
+	}
+	t.dMoment = dMoment
+	return t.recvChannel, nil
+}
+
+// DMoment implements TransportClient.DMoment method.
+func (t *RoutedTransport) DMoment() time.Time {
+	return t.dMoment
+}
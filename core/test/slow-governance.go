@@ -0,0 +1,263 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+// ErrGovernanceUnavailable means the governance backend is unavailable.
+var ErrGovernanceUnavailable = errors.New("governance is unavailable")
+
+// SlowGovernance adapts Governance to utils.GovernanceBackend, each call is
+// delayed to simulate backends querying a contract via RPC.
+type SlowGovernance struct {
+	gov     *Governance
+	latency LatencyModel
+	lock    sync.RWMutex
+	down    bool
+	calls   map[string]int
+}
+
+// NewSlowGovernance constructs a SlowGovernance instance, calls are not
+// delayed when latency model is nil.
+func NewSlowGovernance(
+	gov *Governance, latency LatencyModel) *SlowGovernance {
+	return &SlowGovernance{
+		gov:     gov,
+		latency: latency,
+		calls:   make(map[string]int),
+	}
+}
+
+// SetLatency replaces the latency model.
+func (g *SlowGovernance) SetLatency(latency LatencyModel) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.latency = latency
+}
+
+// SetDown makes all calls fail with ErrGovernanceUnavailable.
+func (g *SlowGovernance) SetDown(down bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.down = down
+}
+
+// Calls returns the count of calls to a method.
+func (g *SlowGovernance) Calls(method string) int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.calls[method]
+}
+
+func (g *SlowGovernance) call(method string) error {
+	g.lock.Lock()
+	g.calls[method]++
+	latency, down := g.latency, g.down
+	g.lock.Unlock()
+	if latency != nil {
+		time.Sleep(latency.Delay())
+	}
+	if down {
+		return ErrGovernanceUnavailable
+	}
+	return nil
+}
+
+// Configuration implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) Configuration(round uint64) (*types.Config, error) {
+	if err := g.call("Configuration"); err != nil {
+		return nil, err
+	}
+	return g.gov.Configuration(round), nil
+}
+
+// CRS implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) CRS(round uint64) (common.Hash, error) {
+	if err := g.call("CRS"); err != nil {
+		return common.Hash{}, err
+	}
+	return g.gov.CRS(round), nil
+}
+
+// NodeSet implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) NodeSet(round uint64) ([]crypto.PublicKey, error) {
+	if err := g.call("NodeSet"); err != nil {
+		return nil, err
+	}
+	return g.gov.NodeSet(round), nil
+}
+
+// GetRoundHeight implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) GetRoundHeight(round uint64) (uint64, error) {
+	if err := g.call("GetRoundHeight"); err != nil {
+		return 0, err
+	}
+	if round == 0 {
+		return g.gov.GetRoundHeight(round), nil
+	}
+	// Governance.GetRoundHeight panics for rounds not ready.
+	g.gov.lock.RLock()
+	defer g.gov.lock.RUnlock()
+	if round >= uint64(len(g.gov.roundBeginHeights)) {
+		return 0, utils.ErrRoundHeightNotReady
+	}
+	return g.gov.roundBeginHeights[round], nil
+}
+
+// DKGResetCount implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) DKGResetCount(round uint64) (uint64, error) {
+	if err := g.call("DKGResetCount"); err != nil {
+		return 0, err
+	}
+	return g.gov.DKGResetCount(round), nil
+}
+
+// DKGComplaints implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) DKGComplaints(
+	round uint64) ([]*typesDKG.Complaint, error) {
+	if err := g.call("DKGComplaints"); err != nil {
+		return nil, err
+	}
+	return g.gov.DKGComplaints(round), nil
+}
+
+// DKGMasterPublicKeys implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) DKGMasterPublicKeys(
+	round uint64) ([]*typesDKG.MasterPublicKey, error) {
+	if err := g.call("DKGMasterPublicKeys"); err != nil {
+		return nil, err
+	}
+	return g.gov.DKGMasterPublicKeys(round), nil
+}
+
+// IsDKGMPKReady implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) IsDKGMPKReady(round uint64) (bool, error) {
+	if err := g.call("IsDKGMPKReady"); err != nil {
+		return false, err
+	}
+	return g.gov.IsDKGMPKReady(round), nil
+}
+
+// IsDKGFinal implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) IsDKGFinal(round uint64) (bool, error) {
+	if err := g.call("IsDKGFinal"); err != nil {
+		return false, err
+	}
+	return g.gov.IsDKGFinal(round), nil
+}
+
+// IsDKGSuccess implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) IsDKGSuccess(round uint64) (bool, error) {
+	if err := g.call("IsDKGSuccess"); err != nil {
+		return false, err
+	}
+	return g.gov.IsDKGSuccess(round), nil
+}
+
+// ProposeCRS implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) ProposeCRS(round uint64, signedCRS []byte) error {
+	if err := g.call("ProposeCRS"); err != nil {
+		return err
+	}
+	g.gov.ProposeCRS(round, signedCRS)
+	return nil
+}
+
+// AddDKGComplaint implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) AddDKGComplaint(
+	complaint *typesDKG.Complaint) error {
+	if err := g.call("AddDKGComplaint"); err != nil {
+		return err
+	}
+	g.gov.AddDKGComplaint(complaint)
+	return nil
+}
+
+// AddDKGMasterPublicKey implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) AddDKGMasterPublicKey(
+	masterPublicKey *typesDKG.MasterPublicKey) error {
+	if err := g.call("AddDKGMasterPublicKey"); err != nil {
+		return err
+	}
+	g.gov.AddDKGMasterPublicKey(masterPublicKey)
+	return nil
+}
+
+// AddDKGMPKReady implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) AddDKGMPKReady(ready *typesDKG.MPKReady) error {
+	if err := g.call("AddDKGMPKReady"); err != nil {
+		return err
+	}
+	g.gov.AddDKGMPKReady(ready)
+	return nil
+}
+
+// AddDKGFinalize implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) AddDKGFinalize(final *typesDKG.Finalize) error {
+	if err := g.call("AddDKGFinalize"); err != nil {
+		return err
+	}
+	g.gov.AddDKGFinalize(final)
+	return nil
+}
+
+// AddDKGSuccess implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) AddDKGSuccess(success *typesDKG.Success) error {
+	if err := g.call("AddDKGSuccess"); err != nil {
+		return err
+	}
+	g.gov.AddDKGSuccess(success)
+	return nil
+}
+
+// ReportForkVote implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) ReportForkVote(vote1, vote2 *types.Vote) error {
+	if err := g.call("ReportForkVote"); err != nil {
+		return err
+	}
+	g.gov.ReportForkVote(vote1, vote2)
+	return nil
+}
+
+// ReportForkBlock implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) ReportForkBlock(block1, block2 *types.Block) error {
+	if err := g.call("ReportForkBlock"); err != nil {
+		return err
+	}
+	g.gov.ReportForkBlock(block1, block2)
+	return nil
+}
+
+// ResetDKG implements utils.GovernanceBackend interface.
+func (g *SlowGovernance) ResetDKG(newSignedCRS []byte) error {
+	if err := g.call("ResetDKG"); err != nil {
+		return err
+	}
+	g.gov.ResetDKG(newSignedCRS)
+	return nil
+}
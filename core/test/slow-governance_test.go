@@ -0,0 +1,138 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
+)
+
+type SlowGovernanceTestSuite struct {
+	suite.Suite
+}
+
+func (s *SlowGovernanceTestSuite) setup(latency time.Duration) (
+	gov *Governance, backend *SlowGovernance) {
+	_, pubKeys, err := NewKeys(4)
+	s.Require().NoError(err)
+	gov, err = NewGovernance(NewState(1, pubKeys, 100*time.Millisecond,
+		&common.NullLogger{}, true), 2)
+	s.Require().NoError(err)
+	backend = NewSlowGovernance(gov, &FixedLatencyModel{
+		Latency: float64(latency) / float64(time.Millisecond),
+	})
+	return
+}
+
+func (s *SlowGovernanceTestSuite) TestMemoize() {
+	gov, backend := s.setup(10 * time.Millisecond)
+	cache, err := utils.NewGovernanceCache(backend,
+		utils.GovernanceCacheConfig{Timeout: time.Second})
+	s.Require().NoError(err)
+	defer cache.Close()
+	for i := 0; i < 3; i++ {
+		s.Require().Equal(gov.Configuration(0), cache.Configuration(0))
+		s.Require().Equal(gov.NodeSet(0), cache.NodeSet(0))
+		s.Require().Equal(gov.CRS(0), cache.CRS(0))
+		s.Require().Equal(gov.GetRoundHeight(0), cache.GetRoundHeight(0))
+	}
+	s.Require().Equal(1, backend.Calls("Configuration"))
+	s.Require().Equal(1, backend.Calls("NodeSet"))
+	s.Require().Equal(1, backend.Calls("CRS"))
+	s.Require().Equal(1, backend.Calls("GetRoundHeight"))
+	// Data not ready are not memoized.
+	_, err = cache.QueryConfiguration(10)
+	s.Require().Equal(utils.ErrConfigurationNotReady, err)
+	_, err = cache.QueryRoundHeight(10)
+	s.Require().Equal(utils.ErrRoundHeightNotReady, err)
+	s.Require().Nil(cache.Configuration(10))
+	s.Require().Equal(3, backend.Calls("Configuration"))
+}
+
+func (s *SlowGovernanceTestSuite) TestTimeout() {
+	gov, backend := s.setup(200 * time.Millisecond)
+	cache, err := utils.NewGovernanceCache(backend,
+		utils.GovernanceCacheConfig{Timeout: 10 * time.Millisecond})
+	s.Require().NoError(err)
+	defer cache.Close()
+	begin := time.Now()
+	_, err = cache.QueryConfiguration(0)
+	s.Require().Equal(utils.ErrGovernanceTimeout, err)
+	s.Require().Nil(cache.Configuration(0))
+	s.Require().True(time.Since(begin) < 200*time.Millisecond)
+	// Pending queries are shared, and their results are memoized when the
+	// backend responses.
+	time.Sleep(300 * time.Millisecond)
+	s.Require().Equal(gov.Configuration(0), cache.Configuration(0))
+	s.Require().Equal(1, backend.Calls("Configuration"))
+	// Errors from the backend are returned.
+	backend.SetLatency(nil)
+	backend.SetDown(true)
+	_, err = cache.QueryCRS(0)
+	s.Require().Equal(ErrGovernanceUnavailable, err)
+	backend.SetDown(false)
+	crs, err := cache.QueryCRS(0)
+	s.Require().NoError(err)
+	s.Require().Equal(gov.CRS(0), crs)
+}
+
+func (s *SlowGovernanceTestSuite) TestDKGReset() {
+	gov, backend := s.setup(0)
+	clock := NewSimulatedClock(time.Now())
+	cache, err := utils.NewGovernanceCache(backend,
+		utils.GovernanceCacheConfig{
+			Timeout:       time.Second,
+			ResetCountTTL: time.Second,
+			Clock:         clock,
+		})
+	s.Require().NoError(err)
+	defer cache.Close()
+	crs := common.NewRandomHash()
+	s.Require().NoError(gov.State().ProposeCRS(2, crs))
+	s.Require().Equal(crs, cache.CRS(2))
+	s.Require().Equal(uint64(0), cache.DKGResetCount(2))
+	// Writes are sent to the backend asynchronously.
+	cache.ResetDKG([]byte("reset"))
+	for i := 0; i < 100 && gov.DKGResetCount(2) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Require().Equal(uint64(1), gov.DKGResetCount(2))
+	// The stale CRS is cached until the reset is observed, the reset count
+	// is cached until expired.
+	s.Require().Equal(crs, cache.CRS(2))
+	s.Require().Equal(1, backend.Calls("CRS"))
+	s.Require().Equal(uint64(0), cache.DKGResetCount(2))
+	s.Require().Equal(1, backend.Calls("DKGResetCount"))
+	clock.Advance(time.Second)
+	s.Require().Equal(uint64(1), cache.DKGResetCount(2))
+	s.Require().Equal(gov.CRS(2), cache.CRS(2))
+	s.Require().NotEqual(crs, cache.CRS(2))
+	s.Require().Equal(2, backend.Calls("CRS"))
+	// The latest reset count observed is returned when the backend is down.
+	backend.SetDown(true)
+	clock.Advance(time.Second)
+	s.Require().Equal(uint64(1), cache.DKGResetCount(2))
+}
+
+func TestSlowGovernance(t *testing.T) {
+	suite.Run(t, new(SlowGovernanceTestSuite))
+}
@@ -24,7 +24,6 @@ import (
 	"github.com/tangerine-network/go-tangerine/rlp"
 	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
-	"github.com/tangerine-network/tangerine-consensus/core/types"
 	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
 )
 
@@ -50,6 +49,8 @@ const (
 	StateChangeNotarySetSize
 	// Node set related.
 	StateAddNode
+	StateRemoveNode
+	StateRotateNode
 )
 
 func (t StateChangeType) String() string {
@@ -78,6 +79,10 @@ func (t StateChangeType) String() string {
 		return "ChangeNotarySetSize"
 	case StateAddNode:
 		return "AddNode"
+	case StateRemoveNode:
+		return "RemoveNode"
+	case StateRotateNode:
+		return "RotateNode"
 	}
 	panic(fmt.Errorf("attempting to dump unknown type of state change: %d", t))
 }
@@ -132,11 +137,17 @@ func (req *StateChangeRequest) Clone() (copied *StateChangeRequest) {
 	//       I don't want different hash for source/copied requests thus would
 	//       copy the hash from source directly.
 	switch req.Type {
-	case StateAddNode:
+	case StateAddNode, StateRemoveNode:
 		srcBytes := req.Payload.([]byte)
 		copiedBytes := make([]byte, len(srcBytes))
 		copy(copiedBytes, srcBytes)
-		req.Payload = copiedBytes
+		copied.Payload = copiedBytes
+	case StateRotateNode:
+		rotateReq := req.Payload.(*nodeRotationRequest)
+		copied.Payload = &nodeRotationRequest{
+			OldKey: common.CopyBytes(rotateReq.OldKey),
+			NewKey: common.CopyBytes(rotateReq.NewKey),
+		}
 	case StateAddCRS:
 		crsReq := req.Payload.(*crsAdditionRequest)
 		copied.Payload = &crsAdditionRequest{
@@ -193,9 +204,12 @@ func (req *StateChangeRequest) String() (ret string) {
 		ret += fmt.Sprintf("%v", time.Duration(req.Payload.(uint64)))
 	case StateChangeNotarySetSize:
 		ret += fmt.Sprintf("%v", req.Payload.(uint32))
-	case StateAddNode:
-		ret += fmt.Sprintf(
-			"%s", types.NewNodeID(req.Payload.(crypto.PublicKey)).String()[:6])
+	case StateAddNode, StateRemoveNode:
+		ret += nodeIDString(req.Payload.([]byte))
+	case StateRotateNode:
+		rotateReq := req.Payload.(*nodeRotationRequest)
+		ret += fmt.Sprintf("%s->%s",
+			nodeIDString(rotateReq.OldKey), nodeIDString(rotateReq.NewKey))
 	default:
 		panic(fmt.Errorf(
 			"attempting to dump unknown type of state change request: %v",
@@ -204,3 +218,12 @@ func (req *StateChangeRequest) String() (ret string) {
 	ret += "}"
 	return
 }
+
+// nodeIDString dumps the node ID of a public key in bytes.
+func nodeIDString(pubKeyBytes []byte) string {
+	nID, err := nodeIDFromBytes(pubKeyBytes)
+	if err != nil {
+		return "<invalid key>"
+	}
+	return nID.String()[:6]
+}
@@ -84,6 +84,11 @@ var (
 	// mode when the State instance is still in local mode.
 	ErrNotInRemoteMode = errors.New(
 		"attempting to use remote functions in local mode")
+	// ErrNodeNotFound means the node to rotate is not in the node set.
+	ErrNodeNotFound = errors.New("node not found")
+	// ErrNodeExists means the new key of a rotation is already in the node
+	// set.
+	ErrNodeExists = errors.New("node exists")
 )
 
 type crsAdditionRequest struct {
@@ -91,6 +96,18 @@ type crsAdditionRequest struct {
 	CRS   common.Hash `json:"crs"`
 }
 
+// NodeRotation is the payload of StateRotateNode, which replaces the key of a
+// node in the node set.
+type NodeRotation struct {
+	OldKey crypto.PublicKey
+	NewKey crypto.PublicKey
+}
+
+type nodeRotationRequest struct {
+	OldKey []byte `json:"old_key"`
+	NewKey []byte `json:"new_key"`
+}
+
 // State emulates what the global state in governace contract on a fullnode.
 type State struct {
 	// Configuration related.
@@ -244,10 +261,13 @@ func (s *State) unpackPayload(
 		var tmp uint32
 		err = rlp.DecodeBytes(raw.Payload, &tmp)
 		v = tmp
-	case StateAddNode:
+	case StateAddNode, StateRemoveNode:
 		var tmp []byte
 		err = rlp.DecodeBytes(raw.Payload, &tmp)
 		v = tmp
+	case StateRotateNode:
+		v = &nodeRotationRequest{}
+		err = rlp.DecodeBytes(raw.Payload, v)
 	default:
 		err = ErrUnknownStateChangeType
 	}
@@ -742,10 +762,47 @@ func (s *State) isValidRequest(req *StateChangeRequest) error {
 		}
 		// TODO(mission): find a smart way to make sure the caller call request
 		//                this change with correct resetCount.
+	case StateRemoveNode:
+		nID, err := nodeIDFromBytes(req.Payload.([]byte))
+		if err != nil {
+			return err
+		}
+		// The node might be removed by others.
+		if _, exists := s.nodes[nID]; !exists {
+			return ErrDuplicatedChange
+		}
+	case StateRotateNode:
+		rotateReq := req.Payload.(*nodeRotationRequest)
+		oldID, err := nodeIDFromBytes(rotateReq.OldKey)
+		if err != nil {
+			return err
+		}
+		newID, err := nodeIDFromBytes(rotateReq.NewKey)
+		if err != nil {
+			return err
+		}
+		_, oldExists := s.nodes[oldID]
+		_, newExists := s.nodes[newID]
+		switch {
+		case !oldExists && newExists:
+			return ErrDuplicatedChange
+		case !oldExists:
+			return ErrNodeNotFound
+		case newExists:
+			return ErrNodeExists
+		}
 	}
 	return nil
 }
 
+func nodeIDFromBytes(pubKeyBytes []byte) (types.NodeID, error) {
+	pubKey, err := ecdsa.NewPublicKeyFromByteSlice(pubKeyBytes)
+	if err != nil {
+		return types.NodeID{}, err
+	}
+	return types.NewNodeID(pubKey), nil
+}
+
 // applyRequest applies a single StateChangeRequest.
 func (s *State) applyRequest(req *StateChangeRequest) error {
 	// NOTE: there would be no lock in this helper, callers should be
@@ -757,6 +814,24 @@ func (s *State) applyRequest(req *StateChangeRequest) error {
 			return err
 		}
 		s.nodes[types.NewNodeID(pubKey)] = pubKey
+	case StateRemoveNode:
+		nID, err := nodeIDFromBytes(req.Payload.([]byte))
+		if err != nil {
+			return err
+		}
+		delete(s.nodes, nID)
+	case StateRotateNode:
+		rotateReq := req.Payload.(*nodeRotationRequest)
+		oldID, err := nodeIDFromBytes(rotateReq.OldKey)
+		if err != nil {
+			return err
+		}
+		newKey, err := ecdsa.NewPublicKeyFromByteSlice(rotateReq.NewKey)
+		if err != nil {
+			return err
+		}
+		delete(s.nodes, oldID)
+		s.nodes[types.NewNodeID(newKey)] = newKey
 	case StateAddCRS:
 		crsRequest := req.Payload.(*crsAdditionRequest)
 		if crsRequest.Round != uint64(len(s.crs)) {
@@ -837,8 +912,14 @@ func (s *State) RequestChange(
 	s.logger.Info("Request Change to State", "type", t, "value", payload)
 	// Patch input parameter's type.
 	switch t {
-	case StateAddNode:
+	case StateAddNode, StateRemoveNode:
 		payload = payload.(crypto.PublicKey).Bytes()
+	case StateRotateNode:
+		rotation := payload.(NodeRotation)
+		payload = &nodeRotationRequest{
+			OldKey: rotation.OldKey.Bytes(),
+			NewKey: rotation.NewKey.Bytes(),
+		}
 	case StateChangeLambdaBA,
 		StateChangeLambdaDKG,
 		StateChangeMinBlockInterval:
@@ -487,6 +487,54 @@ func (s *StateTestSuite) TestUnmatchedResetCount() {
 	s.Require().NoError(st.RequestChange(StateAddDKGFinal, final))
 }
 
+func (s *StateTestSuite) TestNodeChanges() {
+	var (
+		req    = s.Require()
+		lambda = 250 * time.Millisecond
+	)
+	_, keys, err := NewKeys(6)
+	req.NoError(err)
+	genesisNodes, newKey := keys[:5], keys[5]
+	// Remove and rotate nodes in local mode.
+	st := NewState(1, genesisNodes, lambda, &common.NullLogger{}, true)
+	req.NoError(st.RequestChange(StateRemoveNode, genesisNodes[0]))
+	req.Equal(ErrDuplicatedChange,
+		st.RequestChange(StateRemoveNode, genesisNodes[0]))
+	req.NoError(st.RequestChange(StateRotateNode, NodeRotation{
+		OldKey: genesisNodes[1],
+		NewKey: newKey,
+	}))
+	req.Equal(ErrDuplicatedChange, st.RequestChange(StateRotateNode,
+		NodeRotation{OldKey: genesisNodes[1], NewKey: newKey}))
+	req.Equal(ErrNodeNotFound, st.RequestChange(StateRotateNode,
+		NodeRotation{OldKey: genesisNodes[0], NewKey: genesisNodes[1]}))
+	req.Equal(ErrNodeExists, st.RequestChange(StateRotateNode,
+		NodeRotation{OldKey: genesisNodes[2], NewKey: genesisNodes[3]}))
+	_, nodes := st.Snapshot()
+	req.True(s.compareNodes(append([]crypto.PublicKey{newKey},
+		genesisNodes[2:]...), nodes))
+	// Pack those changes in remote mode and apply them to another instance.
+	st = NewState(1, genesisNodes, lambda, &common.NullLogger{}, false)
+	st1 := NewState(1, genesisNodes, lambda, &common.NullLogger{}, false)
+	req.NoError(st.RequestChange(StateRemoveNode, genesisNodes[0]))
+	req.NoError(st.RequestChange(StateRotateNode, NodeRotation{
+		OldKey: genesisNodes[1],
+		NewKey: newKey,
+	}))
+	// Requests from other nodes to remove the same node are ignored.
+	req.NoError(st1.RequestChange(StateRemoveNode, genesisNodes[0]))
+	_, err = st1.PackOwnRequests()
+	req.NoError(err)
+	packed, err := st.PackOwnRequests()
+	req.NoError(err)
+	req.NoError(st1.AddRequestsFromOthers(packed))
+	packed, err = st1.PackRequests()
+	req.NoError(err)
+	req.NoError(st1.Apply(packed))
+	_, nodes1 := st1.Snapshot()
+	req.True(s.compareNodes(nodes, nodes1))
+}
+
 func TestState(t *testing.T) {
 	suite.Run(t, new(StateTestSuite))
 }
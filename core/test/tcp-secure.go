@@ -0,0 +1,266 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/ecdsa"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// Errors for secure connections of TCPTransport.
+var (
+	// ErrPrivateKeyMismatch is reported when the private key to enable secure
+	// connections doesn't belong to the transport.
+	ErrPrivateKeyMismatch = errors.New("private key mismatch")
+	// ErrTCPHandShakeAuthFail is reported when the remote peer fails to prove
+	// the possession of the private key of the node it claims to be.
+	ErrTCPHandShakeAuthFail = errors.New("tcp handshake authentication fail")
+	// ErrTCPFrameAuthFail is reported when a frame received from a secure
+	// connection is tampered, replayed or reordered.
+	ErrTCPFrameAuthFail = errors.New("tcp frame authentication fail")
+)
+
+const (
+	tcpSecureDomain    = "tangerine-consensus-test-tcp-handshake"
+	tcpSecureNonceSize = 32
+)
+
+// tcpSecureHello is the first message of secure handshaking, which carries
+// the public key of the node, along with an ephemeral key and a nonce for
+// this session.
+type tcpSecureHello struct {
+	PublicKey []byte `json:"pubkey"`
+	Ephemeral []byte `json:"ephemeral"`
+	Nonce     []byte `json:"nonce"`
+}
+
+// tcpSecureAuth proves the possession of the private key of a node.
+type tcpSecureAuth struct {
+	Signature crypto.Signature `json:"signature"`
+}
+
+// hashSecureHello calculates the hash to be signed by the sender of 'signer',
+// which is bound to the nonce and ephemeral key from the other side to
+// prevent replaying and man-in-the-middle attacks.
+func hashSecureHello(signer, verifier *tcpSecureHello) common.Hash {
+	return crypto.Keccak256Hash(
+		[]byte(tcpSecureDomain),
+		signer.PublicKey,
+		signer.Ephemeral,
+		signer.Nonce,
+		verifier.Ephemeral,
+		verifier.Nonce)
+}
+
+// secureConn seals frames sent over a net.Conn with AES-GCM, which encrypts
+// and authenticates them. Each direction has its own key, and the sequence
+// number of a frame is used as its nonce, thus replayed or reordered frames
+// can't be opened. Sealing and opening are not safe for concurrent use.
+type secureConn struct {
+	net.Conn
+
+	peerID  types.NodeID
+	sealer  cipher.AEAD
+	opener  cipher.AEAD
+	sendSeq uint64
+	recvSeq uint64
+}
+
+func newSecureConn(conn net.Conn, peerID types.NodeID,
+	sendKey, recvKey []byte) (*secureConn, error) {
+	newAEAD := func(key []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+	sealer, err := newAEAD(sendKey)
+	if err != nil {
+		return nil, err
+	}
+	opener, err := newAEAD(recvKey)
+	if err != nil {
+		return nil, err
+	}
+	return &secureConn{
+		Conn:   conn,
+		peerID: peerID,
+		sealer: sealer,
+		opener: opener,
+	}, nil
+}
+
+func (c *secureConn) nonce(seq uint64) []byte {
+	nonce := make([]byte, c.sealer.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+func (c *secureConn) seal(b []byte) []byte {
+	sealed := c.sealer.Seal(nil, c.nonce(c.sendSeq), b, nil)
+	c.sendSeq++
+	return sealed
+}
+
+func (c *secureConn) open(b []byte) ([]byte, error) {
+	opened, err := c.opener.Open(nil, c.nonce(c.recvSeq), b, nil)
+	if err != nil {
+		return nil, ErrTCPFrameAuthFail
+	}
+	c.recvSeq++
+	return opened, nil
+}
+
+// EnableSecureConn makes this transport prove the possession of the private
+// key of its node to peers when handshaking, and requires the same from them.
+// Frames are encrypted and authenticated by session keys afterward. Peers
+// without secure connections enabled can't connect to this transport. It
+// should be called before Host or Join.
+func (t *TCPTransport) EnableSecureConn(prvKey crypto.PrivateKey) error {
+	if types.NewNodeID(prvKey.PublicKey()) != t.nID {
+		return ErrPrivateKeyMismatch
+	}
+	t.prvKey = prvKey
+	return nil
+}
+
+// handshake identifies the remote peer of conn, the returned connection
+// should be used to read and write frames afterward.
+func (t *TCPTransport) handshake(conn net.Conn, dialer bool) (
+	nID types.NodeID, c net.Conn, err error) {
+	if t.prvKey != nil {
+		return t.secureHandshake(conn, dialer)
+	}
+	if dialer {
+		nID, err = t.clientHandshake(conn)
+	} else {
+		nID, err = t.serverHandshake(conn)
+	}
+	c = conn
+	return
+}
+
+// secureHandshake authenticates both sides of conn, and derives session keys
+// from an ephemeral ECDH exchange. Both sides send their public keys,
+// ephemeral keys and nonces, then sign them along with those from the other
+// side to prove the possession of their private keys.
+func (t *TCPTransport) secureHandshake(conn net.Conn, dialer bool) (
+	nID types.NodeID, c net.Conn, err error) {
+	if err = conn.SetDeadline(time.Now().Add(3 * time.Second)); err != nil {
+		return
+	}
+	curve := elliptic.P256()
+	ephPrv, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return
+	}
+	local := &tcpSecureHello{
+		PublicKey: t.pubKey.Bytes(),
+		Ephemeral: elliptic.Marshal(curve, x, y),
+		Nonce:     make([]byte, tcpSecureNonceSize),
+	}
+	if _, err = rand.Read(local.Nonce); err != nil {
+		return
+	}
+	remote := &tcpSecureHello{}
+	if err = t.exchange(conn, local, remote); err != nil {
+		return
+	}
+	if len(remote.Nonce) != tcpSecureNonceSize {
+		err = ErrTCPHandShakeFail
+		return
+	}
+	remotePubKey, err := ecdsa.NewPublicKeyFromByteSlice(remote.PublicKey)
+	if err != nil {
+		return
+	}
+	rx, ry := elliptic.Unmarshal(curve, remote.Ephemeral)
+	if rx == nil {
+		err = ErrTCPHandShakeFail
+		return
+	}
+	// Exchange signatures.
+	localAuth := &tcpSecureAuth{}
+	if localAuth.Signature, err = t.prvKey.Sign(
+		hashSecureHello(local, remote)); err != nil {
+		return
+	}
+	remoteAuth := &tcpSecureAuth{}
+	if err = t.exchange(conn, localAuth, remoteAuth); err != nil {
+		return
+	}
+	if !remotePubKey.VerifySignature(
+		hashSecureHello(remote, local), remoteAuth.Signature) {
+		err = ErrTCPHandShakeAuthFail
+		return
+	}
+	nID = types.NewNodeID(remotePubKey)
+	// Derive session keys, one for each direction.
+	sx, _ := curve.ScalarMult(rx, ry, ephPrv)
+	secret := make([]byte, (curve.Params().BitSize+7)/8)
+	sxBytes := sx.Bytes()
+	copy(secret[len(secret)-len(sxBytes):], sxBytes)
+	dialerHello, listenerHello := local, remote
+	if !dialer {
+		dialerHello, listenerHello = remote, local
+	}
+	deriveKey := func(label string) []byte {
+		return crypto.Keccak256Hash([]byte(tcpSecureDomain), secret,
+			dialerHello.Nonce, listenerHello.Nonce, []byte(label)).Bytes()
+	}
+	sendKey, recvKey := deriveKey("dialer"), deriveKey("listener")
+	if !dialer {
+		sendKey, recvKey = recvKey, sendKey
+	}
+	sConn, err := newSecureConn(conn, nID, sendKey, recvKey)
+	if err != nil {
+		return
+	}
+	c = sConn
+	return
+}
+
+// exchange writes 'local' to conn and reads 'remote' from it, both in JSON.
+func (t *TCPTransport) exchange(
+	conn net.Conn, local, remote interface{}) (err error) {
+	var payload []byte
+	if payload, err = json.Marshal(local); err != nil {
+		return
+	}
+	if err = t.write(conn, payload); err != nil {
+		return
+	}
+	if payload, err = t.read(conn); err != nil {
+		return
+	}
+	err = json.Unmarshal(payload, remote)
+	return
+}
@@ -108,6 +108,7 @@ type TCPTransport struct {
 	peerType          TransportPeerType
 	nID               types.NodeID
 	pubKey            crypto.PublicKey
+	prvKey            crypto.PrivateKey
 	localPort         int
 	peers             map[types.NodeID]*tcpPeerRecord
 	peersLock         sync.RWMutex
@@ -118,6 +119,7 @@ type TCPTransport struct {
 	throughputRecords []ThroughputRecord
 	throughputLock    sync.Mutex
 	dMoment           time.Time
+	clock             common.Clock
 }
 
 // NewTCPTransport constructs an TCPTransport instance.
@@ -135,6 +137,7 @@ func NewTCPTransport(peerType TransportPeerType, pubKey crypto.PublicKey,
 		localPort:         localPort,
 		marshaller:        marshaller,
 		throughputRecords: []ThroughputRecord{},
+		clock:             &common.SystemClock{},
 	}
 }
 
@@ -246,13 +249,18 @@ func (t *TCPTransport) Broadcast(endpoints map[types.NodeID]struct{},
 			continue
 		}
 		go func(ID types.NodeID) {
-			time.Sleep(latency.Delay())
+			t.clock.Sleep(latency.Delay())
 			t.send(ID, msg, payload)
 		}(nID)
 	}
 	return
 }
 
+// setClock replaces the clock to simulate latencies.
+func (t *TCPTransport) setClock(clock common.Clock) {
+	t.clock = clock
+}
+
 // Close implements Transport.Close method.
 func (t *TCPTransport) Close() (err error) {
 	// Tell all routines raised by us to die.
@@ -276,6 +284,9 @@ func (t *TCPTransport) Peers() (peers []crypto.PublicKey) {
 }
 
 func (t *TCPTransport) write(conn net.Conn, b []byte) (err error) {
+	if sConn, ok := conn.(*secureConn); ok {
+		b = sConn.seal(b)
+	}
 	if len(b) > math.MaxUint32 {
 		return ErrMessageOverflow
 	}
@@ -299,6 +310,9 @@ func (t *TCPTransport) read(conn net.Conn) (b []byte, err error) {
 	if _, err = io.ReadFull(conn, b); err != nil {
 		return
 	}
+	if sConn, ok := conn.(*secureConn); ok {
+		b, err = sConn.open(b)
+	}
 	return
 }
 
@@ -411,7 +425,7 @@ func (t *TCPTransport) connReader(conn net.Conn) {
 	)
 
 	checkErr := func(err error) (toBreak bool) {
-		if err == io.EOF {
+		if err == io.EOF || err == ErrTCPFrameAuthFail {
 			toBreak = true
 			return
 		}
@@ -449,6 +463,11 @@ Loop:
 		if err != nil {
 			panic(err)
 		}
+		// Drop the connection when an authenticated peer claims to be
+		// another one.
+		if sConn, ok := conn.(*secureConn); ok && from != sConn.peerID {
+			break
+		}
 		t.recvChannel <- &TransportEnvelope{
 			PeerType: peerType,
 			From:     from,
@@ -530,7 +549,8 @@ func (t *TCPTransport) listenerRoutine(listener *net.TCPListener) {
 			}
 			continue
 		}
-		if _, err := t.serverHandshake(conn); err != nil {
+		_, conn, err = t.handshake(conn, false)
+		if err != nil {
 			fmt.Println(err)
 			continue
 		}
@@ -562,7 +582,7 @@ func (t *TCPTransport) buildConnectionsToPeers() (err error) {
 				addErr(localErr)
 				return
 			}
-			serverID, localErr := t.clientHandshake(conn)
+			serverID, conn, localErr := t.handshake(conn, true)
 			if localErr != nil {
 				addErr(localErr)
 				return
@@ -647,7 +667,7 @@ func (t *TCPTransportClient) Join(
 				err = e
 				return
 			}
-			nID, e := t.clientHandshake(testConn)
+			nID, _, e := t.handshake(testConn, true)
 			if e != nil {
 				err = e
 				return
@@ -688,7 +708,7 @@ func (t *TCPTransportClient) Join(
 	if err != nil {
 		return
 	}
-	_, err = t.clientHandshake(serverConn)
+	_, serverConn, err = t.handshake(serverConn, true)
 	if err != nil {
 		return
 	}
@@ -784,6 +804,8 @@ func (t *TCPTransportClient) DMoment() time.Time {
 // TCPTransportServer implements TransportServer via TCP connections.
 type TCPTransportServer struct {
 	TCPTransport
+
+	serverPrvKey crypto.PrivateKey
 }
 
 // NewTCPTransportServer constructs TCPTransportServer instance.
@@ -800,9 +822,16 @@ func NewTCPTransportServer(
 		//       won't be zero.
 		TCPTransport: *NewTCPTransport(
 			TransportPeerServer, prvKey.PublicKey(), marshaller, serverPort),
+		serverPrvKey: prvKey,
 	}
 }
 
+// EnableSecureConn enables secure connections with the private key
+// generated for this peer server, see TCPTransport.EnableSecureConn.
+func (t *TCPTransportServer) EnableSecureConn() error {
+	return t.TCPTransport.EnableSecureConn(t.serverPrvKey)
+}
+
 // Host implements TransportServer.Host method.
 func (t *TCPTransportServer) Host() (chan *TransportEnvelope, error) {
 	// The port of peer server should be known to other peers,
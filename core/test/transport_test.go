@@ -235,20 +235,21 @@ func (s *TransportTestSuite) TestFake() {
 	}
 }
 
-func (s *TransportTestSuite) TestTCPLocal() {
-
+func (s *TransportTestSuite) testTCPLocal(serverPort int, secure bool) {
 	var (
-		peerCount  = 13
-		req        = s.Require()
-		peers      = make(map[types.NodeID]*testPeer)
-		prvKeys    = GenerateRandomPrivateKeys(peerCount)
-		err        error
-		wg         sync.WaitGroup
-		serverPort = 8080
-		serverAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(serverPort))
-		server     = &testPeerServer{
-			trans: NewTCPTransportServer(&testMarshaller{}, serverPort)}
+		peerCount   = 13
+		req         = s.Require()
+		peers       = make(map[types.NodeID]*testPeer)
+		prvKeys     = GenerateRandomPrivateKeys(peerCount)
+		err         error
+		wg          sync.WaitGroup
+		serverAddr  = net.JoinHostPort("127.0.0.1", strconv.Itoa(serverPort))
+		serverTrans = NewTCPTransportServer(&testMarshaller{}, serverPort)
+		server      = &testPeerServer{trans: serverTrans}
 	)
+	if secure {
+		req.NoError(serverTrans.EnableSecureConn())
+	}
 	// Setup PeerServer
 	server.recv, err = server.trans.Host()
 	req.Nil(err)
@@ -256,10 +257,14 @@ func (s *TransportTestSuite) TestTCPLocal() {
 	wg.Add(len(prvKeys))
 	for _, prvKey := range prvKeys {
 		nID := types.NewNodeID(prvKey.PublicKey())
+		trans := NewTCPTransportClient(
+			prvKey.PublicKey(), &testMarshaller{}, true)
+		if secure {
+			req.NoError(trans.EnableSecureConn(prvKey))
+		}
 		peer := &testPeer{
-			nID: nID,
-			trans: NewTCPTransportClient(
-				prvKey.PublicKey(), &testMarshaller{}, true),
+			nID:   nID,
+			trans: trans,
 		}
 		peers[nID] = peer
 		go func() {
@@ -282,6 +287,90 @@ func (s *TransportTestSuite) TestTCPLocal() {
 	}
 }
 
+func (s *TransportTestSuite) TestTCPLocal() {
+	s.testTCPLocal(8080, false)
+}
+
+func (s *TransportTestSuite) TestTCPLocalSecure() {
+	s.testTCPLocal(8081, true)
+}
+
+func (s *TransportTestSuite) TestTCPImpersonation() {
+	var (
+		req     = s.Require()
+		prvKeys = GenerateRandomPrivateKeys(3)
+		target  = NewTCPTransport(
+			TransportPeer, prvKeys[0].PublicKey(), &testMarshaller{}, 0)
+	)
+	req.Equal(ErrPrivateKeyMismatch, target.EnableSecureConn(prvKeys[1]))
+	req.NoError(target.EnableSecureConn(prvKeys[0]))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	req.NoError(err)
+	defer ln.Close()
+	// connect handshakes with target via a new connection, then sends a
+	// frame to target via the connection returned by handshaking. Errors on
+	// the dialer side are reported only when target accepts the frame.
+	connect := func(dialer *TCPTransport, send func(net.Conn) error) (
+		nID types.NodeID, received []byte, err error) {
+		dialErr := make(chan error, 1)
+		go func() {
+			dialErr <- func() error {
+				conn, err := net.Dial("tcp", ln.Addr().String())
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				if _, conn, err = dialer.handshake(conn, true); err != nil {
+					return err
+				}
+				return send(conn)
+			}()
+		}()
+		conn, err := ln.Accept()
+		req.NoError(err)
+		defer func(raw net.Conn) {
+			raw.Close()
+			if e := <-dialErr; err == nil {
+				err = e
+			}
+		}(conn)
+		if nID, conn, err = target.handshake(conn, false); err != nil {
+			return
+		}
+		received, err = target.read(conn)
+		return
+	}
+	// An honest peer is authenticated, and the frame from it is received.
+	honest := NewTCPTransport(
+		TransportPeer, prvKeys[1].PublicKey(), &testMarshaller{}, 0)
+	req.NoError(honest.EnableSecureConn(prvKeys[1]))
+	frame := []byte("frame from honest peer")
+	sendSealed := func(conn net.Conn) error {
+		return honest.write(conn, frame)
+	}
+	nID, received, err := connect(honest, sendSealed)
+	req.NoError(err)
+	req.Equal(types.NewNodeID(prvKeys[1].PublicKey()), nID)
+	req.Equal(frame, received)
+	// A peer without secure connections enabled can't connect.
+	plain := NewTCPTransport(
+		TransportPeer, prvKeys[1].PublicKey(), &testMarshaller{}, 0)
+	_, _, err = connect(plain, sendSealed)
+	req.Error(err)
+	// A peer claiming to be the honest one without its private key would
+	// fail the authentication.
+	impostor := NewTCPTransport(
+		TransportPeer, prvKeys[1].PublicKey(), &testMarshaller{}, 0)
+	impostor.prvKey = prvKeys[2]
+	_, _, err = connect(impostor, sendSealed)
+	req.Equal(ErrTCPHandShakeAuthFail, err)
+	// A frame not sealed by session keys would be rejected.
+	_, _, err = connect(honest, func(conn net.Conn) error {
+		return honest.write(conn.(*secureConn).Conn, frame)
+	})
+	req.Equal(ErrTCPFrameAuthFail, err)
+}
+
 func TestTransport(t *testing.T) {
 	suite.Run(t, new(TransportTestSuite))
 }
@@ -23,6 +23,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
 	"github.com/tangerine-network/tangerine-consensus/core/utils"
 )
 
@@ -36,10 +38,11 @@ const (
 	TickerCRS
 )
 
-// defaultTicker is a wrapper to implement ticker interface based on
-// time.Ticker.
+// defaultTicker is a wrapper to implement ticker interface based on the
+// ticker created by common.Clock.
 type defaultTicker struct {
-	ticker     *time.Ticker
+	clock      common.Clock
+	ticker     common.ClockTicker
 	tickerChan chan time.Time
 	duration   time.Duration
 	ctx        context.Context
@@ -47,9 +50,11 @@ type defaultTicker struct {
 	waitGroup  sync.WaitGroup
 }
 
-// newDefaultTicker constructs an defaultTicker instance by giving an interval.
-func newDefaultTicker(lambda time.Duration) *defaultTicker {
-	ticker := &defaultTicker{duration: lambda}
+// newDefaultTicker constructs an defaultTicker instance by giving an interval
+// and the clock to tick with.
+func newDefaultTicker(
+	clock common.Clock, lambda time.Duration) *defaultTicker {
+	ticker := &defaultTicker{clock: clock, duration: lambda}
 	ticker.init()
 	return ticker
 }
@@ -64,6 +69,12 @@ func (t *defaultTicker) Stop() {
 	t.ticker.Stop()
 	t.ctxCancel()
 	t.waitGroup.Wait()
+	// The tick not received by the monitor is dropped.
+	select {
+	case <-t.ticker.C():
+		t.tickDropped()
+	default:
+	}
 	t.ctx = nil
 	t.ctxCancel = nil
 	close(t.tickerChan)
@@ -77,7 +88,7 @@ func (t *defaultTicker) Restart() {
 }
 
 func (t *defaultTicker) init() {
-	t.ticker = time.NewTicker(t.duration)
+	t.ticker = t.clock.NewTicker(t.duration)
 	t.tickerChan = make(chan time.Time)
 	t.ctx, t.ctxCancel = context.WithCancel(context.Background())
 	t.waitGroup.Add(1)
@@ -91,19 +102,31 @@ loop:
 		select {
 		case <-t.ctx.Done():
 			break loop
-		case v := <-t.ticker.C:
+		case v := <-t.ticker.C():
 			select {
 			case t.tickerChan <- v:
 			default:
+				t.tickDropped()
 			}
 		}
 	}
 }
 
+// tickDropped reports a tick not received by the user of this ticker as done,
+// received ticks are reported by the user once handled.
+func (t *defaultTicker) tickDropped() {
+	if tracker, ok := t.clock.(workTracker); ok {
+		tracker.WorkDone()
+	}
+}
+
 // newTicker is a helper to setup a ticker by giving an Governance. If
 // the governace object implements a ticker generator, a ticker from that
-// generator would be returned, else constructs a default one.
-func newTicker(gov Governance, round uint64, tickerType TickerType) (t Ticker) {
+// generator would be returned, else constructs a default one driven by the
+// given clock. An error is returned when the config of that round is not
+// available.
+func newTicker(gov Governance, clock common.Clock, round uint64,
+	tickerType TickerType) (t Ticker, err error) {
 	type tickerGenerator interface {
 		NewTicker(TickerType) Ticker
 	}
@@ -112,16 +135,22 @@ func newTicker(gov Governance, round uint64, tickerType TickerType) (t Ticker) {
 		t = gen.NewTicker(tickerType)
 	}
 	if t == nil {
-		var duration time.Duration
+		var (
+			cfg      *types.Config
+			duration time.Duration
+		)
+		if cfg, err = utils.GetConfig(gov, round, nil); err != nil {
+			return
+		}
 		switch tickerType {
 		case TickerBA:
-			duration = utils.GetConfigWithPanic(gov, round, nil).LambdaBA
+			duration = cfg.LambdaBA
 		case TickerDKG:
-			duration = utils.GetConfigWithPanic(gov, round, nil).LambdaDKG
+			duration = cfg.LambdaDKG
 		default:
 			panic(fmt.Errorf("unknown ticker type: %d", tickerType))
 		}
-		t = newDefaultTicker(duration)
+		t = newDefaultTicker(clock, duration)
 	}
 	return
 }
@@ -0,0 +1,476 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+)
+
+// Recorder captures inputs of a core.Consensus instance into a trace. Modules
+// passed to core.Consensus should be wrapped by the recorder:
+//
+//	rec, err := NewRecorder(w, nID, dMoment, clock)
+//	con := core.NewConsensusWithClock(dMoment, rec.Application(app),
+//	    rec.Governance(gov), rec.Database(db), rec.Network(network), prv,
+//	    rec.Clock(), logger)
+//
+// DKG protocols and private keys are needed to reproduce rounds since
+// DKGDelayRound, they're not recorded unless RecorderConfig.DKGSecrets is
+// given. The trace itself carries no secrets.
+type Recorder struct {
+	writer     *Writer
+	secrets    *Writer
+	clock      common.Clock
+	begin      time.Time
+	marshaller test.Marshaller
+	lock       sync.Mutex
+	err        error
+}
+
+// RecorderConfig is the configuration to record a trace.
+type RecorderConfig struct {
+	// Clock is the clock driving the consensus, common.SystemClock would be
+	// used when it's nil.
+	Clock common.Clock
+	// DKGSecrets receives DKG protocols and private keys of the node in the
+	// trace format, nothing is recorded when it's nil. It should be kept as
+	// secret as the node key.
+	DKGSecrets io.Writer
+}
+
+// NewRecorder constructs a Recorder instance writing the trace to 'w', DKG
+// secrets are not recorded.
+func NewRecorder(w io.Writer, nID types.NodeID, dMoment time.Time,
+	clock common.Clock) (*Recorder, error) {
+	return NewRecorderWithConfig(w, nID, dMoment, RecorderConfig{
+		Clock: clock,
+	})
+}
+
+// NewRecorderWithConfig constructs a Recorder instance writing the trace to
+// 'w' with the configuration.
+func NewRecorderWithConfig(w io.Writer, nID types.NodeID, dMoment time.Time,
+	config RecorderConfig) (*Recorder, error) {
+	clock := config.Clock
+	if clock == nil {
+		clock = &common.SystemClock{}
+	}
+	begin := clock.Now()
+	header := Header{
+		NodeID:  nID,
+		DMoment: dMoment,
+		Begin:   begin,
+	}
+	writer, err := NewWriter(w, &header)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{
+		writer:     writer,
+		clock:      clock,
+		begin:      begin,
+		marshaller: test.NewDefaultMarshaller(nil),
+	}
+	if config.DKGSecrets != nil {
+		if r.secrets, err = NewWriter(config.DKGSecrets, &header); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Close stops recording, and returns the first error during recording.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.writer.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.secrets != nil {
+		if err := r.secrets.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+func (r *Recorder) record(e *Entry) {
+	r.write(r.writer, e)
+}
+
+func (r *Recorder) write(w *Writer, e *Entry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return
+	}
+	e.Offset = r.clock.Now().Sub(r.begin)
+	if err := w.Write(e); err != nil && err != ErrWriterClosed {
+		r.err = err
+	}
+}
+
+func (r *Recorder) recordJSON(e *Entry, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if r.err == nil {
+			r.err = err
+		}
+		return
+	}
+	e.Data = data
+	r.record(e)
+}
+
+// Network wraps core.Network to record received messages.
+func (r *Recorder) Network(n core.Network) core.Network {
+	return &recordingNetwork{Network: n, rec: r}
+}
+
+// Governance wraps core.Governance to record results of queries.
+func (r *Recorder) Governance(g core.Governance) core.Governance {
+	return &recordingGovernance{Governance: g, rec: r}
+}
+
+// Application wraps core.Application to record its decisions and blocks
+// confirmed or delivered.
+func (r *Recorder) Application(app core.Application) core.Application {
+	return &recordingApplication{Application: app, rec: r}
+}
+
+// Database wraps db.Database to record DKG protocols and private keys to
+// RecorderConfig.DKGSecrets, 'dbInst' is returned as is when it's not given.
+func (r *Recorder) Database(dbInst db.Database) db.Database {
+	if r.secrets == nil {
+		return dbInst
+	}
+	return &recordingDatabase{Database: dbInst, rec: r}
+}
+
+// Clock returns a clock recording firings of tickers.
+func (r *Recorder) Clock() common.Clock {
+	return &recordingClock{Clock: r.clock, rec: r}
+}
+
+type recordingNetwork struct {
+	core.Network
+
+	rec  *Recorder
+	once sync.Once
+	recv chan types.Msg
+}
+
+func (n *recordingNetwork) ReceiveChan() <-chan types.Msg {
+	n.once.Do(func() {
+		n.recv = make(chan types.Msg, 1000)
+		go func() {
+			defer close(n.recv)
+			for msg := range n.Network.ReceiveChan() {
+				n.rec.recordMessage(msg)
+				n.recv <- msg
+			}
+		}()
+	})
+	return n.recv
+}
+
+func (r *Recorder) recordMessage(msg types.Msg) {
+	msgType, payload, err := r.marshaller.Marshal(msg.Payload)
+	if err != nil {
+		// Messages not handled by consensus are not recorded.
+		return
+	}
+	// Peers are identified by types.NodeID in test.Network, peers of other
+	// types are recorded as zero values.
+	peerID, _ := msg.PeerID.(types.NodeID)
+	r.record(&Entry{
+		Type:   EntryMessage,
+		Key:    msgType,
+		PeerID: peerID,
+		Data:   payload,
+	})
+}
+
+type recordingGovernance struct {
+	core.Governance
+
+	rec *Recorder
+}
+
+func (g *recordingGovernance) recordQuery(
+	method string, round uint64, result interface{}) {
+	g.rec.recordJSON(&Entry{
+		Type: EntryGovernance,
+		Key:  method,
+		Arg:  round,
+	}, result)
+}
+
+func (g *recordingGovernance) Configuration(round uint64) *types.Config {
+	config := g.Governance.Configuration(round)
+	g.recordQuery("Configuration", round, config)
+	return config
+}
+
+func (g *recordingGovernance) CRS(round uint64) common.Hash {
+	crs := g.Governance.CRS(round)
+	g.recordQuery("CRS", round, crs)
+	return crs
+}
+
+func (g *recordingGovernance) NodeSet(round uint64) []crypto.PublicKey {
+	nodeSet := g.Governance.NodeSet(round)
+	keys := make([][]byte, 0, len(nodeSet))
+	for _, k := range nodeSet {
+		keys = append(keys, k.Bytes())
+	}
+	g.recordQuery("NodeSet", round, keys)
+	return nodeSet
+}
+
+func (g *recordingGovernance) GetRoundHeight(round uint64) uint64 {
+	height := g.Governance.GetRoundHeight(round)
+	g.recordQuery("GetRoundHeight", round, height)
+	return height
+}
+
+func (g *recordingGovernance) DKGComplaints(
+	round uint64) []*typesDKG.Complaint {
+	complaints := g.Governance.DKGComplaints(round)
+	g.recordQuery("DKGComplaints", round, complaints)
+	return complaints
+}
+
+func (g *recordingGovernance) DKGMasterPublicKeys(
+	round uint64) []*typesDKG.MasterPublicKey {
+	mpks := g.Governance.DKGMasterPublicKeys(round)
+	g.recordQuery("DKGMasterPublicKeys", round, mpks)
+	return mpks
+}
+
+func (g *recordingGovernance) IsDKGMPKReady(round uint64) bool {
+	ready := g.Governance.IsDKGMPKReady(round)
+	g.recordQuery("IsDKGMPKReady", round, ready)
+	return ready
+}
+
+func (g *recordingGovernance) IsDKGFinal(round uint64) bool {
+	final := g.Governance.IsDKGFinal(round)
+	g.recordQuery("IsDKGFinal", round, final)
+	return final
+}
+
+func (g *recordingGovernance) IsDKGSuccess(round uint64) bool {
+	success := g.Governance.IsDKGSuccess(round)
+	g.recordQuery("IsDKGSuccess", round, success)
+	return success
+}
+
+func (g *recordingGovernance) DKGResetCount(round uint64) uint64 {
+	count := g.Governance.DKGResetCount(round)
+	g.recordQuery("DKGResetCount", round, count)
+	return count
+}
+
+type recordingApplication struct {
+	core.Application
+
+	rec *Recorder
+}
+
+func (app *recordingApplication) PreparePayload(
+	position types.Position) ([]byte, error) {
+	payload, err := app.Application.PreparePayload(position)
+	if err == nil {
+		app.rec.record(&Entry{
+			Type:     EntryPayload,
+			Position: position,
+			Data:     common.CopyBytes(payload),
+		})
+	}
+	return payload, err
+}
+
+func (app *recordingApplication) PrepareWitness(
+	consensusHeight uint64) (types.Witness, error) {
+	witness, err := app.Application.PrepareWitness(consensusHeight)
+	if err == nil {
+		app.rec.recordJSON(&Entry{
+			Type: EntryWitness,
+			Arg:  consensusHeight,
+		}, witness)
+	}
+	return witness, err
+}
+
+func (app *recordingApplication) VerifyBlock(
+	block *types.Block) types.BlockVerifyStatus {
+	status := app.Application.VerifyBlock(block)
+	app.rec.record(&Entry{
+		Type: EntryVerify,
+		Hash: block.Hash,
+		Arg:  uint64(status),
+	})
+	return status
+}
+
+func (app *recordingApplication) BlockConfirmed(block types.Block) {
+	app.rec.record(&Entry{
+		Type:     EntryConfirmed,
+		Position: block.Position,
+		Hash:     block.Hash,
+	})
+	app.Application.BlockConfirmed(block)
+}
+
+func (app *recordingApplication) BlockDelivered(
+	hash common.Hash, position types.Position, rand []byte) {
+	app.rec.record(&Entry{
+		Type:     EntryDelivered,
+		Position: position,
+		Hash:     hash,
+		Data:     common.CopyBytes(rand),
+	})
+	app.Application.BlockDelivered(hash, position, rand)
+}
+
+// BlockReceived implements core.Debug interface.
+func (app *recordingApplication) BlockReceived(hash common.Hash) {
+	if debug, ok := app.Application.(core.Debug); ok {
+		debug.BlockReceived(hash)
+	}
+}
+
+// BlockReady implements core.Debug interface.
+func (app *recordingApplication) BlockReady(hash common.Hash) {
+	if debug, ok := app.Application.(core.Debug); ok {
+		debug.BlockReady(hash)
+	}
+}
+
+// recordSecret records an entry carrying DKG secrets to
+// RecorderConfig.DKGSecrets.
+func (r *Recorder) recordSecret(e *Entry, v interface{}) {
+	data, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if r.err == nil {
+			r.err = err
+		}
+		return
+	}
+	e.Data = data
+	r.write(r.secrets, e)
+}
+
+type recordingDatabase struct {
+	db.Database
+
+	rec *Recorder
+}
+
+func (d *recordingDatabase) PutOrUpdateDKGProtocol(
+	info db.DKGProtocolInfo) error {
+	if err := d.Database.PutOrUpdateDKGProtocol(info); err != nil {
+		return err
+	}
+	d.rec.recordSecret(&Entry{
+		Type:     EntryDKGProtocol,
+		Position: types.Position{Round: info.Round},
+		Arg:      info.Reset,
+	}, &info)
+	return nil
+}
+
+func (d *recordingDatabase) PutDKGPrivateKey(
+	round, reset uint64, prv dkg.PrivateKey) error {
+	if err := d.Database.PutDKGPrivateKey(round, reset, prv); err != nil {
+		return err
+	}
+	d.rec.recordSecret(&Entry{
+		Type:     EntryDKGPrivateKey,
+		Position: types.Position{Round: round},
+		Arg:      reset,
+	}, &prv)
+	return nil
+}
+
+type recordingClock struct {
+	common.Clock
+
+	rec *Recorder
+}
+
+func (c *recordingClock) NewTicker(d time.Duration) common.ClockTicker {
+	t := &recordingTicker{
+		ticker: c.Clock.NewTicker(d),
+		c:      make(chan time.Time, 1),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-t.done:
+				return
+			case tick := <-t.ticker.C():
+				// Like time.Ticker, ticks are dropped for slow receivers, only
+				// delivered ones are recorded. This routine is the only
+				// sender, so the room checked here is still there.
+				if len(t.c) < cap(t.c) {
+					c.rec.record(&Entry{Type: EntryTick, Arg: uint64(d)})
+					t.c <- tick
+				}
+			}
+		}
+	}()
+	return t
+}
+
+type recordingTicker struct {
+	ticker common.ClockTicker
+	c      chan time.Time
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (t *recordingTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *recordingTicker) Stop() {
+	t.once.Do(func() {
+		t.ticker.Stop()
+		close(t.done)
+	})
+}
@@ -0,0 +1,692 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/go-tangerine/rlp"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/ecdsa"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+)
+
+// Errors for replay.
+var (
+	ErrNodeIDMismatch = errors.New("node ID mismatch")
+	ErrReplayMismatch = errors.New("replay mismatch")
+)
+
+// ReplayConfig is the configuration to replay a trace.
+type ReplayConfig struct {
+	// PrivateKey is the key of the recorded node.
+	PrivateKey crypto.PrivateKey
+	Logger     common.Logger
+	// Timeout is the wall-clock time to wait for the consensus to reach the
+	// next recorded step, ex. receiving a message or proposing a block. The
+	// replay stops and reports the divergence when it's exceeded.
+	Timeout time.Duration
+	// DKGSecrets is the stream recorded to RecorderConfig.DKGSecrets along
+	// with the trace, DKG protocols of the recorded node are only available
+	// from it.
+	DKGSecrets io.Reader
+	// DKGKeys provides DKG private keys of the recorded node not found in
+	// DKGSecrets, ex. its keystore.
+	DKGKeys DKGKeySource
+}
+
+// DKGKeySource provides DKG private keys, it's implemented by
+// keystore.KeyStore and db.Database.
+type DKGKeySource interface {
+	GetDKGPrivateKey(round, reset uint64) (dkg.PrivateKey, error)
+}
+
+// ConfirmedBlock is a block confirmed by the consensus.
+type ConfirmedBlock struct {
+	Hash     common.Hash
+	Position types.Position
+}
+
+func (b ConfirmedBlock) String() string {
+	return fmt.Sprintf("%s@%s", b.Hash.String()[:6], &b.Position)
+}
+
+// ReplayResult contains blocks confirmed in the trace and in the replay.
+type ReplayResult struct {
+	Expected []ConfirmedBlock
+	Replayed []ConfirmedBlock
+	// Diverged is the recorded entry the consensus didn't reach in time, nil
+	// when the whole trace is replayed.
+	Diverged *Entry
+}
+
+// Verify makes sure the replay reproduces the same confirmed blocks, the
+// first divergence is reported if any.
+func (r *ReplayResult) Verify() error {
+	for idx, expected := range r.Expected {
+		if idx >= len(r.Replayed) {
+			return fmt.Errorf("%v: #%d %s is not confirmed, %d of %d confirmed",
+				ErrReplayMismatch, idx, expected, len(r.Replayed),
+				len(r.Expected))
+		}
+		if replayed := r.Replayed[idx]; replayed != expected {
+			return fmt.Errorf("%v: #%d expected %s, replayed %s",
+				ErrReplayMismatch, idx, expected, replayed)
+		}
+	}
+	if r.Diverged != nil {
+		return fmt.Errorf("%v: stuck at %s entry at %v",
+			ErrReplayMismatch, r.Diverged.Type, r.Diverged.Offset)
+	}
+	return nil
+}
+
+// Replay feeds a trace into a fresh core.Consensus instance, which is driven
+// by a simulated clock beginning at the moment the trace begins. The clock
+// only moves to the moments of recorded entries, messages and ticks are fed
+// one by one at those moments, and proposals and confirmations are awaited
+// before moving on. Governance queries are answered by results recorded no
+// later than the moment of queries, DKG protocols and private keys are
+// loaded from ReplayConfig, and messages sent by the consensus are dropped.
+// Rounds since DKGDelayRound are only reproduced when DKG protocols or keys
+// of those rounds are given.
+func Replay(r io.Reader, config ReplayConfig) (*ReplayResult, error) {
+	header, entries, err := ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if types.NewNodeID(config.PrivateKey.PublicKey()) != header.NodeID {
+		return nil, ErrNodeIDMismatch
+	}
+	if config.Logger == nil {
+		config.Logger = &common.NullLogger{}
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	var (
+		clock      = newReplayClock(header.Begin)
+		marshaller = test.NewDefaultMarshaller(nil)
+		result     = &ReplayResult{}
+		app        = newReplayApplication(entries)
+		gov        = newReplayGovernance(clock, header.Begin, entries)
+		network    = newReplayNetwork()
+	)
+	for _, e := range entries {
+		if e.Type == EntryConfirmed {
+			result.Expected = append(result.Expected, ConfirmedBlock{
+				Hash:     e.Hash,
+				Position: e.Position,
+			})
+		}
+	}
+	var secrets []*Entry
+	if config.DKGSecrets != nil {
+		var secretsHeader *Header
+		if secretsHeader, secrets, err = ReadAll(
+			config.DKGSecrets); err != nil {
+			return nil, err
+		}
+		if secretsHeader.NodeID != header.NodeID {
+			return nil, ErrNodeIDMismatch
+		}
+	}
+	memDB, err := db.NewMemBackedDB()
+	if err != nil {
+		return nil, err
+	}
+	dbInst, err := newReplayDatabase(memDB, secrets, config.DKGKeys)
+	if err != nil {
+		return nil, err
+	}
+	con := core.NewConsensusWithClock(header.DMoment, app, gov, dbInst,
+		network, config.PrivateKey, clock, config.Logger)
+	go con.Run(make(chan struct{}))
+	var (
+		confirmed int
+		proposed  = make(map[types.Position]int)
+	)
+Loop:
+	for _, e := range entries {
+		if d := header.Begin.Add(e.Offset).Sub(clock.Now()); d > 0 {
+			clock.Advance(d)
+		}
+		timeout := time.After(config.Timeout)
+		reached := true
+		switch e.Type {
+		case EntryMessage:
+			var payload interface{}
+			if payload, err = marshaller.Unmarshal(e.Key, e.Data); err != nil {
+				break Loop
+			}
+			select {
+			case network.recv <- types.Msg{PeerID: e.PeerID, Payload: payload}:
+			case <-timeout:
+				reached = false
+			}
+		case EntryTick:
+			reached = clock.tick(time.Duration(e.Arg), timeout)
+		case EntryPayload:
+			proposed[e.Position]++
+			count := proposed[e.Position]
+			reached = app.wait(func() bool {
+				return app.proposed[e.Position] >= count
+			}, timeout)
+		case EntryConfirmed:
+			confirmed++
+			reached = app.wait(func() bool {
+				return len(app.confirmed) >= confirmed
+			}, timeout)
+		}
+		if !reached {
+			result.Diverged = e
+			break
+		}
+	}
+	// Keep the clock moving until the consensus is stopped, or it might be
+	// blocked forever when sleeping.
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		con.Stop()
+		network.cancel()
+	}()
+	for {
+		select {
+		case <-stopped:
+			if err != nil {
+				return nil, err
+			}
+			result.Replayed = app.getConfirmed()
+			return result, nil
+		default:
+		}
+		if !clock.AdvanceToNext() {
+			runtime.Gosched()
+		}
+	}
+}
+
+// replayClock is a simulated clock whose tickers only fire when ticks are
+// replayed from the trace.
+type replayClock struct {
+	*test.SimulatedClock
+
+	lock    sync.Mutex
+	tickers []*replayTicker
+	changed chan struct{}
+}
+
+func newReplayClock(begin time.Time) *replayClock {
+	return &replayClock{
+		SimulatedClock: test.NewSimulatedClock(begin),
+		changed:        make(chan struct{}),
+	}
+}
+
+// NewTicker implements common.Clock interface.
+func (c *replayClock) NewTicker(d time.Duration) common.ClockTicker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &replayTicker{
+		clock:    c,
+		interval: d,
+		c:        make(chan time.Time),
+		done:     make(chan struct{}),
+	}
+	c.tickers = append(c.tickers, t)
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return t
+}
+
+// tick hands a tick to the oldest running ticker with the interval, it
+// returns false when no ticker takes it before timeout.
+func (c *replayClock) tick(
+	interval time.Duration, timeout <-chan time.Time) bool {
+	for {
+		var (
+			ticker  *replayTicker
+			changed <-chan struct{}
+		)
+		func() {
+			c.lock.Lock()
+			defer c.lock.Unlock()
+			for _, t := range c.tickers {
+				if t.interval == interval {
+					ticker = t
+					break
+				}
+			}
+			changed = c.changed
+		}()
+		if ticker == nil {
+			select {
+			case <-changed:
+				continue
+			case <-timeout:
+				return false
+			}
+		}
+		select {
+		case ticker.c <- c.Now():
+			return true
+		case <-ticker.done:
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func (c *replayClock) removeTicker(t *replayTicker) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for idx, ticker := range c.tickers {
+		if ticker == t {
+			c.tickers = append(c.tickers[:idx], c.tickers[idx+1:]...)
+			break
+		}
+	}
+}
+
+type replayTicker struct {
+	clock    *replayClock
+	interval time.Duration
+	c        chan time.Time
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (t *replayTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *replayTicker) Stop() {
+	t.once.Do(func() {
+		t.clock.removeTicker(t)
+		close(t.done)
+	})
+}
+
+// replayDatabase loads DKG protocols and private keys from recorded DKG
+// secrets, thus the DKG of the replayed consensus derives the same keys as
+// recorded ones. Keys not recorded are loaded from the key source.
+type replayDatabase struct {
+	db.Database
+
+	protocols []db.DKGProtocolInfo
+	prvKeys   map[[2]uint64]dkg.PrivateKey
+	keys      DKGKeySource
+}
+
+func newReplayDatabase(dbInst db.Database, entries []*Entry,
+	keys DKGKeySource) (*replayDatabase, error) {
+	d := &replayDatabase{
+		Database: dbInst,
+		prvKeys:  make(map[[2]uint64]dkg.PrivateKey),
+		keys:     keys,
+	}
+	for _, e := range entries {
+		switch e.Type {
+		case EntryDKGProtocol:
+			// Only the first one of each round and reset is kept, the
+			// progress after that is reproduced by replayed messages.
+			if n := len(d.protocols); n > 0 &&
+				!isNewerDKG(e.Position.Round, e.Arg, d.protocols[n-1]) {
+				break
+			}
+			info := db.DKGProtocolInfo{}
+			if err := rlp.DecodeBytes(e.Data, &info); err != nil {
+				return nil, err
+			}
+			d.protocols = append(d.protocols, info)
+		case EntryDKGPrivateKey:
+			prv := dkg.PrivateKey{}
+			if err := rlp.DecodeBytes(e.Data, &prv); err != nil {
+				return nil, err
+			}
+			d.prvKeys[[2]uint64{e.Position.Round, e.Arg}] = prv
+		}
+	}
+	return d, nil
+}
+
+func isNewerDKG(round, reset uint64, info db.DKGProtocolInfo) bool {
+	return round > info.Round || (round == info.Round && reset > info.Reset)
+}
+
+// GetDKGProtocol implements db.Database interface. DKG protocols are
+// registered in the order of rounds and resets, the recorded one following
+// the saved one is the one being registered.
+func (d *replayDatabase) GetDKGProtocol() (db.DKGProtocolInfo, error) {
+	info, err := d.Database.GetDKGProtocol()
+	if err != nil && err != db.ErrDKGProtocolDoesNotExist {
+		return info, err
+	}
+	for _, p := range d.protocols {
+		if err != nil || isNewerDKG(p.Round, p.Reset, info) {
+			return p, nil
+		}
+	}
+	return info, err
+}
+
+// GetDKGPrivateKey implements db.Database interface, recorded keys and then
+// keys from the key source are returned when not saved by the replayed
+// consensus.
+func (d *replayDatabase) GetDKGPrivateKey(
+	round, reset uint64) (dkg.PrivateKey, error) {
+	prv, err := d.Database.GetDKGPrivateKey(round, reset)
+	if err != db.ErrDKGPrivateKeyDoesNotExist {
+		return prv, err
+	}
+	if recorded, exists := d.prvKeys[[2]uint64{round, reset}]; exists {
+		return recorded, nil
+	}
+	if d.keys != nil {
+		return d.keys.GetDKGPrivateKey(round, reset)
+	}
+	return prv, err
+}
+
+type replayNetwork struct {
+	recv        chan types.Msg
+	badPeerChan chan interface{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func newReplayNetwork() *replayNetwork {
+	n := &replayNetwork{
+		recv:        make(chan types.Msg),
+		badPeerChan: make(chan interface{}, 1000),
+	}
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	go func() {
+		for {
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-n.badPeerChan:
+			}
+		}
+	}()
+	return n
+}
+
+func (n *replayNetwork) PullBlocks(common.Hashes)                        {}
+func (n *replayNetwork) PullVotes(types.Position)                        {}
+func (n *replayNetwork) BroadcastVote(*types.Vote)                       {}
+func (n *replayNetwork) BroadcastBlock(*types.Block)                     {}
+func (n *replayNetwork) BroadcastAgreementResult(*types.AgreementResult) {}
+func (n *replayNetwork) SendDKGPrivateShare(
+	crypto.PublicKey, *typesDKG.PrivateShare) {
+}
+func (n *replayNetwork) BroadcastDKGPrivateShare(*typesDKG.PrivateShare) {}
+func (n *replayNetwork) BroadcastDKGPartialSignature(
+	*typesDKG.PartialSignature) {
+}
+func (n *replayNetwork) ReceiveChan() <-chan types.Msg { return n.recv }
+
+func (n *replayNetwork) ReportBadPeerChan() chan<- interface{} {
+	return n.badPeerChan
+}
+
+type replayApplication struct {
+	payloads  map[types.Position][]byte
+	witnesses map[uint64][]byte
+	verified  map[common.Hash]types.BlockVerifyStatus
+	lock      sync.Mutex
+	changed   chan struct{}
+	proposed  map[types.Position]int
+	confirmed []ConfirmedBlock
+}
+
+func newReplayApplication(entries []*Entry) *replayApplication {
+	app := &replayApplication{
+		payloads:  make(map[types.Position][]byte),
+		witnesses: make(map[uint64][]byte),
+		verified:  make(map[common.Hash]types.BlockVerifyStatus),
+		changed:   make(chan struct{}),
+		proposed:  make(map[types.Position]int),
+	}
+	for _, e := range entries {
+		switch e.Type {
+		case EntryPayload:
+			app.payloads[e.Position] = e.Data
+		case EntryWitness:
+			app.witnesses[e.Arg] = e.Data
+		case EntryVerify:
+			app.verified[e.Hash] = types.BlockVerifyStatus(e.Arg)
+		}
+	}
+	return app
+}
+
+// notifyNoLock wakes up routines waiting for changes.
+func (app *replayApplication) notifyNoLock() {
+	close(app.changed)
+	app.changed = make(chan struct{})
+}
+
+// wait blocks until 'cond' is satisfied, it's checked under the lock. False
+// is returned when timeout.
+func (app *replayApplication) wait(
+	cond func() bool, timeout <-chan time.Time) bool {
+	for {
+		var changed <-chan struct{}
+		if func() bool {
+			app.lock.Lock()
+			defer app.lock.Unlock()
+			changed = app.changed
+			return cond()
+		}() {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func (app *replayApplication) PreparePayload(
+	position types.Position) ([]byte, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.proposed[position]++
+	app.notifyNoLock()
+	return common.CopyBytes(app.payloads[position]), nil
+}
+
+func (app *replayApplication) PrepareWitness(
+	consensusHeight uint64) (witness types.Witness, err error) {
+	if data, exists := app.witnesses[consensusHeight]; exists {
+		err = json.Unmarshal(data, &witness)
+	}
+	return
+}
+
+func (app *replayApplication) VerifyBlock(
+	block *types.Block) types.BlockVerifyStatus {
+	if status, exists := app.verified[block.Hash]; exists {
+		return status
+	}
+	return types.VerifyOK
+}
+
+func (app *replayApplication) BlockConfirmed(block types.Block) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.confirmed = append(app.confirmed, ConfirmedBlock{
+		Hash:     block.Hash,
+		Position: block.Position,
+	})
+	app.notifyNoLock()
+}
+
+func (app *replayApplication) BlockDelivered(
+	common.Hash, types.Position, []byte) {
+}
+
+func (app *replayApplication) getConfirmed() []ConfirmedBlock {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return append([]ConfirmedBlock{}, app.confirmed...)
+}
+
+type replayGovernanceKey struct {
+	method string
+	round  uint64
+}
+
+// replayGovernance answers queries by recorded results, the latest one
+// recorded no later than the moment of queries is used. The earliest one
+// is used when all of them are recorded later.
+type replayGovernance struct {
+	clock   common.Clock
+	begin   time.Time
+	answers map[replayGovernanceKey][]*Entry
+}
+
+func newReplayGovernance(clock common.Clock, begin time.Time,
+	entries []*Entry) *replayGovernance {
+	g := &replayGovernance{
+		clock:   clock,
+		begin:   begin,
+		answers: make(map[replayGovernanceKey][]*Entry),
+	}
+	for _, e := range entries {
+		if e.Type != EntryGovernance {
+			continue
+		}
+		key := replayGovernanceKey{method: e.Key, round: e.Arg}
+		g.answers[key] = append(g.answers[key], e)
+	}
+	return g
+}
+
+func (g *replayGovernance) answer(
+	method string, round uint64, result interface{}) bool {
+	answers := g.answers[replayGovernanceKey{method: method, round: round}]
+	if len(answers) == 0 {
+		return false
+	}
+	offset := g.clock.Now().Sub(g.begin)
+	idx := sort.Search(len(answers), func(i int) bool {
+		return answers[i].Offset > offset
+	})
+	if idx > 0 {
+		idx--
+	}
+	if err := json.Unmarshal(answers[idx].Data, result); err != nil {
+		panic(err)
+	}
+	return true
+}
+
+func (g *replayGovernance) Configuration(round uint64) *types.Config {
+	var config *types.Config
+	g.answer("Configuration", round, &config)
+	return config
+}
+
+func (g *replayGovernance) CRS(round uint64) (crs common.Hash) {
+	g.answer("CRS", round, &crs)
+	return
+}
+
+func (g *replayGovernance) NodeSet(round uint64) []crypto.PublicKey {
+	var keys [][]byte
+	if !g.answer("NodeSet", round, &keys) {
+		return nil
+	}
+	nodeSet := make([]crypto.PublicKey, 0, len(keys))
+	for _, k := range keys {
+		pubKey, err := ecdsa.NewPublicKeyFromByteSlice(k)
+		if err != nil {
+			panic(err)
+		}
+		nodeSet = append(nodeSet, pubKey)
+	}
+	return nodeSet
+}
+
+func (g *replayGovernance) GetRoundHeight(round uint64) (height uint64) {
+	g.answer("GetRoundHeight", round, &height)
+	return
+}
+
+func (g *replayGovernance) DKGComplaints(
+	round uint64) (complaints []*typesDKG.Complaint) {
+	g.answer("DKGComplaints", round, &complaints)
+	return
+}
+
+func (g *replayGovernance) DKGMasterPublicKeys(
+	round uint64) (mpks []*typesDKG.MasterPublicKey) {
+	g.answer("DKGMasterPublicKeys", round, &mpks)
+	return
+}
+
+func (g *replayGovernance) IsDKGMPKReady(round uint64) (ready bool) {
+	g.answer("IsDKGMPKReady", round, &ready)
+	return
+}
+
+func (g *replayGovernance) IsDKGFinal(round uint64) (final bool) {
+	g.answer("IsDKGFinal", round, &final)
+	return
+}
+
+func (g *replayGovernance) IsDKGSuccess(round uint64) (success bool) {
+	g.answer("IsDKGSuccess", round, &success)
+	return
+}
+
+func (g *replayGovernance) DKGResetCount(round uint64) (count uint64) {
+	g.answer("DKGResetCount", round, &count)
+	return
+}
+
+// Proposals from the replayed consensus are dropped, results of governance
+// are decided by the trace.
+func (g *replayGovernance) ProposeCRS(uint64, []byte)                       {}
+func (g *replayGovernance) AddDKGComplaint(*typesDKG.Complaint)             {}
+func (g *replayGovernance) AddDKGMasterPublicKey(*typesDKG.MasterPublicKey) {}
+func (g *replayGovernance) AddDKGMPKReady(*typesDKG.MPKReady)               {}
+func (g *replayGovernance) AddDKGFinalize(*typesDKG.Finalize)               {}
+func (g *replayGovernance) AddDKGSuccess(*typesDKG.Success)                 {}
+func (g *replayGovernance) ReportForkVote(*types.Vote, *types.Vote)         {}
+func (g *replayGovernance) ReportForkBlock(*types.Block, *types.Block)      {}
+func (g *replayGovernance) ResetDKG([]byte)                                 {}
@@ -0,0 +1,212 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// Version is the version of trace format.
+const Version uint32 = 1
+
+// Errors for trace.
+var (
+	ErrUnsupportedVersion = errors.New("unsupported trace version")
+	ErrWriterClosed       = errors.New("trace writer closed")
+)
+
+// EntryType is the type of entries in a trace.
+type EntryType uint8
+
+// EntryType enums.
+const (
+	// EntryMessage is a message received from Network.ReceiveChan.
+	EntryMessage EntryType = iota
+	// EntryGovernance is the result of a query to Governance.
+	EntryGovernance
+	// EntryTick is a firing of tickers.
+	EntryTick
+	// EntryPayload is the result of Application.PreparePayload.
+	EntryPayload
+	// EntryWitness is the result of Application.PrepareWitness.
+	EntryWitness
+	// EntryVerify is the result of Application.VerifyBlock.
+	EntryVerify
+	// EntryConfirmed is a block passed to Application.BlockConfirmed.
+	EntryConfirmed
+	// EntryDelivered is a block passed to Application.BlockDelivered.
+	EntryDelivered
+	// EntryDKGProtocol is a DKG protocol saved to db.Database, it's only
+	// recorded to RecorderConfig.DKGSecrets.
+	EntryDKGProtocol
+	// EntryDKGPrivateKey is a DKG private key saved to db.Database, it's only
+	// recorded to RecorderConfig.DKGSecrets.
+	EntryDKGPrivateKey
+)
+
+func (t EntryType) String() string {
+	switch t {
+	case EntryMessage:
+		return "message"
+	case EntryGovernance:
+		return "governance"
+	case EntryTick:
+		return "tick"
+	case EntryPayload:
+		return "payload"
+	case EntryWitness:
+		return "witness"
+	case EntryVerify:
+		return "verify"
+	case EntryConfirmed:
+		return "confirmed"
+	case EntryDelivered:
+		return "delivered"
+	case EntryDKGProtocol:
+		return "dkg-protocol"
+	case EntryDKGPrivateKey:
+		return "dkg-private-key"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// Header is the first record of a trace.
+type Header struct {
+	Version uint32
+	NodeID  types.NodeID
+	DMoment time.Time
+	// Begin is the moment to begin recording, offsets of entries are
+	// relative to it.
+	Begin time.Time
+}
+
+// Entry is a record in a trace, fields not used by its type are left empty.
+type Entry struct {
+	Type   EntryType
+	Offset time.Duration
+	// Key is the type of messages, or the name of governance methods.
+	Key      string
+	PeerID   types.NodeID
+	Arg      uint64
+	Position types.Position
+	Hash     common.Hash
+	Data     []byte
+}
+
+// Writer writes a gzipped stream of gob-encoded header and entries.
+type Writer struct {
+	gz     *gzip.Writer
+	enc    *gob.Encoder
+	lock   sync.Mutex
+	closed bool
+}
+
+// NewWriter constructs a Writer instance and writes the header.
+func NewWriter(w io.Writer, header *Header) (*Writer, error) {
+	gz := gzip.NewWriter(w)
+	writer := &Writer{gz: gz, enc: gob.NewEncoder(gz)}
+	header.Version = Version
+	if err := writer.enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write appends an entry.
+func (w *Writer) Write(e *Entry) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	return w.enc.Encode(e)
+}
+
+// Close flushes the stream, the underlying writer is not closed.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.gz.Close()
+}
+
+// Reader reads a trace written by Writer.
+type Reader struct {
+	Header Header
+	dec    *gob.Decoder
+}
+
+// NewReader constructs a Reader instance and reads the header.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := &Reader{dec: gob.NewDecoder(gz)}
+	if err = reader.dec.Decode(&reader.Header); err != nil {
+		return nil, err
+	}
+	if reader.Header.Version != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	return reader, nil
+}
+
+// Read returns the next entry, io.EOF is returned at the end of the trace.
+func (r *Reader) Read() (*Entry, error) {
+	e := &Entry{}
+	if err := r.dec.Decode(e); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			// The trace is truncated, ex. the recording node crashed.
+			err = io.EOF
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// ReadAll reads the header and all entries of a trace.
+func ReadAll(r io.Reader) (header *Header, entries []*Entry, err error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return
+	}
+	header = &reader.Header
+	for {
+		var e *Entry
+		if e, err = reader.Read(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto/dkg"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+type TraceTestSuite struct {
+	suite.Suite
+}
+
+func (s *TraceTestSuite) TestWriterReader() {
+	var (
+		buf    bytes.Buffer
+		nID    = types.NodeID{Hash: common.NewRandomHash()}
+		now    = time.Now().UTC()
+		header = &Header{NodeID: nID, DMoment: now, Begin: now}
+	)
+	w, err := NewWriter(&buf, header)
+	s.Require().NoError(err)
+	entries := []*Entry{
+		{Type: EntryTick, Offset: time.Second, Arg: 100},
+		{
+			Type:     EntryConfirmed,
+			Offset:   2 * time.Second,
+			Position: types.Position{Round: 1, Height: 2},
+			Hash:     common.NewRandomHash(),
+		},
+		{
+			Type:   EntryMessage,
+			Offset: 3 * time.Second,
+			Key:    "vote",
+			PeerID: nID,
+			Data:   []byte("{}"),
+		},
+	}
+	for _, e := range entries {
+		s.Require().NoError(w.Write(e))
+	}
+	s.Require().NoError(w.Close())
+	s.Require().Equal(ErrWriterClosed, w.Write(entries[0]))
+	data := buf.Bytes()
+	readHeader, readEntries, err := ReadAll(bytes.NewReader(data))
+	s.Require().NoError(err)
+	s.Require().Equal(Version, readHeader.Version)
+	s.Require().Equal(nID, readHeader.NodeID)
+	s.Require().True(now.Equal(readHeader.Begin))
+	s.Require().Equal(entries, readEntries)
+	// A truncated trace should be readable till the broken entry.
+	r, err := NewReader(bytes.NewReader(data[:len(data)-10]))
+	s.Require().NoError(err)
+	for {
+		var e *Entry
+		if e, err = r.Read(); err != nil {
+			break
+		}
+		s.Require().Equal(entries[0].Type, e.Type)
+		entries = entries[1:]
+	}
+	s.Require().Equal(io.EOF, err)
+	s.Require().NotEmpty(entries)
+}
+
+func (s *TraceTestSuite) TestRecorder() {
+	var (
+		buf   bytes.Buffer
+		now   = time.Now().UTC()
+		clock = test.NewSimulatedClock(now)
+	)
+	prvKeys, pubKeys, err := test.NewKeys(4)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(1, pubKeys, time.Second,
+		&common.NullLogger{}, true), 2)
+	s.Require().NoError(err)
+	nID := types.NewNodeID(prvKeys[0].PublicKey())
+	rec, err := NewRecorder(&buf, nID, now, clock)
+	s.Require().NoError(err)
+	var (
+		recGov = rec.Governance(gov)
+		recApp = rec.Application(test.NewApp(0, nil, nil))
+		inner  = newReplayNetwork()
+		recNet = rec.Network(inner)
+		block  = &types.Block{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Height: 1},
+		}
+		vote = types.NewVote(types.VoteInit, common.NewRandomHash(), 1)
+	)
+	defer inner.cancel()
+	// Governance queries.
+	config := recGov.Configuration(0)
+	nodeSet := recGov.NodeSet(0)
+	crs := recGov.CRS(0)
+	// Application decisions.
+	clock.Advance(time.Second)
+	payload, err := recApp.PreparePayload(block.Position)
+	s.Require().NoError(err)
+	status := recApp.VerifyBlock(block)
+	recApp.BlockConfirmed(*block)
+	// Inbound messages.
+	inner.recv <- types.Msg{PeerID: nID, Payload: vote}
+	msg := <-recNet.ReceiveChan()
+	s.Require().Equal(vote, msg.Payload)
+	// Ticker firings.
+	ticker := rec.Clock().NewTicker(time.Second)
+	clock.Advance(time.Second)
+	<-ticker.C()
+	ticker.Stop()
+	s.Require().NoError(rec.Close())
+	// Check the recorded trace.
+	header, entries, err := ReadAll(&buf)
+	s.Require().NoError(err)
+	s.Require().Equal(nID, header.NodeID)
+	entryTypes := []EntryType{}
+	for _, e := range entries {
+		entryTypes = append(entryTypes, e.Type)
+	}
+	s.Require().Equal([]EntryType{
+		EntryGovernance, EntryGovernance, EntryGovernance,
+		EntryPayload, EntryVerify, EntryConfirmed,
+		EntryMessage, EntryTick,
+	}, entryTypes)
+	s.Require().Equal(time.Second, entries[3].Offset)
+	s.Require().Equal(block.Hash, entries[5].Hash)
+	s.Require().Equal(nID, entries[6].PeerID)
+	s.Require().Equal(uint64(time.Second), entries[7].Arg)
+	// Recorded results should be answered in replay.
+	replayGov := newReplayGovernance(clock, header.Begin, entries)
+	s.Require().Equal(config, replayGov.Configuration(0))
+	s.Require().Equal(crs, replayGov.CRS(0))
+	s.Require().Len(replayGov.NodeSet(0), len(nodeSet))
+	for idx, key := range replayGov.NodeSet(0) {
+		s.Require().Equal(nodeSet[idx].Bytes(), key.Bytes())
+	}
+	s.Require().Nil(replayGov.Configuration(1))
+	replayApp := newReplayApplication(entries)
+	replayedPayload, err := replayApp.PreparePayload(block.Position)
+	s.Require().NoError(err)
+	s.Require().Equal(payload, replayedPayload)
+	s.Require().Equal(status, replayApp.VerifyBlock(block))
+}
+
+func (s *TraceTestSuite) TestReplayDatabase() {
+	var (
+		buf     bytes.Buffer
+		secrets bytes.Buffer
+		now     = time.Now().UTC()
+		nID     = types.NodeID{Hash: common.NewRandomHash()}
+		prv     = dkg.NewPrivateKey()
+		prv2    = dkg.NewPrivateKey()
+		dkgOf   = func(round, reset, step uint64) db.DKGProtocolInfo {
+			return db.DKGProtocolInfo{
+				ID:    nID,
+				Round: round,
+				Reset: reset,
+				Step:  step,
+			}
+		}
+	)
+	// DKG secrets are not recorded by default.
+	rec, err := NewRecorder(&buf, nID, now, test.NewSimulatedClock(now))
+	s.Require().NoError(err)
+	recordDB, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().Equal(db.Database(recordDB), rec.Database(recordDB))
+	s.Require().NoError(rec.Close())
+	_, entries, err := ReadAll(&buf)
+	s.Require().NoError(err)
+	s.Require().Empty(entries)
+	buf.Reset()
+	rec, err = NewRecorderWithConfig(&buf, nID, now, RecorderConfig{
+		Clock:      test.NewSimulatedClock(now),
+		DKGSecrets: &secrets,
+	})
+	s.Require().NoError(err)
+	recDB := rec.Database(recordDB)
+	s.Require().NoError(recDB.PutOrUpdateDKGProtocol(dkgOf(1, 0, 0)))
+	s.Require().NoError(recDB.PutOrUpdateDKGProtocol(dkgOf(1, 0, 3)))
+	s.Require().NoError(recDB.PutDKGPrivateKey(1, 0, *prv))
+	s.Require().NoError(recDB.PutOrUpdateDKGProtocol(dkgOf(2, 0, 0)))
+	s.Require().NoError(recDB.PutOrUpdateDKGProtocol(dkgOf(2, 1, 0)))
+	s.Require().NoError(rec.Close())
+	// DKG secrets are only recorded to the secrets stream.
+	_, entries, err = ReadAll(&buf)
+	s.Require().NoError(err)
+	s.Require().Empty(entries)
+	header, entries, err := ReadAll(&secrets)
+	s.Require().NoError(err)
+	s.Require().Equal(nID, header.NodeID)
+	s.Require().Len(entries, 5)
+	replayDB, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	keys, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NoError(keys.PutDKGPrivateKey(2, 1, *prv2))
+	dbInst, err := newReplayDatabase(replayDB, entries, keys)
+	s.Require().NoError(err)
+	// The first recorded DKG protocol after the saved one is returned.
+	check := func(round, reset, step uint64) {
+		info, err := dbInst.GetDKGProtocol()
+		s.Require().NoError(err)
+		s.Require().Equal(nID, info.ID)
+		s.Require().Equal(round, info.Round)
+		s.Require().Equal(reset, info.Reset)
+		s.Require().Equal(step, info.Step)
+	}
+	check(1, 0, 0)
+	s.Require().NoError(dbInst.PutOrUpdateDKGProtocol(dkgOf(1, 0, 2)))
+	check(2, 0, 0)
+	s.Require().NoError(dbInst.PutOrUpdateDKGProtocol(dkgOf(2, 0, 1)))
+	check(2, 1, 0)
+	s.Require().NoError(dbInst.PutOrUpdateDKGProtocol(dkgOf(2, 1, 1)))
+	check(2, 1, 1)
+	// Recorded DKG private keys are returned when not saved.
+	key, err := dbInst.GetDKGPrivateKey(1, 0)
+	s.Require().NoError(err)
+	s.Require().Equal(prv.Bytes(), key.Bytes())
+	// Keys not recorded are loaded from the key source.
+	key, err = dbInst.GetDKGPrivateKey(2, 1)
+	s.Require().NoError(err)
+	s.Require().Equal(prv2.Bytes(), key.Bytes())
+	_, err = dbInst.GetDKGPrivateKey(2, 0)
+	s.Require().Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+}
+
+func (s *TraceTestSuite) TestVerify() {
+	blocks := []ConfirmedBlock{}
+	for i := uint64(0); i < 3; i++ {
+		blocks = append(blocks, ConfirmedBlock{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Height: i},
+		})
+	}
+	result := &ReplayResult{Expected: blocks, Replayed: blocks}
+	s.Require().NoError(result.Verify())
+	result.Replayed = blocks[:2]
+	s.Require().Error(result.Verify())
+	result.Replayed = append([]ConfirmedBlock{}, blocks...)
+	result.Replayed[1].Hash = common.NewRandomHash()
+	s.Require().Error(result.Verify())
+}
+
+func TestTrace(t *testing.T) {
+	suite.Run(t, new(TraceTestSuite))
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 )
 
 // AgreementResult describes an agremeent result.
@@ -31,6 +32,30 @@ type AgreementResult struct {
 	Votes        []Vote      `json:"votes"`
 	IsEmptyBlock bool        `json:"is_empty_block"`
 	Randomness   []byte      `json:"randomness"`
+	// Certificate proves the randomness of results after DKGDelayRound, it's
+	// missing when the randomness is recovered from DKG partial signatures
+	// instead of votes.
+	Certificate *AgreementCertificate `json:"certificate,omitempty" rlp:"nil"`
+}
+
+// Clone returns a deep copy of this agreement result.
+func (r *AgreementResult) Clone() *AgreementResult {
+	rcopy := &AgreementResult{
+		BlockHash:    r.BlockHash,
+		Position:     r.Position,
+		IsEmptyBlock: r.IsEmptyBlock,
+		Randomness:   common.CopyBytes(r.Randomness),
+	}
+	if r.Votes != nil {
+		rcopy.Votes = make([]Vote, 0, len(r.Votes))
+		for _, v := range r.Votes {
+			rcopy.Votes = append(rcopy.Votes, *v.Clone())
+		}
+	}
+	if r.Certificate != nil {
+		rcopy.Certificate = r.Certificate.Clone()
+	}
+	return rcopy
 }
 
 func (r *AgreementResult) String() string {
@@ -42,3 +67,99 @@ func (r *AgreementResult) String() string {
 		r.BlockHash.String()[:6], r.Position,
 		hex.EncodeToString(r.Randomness)[:6])
 }
+
+// AgreementCertificate is the compact form of AgreementResult. Instead of
+// carrying all votes, the partial signatures of commit votes are recovered
+// into one threshold signature, and the voters are recorded in a bitmap
+// indexed by the order of sorted notary set. The threshold signature only
+// covers the block hash, the bitmap is signed by the notary producing this
+// certificate.
+type AgreementCertificate struct {
+	BlockHash         common.Hash      `json:"block_hash"`
+	Position          Position         `json:"position"`
+	IsEmptyBlock      bool             `json:"is_empty_block"`
+	Signers           []byte           `json:"signers"`
+	Signature         []byte           `json:"signature"`
+	ProposerID        NodeID           `json:"proposer_id"`
+	ProposerSignature crypto.Signature `json:"proposer_signature"`
+}
+
+// Clone returns a deep copy of this certificate.
+func (c *AgreementCertificate) Clone() *AgreementCertificate {
+	return &AgreementCertificate{
+		BlockHash:         c.BlockHash,
+		Position:          c.Position,
+		IsEmptyBlock:      c.IsEmptyBlock,
+		Signers:           common.CopyBytes(c.Signers),
+		Signature:         common.CopyBytes(c.Signature),
+		ProposerID:        c.ProposerID,
+		ProposerSignature: c.ProposerSignature.Clone(),
+	}
+}
+
+// Signed checks if the idx-th node in sorted notary set is a signer.
+func (c *AgreementCertificate) Signed(idx int) bool {
+	if idx < 0 || idx/8 >= len(c.Signers) {
+		return false
+	}
+	return c.Signers[idx/8]&(1<<uint(idx%8)) != 0
+}
+
+// SignerCount returns the count of signers.
+func (c *AgreementCertificate) SignerCount() (count int) {
+	for _, b := range c.Signers {
+		for ; b != 0; b &= b - 1 {
+			count++
+		}
+	}
+	return
+}
+
+// AgreementResult converts this certificate to an AgreementResult without
+// votes, the threshold signature is the randomness of that block, and this
+// certificate is attached as its proof.
+func (c *AgreementCertificate) AgreementResult() *AgreementResult {
+	return &AgreementResult{
+		BlockHash:    c.BlockHash,
+		Position:     c.Position,
+		IsEmptyBlock: c.IsEmptyBlock,
+		Randomness:   c.Signature,
+		Certificate:  c,
+	}
+}
+
+func (c *AgreementCertificate) String() string {
+	return fmt.Sprintf("agreementCertificate{Block:%s Pos:%s Signers:%d}",
+		c.BlockHash.String()[:6], c.Position, c.SignerCount())
+}
+
+// BlockRange is one page of consecutive finalized blocks with their agreement
+// certificates, it's the response to pulling blocks by height range. Each
+// block refers to the hash of the previous one, and blocks after
+// DKGDelayRound are proven finalized by their agreement certificates.
+type BlockRange struct {
+	// From and To is the requested height range, [From, To).
+	From   uint64   `json:"from"`
+	To     uint64   `json:"to"`
+	Blocks []*Block `json:"blocks"`
+	// Certificates are agreement certificates of blocks after DKGDelayRound,
+	// in the same order as those blocks. Blocks before DKGDelayRound come
+	// first and have no certificates.
+	Certificates []*AgreementCertificate `json:"certificates"`
+}
+
+// Next returns the height of the first block not in this page, and if there
+// are remaining blocks in the requested range. A page without blocks means
+// the peer has nothing more to serve.
+func (r *BlockRange) Next() (uint64, bool) {
+	if len(r.Blocks) == 0 {
+		return r.From, false
+	}
+	next := r.Blocks[len(r.Blocks)-1].Position.Height + 1
+	return next, next < r.To
+}
+
+func (r *BlockRange) String() string {
+	return fmt.Sprintf("blockRange{From:%d To:%d Blocks:%d}",
+		r.From, r.To, len(r.Blocks))
+}
@@ -22,3 +22,44 @@ type Msg struct {
 	PeerID  interface{}
 	Payload interface{}
 }
+
+// Offense is the kind of misbehavior a peer is reported for.
+type Offense int
+
+// Offense enums.
+const (
+	OffenseUnknown Offense = iota
+	OffenseBadSignature
+	OffenseWrongRandomness
+	OffenseInvalidEmptyBlockHash
+	OffenseUnsolicitedDKGShare
+	OffenseInvalidMessage
+	// Do not add any type below MaxOffense.
+	MaxOffense
+)
+
+func (o Offense) String() string {
+	switch o {
+	case OffenseUnknown:
+		return "Unknown"
+	case OffenseBadSignature:
+		return "BadSignature"
+	case OffenseWrongRandomness:
+		return "WrongRandomness"
+	case OffenseInvalidEmptyBlockHash:
+		return "InvalidEmptyBlockHash"
+	case OffenseUnsolicitedDKGShare:
+		return "UnsolicitedDKGShare"
+	case OffenseInvalidMessage:
+		return "InvalidMessage"
+	}
+	return "Invalid"
+}
+
+// BadPeerReport describes why a peer is reported, it's passed to networks
+// implementing core.BadPeerReporter.
+type BadPeerReport struct {
+	PeerID  interface{}
+	Offense Offense
+	Err     error
+}
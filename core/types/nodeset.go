@@ -18,9 +18,11 @@
 package types
 
 import (
+	"bytes"
 	"container/heap"
 	"encoding/binary"
 	"math/big"
+	"math/bits"
 
 	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
@@ -29,6 +31,8 @@ import (
 // NodeSet is the node set structure as defined in DEXON consensus core.
 type NodeSet struct {
 	IDs map[NodeID]struct{}
+	// Weights of nodes, nodes not found are weighted by 1.
+	Weights map[NodeID]uint64
 }
 
 // SubSetTarget is the sub set target for GetSubSet().
@@ -43,16 +47,27 @@ const (
 	targetNodeLeader
 )
 
+// Count of fractional bits of fixed-point logarithms in weighted ranks.
+const rankLogFracBits = 48
+
 type nodeRank struct {
 	ID   NodeID
 	rank *big.Int
 }
 
+// less compares ranks, ties are broken by node IDs to be deterministic.
+func (r *nodeRank) less(other *nodeRank) bool {
+	if c := r.rank.Cmp(other.rank); c != 0 {
+		return c < 0
+	}
+	return bytes.Compare(r.ID.Hash[:], other.ID.Hash[:]) < 0
+}
+
 // rankHeap is a MaxHeap structure.
 type rankHeap []*nodeRank
 
 func (h rankHeap) Len() int           { return len(h) }
-func (h rankHeap) Less(i, j int) bool { return h[i].rank.Cmp(h[j].rank) > 0 }
+func (h rankHeap) Less(i, j int) bool { return h[j].less(h[i]) }
 func (h rankHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 func (h *rankHeap) Push(x interface{}) {
 	*h = append(*h, x.(*nodeRank))
@@ -100,16 +115,51 @@ func (ns *NodeSet) Add(ID NodeID) {
 	ns.IDs[ID] = struct{}{}
 }
 
+// AddWithWeight adds a NodeID with its weight to the set.
+func (ns *NodeSet) AddWithWeight(ID NodeID, weight uint64) {
+	ns.IDs[ID] = struct{}{}
+	if ns.Weights == nil {
+		ns.Weights = make(map[NodeID]uint64)
+	}
+	ns.Weights[ID] = weight
+}
+
+// Weight returns the weight of a node in the set.
+func (ns *NodeSet) Weight(ID NodeID) uint64 {
+	if _, exist := ns.IDs[ID]; !exist {
+		return 0
+	}
+	if weight, exist := ns.Weights[ID]; exist {
+		return weight
+	}
+	return 1
+}
+
+// TotalWeight returns the sum of weights of the given nodes.
+func (ns *NodeSet) TotalWeight(IDs map[NodeID]struct{}) (total uint64) {
+	for ID := range IDs {
+		total += ns.Weight(ID)
+	}
+	return
+}
+
 // Clone the NodeSet.
 func (ns *NodeSet) Clone() *NodeSet {
 	nsCopy := NewNodeSet()
 	for ID := range ns.IDs {
 		nsCopy.Add(ID)
 	}
+	for ID, weight := range ns.Weights {
+		nsCopy.AddWithWeight(ID, weight)
+	}
 	return nsCopy
 }
 
-// GetSubSet returns the subset of given target.
+// GetSubSet returns the subset of given target. When weights are given, it's
+// a weighted random sampling without replacement: each pick selects one of
+// the remaining nodes with probability proportional to its weight; nodes
+// with zero weight are never selected; nodes absent from Weights are
+// weighted 1.
 func (ns *NodeSet) GetSubSet(
 	size int, target *SubSetTarget) map[NodeID]struct{} {
 	if size == 0 {
@@ -118,14 +168,23 @@ func (ns *NodeSet) GetSubSet(
 	h := rankHeap{}
 	idx := 0
 	for nID := range ns.IDs {
+		var rank *nodeRank
+		if ns.Weights == nil {
+			rank = newNodeRank(nID, target)
+		} else {
+			weight := ns.Weight(nID)
+			if weight == 0 {
+				continue
+			}
+			rank = newWeightedNodeRank(nID, weight, target)
+		}
 		if idx < size {
-			h = append(h, newNodeRank(nID, target))
+			h = append(h, rank)
 		} else if idx == size {
 			heap.Init(&h)
 		}
 		if idx >= size {
-			rank := newNodeRank(nID, target)
-			if rank.rank.Cmp(h[0].rank) < 0 {
+			if rank.less(h[0]) {
 				h[0] = rank
 				heap.Fix(&h, 0)
 			}
@@ -160,3 +219,48 @@ func newNodeRank(ID NodeID, target *SubSetTarget) *nodeRank {
 		rank: num,
 	}
 }
+
+// newWeightedNodeRank ranks a node by -log2(1-u)/weight, where u in [0, 1)
+// is derived from the hash ranking it without weights. The ranks are
+// exponentially distributed with rates proportional to weights, thus the
+// node with the lowest rank is selected with probability proportional to its
+// weight. The logarithm is computed in fixed-point integers to be identical
+// on all platforms, its error is below 2^-48.
+func newWeightedNodeRank(
+	ID NodeID, weight uint64, target *SubSetTarget) *nodeRank {
+	rank := newNodeRank(ID, target)
+	// 1-u = (2^256 - hash) / 2^256.
+	remain := new(big.Int).Lsh(big.NewInt(1), 256)
+	remain.Sub(remain, rank.rank)
+	logRemain := (uint64(256) << rankLogFracBits) - log2Fixed(remain)
+	rank.rank.SetUint64(logRemain)
+	rank.rank.Lsh(rank.rank, 64)
+	rank.rank.Div(rank.rank, new(big.Int).SetUint64(weight))
+	return rank
+}
+
+// log2Fixed computes log2(x) of a positive integer in fixed-point with
+// rankLogFracBits fractional bits, the result is truncated.
+func log2Fixed(x *big.Int) uint64 {
+	// Normalize x to m/2^62 in [1, 2).
+	const normBits = 62
+	n := uint(x.BitLen() - 1)
+	var m uint64
+	if n >= normBits {
+		m = new(big.Int).Rsh(x, n-normBits).Uint64()
+	} else {
+		m = x.Uint64() << (normBits - n)
+	}
+	result := uint64(n) << rankLogFracBits
+	// Each squaring of m doubles its logarithm, the integer part of the
+	// doubled one is the next bit.
+	for bit := uint64(1) << (rankLogFracBits - 1); bit > 0; bit >>= 1 {
+		hi, lo := bits.Mul64(m, m)
+		m = hi<<(64-normBits) | lo>>normBits
+		if m >= 2<<normBits {
+			m >>= 1
+			result |= bit
+		}
+	}
+	return result
+}
@@ -18,6 +18,7 @@
 package types
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -65,6 +66,72 @@ func (s *NodeSetTestSuite) TestGetSubSetZeroSize() {
 	s.Len(emptySet, 0)
 }
 
+func (s *NodeSetTestSuite) TestGetWeightedSubSet() {
+	total := 10
+	nodes := NewNodeSet()
+	for len(nodes.IDs) < total {
+		nodes.Add(NodeID{common.NewRandomHash()})
+	}
+	target := NewNotarySetTarget(common.NewRandomHash())
+	size := 4
+	// Nodes weighted by 1 are identical to nodes without weights.
+	weighted := nodes.Clone()
+	for nID := range weighted.IDs {
+		weighted.AddWithWeight(nID, 1)
+	}
+	s.Equal(nodes.GetSubSet(size, target), weighted.GetSubSet(size, target))
+	// Nodes weighted by 0 are never selected, and heavy nodes are always
+	// selected.
+	var zero, heavy NodeID
+	for nID := range weighted.IDs {
+		if zero == (NodeID{}) {
+			zero = nID
+			weighted.AddWithWeight(nID, 0)
+		} else if heavy == (NodeID{}) {
+			heavy = nID
+			weighted.AddWithWeight(nID, 1<<62)
+		}
+	}
+	s.Equal(uint64(1<<62+total-2), weighted.TotalWeight(weighted.IDs))
+	for i := 0; i < 100; i++ {
+		target := NewNotarySetTarget(common.NewRandomHash())
+		subSet := weighted.GetSubSet(size, target)
+		s.Len(subSet, size)
+		s.NotContains(subSet, zero)
+		s.Contains(subSet, heavy)
+		// Selection is deterministic.
+		s.Equal(subSet, weighted.Clone().GetSubSet(size, target))
+	}
+}
+
+func (s *NodeSetTestSuite) TestWeightedSubSetProportional() {
+	nodes := NewNodeSet()
+	light := NodeID{common.NewRandomHash()}
+	heavy := NodeID{common.NewRandomHash()}
+	nodes.AddWithWeight(light, 1)
+	nodes.AddWithWeight(heavy, 3)
+	// The light node should be selected in about 1/4 of targets.
+	count := 0
+	trials := 4000
+	for i := 0; i < trials; i++ {
+		target := NewNotarySetTarget(common.NewRandomHash())
+		if _, exist := nodes.GetSubSet(1, target)[light]; exist {
+			count++
+		}
+	}
+	s.InDelta(0.25, float64(count)/float64(trials), 0.05)
+}
+
+func (s *NodeSetTestSuite) TestLog2Fixed() {
+	one := uint64(1) << rankLogFracBits
+	s.Equal(uint64(0), log2Fixed(big.NewInt(1)))
+	s.Equal(10*one, log2Fixed(big.NewInt(1024)))
+	s.Equal(256*one, log2Fixed(new(big.Int).Lsh(big.NewInt(1), 256)))
+	// log2(3) = 1.584962500721156...
+	s.InDelta(1.584962500721156,
+		float64(log2Fixed(big.NewInt(3)))/float64(one), 1e-12)
+}
+
 func TestNodeSet(t *testing.T) {
 	suite.Run(t, new(NodeSetTestSuite))
 }
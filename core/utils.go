@@ -18,6 +18,7 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -163,16 +164,32 @@ func VerifyAgreementResult(
 		if len(res.Randomness) == 0 {
 			return ErrMissingRandomness
 		}
-		return nil
+		// The randomness would be verified when processing this result,
+		// only the certificate is checked here.
+		if res.Certificate == nil {
+			return nil
+		}
+		cert := res.Certificate
+		if cert.BlockHash != res.BlockHash ||
+			cert.Position != res.Position ||
+			cert.IsEmptyBlock != res.IsEmptyBlock ||
+			!bytes.Equal(cert.Signature, res.Randomness) {
+			return ErrCertificateMismatch
+		}
+		return verifyAgreementCertificateWithoutTSig(cert, cache)
 	}
-	notarySet, err := cache.GetNotarySet(res.Position.Round)
+	weights, err := cache.GetNotarySetWeights(res.Position.Round)
 	if err != nil {
 		return err
 	}
-	if len(res.Votes) < len(notarySet)*2/3+1 {
+	if len(res.Votes) == 0 {
 		return ErrNotEnoughVotes
 	}
-	voted := make(map[types.NodeID]struct{}, len(notarySet))
+	var totalWeight, votedWeight uint64
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+	voted := make(map[types.NodeID]struct{}, len(weights))
 	voteType := res.Votes[0].Type
 	votePeriod := res.Votes[0].Period
 	if voteType != types.VoteFastCom && voteType != types.VoteCom {
@@ -197,7 +214,8 @@ func VerifyAgreementResult(
 		if vote.Position != res.Position {
 			return ErrIncorrectVotePosition
 		}
-		if _, exist := notarySet[vote.ProposerID]; !exist {
+		weight, exist := weights[vote.ProposerID]
+		if !exist {
 			return ErrIncorrectVoteProposer
 		}
 		ok, err := utils.VerifyVoteSignature(&vote)
@@ -207,14 +225,51 @@ func VerifyAgreementResult(
 		if !ok {
 			return ErrIncorrectVoteSignature
 		}
-		voted[vote.ProposerID] = struct{}{}
+		if _, exist := voted[vote.ProposerID]; !exist {
+			voted[vote.ProposerID] = struct{}{}
+			votedWeight += weight
+		}
 	}
-	if len(voted) < len(notarySet)*2/3+1 {
+	if votedWeight < utils.GetBAWeightThreshold(totalWeight) {
 		return ErrNotEnoughVotes
 	}
 	return nil
 }
 
+// VerifyFinalizedBlock verifies the hash, the signature and the randomness of
+// a finalized block. The randomness of blocks before DKGDelayRound is not
+// verified.
+func VerifyFinalizedBlock(b *types.Block, cache *TSigVerifierCache) error {
+	if b.IsEmpty() {
+		hash, err := utils.HashBlock(b)
+		if err != nil {
+			return err
+		}
+		if hash != b.Hash {
+			return ErrIncorrectHash
+		}
+	} else if err := utils.VerifyBlockSignature(b); err != nil {
+		return err
+	}
+	if b.Position.Round < DKGDelayRound {
+		return nil
+	}
+	verifier, ok, err := cache.UpdateAndGet(b.Position.Round)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCannotVerifyBlockRandomness
+	}
+	if !verifier.VerifySignature(b.Hash, crypto.Signature{
+		Type:      "bls",
+		Signature: b.Randomness,
+	}) {
+		return ErrIncorrectBlockRandomness
+	}
+	return nil
+}
+
 // DiffUint64 calculates difference between two uint64.
 func DiffUint64(a, b uint64) uint64 {
 	if a > b {
@@ -235,9 +290,73 @@ func isTravisCI() bool {
 	return isCI() && os.Getenv("TRAVIS") == "true"
 }
 
-// checkWithCancel is a helper to perform periodic checking with cancel.
-func checkWithCancel(parentCtx context.Context, interval time.Duration,
-	checker func() bool) (ret bool) {
+// workTracker is implemented by clocks of discrete-event simulations, ex.
+// test.SimulatedClock, which don't move forward until work in flight is done.
+// Messages received from the network, ticks of tickers and items passed
+// between routines are work in flight until handled.
+type workTracker interface {
+	// AddWork marks 'n' pieces of work in flight.
+	AddWork(n int)
+	// WorkDone marks a piece of work in flight as done.
+	WorkDone()
+	// AfterWork is like common.Clock.After, the wake-up is work in flight.
+	AfterWork(d time.Duration) <-chan time.Time
+}
+
+// addWork marks 'n' pieces of work in flight if the clock tracks work, it
+// should be called before passing work to another routine.
+func addWork(clock common.Clock, n int) {
+	if t, ok := clock.(workTracker); ok {
+		t.AddWork(n)
+	}
+}
+
+// workCounter counts work received by a routine, which should be reported as
+// done before the routine blocks.
+type workCounter struct {
+	clock   common.Clock
+	pending int
+}
+
+// add marks 'n' pieces of work received.
+func (w *workCounter) add(n int) {
+	w.pending += n
+}
+
+// addTick marks a tick received from the ticker, only ticks of tickers driven
+// by the clock are work in flight.
+func (w *workCounter) addTick(ticker Ticker) {
+	if _, ok := ticker.(*defaultTicker); ok {
+		w.pending++
+	}
+}
+
+// done reports all work received as done.
+func (w *workCounter) done() {
+	if t, ok := w.clock.(workTracker); ok {
+		for ; w.pending > 0; w.pending-- {
+			t.WorkDone()
+		}
+	}
+	w.pending = 0
+}
+
+// sleep is like common.Clock.Sleep, work received is done before sleeping
+// and the wake-up is received as work.
+func (w *workCounter) sleep(d time.Duration) {
+	w.done()
+	if t, ok := w.clock.(workTracker); ok {
+		<-t.AfterWork(d)
+		w.pending++
+		return
+	}
+	w.clock.Sleep(d)
+}
+
+// checkWithCancel is a helper to perform periodic checking with cancel, the
+// interval is measured by the given clock.
+func checkWithCancel(parentCtx context.Context, clock common.Clock,
+	interval time.Duration, checker func() bool) (ret bool) {
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 Loop:
@@ -248,7 +367,7 @@ Loop:
 		select {
 		case <-ctx.Done():
 			break Loop
-		case <-time.After(interval):
+		case <-clock.After(interval):
 		}
 	}
 	return
@@ -0,0 +1,128 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// Errors for block range.
+var (
+	// ErrInvalidBlockRange is reported when the requested height range is
+	// empty.
+	ErrInvalidBlockRange = errors.New("invalid block range")
+	// ErrBlockRangeMismatch is reported when blocks and agreement
+	// certificates in a types.BlockRange don't match each other.
+	ErrBlockRangeMismatch = errors.New("block range mismatch")
+	// ErrBlockRangeNotContinuous is reported when blocks in a
+	// types.BlockRange are not consecutive, or out of the requested range.
+	ErrBlockRangeNotContinuous = errors.New("block range not continuous")
+	// ErrBlockRangeNotFinalized is reported when some block in a
+	// types.BlockRange is not finalized.
+	ErrBlockRangeNotFinalized = errors.New("block range not finalized")
+)
+
+// GetBlockRange reads at most 'limit' finalized blocks with height in
+// [from, to) from the compaction chain in db, along with agreement
+// certificates of blocks after DKGDelayRound. The page ends before the first
+// block without its certificate, the requester should ask others for it.
+func GetBlockRange(dbInst db.Reader, from, to uint64, limit int) (
+	*types.BlockRange, error) {
+	if from >= to || limit <= 0 {
+		return nil, ErrInvalidBlockRange
+	}
+	r := &types.BlockRange{From: from, To: to}
+	_, tipHeight := dbInst.GetCompactionChainTipInfo()
+	end := to
+	if end > tipHeight+1 {
+		end = tipHeight + 1
+	}
+	if end > from+uint64(limit) {
+		end = from + uint64(limit)
+	}
+	if from >= end {
+		return r, nil
+	}
+	blocks, err := dbInst.GetBlocksInRange(from, end)
+	if err != nil {
+		return nil, err
+	}
+	for i := range blocks {
+		b := &blocks[i]
+		// Stop at the first gap, the requester would ask again from there.
+		if b.Position.Height != from+uint64(len(r.Blocks)) {
+			break
+		}
+		if b.Position.Round >= dkgDelayRound {
+			cert, err := dbInst.GetAgreementCertificate(b.Hash)
+			if err == db.ErrAgreementCertificateDoesNotExist {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			r.Certificates = append(r.Certificates, &cert)
+		}
+		r.Blocks = append(r.Blocks, b)
+	}
+	return r, nil
+}
+
+// VerifyBlockRange checks if blocks in a types.BlockRange are finalized,
+// consecutive and chained by parent hashes, and match their agreement
+// certificates. The signatures and randomness of blocks, and the agreement
+// certificates are not verified here.
+func VerifyBlockRange(r *types.BlockRange) error {
+	// Blocks before DKGDelayRound have no certificates.
+	uncertified := len(r.Blocks) - len(r.Certificates)
+	if uncertified < 0 {
+		return ErrBlockRangeMismatch
+	}
+	for i, b := range r.Blocks {
+		if b == nil {
+			return ErrBlockRangeMismatch
+		}
+		if (b.Position.Round < dkgDelayRound) != (i < uncertified) {
+			return ErrBlockRangeMismatch
+		}
+		if i >= uncertified {
+			cert := r.Certificates[i-uncertified]
+			if cert == nil || cert.BlockHash != b.Hash ||
+				cert.Position != b.Position ||
+				cert.IsEmptyBlock != b.IsEmpty() ||
+				!bytes.Equal(cert.Signature, b.Randomness) {
+				return ErrBlockRangeMismatch
+			}
+		}
+		if b.Position.Height != r.From+uint64(i) ||
+			b.Position.Height >= r.To {
+			return ErrBlockRangeNotContinuous
+		}
+		if i > 0 && b.ParentHash != r.Blocks[i-1].Hash {
+			return ErrBlockRangeNotContinuous
+		}
+		if !b.IsFinalized() {
+			return ErrBlockRangeNotFinalized
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+type BlockRangeTestSuite struct {
+	suite.Suite
+}
+
+// prepareChain puts a chain of finalized blocks into db, the compaction chain
+// tip is at 'tip'. The first two blocks are before DKGDelayRound, others come
+// with agreement certificates except those at 'uncertified' heights.
+func (s *BlockRangeTestSuite) prepareChain(count int, tip uint64,
+	uncertified ...uint64) (*db.MemBackedDB, []*types.Block) {
+	dkgDelayRound = 1
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	var (
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := 0; i < count; i++ {
+		b := &types.Block{
+			ParentHash: parent,
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: types.GenesisHeight + uint64(i)},
+			Randomness: common.GenerateRandomBytes(),
+		}
+		if i%2 == 0 {
+			b.ProposerID = types.NodeID{Hash: common.NewRandomHash()}
+		}
+		if i >= 2 {
+			b.Position.Round = 1
+		}
+		certified := b.Position.Round >= dkgDelayRound
+		for _, h := range uncertified {
+			if h == b.Position.Height {
+				certified = false
+			}
+		}
+		if certified {
+			s.Require().NoError(dbInst.PutAgreementCertificate(
+				types.AgreementCertificate{
+					BlockHash:    b.Hash,
+					Position:     b.Position,
+					IsEmptyBlock: b.IsEmpty(),
+					Signature:    b.Randomness,
+				}))
+		}
+		s.Require().NoError(dbInst.PutBlock(*b))
+		if b.Position.Height <= tip {
+			s.Require().NoError(
+				dbInst.PutCompactionChainTipInfo(b.Hash, b.Position.Height))
+		}
+		blocks = append(blocks, b)
+		parent = b.Hash
+	}
+	return dbInst, blocks
+}
+
+func (s *BlockRangeTestSuite) TestGetBlockRange() {
+	dbInst, blocks := s.prepareChain(10, 7)
+	// Blocks after the compaction chain tip are not served.
+	r, err := GetBlockRange(dbInst, 3, 20, 100)
+	s.Require().NoError(err)
+	s.Require().Len(r.Blocks, 5)
+	s.Require().Len(r.Certificates, 5)
+	for i, b := range r.Blocks {
+		s.Require().Equal(blocks[i+2].Hash, b.Hash)
+		s.Require().Equal(b.Hash, r.Certificates[i].BlockHash)
+	}
+	next, more := r.Next()
+	s.Require().Equal(uint64(8), next)
+	s.Require().True(more)
+	s.Require().NoError(VerifyBlockRange(r))
+	// Paging.
+	r, err = GetBlockRange(dbInst, 1, 20, 3)
+	s.Require().NoError(err)
+	s.Require().Len(r.Blocks, 3)
+	s.Require().Len(r.Certificates, 1)
+	next, more = r.Next()
+	s.Require().Equal(uint64(4), next)
+	s.Require().True(more)
+	s.Require().NoError(VerifyBlockRange(r))
+	// Nothing to serve.
+	r, err = GetBlockRange(dbInst, 8, 20, 3)
+	s.Require().NoError(err)
+	s.Require().Len(r.Blocks, 0)
+	_, more = r.Next()
+	s.Require().False(more)
+	// Invalid range.
+	_, err = GetBlockRange(dbInst, 5, 5, 3)
+	s.Require().Equal(ErrInvalidBlockRange, err)
+	// The page ends before blocks without agreement certificates.
+	dbInst, blocks = s.prepareChain(10, 10, 6)
+	r, err = GetBlockRange(dbInst, 1, 20, 100)
+	s.Require().NoError(err)
+	s.Require().Len(r.Blocks, 5)
+	s.Require().Len(r.Certificates, 3)
+	next, more = r.Next()
+	s.Require().Equal(blocks[5].Position.Height, next)
+	s.Require().True(more)
+	s.Require().NoError(VerifyBlockRange(r))
+}
+
+func (s *BlockRangeTestSuite) TestVerifyBlockRange() {
+	dbInst, blocks := s.prepareChain(5, 5)
+	load := func() *types.BlockRange {
+		r, err := GetBlockRange(dbInst, 1, 6, 10)
+		s.Require().NoError(err)
+		s.Require().NoError(VerifyBlockRange(r))
+		return r
+	}
+	// Broken parent hash.
+	r := load()
+	r.Blocks[2].ParentHash = common.NewRandomHash()
+	s.Require().Equal(ErrBlockRangeNotContinuous, VerifyBlockRange(r))
+	// Skipped height.
+	r = load()
+	r.Blocks = append(r.Blocks[:1], r.Blocks[2:]...)
+	s.Require().Equal(ErrBlockRangeNotContinuous, VerifyBlockRange(r))
+	// Not starting from the requested height.
+	r = load()
+	r.From = blocks[1].Position.Height
+	s.Require().Equal(ErrBlockRangeNotContinuous, VerifyBlockRange(r))
+	// Out of the requested range.
+	r = load()
+	r.To = blocks[3].Position.Height
+	s.Require().Equal(ErrBlockRangeNotContinuous, VerifyBlockRange(r))
+	// Not finalized.
+	r = load()
+	r.Blocks[1].Randomness = nil
+	s.Require().Equal(ErrBlockRangeNotFinalized, VerifyBlockRange(r))
+	// Missing blocks.
+	r = load()
+	r.Blocks[3] = nil
+	s.Require().Equal(ErrBlockRangeMismatch, VerifyBlockRange(r))
+	// Missing agreement certificates.
+	r = load()
+	r.Certificates = r.Certificates[1:]
+	s.Require().Equal(ErrBlockRangeMismatch, VerifyBlockRange(r))
+	// Agreement certificates not matching blocks.
+	r = load()
+	r.Certificates[1].Signature = common.GenerateRandomBytes()
+	s.Require().Equal(ErrBlockRangeMismatch, VerifyBlockRange(r))
+	// Agreement certificates of blocks before DKGDelayRound.
+	r = load()
+	r.Certificates = append([]*types.AgreementCertificate{
+		r.Certificates[0]}, r.Certificates...)
+	s.Require().Equal(ErrBlockRangeMismatch, VerifyBlockRange(r))
+}
+
+func TestBlockRange(t *testing.T) {
+	suite.Run(t, new(BlockRangeTestSuite))
+}
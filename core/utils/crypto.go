@@ -121,6 +121,38 @@ func VerifyVoteSignature(vote *types.Vote) (bool, error) {
 	return true, nil
 }
 
+// HashAgreementCertificate generates hash of a types.AgreementCertificate.
+func HashAgreementCertificate(cert *types.AgreementCertificate) common.Hash {
+	hashPosition := HashPosition(cert.Position)
+	isEmptyBlock := []byte{0}
+	if cert.IsEmptyBlock {
+		isEmptyBlock[0] = 1
+	}
+	return crypto.Keccak256Hash(
+		cert.ProposerID.Hash[:],
+		cert.BlockHash[:],
+		hashPosition[:],
+		isEmptyBlock,
+		cert.Signers,
+		cert.Signature,
+	)
+}
+
+// VerifyAgreementCertificateSignature verifies the proposer signature of
+// types.AgreementCertificate.
+func VerifyAgreementCertificateSignature(
+	cert *types.AgreementCertificate) (bool, error) {
+	hash := HashAgreementCertificate(cert)
+	pubKey, err := crypto.SigToPub(hash, cert.ProposerSignature)
+	if err != nil {
+		return false, err
+	}
+	if cert.ProposerID != types.NewNodeID(pubKey) {
+		return false, nil
+	}
+	return true, nil
+}
+
 func hashCRS(block *types.Block, crs common.Hash) common.Hash {
 	hashPos := HashPosition(block.Position)
 	if block.Position.Round < dkgDelayRound {
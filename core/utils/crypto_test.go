@@ -126,6 +126,26 @@ func (s *CryptoTestSuite) TestVoteSignature() {
 	s.False(ok)
 }
 
+func (s *CryptoTestSuite) TestAgreementCertificateSignature() {
+	prv, err := ecdsa.NewPrivateKey()
+	s.Require().NoError(err)
+	cert := &types.AgreementCertificate{
+		BlockHash: common.NewRandomHash(),
+		Position:  types.Position{Round: 1, Height: 10},
+		Signers:   []byte{0x7f},
+		Signature: common.GenerateRandomBytes(),
+	}
+	s.Require().NoError(NewSigner(prv).SignAgreementCertificate(cert))
+	ok, err := VerifyAgreementCertificateSignature(cert)
+	s.Require().NoError(err)
+	s.True(ok)
+	// The signer bitmap is covered by the signature.
+	cert.Signers[0] = 0xfe
+	ok, err = VerifyAgreementCertificateSignature(cert)
+	s.Require().NoError(err)
+	s.False(ok)
+}
+
 func (s *CryptoTestSuite) TestCRSSignature() {
 	dkgDelayRound = 1
 	crs := common.NewRandomHash()
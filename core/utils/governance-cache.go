@@ -0,0 +1,717 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	typesDKG "github.com/tangerine-network/tangerine-consensus/core/types/dkg"
+)
+
+var (
+	// ErrGovernanceTimeout means the governance backend doesn't respond in
+	// time.
+	ErrGovernanceTimeout = errors.New("governance query timeout")
+	// ErrRoundHeightNotReady means the begin height of a round is unknown.
+	ErrRoundHeightNotReady = errors.New("round height is not ready")
+	// ErrInvalidGovernanceTimeout means the timeout of governance queries is
+	// not positive.
+	ErrInvalidGovernanceTimeout = errors.New("invalid governance timeout")
+)
+
+// DefaultResetCountTTL is the duration a DKG reset count is cached when
+// GovernanceCacheConfig.ResetCountTTL is not set.
+const DefaultResetCountTTL = time.Second
+
+// GovernanceBackend is the source of governance data which might be slow or
+// unavailable, ex. a governance contract queried via RPC. Unlike
+// core.Governance, errors are returned when data is not available yet.
+type GovernanceBackend interface {
+	Configuration(round uint64) (*types.Config, error)
+	CRS(round uint64) (common.Hash, error)
+	NodeSet(round uint64) ([]crypto.PublicKey, error)
+	GetRoundHeight(round uint64) (uint64, error)
+	DKGResetCount(round uint64) (uint64, error)
+	DKGComplaints(round uint64) ([]*typesDKG.Complaint, error)
+	DKGMasterPublicKeys(round uint64) ([]*typesDKG.MasterPublicKey, error)
+	IsDKGMPKReady(round uint64) (bool, error)
+	IsDKGFinal(round uint64) (bool, error)
+	IsDKGSuccess(round uint64) (bool, error)
+
+	ProposeCRS(round uint64, signedCRS []byte) error
+	AddDKGComplaint(complaint *typesDKG.Complaint) error
+	AddDKGMasterPublicKey(masterPublicKey *typesDKG.MasterPublicKey) error
+	AddDKGMPKReady(ready *typesDKG.MPKReady) error
+	AddDKGFinalize(final *typesDKG.Finalize) error
+	AddDKGSuccess(success *typesDKG.Success) error
+	ReportForkVote(vote1, vote2 *types.Vote) error
+	ReportForkBlock(block1, block2 *types.Block) error
+	ResetDKG(newSignedCRS []byte) error
+}
+
+// governanceRound caches governance data of one round. Fields below
+// resetCount are only valid for that reset count.
+type governanceRound struct {
+	config      *types.Config
+	nodeSet     []crypto.PublicKey
+	height      uint64
+	heightReady bool
+
+	resetCount   uint64
+	resetCountAt time.Time
+	crs          common.Hash
+	mpks         []*typesDKG.MasterPublicKey
+	complaints   []*typesDKG.Complaint
+	mpkReady     bool
+	final        bool
+	success      bool
+}
+
+func (r *governanceRound) reset(resetCount uint64) {
+	*r = governanceRound{
+		config:      r.config,
+		nodeSet:     r.nodeSet,
+		height:      r.height,
+		heightReady: r.heightReady,
+		resetCount:  resetCount,
+	}
+}
+
+type governanceQueryKey struct {
+	method     string
+	round      uint64
+	resetCount uint64
+}
+
+type governanceQuery struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// GovernanceCacheConfig is the configuration of GovernanceCache.
+type GovernanceCacheConfig struct {
+	// Timeout is the longest duration callers wait for the backend.
+	Timeout time.Duration
+	// ResetCountTTL is the duration a DKG reset count is cached.
+	ResetCountTTL time.Duration
+	// Clock is the clock measuring timeouts, the system clock is used when
+	// nil.
+	Clock common.Clock
+	// Logger is the logger, nothing is logged when nil.
+	Logger common.Logger
+}
+
+// GovernanceCache implements core.Governance on top of a GovernanceBackend.
+// Immutable data of rounds, like configurations and node sets, are memoized.
+// Data depending on DKG resetting, like CRS and DKG results, are memoized
+// until a larger DKG reset count is observed via DKGResetCount, which is
+// cached for ResetCountTTL.
+//
+// Queries to the backend are deduplicated and never block callers longer
+// than the timeout, methods of core.Governance return zero values when data
+// is not available, while Query* methods return errors. Writing methods are
+// queued without blocking, and sent to the backend in order by another
+// goroutine.
+type GovernanceCache struct {
+	backend       GovernanceBackend
+	timeout       time.Duration
+	resetCountTTL time.Duration
+	clock         common.Clock
+	logger        common.Logger
+	lock          sync.RWMutex
+	rounds        map[uint64]*governanceRound
+	queries       map[governanceQueryKey]*governanceQuery
+	writeLock     sync.Mutex
+	writes        []func()
+	writeNotify   chan struct{}
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+// NewGovernanceCache constructs a GovernanceCache instance.
+func NewGovernanceCache(backend GovernanceBackend,
+	config GovernanceCacheConfig) (*GovernanceCache, error) {
+	if config.Timeout <= 0 {
+		return nil, ErrInvalidGovernanceTimeout
+	}
+	if config.ResetCountTTL <= 0 {
+		config.ResetCountTTL = DefaultResetCountTTL
+	}
+	if config.Clock == nil {
+		config.Clock = &common.SystemClock{}
+	}
+	if config.Logger == nil {
+		config.Logger = &common.NullLogger{}
+	}
+	c := &GovernanceCache{
+		backend:       backend,
+		timeout:       config.Timeout,
+		resetCountTTL: config.ResetCountTTL,
+		clock:         config.Clock,
+		logger:        config.Logger,
+		rounds:        make(map[uint64]*governanceRound),
+		queries:       make(map[governanceQueryKey]*governanceQuery),
+		writeNotify:   make(chan struct{}, 1),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	go c.writeLoop()
+	return c, nil
+}
+
+func (c *GovernanceCache) writeLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.writeNotify:
+		}
+		for {
+			write := func() func() {
+				c.writeLock.Lock()
+				defer c.writeLock.Unlock()
+				if len(c.writes) == 0 {
+					return nil
+				}
+				write := c.writes[0]
+				c.writes[0] = nil
+				c.writes = c.writes[1:]
+				return write
+			}()
+			if write == nil {
+				break
+			}
+			select {
+			case <-c.ctx.Done():
+				return
+			default:
+			}
+			write()
+		}
+	}
+}
+
+// Close stops sending writes to the backend, pending ones are dropped.
+func (c *GovernanceCache) Close() {
+	c.cancel()
+}
+
+// Purge removes cached data of rounds before a specific round.
+func (c *GovernanceCache) Purge(round uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for r := range c.rounds {
+		if r < round {
+			delete(c.rounds, r)
+		}
+	}
+}
+
+func (c *GovernanceCache) roundNoLock(round uint64) *governanceRound {
+	r, exists := c.rounds[round]
+	if !exists {
+		r = &governanceRound{}
+		c.rounds[round] = r
+	}
+	return r
+}
+
+// cached returns the cached value picked by 'get', and the latest reset count
+// observed for that round.
+func (c *GovernanceCache) cached(round uint64,
+	get func(r *governanceRound) (interface{}, bool)) (
+	value interface{}, resetCount uint64, exists bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	r, found := c.rounds[round]
+	if !found {
+		return
+	}
+	value, exists = get(r)
+	resetCount = r.resetCount
+	return
+}
+
+// query calls 'fn' in another goroutine and waits for its result no longer
+// than the timeout. Concurrent queries with the same key share one call to
+// the backend. When succeeded, 'store' is called with the result and the
+// cached data of that round, under the lock.
+func (c *GovernanceCache) query(key governanceQueryKey,
+	fn func() (interface{}, error),
+	store func(r *governanceRound, value interface{})) (interface{}, error) {
+	c.lock.Lock()
+	q, exists := c.queries[key]
+	if !exists {
+		q = &governanceQuery{done: make(chan struct{})}
+		c.queries[key] = q
+		go func() {
+			value, err := fn()
+			c.lock.Lock()
+			defer c.lock.Unlock()
+			if err == nil && store != nil {
+				store(c.roundNoLock(key.round), value)
+			}
+			q.value, q.err = value, err
+			delete(c.queries, key)
+			close(q.done)
+		}()
+	}
+	c.lock.Unlock()
+	select {
+	case <-q.done:
+		return q.value, q.err
+	case <-c.clock.After(c.timeout):
+		return nil, ErrGovernanceTimeout
+	}
+}
+
+// QueryConfiguration returns the configuration of a round.
+func (c *GovernanceCache) QueryConfiguration(
+	round uint64) (*types.Config, error) {
+	value, _, exists := c.cached(round,
+		func(r *governanceRound) (interface{}, bool) {
+			return r.config, r.config != nil
+		})
+	if !exists {
+		var err error
+		value, err = c.query(
+			governanceQueryKey{method: "Configuration", round: round},
+			func() (interface{}, error) {
+				config, err := c.backend.Configuration(round)
+				if err == nil && config == nil {
+					err = ErrConfigurationNotReady
+				}
+				return config, err
+			},
+			func(r *governanceRound, value interface{}) {
+				r.config = value.(*types.Config)
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value.(*types.Config), nil
+}
+
+// QueryNodeSet returns the node set of a round.
+func (c *GovernanceCache) QueryNodeSet(
+	round uint64) ([]crypto.PublicKey, error) {
+	value, _, exists := c.cached(round,
+		func(r *governanceRound) (interface{}, bool) {
+			return r.nodeSet, len(r.nodeSet) > 0
+		})
+	if !exists {
+		var err error
+		value, err = c.query(
+			governanceQueryKey{method: "NodeSet", round: round},
+			func() (interface{}, error) {
+				nodeSet, err := c.backend.NodeSet(round)
+				if err == nil && len(nodeSet) == 0 {
+					err = ErrNodeSetNotReady
+				}
+				return nodeSet, err
+			},
+			func(r *governanceRound, value interface{}) {
+				r.nodeSet = value.([]crypto.PublicKey)
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append([]crypto.PublicKey{}, value.([]crypto.PublicKey)...), nil
+}
+
+// QueryRoundHeight returns the begin height of a round.
+func (c *GovernanceCache) QueryRoundHeight(round uint64) (uint64, error) {
+	value, _, exists := c.cached(round,
+		func(r *governanceRound) (interface{}, bool) {
+			return r.height, r.heightReady
+		})
+	if !exists {
+		var err error
+		value, err = c.query(
+			governanceQueryKey{method: "GetRoundHeight", round: round},
+			func() (interface{}, error) {
+				return c.backend.GetRoundHeight(round)
+			},
+			func(r *governanceRound, value interface{}) {
+				r.height, r.heightReady = value.(uint64), true
+			})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return value.(uint64), nil
+}
+
+// QueryDKGResetCount returns the DKG reset count of a round, which is queried
+// from the backend when the cached one is older than ResetCountTTL. Data
+// depending on older reset counts are invalidated.
+func (c *GovernanceCache) QueryDKGResetCount(round uint64) (uint64, error) {
+	now := c.clock.Now()
+	value, _, exists := c.cached(round,
+		func(r *governanceRound) (interface{}, bool) {
+			return r.resetCount, !r.resetCountAt.IsZero() &&
+				now.Sub(r.resetCountAt) < c.resetCountTTL
+		})
+	if exists {
+		return value.(uint64), nil
+	}
+	value, err := c.query(
+		governanceQueryKey{method: "DKGResetCount", round: round},
+		func() (interface{}, error) {
+			return c.backend.DKGResetCount(round)
+		},
+		func(r *governanceRound, value interface{}) {
+			if resetCount := value.(uint64); resetCount > r.resetCount {
+				r.reset(resetCount)
+			}
+			r.resetCountAt = c.clock.Now()
+		})
+	if err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+// queryWithReset is a helper to query data depending on DKG resetting, the
+// result is only stored when the reset count of that round is not changed.
+func (c *GovernanceCache) queryWithReset(method string, round uint64,
+	get func(r *governanceRound) (interface{}, bool),
+	fn func() (interface{}, error),
+	store func(r *governanceRound, value interface{})) (interface{}, error) {
+	value, resetCount, exists := c.cached(round, get)
+	if exists {
+		return value, nil
+	}
+	return c.query(governanceQueryKey{
+		method:     method,
+		round:      round,
+		resetCount: resetCount,
+	}, fn, func(r *governanceRound, value interface{}) {
+		if r.resetCount == resetCount {
+			store(r, value)
+		}
+	})
+}
+
+// QueryCRS returns the CRS of a round.
+func (c *GovernanceCache) QueryCRS(round uint64) (common.Hash, error) {
+	value, err := c.queryWithReset("CRS", round,
+		func(r *governanceRound) (interface{}, bool) {
+			return r.crs, r.crs != common.Hash{}
+		},
+		func() (interface{}, error) {
+			crs, err := c.backend.CRS(round)
+			if err == nil && (crs == common.Hash{}) {
+				err = ErrCRSNotReady
+			}
+			return crs, err
+		},
+		func(r *governanceRound, value interface{}) {
+			r.crs = value.(common.Hash)
+		})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return value.(common.Hash), nil
+}
+
+// QueryDKGMasterPublicKeys returns DKG master public keys of a round, they
+// are cached once DKG of that round is final.
+func (c *GovernanceCache) QueryDKGMasterPublicKeys(
+	round uint64) ([]*typesDKG.MasterPublicKey, error) {
+	value, err := c.queryWithReset("DKGMasterPublicKeys", round,
+		func(r *governanceRound) (interface{}, bool) {
+			return r.mpks, r.final && r.mpks != nil
+		},
+		func() (interface{}, error) {
+			return c.backend.DKGMasterPublicKeys(round)
+		},
+		func(r *governanceRound, value interface{}) {
+			if r.final {
+				r.mpks = value.([]*typesDKG.MasterPublicKey)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*typesDKG.MasterPublicKey), nil
+}
+
+// QueryDKGComplaints returns DKG complaints of a round, they are cached once
+// DKG of that round is final.
+func (c *GovernanceCache) QueryDKGComplaints(
+	round uint64) ([]*typesDKG.Complaint, error) {
+	value, err := c.queryWithReset("DKGComplaints", round,
+		func(r *governanceRound) (interface{}, bool) {
+			return r.complaints, r.final && r.complaints != nil
+		},
+		func() (interface{}, error) {
+			return c.backend.DKGComplaints(round)
+		},
+		func(r *governanceRound, value interface{}) {
+			if r.final {
+				r.complaints = value.([]*typesDKG.Complaint)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*typesDKG.Complaint), nil
+}
+
+// queryDKGFlag is a helper to query DKG flags, which are cached once they
+// are true.
+func (c *GovernanceCache) queryDKGFlag(method string, round uint64,
+	flag func(r *governanceRound) *bool,
+	fn func(round uint64) (bool, error)) (bool, error) {
+	value, err := c.queryWithReset(method, round,
+		func(r *governanceRound) (interface{}, bool) {
+			return true, *flag(r)
+		},
+		func() (interface{}, error) {
+			return fn(round)
+		},
+		func(r *governanceRound, value interface{}) {
+			*flag(r) = value.(bool)
+		})
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+// QueryDKGMPKReady checks if DKG master public keys of a round are ready.
+func (c *GovernanceCache) QueryDKGMPKReady(round uint64) (bool, error) {
+	return c.queryDKGFlag("IsDKGMPKReady", round,
+		func(r *governanceRound) *bool { return &r.mpkReady },
+		c.backend.IsDKGMPKReady)
+}
+
+// QueryDKGFinal checks if DKG of a round is final.
+func (c *GovernanceCache) QueryDKGFinal(round uint64) (bool, error) {
+	return c.queryDKGFlag("IsDKGFinal", round,
+		func(r *governanceRound) *bool { return &r.final },
+		c.backend.IsDKGFinal)
+}
+
+// QueryDKGSuccess checks if DKG of a round is success.
+func (c *GovernanceCache) QueryDKGSuccess(round uint64) (bool, error) {
+	return c.queryDKGFlag("IsDKGSuccess", round,
+		func(r *governanceRound) *bool { return &r.success },
+		c.backend.IsDKGSuccess)
+}
+
+func (c *GovernanceCache) logQueryError(
+	method string, round uint64, err error) {
+	c.logger.Debug("Governance data is not available",
+		"method", method,
+		"round", round,
+		"error", err)
+}
+
+// Configuration implements core.Governance interface.
+func (c *GovernanceCache) Configuration(round uint64) *types.Config {
+	config, err := c.QueryConfiguration(round)
+	if err != nil {
+		c.logQueryError("Configuration", round, err)
+	}
+	return config
+}
+
+// CRS implements core.Governance interface.
+func (c *GovernanceCache) CRS(round uint64) common.Hash {
+	crs, err := c.QueryCRS(round)
+	if err != nil {
+		c.logQueryError("CRS", round, err)
+	}
+	return crs
+}
+
+// NodeSet implements core.Governance interface.
+func (c *GovernanceCache) NodeSet(round uint64) []crypto.PublicKey {
+	nodeSet, err := c.QueryNodeSet(round)
+	if err != nil {
+		c.logQueryError("NodeSet", round, err)
+	}
+	return nodeSet
+}
+
+// GetRoundHeight implements core.Governance interface.
+func (c *GovernanceCache) GetRoundHeight(round uint64) uint64 {
+	height, err := c.QueryRoundHeight(round)
+	if err != nil {
+		c.logQueryError("GetRoundHeight", round, err)
+	}
+	return height
+}
+
+// DKGResetCount implements core.Governance interface, the latest reset count
+// observed is returned when the backend is not available.
+func (c *GovernanceCache) DKGResetCount(round uint64) uint64 {
+	resetCount, err := c.QueryDKGResetCount(round)
+	if err != nil {
+		c.logQueryError("DKGResetCount", round, err)
+		_, resetCount, _ = c.cached(round,
+			func(*governanceRound) (interface{}, bool) { return nil, false })
+	}
+	return resetCount
+}
+
+// DKGComplaints implements core.Governance interface.
+func (c *GovernanceCache) DKGComplaints(
+	round uint64) []*typesDKG.Complaint {
+	complaints, err := c.QueryDKGComplaints(round)
+	if err != nil {
+		c.logQueryError("DKGComplaints", round, err)
+	}
+	return complaints
+}
+
+// DKGMasterPublicKeys implements core.Governance interface.
+func (c *GovernanceCache) DKGMasterPublicKeys(
+	round uint64) []*typesDKG.MasterPublicKey {
+	mpks, err := c.QueryDKGMasterPublicKeys(round)
+	if err != nil {
+		c.logQueryError("DKGMasterPublicKeys", round, err)
+	}
+	return mpks
+}
+
+// IsDKGMPKReady implements core.Governance interface.
+func (c *GovernanceCache) IsDKGMPKReady(round uint64) bool {
+	ready, err := c.QueryDKGMPKReady(round)
+	if err != nil {
+		c.logQueryError("IsDKGMPKReady", round, err)
+	}
+	return ready
+}
+
+// IsDKGFinal implements core.Governance interface.
+func (c *GovernanceCache) IsDKGFinal(round uint64) bool {
+	final, err := c.QueryDKGFinal(round)
+	if err != nil {
+		c.logQueryError("IsDKGFinal", round, err)
+	}
+	return final
+}
+
+// IsDKGSuccess implements core.Governance interface.
+func (c *GovernanceCache) IsDKGSuccess(round uint64) bool {
+	success, err := c.QueryDKGSuccess(round)
+	if err != nil {
+		c.logQueryError("IsDKGSuccess", round, err)
+	}
+	return success
+}
+
+// send queues a writing method of the backend, callers of core.Governance
+// don't wait for them.
+func (c *GovernanceCache) send(method string, fn func() error) {
+	select {
+	case <-c.ctx.Done():
+		return
+	default:
+	}
+	func() {
+		c.writeLock.Lock()
+		defer c.writeLock.Unlock()
+		c.writes = append(c.writes, func() {
+			if err := fn(); err != nil {
+				c.logger.Error("Failed to send to governance",
+					"method", method,
+					"error", err)
+			}
+		})
+	}()
+	select {
+	case c.writeNotify <- struct{}{}:
+	default:
+	}
+}
+
+// ProposeCRS implements core.Governance interface.
+func (c *GovernanceCache) ProposeCRS(round uint64, signedCRS []byte) {
+	c.send("ProposeCRS", func() error {
+		return c.backend.ProposeCRS(round, signedCRS)
+	})
+}
+
+// AddDKGComplaint implements core.Governance interface.
+func (c *GovernanceCache) AddDKGComplaint(complaint *typesDKG.Complaint) {
+	c.send("AddDKGComplaint", func() error {
+		return c.backend.AddDKGComplaint(complaint)
+	})
+}
+
+// AddDKGMasterPublicKey implements core.Governance interface.
+func (c *GovernanceCache) AddDKGMasterPublicKey(
+	masterPublicKey *typesDKG.MasterPublicKey) {
+	c.send("AddDKGMasterPublicKey", func() error {
+		return c.backend.AddDKGMasterPublicKey(masterPublicKey)
+	})
+}
+
+// AddDKGMPKReady implements core.Governance interface.
+func (c *GovernanceCache) AddDKGMPKReady(ready *typesDKG.MPKReady) {
+	c.send("AddDKGMPKReady", func() error {
+		return c.backend.AddDKGMPKReady(ready)
+	})
+}
+
+// AddDKGFinalize implements core.Governance interface.
+func (c *GovernanceCache) AddDKGFinalize(final *typesDKG.Finalize) {
+	c.send("AddDKGFinalize", func() error {
+		return c.backend.AddDKGFinalize(final)
+	})
+}
+
+// AddDKGSuccess implements core.Governance interface.
+func (c *GovernanceCache) AddDKGSuccess(success *typesDKG.Success) {
+	c.send("AddDKGSuccess", func() error {
+		return c.backend.AddDKGSuccess(success)
+	})
+}
+
+// ReportForkVote implements core.Governance interface.
+func (c *GovernanceCache) ReportForkVote(vote1, vote2 *types.Vote) {
+	c.send("ReportForkVote", func() error {
+		return c.backend.ReportForkVote(vote1, vote2)
+	})
+}
+
+// ReportForkBlock implements core.Governance interface.
+func (c *GovernanceCache) ReportForkBlock(block1, block2 *types.Block) {
+	c.send("ReportForkBlock", func() error {
+		return c.backend.ReportForkBlock(block1, block2)
+	})
+}
+
+// ResetDKG implements core.Governance interface.
+func (c *GovernanceCache) ResetDKG(newSignedCRS []byte) {
+	c.send("ResetDKG", func() error {
+		return c.backend.ResetDKG(newSignedCRS)
+	})
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// governanceCacheClock is a common.Clock whose timers only fire when told
+// to.
+type governanceCacheClock struct {
+	common.SystemClock
+
+	lock  sync.Mutex
+	now   time.Time
+	after chan time.Time
+}
+
+func (c *governanceCacheClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *governanceCacheClock) After(time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.after
+}
+
+// expire makes all timers expire until reset.
+func (c *governanceCacheClock) expire(expired bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.after = make(chan time.Time)
+	if expired {
+		close(c.after)
+	}
+}
+
+func (c *governanceCacheClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// governanceCacheBackend is a GovernanceBackend whose queries block until
+// released, methods not overridden are not expected to be called.
+type governanceCacheBackend struct {
+	GovernanceBackend
+
+	lock       sync.Mutex
+	release    chan struct{}
+	calls      map[string]int
+	resetCount uint64
+	sent       []uint64
+}
+
+func newGovernanceCacheBackend() *governanceCacheBackend {
+	return &governanceCacheBackend{
+		release: make(chan struct{}),
+		calls:   make(map[string]int),
+	}
+}
+
+func (b *governanceCacheBackend) call(method string) {
+	func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.calls[method]++
+	}()
+	<-b.release
+}
+
+func (b *governanceCacheBackend) numCalls(method string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.calls[method]
+}
+
+func (b *governanceCacheBackend) Configuration(
+	round uint64) (*types.Config, error) {
+	b.call("Configuration")
+	return &types.Config{NotarySetSize: 4}, nil
+}
+
+func (b *governanceCacheBackend) CRS(round uint64) (common.Hash, error) {
+	b.call("CRS")
+	return common.Hash{}, nil
+}
+
+func (b *governanceCacheBackend) DKGResetCount(round uint64) (uint64, error) {
+	b.call("DKGResetCount")
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.resetCount, nil
+}
+
+func (b *governanceCacheBackend) ProposeCRS(round uint64, _ []byte) error {
+	b.call("ProposeCRS")
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sent = append(b.sent, round)
+	return nil
+}
+
+type GovernanceCacheTestSuite struct {
+	suite.Suite
+}
+
+func (s *GovernanceCacheTestSuite) newCache(
+	backend GovernanceBackend) (*GovernanceCache, *governanceCacheClock) {
+	clock := &governanceCacheClock{now: time.Now()}
+	clock.expire(false)
+	cache, err := NewGovernanceCache(backend, GovernanceCacheConfig{
+		Timeout:       time.Hour,
+		ResetCountTTL: time.Minute,
+		Clock:         clock,
+	})
+	s.Require().NoError(err)
+	return cache, clock
+}
+
+func (s *GovernanceCacheTestSuite) TestInvalidTimeout() {
+	_, err := NewGovernanceCache(
+		newGovernanceCacheBackend(), GovernanceCacheConfig{})
+	s.Require().Equal(ErrInvalidGovernanceTimeout, err)
+	_, err = NewGovernanceCache(newGovernanceCacheBackend(),
+		GovernanceCacheConfig{Timeout: -time.Second})
+	s.Require().Equal(ErrInvalidGovernanceTimeout, err)
+}
+
+func (s *GovernanceCacheTestSuite) TestTimeoutByClock() {
+	backend := newGovernanceCacheBackend()
+	cache, clock := s.newCache(backend)
+	defer cache.Close()
+	// The timeout is measured by the injected clock.
+	clock.expire(true)
+	_, err := cache.QueryConfiguration(0)
+	s.Require().Equal(ErrGovernanceTimeout, err)
+	s.Require().Nil(cache.Configuration(0))
+	// Helpers return the error instead of panic.
+	_, err = GetConfig(cache, 0, nil)
+	s.Require().Equal(ErrGovernanceTimeout, err)
+	_, err = GetCRS(cache, 0, nil)
+	s.Require().Equal(ErrGovernanceTimeout, err)
+	// When the backend responses, the result is memoized.
+	clock.expire(false)
+	close(backend.release)
+	config, err := GetConfig(cache, 0, nil)
+	s.Require().NoError(err)
+	s.Require().Equal(uint32(4), config.NotarySetSize)
+	s.Require().Equal(1, backend.numCalls("Configuration"))
+	_, err = GetCRS(cache, 0, nil)
+	s.Require().Equal(ErrCRSNotReady, err)
+}
+
+func (s *GovernanceCacheTestSuite) TestResetCountCached() {
+	backend := newGovernanceCacheBackend()
+	close(backend.release)
+	cache, clock := s.newCache(backend)
+	defer cache.Close()
+	s.Require().Equal(uint64(0), cache.DKGResetCount(1))
+	backend.lock.Lock()
+	backend.resetCount = 1
+	backend.lock.Unlock()
+	s.Require().Equal(uint64(0), cache.DKGResetCount(1))
+	s.Require().Equal(1, backend.numCalls("DKGResetCount"))
+	// The reset count is queried again when expired.
+	clock.advance(time.Minute)
+	s.Require().Equal(uint64(1), cache.DKGResetCount(1))
+	s.Require().Equal(2, backend.numCalls("DKGResetCount"))
+}
+
+func (s *GovernanceCacheTestSuite) TestSendNonBlocking() {
+	backend := newGovernanceCacheBackend()
+	cache, _ := s.newCache(backend)
+	defer cache.Close()
+	// Writes are queued without blocking when the backend is stuck.
+	count := 5000
+	for i := 0; i < count; i++ {
+		cache.ProposeCRS(uint64(i), nil)
+	}
+	close(backend.release)
+	for i := 0; i < 100 && backend.numCalls("ProposeCRS") < count; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+	s.Require().Len(backend.sent, count)
+	for i, round := range backend.sent {
+		s.Require().Equal(uint64(i), round)
+	}
+}
+
+func TestGovernanceCache(t *testing.T) {
+	suite.Run(t, new(GovernanceCacheTestSuite))
+}
@@ -56,6 +56,14 @@ type NodeSetCacheInterface interface {
 	NodeSet(round uint64) []crypto.PublicKey
 }
 
+// NodeWeightInterface is an optional interface of NodeSetCacheInterface to
+// provide weights of nodes, which are usually their stakes.
+type NodeWeightInterface interface {
+	// NodeWeights returns weights of nodes at a given round, nodes not found
+	// are weighted by 1.
+	NodeWeights(round uint64) map[types.NodeID]uint64
+}
+
 // NodeSetCache caches node set information.
 //
 // NOTE: this module doesn't handle DKG resetting and can only be used along
@@ -132,10 +140,31 @@ func (cache *NodeSetCache) GetNotarySet(
 	return cache.cloneMap(IDs.notarySet), nil
 }
 
+// GetNotarySetWeights returns weights of nodes in notary set of this round.
+func (cache *NodeSetCache) GetNotarySetWeights(
+	round uint64) (map[types.NodeID]uint64, error) {
+	IDs, err := cache.getOrUpdate(round)
+	if err != nil {
+		return nil, err
+	}
+	weights := make(map[types.NodeID]uint64, len(IDs.notarySet))
+	for nID := range IDs.notarySet {
+		weights[nID] = IDs.nodeSet.Weight(nID)
+	}
+	return weights, nil
+}
+
 // Purge a specific round.
 func (cache *NodeSetCache) Purge(rID uint64) {
 	cache.lock.Lock()
 	defer cache.lock.Unlock()
+	cache.purgeNoLock(rID)
+}
+
+// purgeNoLock removes a round and releases public keys of nodes in that
+// round, keys of nodes leaving the node set are removed when no cached round
+// refers to them.
+func (cache *NodeSetCache) purgeNoLock(rID uint64) {
 	nIDs, exist := cache.rounds[rID]
 	if !exist {
 		return
@@ -193,11 +222,28 @@ func (cache *NodeSetCache) update(round uint64) (nIDs *sets, err error) {
 		err = ErrCRSNotReady
 		return
 	}
+	var weights map[types.NodeID]uint64
+	if weightIntf, ok := cache.nsIntf.(NodeWeightInterface); ok {
+		weights = weightIntf.NodeWeights(round)
+	}
+	cfg := cache.nsIntf.Configuration(round)
+	if cfg == nil {
+		err = ErrConfigurationNotReady
+		return
+	}
+	// The node set of a round might be updated more than once, release keys
+	// referred by the previous one, or keys of nodes left would never be
+	// removed.
+	cache.purgeNoLock(round)
 	// Cache new round.
 	nodeSet := types.NewNodeSet()
 	for _, key := range keySet {
 		nID := types.NewNodeID(key)
-		nodeSet.Add(nID)
+		if weight, exist := weights[nID]; exist {
+			nodeSet.AddWithWeight(nID, weight)
+		} else {
+			nodeSet.Add(nID)
+		}
 		if rec, exists := cache.keyPool[nID]; exists {
 			rec.refCnt++
 		} else {
@@ -207,11 +253,6 @@ func (cache *NodeSetCache) update(round uint64) (nIDs *sets, err error) {
 			}{key, 1}
 		}
 	}
-	cfg := cache.nsIntf.Configuration(round)
-	if cfg == nil {
-		err = ErrConfigurationNotReady
-		return
-	}
 	nIDs = &sets{
 		crs:       crs,
 		nodeSet:   nodeSet,
@@ -221,18 +262,11 @@ func (cache *NodeSetCache) update(round uint64) (nIDs *sets, err error) {
 		int(cfg.NotarySetSize), types.NewNotarySetTarget(crs))
 	cache.rounds[round] = nIDs
 	// Purge older rounds.
-	for rID, nIDs := range cache.rounds {
-		nodeSet := nIDs.nodeSet
-		if round-rID <= 5 {
+	for rID := range cache.rounds {
+		if rID+5 >= round {
 			continue
 		}
-		for nID := range nodeSet.IDs {
-			rec := cache.keyPool[nID]
-			if rec.refCnt--; rec.refCnt == 0 {
-				delete(cache.keyPool, nID)
-			}
-		}
-		delete(cache.rounds, rID)
+		cache.purgeNoLock(rID)
 	}
 	return
 }
@@ -54,6 +54,24 @@ func (g *nsIntf) NodeSet(round uint64) []crypto.PublicKey {
 	return g.curKeys
 }
 
+// weightedNsIntf implements NodeWeightInterface, the first three nodes are
+// weighted by 0 and the fourth one is weighted by 100.
+type weightedNsIntf struct {
+	nsIntf
+}
+
+func (g *weightedNsIntf) NodeWeights(round uint64) map[types.NodeID]uint64 {
+	weights := make(map[types.NodeID]uint64)
+	for idx, key := range g.curKeys[:4] {
+		if idx < 3 {
+			weights[types.NewNodeID(key)] = 0
+		} else {
+			weights[types.NewNodeID(key)] = 100
+		}
+	}
+	return weights
+}
+
 type NodeSetCacheTestSuite struct {
 	suite.Suite
 }
@@ -106,6 +124,38 @@ func (s *NodeSetCacheTestSuite) TestBasicUsage() {
 	}
 }
 
+func (s *NodeSetCacheTestSuite) TestNodeWeights() {
+	var (
+		nsIntf = &weightedNsIntf{nsIntf{
+			s:   s,
+			crs: common.NewRandomHash(),
+		}}
+		cache = NewNodeSetCache(nsIntf)
+		req   = s.Require()
+	)
+	weights, err := cache.GetNotarySetWeights(0)
+	req.NoError(err)
+	// Nodes weighted by 0 should never be selected, and there are exactly 7
+	// nodes left for a notary set of size 7.
+	req.Len(weights, 7)
+	for idx, key := range nsIntf.curKeys {
+		weight, exist := weights[types.NewNodeID(key)]
+		switch {
+		case idx < 3:
+			req.False(exist)
+		case idx == 3:
+			req.True(exist)
+			req.Equal(uint64(100), weight)
+		default:
+			req.True(exist)
+			req.Equal(uint64(1), weight)
+		}
+	}
+	nodeSet, err := cache.GetNodeSet(0)
+	req.NoError(err)
+	req.Equal(uint64(106), nodeSet.TotalWeight(nodeSet.IDs))
+}
+
 func (s *NodeSetCacheTestSuite) TestTouch() {
 	var (
 		nsIntf = &nsIntf{
@@ -144,6 +194,37 @@ func (s *NodeSetCacheTestSuite) TestPurge() {
 	req.False(exist)
 }
 
+func (s *NodeSetCacheTestSuite) TestNodeLeave() {
+	var (
+		nsIntf = &nsIntf{
+			s:   s,
+			crs: common.NewRandomHash(),
+		}
+		cache = NewNodeSetCache(nsIntf)
+		req   = s.Require()
+	)
+	// Nodes in the previous node set of the same round leave when touched
+	// again, their keys should be removed.
+	req.NoError(cache.Touch(1))
+	oldKeys := nsIntf.curKeys
+	req.NoError(cache.Touch(1))
+	for _, key := range oldKeys {
+		_, exists := cache.GetPublicKey(types.NewNodeID(key))
+		req.False(exists)
+	}
+	for _, key := range nsIntf.curKeys {
+		_, exists := cache.GetPublicKey(types.NewNodeID(key))
+		req.True(exists)
+	}
+	// Updating an older round should not purge newer rounds.
+	req.NoError(cache.Touch(10))
+	req.NoError(cache.Touch(2))
+	_, exists := cache.get(10)
+	req.True(exists)
+	_, exists = cache.get(1)
+	req.False(exists)
+}
+
 func TestNodeSetCache(t *testing.T) {
 	suite.Run(t, new(NodeSetCacheTestSuite))
 }
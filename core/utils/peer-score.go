@@ -0,0 +1,283 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// negligibleScore is the score magnitude below which a peer is forgotten.
+const negligibleScore = 0.01
+
+// PeerStatus is the status of a peer derived from its score.
+type PeerStatus int
+
+// PeerStatus enums.
+const (
+	PeerNormal PeerStatus = iota
+	PeerDeprioritized
+	PeerBanned
+)
+
+func (s PeerStatus) String() string {
+	switch s {
+	case PeerNormal:
+		return "Normal"
+	case PeerDeprioritized:
+		return "Deprioritized"
+	case PeerBanned:
+		return "Banned"
+	}
+	return "Unknown"
+}
+
+// PeerScoreConfig is the configuration of PeerScorer.
+type PeerScoreConfig struct {
+	// Penalties is the score deducted for each kind of offense, the penalty
+	// of types.OffenseUnknown is applied to offenses not listed here.
+	Penalties map[types.Offense]float64
+	// HalfLife is the duration for a score to decay to its half.
+	HalfLife time.Duration
+	// DeprioritizeThreshold is the score at or below which a peer is
+	// deprioritized.
+	DeprioritizeThreshold float64
+	// BanThreshold is the score at or below which a peer is banned.
+	BanThreshold float64
+	// BanDuration is how long a ban lasts.
+	BanDuration time.Duration
+	// Clock is used to decay scores, the wall-clock would be used when it's
+	// nil.
+	Clock common.Clock
+	// MaxPeers is the maximum number of peers tracked, the least punished
+	// peer is forgotten to track a new one when it's reached. It's unlimited
+	// when it's 0.
+	MaxPeers int
+}
+
+// DefaultPeerScoreConfig returns the default configuration of PeerScorer.
+func DefaultPeerScoreConfig() PeerScoreConfig {
+	return PeerScoreConfig{
+		Penalties: map[types.Offense]float64{
+			types.OffenseUnknown:               5,
+			types.OffenseBadSignature:          20,
+			types.OffenseWrongRandomness:       20,
+			types.OffenseInvalidEmptyBlockHash: 10,
+			types.OffenseUnsolicitedDKGShare:   10,
+			types.OffenseInvalidMessage:        5,
+		},
+		HalfLife:              time.Minute,
+		DeprioritizeThreshold: -20,
+		BanThreshold:          -50,
+		BanDuration:           5 * time.Minute,
+		MaxPeers:              1024,
+	}
+}
+
+type peerScore struct {
+	score       float64
+	updated     time.Time
+	bannedUntil time.Time
+}
+
+// PeerScorer keeps reputation of peers from reports of their offenses. Scores
+// decay toward zero over time, peers with low scores are deprioritized or
+// banned for a while. It's safe for concurrent use and is meant to be shared
+// by implementations of core.Network. Peers are identified by map keys, thus
+// peer IDs of types not comparable are ignored.
+type PeerScorer struct {
+	config    PeerScoreConfig
+	clock     common.Clock
+	lock      sync.Mutex
+	peers     map[interface{}]*peerScore
+	lastSweep time.Time
+}
+
+// NewPeerScorer constructs a PeerScorer instance.
+func NewPeerScorer(config PeerScoreConfig) *PeerScorer {
+	s := &PeerScorer{
+		config: config,
+		clock:  config.Clock,
+		peers:  make(map[interface{}]*peerScore),
+	}
+	if s.clock == nil {
+		s.clock = &common.SystemClock{}
+	}
+	s.lastSweep = s.clock.Now()
+	return s
+}
+
+// isValidPeer checks if a peer ID could be used as a map key.
+func isValidPeer(peer interface{}) bool {
+	return peer != nil && reflect.TypeOf(peer).Comparable()
+}
+
+// Report applies the penalty of a report and returns the reported peer with
+// its latest status. The report could be a *types.BadPeerReport or just the
+// peer ID, which is treated as types.OffenseUnknown. Reports of invalid peer
+// IDs are ignored, and nil is returned as the peer.
+func (s *PeerScorer) Report(report interface{}) (
+	peer interface{}, status PeerStatus) {
+	offense := types.OffenseUnknown
+	switch r := report.(type) {
+	case *types.BadPeerReport:
+		peer, offense = r.PeerID, r.Offense
+	default:
+		peer = r
+	}
+	if !isValidPeer(peer) {
+		peer = nil
+		return
+	}
+	penalty, exists := s.config.Penalties[offense]
+	if !exists {
+		penalty = s.config.Penalties[types.OffenseUnknown]
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := s.clock.Now()
+	s.sweepNoLock(now)
+	ps := s.decayNoLock(peer, now)
+	if ps == nil {
+		if s.config.MaxPeers > 0 && len(s.peers) >= s.config.MaxPeers {
+			s.evictNoLock(now)
+		}
+		ps = &peerScore{updated: now}
+		s.peers[peer] = ps
+	}
+	ps.score -= penalty
+	if ps.score <= s.config.BanThreshold {
+		ps.bannedUntil = now.Add(s.config.BanDuration)
+	}
+	status = s.statusNoLock(ps, now)
+	return
+}
+
+// Score returns the current score of a peer, 0 for unknown peers.
+func (s *PeerScorer) Score(peer interface{}) float64 {
+	if !isValidPeer(peer) {
+		return 0
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if ps := s.decayNoLock(peer, s.clock.Now()); ps != nil {
+		return ps.score
+	}
+	return 0
+}
+
+// Status returns the current status of a peer.
+func (s *PeerScorer) Status(peer interface{}) PeerStatus {
+	if !isValidPeer(peer) {
+		return PeerNormal
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := s.clock.Now()
+	return s.statusNoLock(s.decayNoLock(peer, now), now)
+}
+
+func (s *PeerScorer) statusNoLock(ps *peerScore, now time.Time) PeerStatus {
+	switch {
+	case ps == nil:
+		return PeerNormal
+	case now.Before(ps.bannedUntil):
+		return PeerBanned
+	case ps.score <= s.config.DeprioritizeThreshold:
+		return PeerDeprioritized
+	}
+	return PeerNormal
+}
+
+// decayNoLock brings the score of a peer up to date, peers not banned and
+// with negligible scores are forgotten.
+func (s *PeerScorer) decayNoLock(
+	peer interface{}, now time.Time) *peerScore {
+	ps, exists := s.peers[peer]
+	if !exists {
+		return nil
+	}
+	if elapsed := now.Sub(ps.updated); elapsed > 0 {
+		if s.config.HalfLife > 0 {
+			ps.score *= math.Pow(
+				0.5, float64(elapsed)/float64(s.config.HalfLife))
+		}
+		ps.updated = now
+	}
+	if ps.score > -negligibleScore && !now.Before(ps.bannedUntil) {
+		delete(s.peers, peer)
+		return nil
+	}
+	return ps
+}
+
+// sweepNoLock forgets peers with negligible scores once per half-life, thus
+// peers never queried again don't stay forever.
+func (s *PeerScorer) sweepNoLock(now time.Time) {
+	if s.config.HalfLife <= 0 || now.Sub(s.lastSweep) < s.config.HalfLife {
+		return
+	}
+	s.lastSweep = now
+	for peer := range s.peers {
+		s.decayNoLock(peer, now)
+	}
+}
+
+// evictNoLock forgets the least punished peer unless some peer is forgotten
+// after decay. Banned peers are only forgotten when all peers are banned, the
+// one whose ban ends first is picked then.
+func (s *PeerScorer) evictNoLock(now time.Time) {
+	var (
+		victim    interface{}
+		victimPS  *peerScore
+		forgotten bool
+	)
+	for peer := range s.peers {
+		ps := s.decayNoLock(peer, now)
+		if ps == nil {
+			forgotten = true
+			continue
+		}
+		if victimPS == nil {
+			victim, victimPS = peer, ps
+			continue
+		}
+		banned := now.Before(ps.bannedUntil)
+		victimBanned := now.Before(victimPS.bannedUntil)
+		switch {
+		case banned != victimBanned:
+			if !banned {
+				victim, victimPS = peer, ps
+			}
+		case banned:
+			if ps.bannedUntil.Before(victimPS.bannedUntil) {
+				victim, victimPS = peer, ps
+			}
+		case ps.score > victimPS.score:
+			victim, victimPS = peer, ps
+		}
+	}
+	if !forgotten && victimPS != nil {
+		delete(s.peers, victim)
+	}
+}
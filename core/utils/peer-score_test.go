@@ -0,0 +1,196 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+)
+
+// manualClock is a common.Clock whose time only moves when told to.
+type manualClock struct {
+	common.SystemClock
+
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+type PeerScoreTestSuite struct {
+	suite.Suite
+}
+
+func (s *PeerScoreTestSuite) newScorer() (*PeerScorer, *manualClock) {
+	clock := &manualClock{now: time.Now()}
+	config := DefaultPeerScoreConfig()
+	config.Clock = clock
+	return NewPeerScorer(config), clock
+}
+
+func (s *PeerScoreTestSuite) TestOffenses() {
+	scorer, _ := s.newScorer()
+	peer := types.NodeID{Hash: common.NewRandomHash()}
+	// A raw peer ID is treated as an unknown offense.
+	reported, status := scorer.Report(peer)
+	s.Require().Equal(peer, reported)
+	s.Require().Equal(PeerNormal, status)
+	s.Require().Equal(float64(-5), scorer.Score(peer))
+	// Bad signatures are punished more than invalid messages.
+	_, status = scorer.Report(&types.BadPeerReport{
+		PeerID:  peer,
+		Offense: types.OffenseBadSignature,
+		Err:     errors.New("bad signature"),
+	})
+	s.Require().Equal(PeerDeprioritized, status)
+	s.Require().Equal(float64(-25), scorer.Score(peer))
+	s.Require().Equal(PeerDeprioritized, scorer.Status(peer))
+	// Other peers are not affected.
+	other := types.NodeID{Hash: common.NewRandomHash()}
+	s.Require().Equal(PeerNormal, scorer.Status(other))
+	s.Require().Equal(float64(0), scorer.Score(other))
+	// Nil peer is ignored.
+	reported, status = scorer.Report(nil)
+	s.Require().Nil(reported)
+	s.Require().Equal(PeerNormal, status)
+}
+
+func (s *PeerScoreTestSuite) TestSpammingPeerBanned() {
+	scorer, clock := s.newScorer()
+	spammer := types.NodeID{Hash: common.NewRandomHash()}
+	report := &types.BadPeerReport{
+		PeerID:  spammer,
+		Offense: types.OffenseWrongRandomness,
+	}
+	var status PeerStatus
+	for i := 0; i < 3; i++ {
+		_, status = scorer.Report(report)
+	}
+	s.Require().Equal(PeerBanned, status)
+	// The ban stays even if the score recovers.
+	clock.now = clock.now.Add(4 * time.Minute)
+	s.Require().Equal(PeerBanned, scorer.Status(spammer))
+	s.Require().True(scorer.Score(spammer) > -20)
+	// The ban is lifted after BanDuration.
+	clock.now = clock.now.Add(time.Minute)
+	s.Require().Equal(PeerNormal, scorer.Status(spammer))
+}
+
+func (s *PeerScoreTestSuite) TestDecay() {
+	scorer, clock := s.newScorer()
+	peer := types.NodeID{Hash: common.NewRandomHash()}
+	scorer.Report(&types.BadPeerReport{
+		PeerID:  peer,
+		Offense: types.OffenseBadSignature,
+	})
+	s.Require().Equal(PeerDeprioritized, scorer.Status(peer))
+	// Half of the score is recovered after a half-life.
+	clock.now = clock.now.Add(time.Minute)
+	s.Require().InDelta(-10, scorer.Score(peer), 0.0001)
+	s.Require().Equal(PeerNormal, scorer.Status(peer))
+	// Peers with negligible scores are forgotten.
+	clock.now = clock.now.Add(time.Hour)
+	s.Require().Equal(float64(0), scorer.Score(peer))
+	s.Require().Len(scorer.peers, 0)
+}
+
+func (s *PeerScoreTestSuite) TestInvalidPeer() {
+	scorer, _ := s.newScorer()
+	// Peer IDs not comparable are ignored instead of panicking.
+	for _, peer := range []interface{}{
+		[]byte{1, 2, 3},
+		map[string]int{},
+		struct{ IDs []types.NodeID }{},
+	} {
+		reported, status := scorer.Report(peer)
+		s.Require().Nil(reported)
+		s.Require().Equal(PeerNormal, status)
+		_, status = scorer.Report(&types.BadPeerReport{
+			PeerID:  peer,
+			Offense: types.OffenseBadSignature,
+		})
+		s.Require().Equal(PeerNormal, status)
+		s.Require().Equal(float64(0), scorer.Score(peer))
+		s.Require().Equal(PeerNormal, scorer.Status(peer))
+	}
+	s.Require().Len(scorer.peers, 0)
+}
+
+func (s *PeerScoreTestSuite) TestSweep() {
+	scorer, clock := s.newScorer()
+	peers := []types.NodeID{}
+	for i := 0; i < 10; i++ {
+		peer := types.NodeID{Hash: common.NewRandomHash()}
+		peers = append(peers, peer)
+		scorer.Report(peer)
+	}
+	s.Require().Len(scorer.peers, 10)
+	// Peers never queried again are forgotten by later reports.
+	clock.now = clock.now.Add(time.Hour)
+	scorer.Report(peers[0])
+	s.Require().Len(scorer.peers, 1)
+	s.Require().Equal(float64(-5), scorer.Score(peers[0]))
+}
+
+func (s *PeerScoreTestSuite) TestMaxPeers() {
+	clock := &manualClock{now: time.Now()}
+	config := DefaultPeerScoreConfig()
+	config.Clock = clock
+	config.MaxPeers = 3
+	scorer := NewPeerScorer(config)
+	newPeer := func() types.NodeID {
+		return types.NodeID{Hash: common.NewRandomHash()}
+	}
+	banned, bad, minor := newPeer(), newPeer(), newPeer()
+	for i := 0; i < 3; i++ {
+		scorer.Report(&types.BadPeerReport{
+			PeerID:  banned,
+			Offense: types.OffenseBadSignature,
+		})
+	}
+	s.Require().Equal(PeerBanned, scorer.Status(banned))
+	scorer.Report(&types.BadPeerReport{
+		PeerID:  bad,
+		Offense: types.OffenseBadSignature,
+	})
+	scorer.Report(minor)
+	// The least punished peer is forgotten for a new one.
+	other := newPeer()
+	scorer.Report(other)
+	s.Require().Len(scorer.peers, 3)
+	s.Require().Equal(float64(0), scorer.Score(minor))
+	s.Require().Equal(PeerBanned, scorer.Status(banned))
+	s.Require().Equal(float64(-20), scorer.Score(bad))
+	s.Require().Equal(float64(-5), scorer.Score(other))
+	// Banned peers are kept while others could be forgotten.
+	scorer.Report(newPeer())
+	scorer.Report(newPeer())
+	s.Require().Len(scorer.peers, 3)
+	s.Require().Equal(PeerBanned, scorer.Status(banned))
+}
+
+func TestPeerScore(t *testing.T) {
+	suite.Run(t, new(PeerScoreTestSuite))
+}
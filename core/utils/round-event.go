@@ -166,6 +166,8 @@ type RoundEvent struct {
 	lastTriggeredResetCount uint64
 	roundShift              uint64
 	gpkInvalid              bool
+	initCRS                 common.Hash
+	initConfig              *types.Config
 	ctx                     context.Context
 	ctxCancel               context.CancelFunc
 }
@@ -177,12 +179,21 @@ func NewRoundEvent(parentCtx context.Context, gov governanceAccessor,
 	// We need to generate valid ending block height of this round (taken
 	// DKG reset count into consideration).
 	logger.Info("New RoundEvent", "position", initPos, "shift", roundShift)
-	initConfig := GetConfigWithPanic(gov, initPos.Round, logger)
+	initConfig, err := GetConfig(gov, initPos.Round, logger)
+	if err != nil {
+		return nil, err
+	}
+	initCRS, err := GetCRS(gov, initPos.Round, logger)
+	if err != nil {
+		return nil, err
+	}
 	e := &RoundEvent{
 		gov:                gov,
 		logger:             logger,
 		lastTriggeredRound: initPos.Round,
 		roundShift:         roundShift,
+		initCRS:            initCRS,
+		initConfig:         initConfig,
 	}
 	e.ctx, e.ctxCancel = context.WithCancel(parentCtx)
 	e.config = RoundBasedConfig{}
@@ -226,8 +237,8 @@ func (e *RoundEvent) TriggerInitEvent() {
 		Round:       e.lastTriggeredRound,
 		Reset:       e.lastTriggeredResetCount,
 		BeginHeight: e.config.LastPeriodBeginHeight(),
-		CRS:         GetCRSWithPanic(e.gov, e.lastTriggeredRound, e.logger),
-		Config:      GetConfigWithPanic(e.gov, e.lastTriggeredRound, e.logger),
+		CRS:         e.initCRS,
+		Config:      e.initConfig,
 	}}
 	for _, h := range e.handlers {
 		h(events)
@@ -279,6 +290,12 @@ func (e *RoundEvent) ValidateNextRound(blockHeight uint64) (count uint) {
 
 func (e *RoundEvent) check(blockHeight, startRound uint64) (
 	param RoundEventParam, triggered bool) {
+	// CRS and config of the triggered round are queried before any state is
+	// changed, failed queries would be retried in later checks.
+	var (
+		crs common.Hash
+		cfg *types.Config
+	)
 	defer func() {
 		if !triggered {
 			return
@@ -291,8 +308,8 @@ func (e *RoundEvent) check(blockHeight, startRound uint64) (
 		param.Round = e.lastTriggeredRound
 		param.Reset = e.lastTriggeredResetCount
 		param.BeginHeight = e.config.LastPeriodBeginHeight()
-		param.CRS = GetCRSWithPanic(e.gov, e.lastTriggeredRound, e.logger)
-		param.Config = GetConfigWithPanic(e.gov, e.lastTriggeredRound, e.logger)
+		param.CRS = crs
+		param.Config = cfg
 		e.logger.Info("New RoundEvent triggered",
 			"round", e.lastTriggeredRound+1,
 			"reset", e.lastTriggeredResetCount,
@@ -307,9 +324,26 @@ func (e *RoundEvent) check(blockHeight, startRound uint64) (
 		// knows.
 		return
 	}
-	nextCfg := GetConfigWithPanic(e.gov, nextRound, e.logger)
+	nextCfg, err := GetConfig(e.gov, nextRound, e.logger)
+	if err != nil {
+		e.logger.Warn("Failed to get config", "round", nextRound, "error", err)
+		return
+	}
 	resetCount := e.gov.DKGResetCount(nextRound)
 	if resetCount > e.lastTriggeredResetCount {
+		if crs, err = GetCRS(e.gov, e.lastTriggeredRound, e.logger); err != nil {
+			e.logger.Warn("Failed to get CRS",
+				"round", e.lastTriggeredRound,
+				"error", err)
+			return
+		}
+		if cfg, err = GetConfig(
+			e.gov, e.lastTriggeredRound, e.logger); err != nil {
+			e.logger.Warn("Failed to get config",
+				"round", e.lastTriggeredRound,
+				"error", err)
+			return
+		}
 		e.lastTriggeredResetCount++
 		e.config.ExtendLength()
 		e.gpkInvalid = false
@@ -330,6 +364,11 @@ func (e *RoundEvent) check(blockHeight, startRound uint64) (
 			return
 		}
 	}
+	if crs, err = GetCRS(e.gov, nextRound, e.logger); err != nil {
+		e.logger.Warn("Failed to get CRS", "round", nextRound, "error", err)
+		return
+	}
+	cfg = nextCfg
 	// The DKG set for next round is well prepared.
 	e.lastTriggeredRound = nextRound
 	e.lastTriggeredResetCount = 0
@@ -79,6 +79,14 @@ func (s *Signer) SignVote(v *types.Vote) (err error) {
 	return
 }
 
+// SignAgreementCertificate signs a types.AgreementCertificate.
+func (s *Signer) SignAgreementCertificate(
+	cert *types.AgreementCertificate) (err error) {
+	cert.ProposerID = s.proposerID
+	cert.ProposerSignature, err = s.prvKey.Sign(HashAgreementCertificate(cert))
+	return
+}
+
 // SignCRS signs CRS signature of types.Block.
 func (s *Signer) SignCRS(b *types.Block, crs common.Hash) (err error) {
 	if b.ProposerID != s.proposerID {
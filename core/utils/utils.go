@@ -37,16 +37,36 @@ type configAccessor interface {
 	Configuration(round uint64) *types.Config
 }
 
-// GetConfigWithPanic is a helper to access configs, and panic when config for
-// that round is not ready yet.
-func GetConfigWithPanic(accessor configAccessor, round uint64,
-	logger common.Logger) *types.Config {
+// configQuerier is implemented by governances able to tell why a config is
+// not available, ex. GovernanceCache.
+type configQuerier interface {
+	QueryConfiguration(round uint64) (*types.Config, error)
+}
+
+// GetConfig is a helper to access configs, an error is returned when config
+// for that round is not available.
+func GetConfig(accessor configAccessor, round uint64,
+	logger common.Logger) (*types.Config, error) {
 	if logger != nil {
 		logger.Debug("Calling Governance.Configuration", "round", round)
 	}
+	if q, ok := accessor.(configQuerier); ok {
+		return q.QueryConfiguration(round)
+	}
 	c := accessor.Configuration(round)
 	if c == nil {
-		panic(fmt.Errorf("configuration is not ready %v", round))
+		return nil, ErrConfigurationNotReady
+	}
+	return c, nil
+}
+
+// GetConfigWithPanic is a helper to access configs, and panic when config for
+// that round is not ready yet.
+func GetConfigWithPanic(accessor configAccessor, round uint64,
+	logger common.Logger) *types.Config {
+	c, err := GetConfig(accessor, round, logger)
+	if err != nil {
+		panic(fmt.Errorf("configuration is not ready %v: %v", round, err))
 	}
 	return c
 }
@@ -55,16 +75,36 @@ type crsAccessor interface {
 	CRS(round uint64) common.Hash
 }
 
-// GetCRSWithPanic is a helper to access CRS, and panic when CRS for that
-// round is not ready yet.
-func GetCRSWithPanic(accessor crsAccessor, round uint64,
-	logger common.Logger) common.Hash {
+// crsQuerier is implemented by governances able to tell why a CRS is not
+// available, ex. GovernanceCache.
+type crsQuerier interface {
+	QueryCRS(round uint64) (common.Hash, error)
+}
+
+// GetCRS is a helper to access CRS, an error is returned when CRS for that
+// round is not available.
+func GetCRS(accessor crsAccessor, round uint64,
+	logger common.Logger) (common.Hash, error) {
 	if logger != nil {
 		logger.Debug("Calling Governance.CRS", "round", round)
 	}
+	if q, ok := accessor.(crsQuerier); ok {
+		return q.QueryCRS(round)
+	}
 	crs := accessor.CRS(round)
 	if (crs == common.Hash{}) {
-		panic(fmt.Errorf("CRS is not ready %v", round))
+		return common.Hash{}, ErrCRSNotReady
+	}
+	return crs, nil
+}
+
+// GetCRSWithPanic is a helper to access CRS, and panic when CRS for that
+// round is not ready yet.
+func GetCRSWithPanic(accessor crsAccessor, round uint64,
+	logger common.Logger) common.Hash {
+	crs, err := GetCRS(accessor, round, logger)
+	if err != nil {
+		panic(fmt.Errorf("CRS is not ready %v: %v", round, err))
 	}
 	return crs
 }
@@ -140,6 +180,15 @@ func GetDKGNackThreshold(config *types.Config) int {
 }
 
 // GetDKGThreshold return expected threshold for given DKG set size.
+//
+// The threshold counts shares instead of weights, each notary holds exactly
+// one share no matter its weight, weights only decide which nodes are
+// selected by types.NodeSet.GetSubSet. Let k be the notary set size and p be
+// the total weight of byzantine nodes divided by the total weight excluding
+// the k-1 heaviest nodes. Each pick of GetSubSet selects a byzantine node
+// with probability at most p, thus when p < 1/3, the chance that byzantine
+// nodes are more than 1/3 of the notary set is at most exp(-2k(1/3-p)^2) by
+// Hoeffding's inequality.
 func GetDKGThreshold(config *types.Config) int {
 	return int(config.NotarySetSize*2/3) + 1
 }
@@ -154,6 +203,18 @@ func GetBAThreshold(config *types.Config) int {
 	return int(config.NotarySetSize*2/3 + 1)
 }
 
+// GetBAWeightThreshold return threshold of accumulated weights for BA votes,
+// given the total weight of notary set.
+func GetBAWeightThreshold(totalWeight uint64) uint64 {
+	return totalWeight*2/3 + 1
+}
+
+// GetDKGValidWeightThreshold return threshold of accumulated weights for DKG
+// set to be considered valid, given the total weight of DKG set.
+func GetDKGValidWeightThreshold(totalWeight uint64) uint64 {
+	return totalWeight * 5 / 6
+}
+
 // GetNextRoundValidationHeight returns the block height to check if the next
 // round is ready.
 func GetNextRoundValidationHeight(begin, length uint64) uint64 {
@@ -185,7 +246,14 @@ func IsDKGValid(
 		logger.Debug("DKG is not successful", "round", round, "reset", reset)
 		return
 	}
-	cfg := GetConfigWithPanic(gov, round, logger)
+	cfg, err := GetConfig(gov, round, logger)
+	if err != nil {
+		logger.Debug("Config is not ready",
+			"round", round,
+			"reset", reset,
+			"error", err)
+		return
+	}
 	gpk, err := typesDKG.NewGroupPublicKey(
 		round,
 		gov.DKGMasterPublicKeys(round),
@@ -207,6 +275,33 @@ func IsDKGValid(
 		gpkInvalid = true
 		return
 	}
+	if weightIntf, ok := gov.(NodeWeightInterface); ok {
+		// The qualified nodes should also hold enough weights among nodes
+		// joining this DKG.
+		weights := weightIntf.NodeWeights(round)
+		weightOf := func(nID types.NodeID) uint64 {
+			if weight, exist := weights[nID]; exist {
+				return weight
+			}
+			return 1
+		}
+		var total, qualified uint64
+		for _, mpk := range gov.DKGMasterPublicKeys(round) {
+			total += weightOf(mpk.ProposerID)
+		}
+		for nID := range gpk.QualifyNodeIDs {
+			qualified += weightOf(nID)
+		}
+		if qualified < GetDKGValidWeightThreshold(total) {
+			logger.Debug("Group public key weight threshold not reach",
+				"round", round,
+				"reset", reset,
+				"qualified", qualified,
+				"total", total)
+			gpkInvalid = true
+			return
+		}
+	}
 	valid = true
 	return
 }
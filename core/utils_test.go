@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 	"github.com/tangerine-network/tangerine-consensus/core/test"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 	"github.com/tangerine-network/tangerine-consensus/core/utils"
@@ -119,6 +120,45 @@ func (s *UtilsTestSuite) TestVerifyAgreementResult() {
 	s.Equal(ErrNotEnoughVotes, VerifyAgreementResult(baResult, cache))
 }
 
+func (s *UtilsTestSuite) TestVerifyWeightedAgreementResult() {
+	prvKeys, pubKeys, err := test.NewKeys(4)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	// The total weight is 8, and the threshold is 6.
+	gov.SetNodeWeights(map[types.NodeID]uint64{
+		types.NewNodeID(pubKeys[0]): 5,
+	})
+	cache := utils.NewNodeSetCache(gov)
+	hash := common.NewRandomHash()
+	pos := types.Position{
+		Round:  0,
+		Height: 20,
+	}
+	newResult := func(prvKeys []crypto.PrivateKey) *types.AgreementResult {
+		baResult := &types.AgreementResult{
+			BlockHash: hash,
+			Position:  pos,
+		}
+		for _, prvKey := range prvKeys {
+			vote := types.NewVote(types.VoteCom, hash, 0)
+			vote.Position = pos
+			s.Require().NoError(utils.NewSigner(prvKey).SignVote(vote))
+			baResult.Votes = append(baResult.Votes, *vote)
+		}
+		return baResult
+	}
+	// The heavy node and one light node are enough.
+	s.Require().NoError(VerifyAgreementResult(newResult(prvKeys[:2]), cache))
+	// Light nodes are not enough, even they are the majority.
+	s.Require().Equal(ErrNotEnoughVotes,
+		VerifyAgreementResult(newResult(prvKeys[1:]), cache))
+	// The heavy node alone is not enough.
+	s.Require().Equal(ErrNotEnoughVotes,
+		VerifyAgreementResult(newResult(prvKeys[:1]), cache))
+}
+
 func TestUtils(t *testing.T) {
 	suite.Run(t, new(UtilsTestSuite))
 }
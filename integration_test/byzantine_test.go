@@ -42,10 +42,14 @@ type ByzantineTestSuite struct {
 	suite.Suite
 
 	directLatencyModel map[types.NodeID]test.LatencyModel
+	faults             *test.FaultPlan
+	behaviors          map[types.NodeID][]test.ByzantineBehavior
 }
 
 func (s *ByzantineTestSuite) SetupTest() {
 	s.directLatencyModel = make(map[types.NodeID]test.LatencyModel)
+	s.faults = nil
+	s.behaviors = make(map[types.NodeID][]test.ByzantineBehavior)
 }
 
 func (s *ByzantineTestSuite) setupNodes(
@@ -77,12 +81,14 @@ func (s *ByzantineTestSuite) setupNodes(
 			Type:          test.NetworkTypeFake,
 			DirectLatency: directLatencyModel,
 			GossipLatency: &test.FixedLatencyModel{},
-			Marshaller:    test.NewDefaultMarshaller(nil)},
+			Marshaller:    test.NewDefaultMarshaller(nil),
+			Faults:        s.faults},
 		)
 		gov := seedGov.Clone()
 		gov.SwitchToRemoteMode(networkModule)
 		gov.NotifyRound(0, types.GenesisHeight)
 		networkModule.AttachNodeSetCache(utils.NewNodeSetCache(gov))
+		networkModule.AttachDatabase(dbInst)
 		f, err := os.Create(fmt.Sprintf("log.%d.log", i))
 		if err != nil {
 			panic(err)
@@ -104,17 +110,22 @@ func (s *ByzantineTestSuite) setupNodes(
 		}()
 	}
 	// Make sure transport layer is ready.
+	server.SetDMoment(dMoment)
 	s.Require().NoError(server.WaitForPeers(uint32(len(prvKeys))))
 	wg.Wait()
 	for _, k := range prvKeys {
 		node := nodes[types.NewNodeID(k.PublicKey())]
+		var network core.Network = node.network
+		if behaviors, exists := s.behaviors[node.ID]; exists {
+			network = test.NewByzantineNetwork(node.network, k, behaviors...)
+		}
 		// Now is the consensus module.
 		node.con = core.NewConsensus(
 			dMoment,
 			node.app,
 			node.gov,
 			node.db,
-			node.network,
+			network,
 			k,
 			node.logger,
 		)
@@ -264,6 +275,303 @@ Loop:
 	s.verifyNodes(nodes)
 }
 
+func (s *ByzantineTestSuite) TestOneHeavyDeadNode() {
+	// 6 nodes setup with one dead node weighted by 2. The total weight is 7,
+	// and the remaining 5 nodes hold just enough weight to reach agreement.
+	var (
+		req        = s.Require()
+		peerCount  = 6
+		dMoment    = time.Now().UTC()
+		untilRound = uint64(3)
+	)
+	if testing.Short() {
+		untilRound = 1
+	}
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	lambda := 100 * time.Millisecond
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, lambda, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	deadNodeID := types.NewNodeID(pubKeys[0])
+	seedGov.SetNodeWeights(map[types.NodeID]uint64{deadNodeID: 2})
+	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	for _, n := range nodes {
+		if n.ID == deadNodeID {
+			continue
+		}
+		go n.con.Run(make(chan struct{}))
+		defer n.con.Stop()
+	}
+	// Clean deadNode's network receive channel, or it might exceed the limit
+	// and block other go routines.
+	dummyReceiverCtxCancel, _ := utils.LaunchDummyReceiver(
+		context.Background(), nodes[deadNodeID].network.ReceiveChan(), nil)
+	defer dummyReceiverCtxCancel()
+Loop:
+	for {
+		<-time.After(5 * time.Second)
+		fmt.Println("check latest position delivered by each node")
+		for _, n := range nodes {
+			if n.ID == deadNodeID {
+				continue
+			}
+			latestPos := n.app.GetLatestDeliveredPosition()
+			fmt.Println("latestPos", n.ID, &latestPos)
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		break
+	}
+	delete(nodes, deadNodeID)
+	s.verifyNodes(nodes)
+}
+
+func (s *ByzantineTestSuite) TestHeavyByzantineNodes() {
+	// 7 nodes setup with one honest node weighted by 5, and two byzantine
+	// nodes weighted by 2. The total weight is 13 and the byzantine ones hold
+	// less than 1/3 of it. Heavy nodes could reach the weight threshold with
+	// only 3 voters, which are not enough to recover randomness by TSig after
+	// DKGDelayRound.
+	var (
+		req        = s.Require()
+		peerCount  = 7
+		dMoment    = time.Now().UTC()
+		untilRound = core.DKGDelayRound + 2
+	)
+	if testing.Short() {
+		untilRound = core.DKGDelayRound + 1
+	}
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	lambda := 100 * time.Millisecond
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, lambda, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	var (
+		heavyNodeID = types.NewNodeID(pubKeys[0])
+		byzNodeID1  = types.NewNodeID(pubKeys[1])
+		byzNodeID2  = types.NewNodeID(pubKeys[2])
+	)
+	seedGov.SetNodeWeights(map[types.NodeID]uint64{
+		heavyNodeID: 5,
+		byzNodeID1:  2,
+		byzNodeID2:  2,
+	})
+	s.behaviors[byzNodeID1] = []test.ByzantineBehavior{
+		test.ByzantineEquivocateVote,
+		test.ByzantineReplayVote,
+	}
+	s.behaviors[byzNodeID2] = []test.ByzantineBehavior{
+		test.ByzantineInvalidPartialSignature,
+		test.ByzantineWithholdShare,
+	}
+	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	for _, n := range nodes {
+		go n.con.Run(make(chan struct{}))
+		defer n.con.Stop()
+	}
+Loop:
+	for {
+		<-time.After(5 * time.Second)
+		fmt.Println("check latest position delivered by each node")
+		for _, n := range nodes {
+			latestPos := n.app.GetLatestDeliveredPosition()
+			fmt.Println("latestPos", n.ID, &latestPos)
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		break
+	}
+	s.verifyNodes(nodes)
+	// Blocks after DKGDelayRound should carry randomness recovered by TSig.
+	for _, n := range nodes {
+		n.app.WithLock(func(app *test.App) {
+			for _, rec := range app.Delivered {
+				if rec.Pos.Round < core.DKGDelayRound {
+					continue
+				}
+				req.NotEqual(core.NoRand, rec.Rand, "position: %v", &rec.Pos)
+			}
+		})
+	}
+}
+
+func (s *ByzantineTestSuite) TestPartitionHeal() {
+	// 4 nodes are split into 2 groups after genesis, neither of them is able
+	// to reach agreement until the partition is healed.
+	var (
+		req        = s.Require()
+		peerCount  = 4
+		dMoment    = time.Now().UTC()
+		healAt     = 3 * time.Second
+		untilRound = uint64(3)
+	)
+	if testing.Short() {
+		untilRound = 1
+	}
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	lambda := 100 * time.Millisecond
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, lambda, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	s.faults = &test.FaultPlan{
+		Partitions: []test.Partition{{
+			Groups: [][]types.NodeID{
+				{types.NewNodeID(pubKeys[0]), types.NewNodeID(pubKeys[1])},
+				{types.NewNodeID(pubKeys[2]), types.NewNodeID(pubKeys[3])},
+			},
+			End: healAt,
+		}},
+	}
+	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	for _, n := range nodes {
+		go n.con.Run(make(chan struct{}))
+		defer n.con.Stop()
+	}
+	time.Sleep(dMoment.Add(healAt).Sub(time.Now()) - lambda)
+	for _, n := range nodes {
+		latestPos := n.app.GetLatestDeliveredPosition()
+		req.Equal(types.Position{}, latestPos)
+	}
+Loop:
+	for {
+		<-time.After(5 * time.Second)
+		fmt.Println("check latest position delivered by each node")
+		for _, n := range nodes {
+			latestPos := n.app.GetLatestDeliveredPosition()
+			fmt.Println("latestPos", n.ID, &latestPos)
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		break
+	}
+	s.verifyNodes(nodes)
+}
+
+func (s *ByzantineTestSuite) TestForkingNode() {
+	// 4 nodes setup with one node forking votes and blocks, the fork should
+	// be reported by others without breaking safety.
+	var (
+		req        = s.Require()
+		peerCount  = 4
+		dMoment    = time.Now().UTC()
+		untilRound = uint64(3)
+	)
+	if testing.Short() {
+		untilRound = 1
+	}
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	lambda := 100 * time.Millisecond
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, lambda, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	forkingNodeID := types.NewNodeID(pubKeys[0])
+	s.behaviors[forkingNodeID] = []test.ByzantineBehavior{
+		test.ByzantineEquivocateVote,
+		test.ByzantineForkBlock,
+	}
+	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	for _, n := range nodes {
+		go n.con.Run(make(chan struct{}))
+		defer n.con.Stop()
+	}
+Loop:
+	for {
+		<-time.After(5 * time.Second)
+		fmt.Println("check latest position delivered by each node")
+		for _, n := range nodes {
+			latestPos := n.app.GetLatestDeliveredPosition()
+			fmt.Println("latestPos", n.ID, &latestPos)
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		break
+	}
+	s.verifyNodes(nodes)
+	for _, n := range nodes {
+		if n.ID == forkingNodeID {
+			continue
+		}
+		forkVotes, forkBlocks := n.gov.ForkReports()
+		req.NotZero(forkVotes[forkingNodeID])
+		req.NotZero(forkBlocks[forkingNodeID])
+		req.Len(forkVotes, 1)
+		req.Len(forkBlocks, 1)
+	}
+}
+
+func (s *ByzantineTestSuite) TestMisbehavingNode() {
+	// 4 nodes setup with one node misbehaving in DKG, replaying votes and
+	// spamming pull requests.
+	var (
+		req        = s.Require()
+		peerCount  = 4
+		dMoment    = time.Now().UTC()
+		untilRound = uint64(3)
+	)
+	if testing.Short() {
+		untilRound = 1
+	}
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	lambda := 100 * time.Millisecond
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, lambda, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	s.behaviors[types.NewNodeID(pubKeys[0])] = []test.ByzantineBehavior{
+		test.ByzantineWithholdShare,
+		test.ByzantineInvalidPartialSignature,
+		test.ByzantineReplayVote,
+		test.ByzantineSpamPull,
+	}
+	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	for _, n := range nodes {
+		go n.con.Run(make(chan struct{}))
+		defer n.con.Stop()
+	}
+Loop:
+	for {
+		<-time.After(5 * time.Second)
+		fmt.Println("check latest position delivered by each node")
+		for _, n := range nodes {
+			latestPos := n.app.GetLatestDeliveredPosition()
+			fmt.Println("latestPos", n.ID, &latestPos)
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		break
+	}
+	s.verifyNodes(nodes)
+}
+
 func TestByzantine(t *testing.T) {
 	suite.Run(t, new(ByzantineTestSuite))
 }
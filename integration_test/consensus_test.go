@@ -18,6 +18,7 @@
 package integration
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
@@ -33,6 +34,7 @@ import (
 	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/syncer"
 	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/trace"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 	"github.com/tangerine-network/tangerine-consensus/core/utils"
 )
@@ -101,6 +103,15 @@ func (s *ConsensusTestSuite) setupNodes(
 	dMoment time.Time,
 	prvKeys []crypto.PrivateKey,
 	seedGov *test.Governance) map[types.NodeID]*node {
+	return s.setupNodesWithClock(
+		dMoment, prvKeys, seedGov, &common.SystemClock{})
+}
+
+func (s *ConsensusTestSuite) setupNodesWithClock(
+	dMoment time.Time,
+	prvKeys []crypto.PrivateKey,
+	seedGov *test.Governance,
+	clock common.Clock) map[types.NodeID]*node {
 	var (
 		wg        sync.WaitGroup
 		initRound uint64
@@ -120,12 +131,14 @@ func (s *ConsensusTestSuite) setupNodes(
 			Type:          test.NetworkTypeFake,
 			DirectLatency: &test.FixedLatencyModel{},
 			GossipLatency: &test.FixedLatencyModel{},
-			Marshaller:    test.NewDefaultMarshaller(nil)},
+			Marshaller:    test.NewDefaultMarshaller(nil),
+			Clock:         clock},
 		)
 		gov := seedGov.Clone()
 		gov.SwitchToRemoteMode(networkModule)
 		gov.NotifyRound(initRound, types.GenesisHeight)
 		networkModule.AttachNodeSetCache(utils.NewNodeSetCache(gov))
+		networkModule.AttachDatabase(dbInst)
 		f, err := os.Create(fmt.Sprintf("log.%d.log", i))
 		if err != nil {
 			panic(err)
@@ -156,19 +169,52 @@ func (s *ConsensusTestSuite) setupNodes(
 	for _, k := range prvKeys {
 		node := nodes[types.NewNodeID(k.PublicKey())]
 		// Now is the consensus module.
-		node.con = core.NewConsensus(
+		node.con = core.NewConsensusWithClock(
 			dMoment,
 			node.app,
 			node.gov,
 			node.db,
 			node.network,
 			k,
+			clock,
 			node.logger,
 		)
 	}
 	return nodes
 }
 
+// driveClock moves the simulated clock from one timer to the next until the
+// returned function is called. Before each move, it waits until at least
+// 'pending' timers are registered, ex. routines of all nodes are waiting on
+// the clock, and yields for a moment to let routines handle fired timers.
+func driveClock(clock *test.SimulatedClock, pending int) (stop func()) {
+	stopDriver := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stopDriver:
+				return
+			default:
+			}
+			clock.BlockUntil(pending)
+			time.Sleep(100 * time.Microsecond)
+			clock.AdvanceToNext()
+		}
+	}()
+	return func() { close(stopDriver) }
+}
+
+// watchNodes monitors invariants among nodes until the context is done.
+func (s *ConsensusTestSuite) watchNodes(ctx context.Context,
+	nodes map[types.NodeID]*node, clock common.Clock) *test.InvariantChecker {
+	checker := test.NewInvariantChecker(clock, time.Minute)
+	for _, n := range nodes {
+		checker.Watch(n.ID, n.app)
+	}
+	go checker.Run(ctx, time.Second)
+	return checker
+}
+
 func (s *ConsensusTestSuite) verifyNodes(nodes map[types.NodeID]*node) {
 	for ID, node := range nodes {
 		s.Require().NoError(test.VerifyDB(node.db))
@@ -189,11 +235,7 @@ func (s *ConsensusTestSuite) syncBlocksWithSomeNode(
 	syncedCon *core.Consensus, syncerHeight uint64, err error) {
 	syncerHeight = nextSyncHeight
 	// Setup revealer.
-	DBAll, err := sourceNode.db.GetAllBlocks()
-	if err != nil {
-		return
-	}
-	r, err := test.NewBlockRevealerByPosition(DBAll, nextSyncHeight)
+	r, err := test.NewBlockRevealerByPosition(sourceNode.db, nextSyncHeight)
 	if err != nil {
 		return
 	}
@@ -285,13 +327,20 @@ func (s *ConsensusTestSuite) TestSimple() {
 		test.StateChangeRoundLength, uint64(100)))
 	// A short round interval.
 	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checker := s.watchNodes(ctx, nodes, &common.SystemClock{})
 	for _, n := range nodes {
 		go n.con.Run(make(chan struct{}))
 		defer n.con.Stop()
 	}
 Loop:
 	for {
-		<-time.After(5 * time.Second)
+		select {
+		case <-checker.Failed():
+			req.FailNow(checker.Report())
+		case <-time.After(5 * time.Second):
+		}
 		for _, n := range nodes {
 			latestPos := n.app.GetLatestDeliveredPosition()
 			fmt.Println("latestPos", n.ID, &latestPos)
@@ -302,6 +351,61 @@ Loop:
 		// Oh ya.
 		break
 	}
+	req.NoError(checker.Err(), checker.Report())
+	s.verifyNodes(nodes)
+}
+
+func (s *ConsensusTestSuite) TestSimulatedClock() {
+	// The same scenario as TestSimple, but all nodes are driven by a
+	// simulated clock, which moves much faster than the wall-clock:
+	//  - Node set is equals to DKG set and notary set in each round.
+	//  - DKG would be performed since DKGDelayRound.
+	var (
+		req        = s.Require()
+		peerCount  = 4
+		clock      = test.NewSimulatedClock(time.Now().UTC())
+		dMoment    = clock.Now()
+		untilRound = uint64(4)
+	)
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, 100*time.Millisecond, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	nodes := s.setupNodesWithClock(dMoment, prvKeys, seedGov, clock)
+	// The clock should keep moving until all nodes are stopped, or they
+	// might be blocked forever when sleeping. It moves only when routines of
+	// all nodes are waiting on it.
+	defer driveClock(clock, peerCount)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checker := s.watchNodes(ctx, nodes, clock)
+	for _, n := range nodes {
+		go n.con.Run(make(chan struct{}))
+		defer n.con.Stop()
+	}
+	deadline := time.Now().Add(2 * time.Minute)
+Loop:
+	for {
+		req.True(time.Now().Before(deadline), "timeout")
+		select {
+		case <-checker.Failed():
+			req.FailNow(checker.Report())
+		case <-time.After(100 * time.Millisecond):
+		}
+		for _, n := range nodes {
+			latestPos := n.app.GetLatestDeliveredPosition()
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		break
+	}
+	req.NoError(checker.Err(), checker.Report())
 	s.verifyNodes(nodes)
 }
 
@@ -385,6 +489,75 @@ Loop:
 	s.verifyNodes(nodes)
 }
 
+func (s *ConsensusTestSuite) TestNodeJoinLeave() {
+	var (
+		req        = s.Require()
+		peerCount  = 6
+		dMoment    = time.Now().UTC()
+		untilRound = uint64(6)
+	)
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	// Only the first 5 nodes are in the genesis node set, the last one is
+	// waiting to join.
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound, pubKeys[:5], 100*time.Millisecond,
+			&common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	// Pick master node, and register changes on it.
+	pickedNode := nodes[types.NewNodeID(pubKeys[2])]
+	// Node 5 joins and node 0 leaves in round 3.
+	req.NoError(pickedNode.gov.RegisterConfigChange(
+		3, test.StateAddNode, pubKeys[5]))
+	req.NoError(pickedNode.gov.RegisterConfigChange(
+		3, test.StateRemoveNode, pubKeys[0]))
+	// Run test.
+	for _, n := range nodes {
+		go n.con.Run(make(chan struct{}))
+		defer n.con.Stop()
+	}
+Loop:
+	for {
+		<-time.After(5 * time.Second)
+		for _, n := range nodes {
+			latestPos := n.app.GetLatestDeliveredPosition()
+			fmt.Println("latestPos", n.ID, &latestPos)
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		// Oh ya.
+		break
+	}
+	s.verifyNodes(nodes)
+	// Make sure node sets are changed as expected on all nodes.
+	nodeSetOf := func(n *node, round uint64) map[types.NodeID]struct{} {
+		nIDs := make(map[types.NodeID]struct{})
+		for _, k := range n.gov.NodeSet(round) {
+			nIDs[types.NewNodeID(k)] = struct{}{}
+		}
+		return nIDs
+	}
+	expected := []map[types.NodeID]struct{}{
+		make(map[types.NodeID]struct{}),
+		make(map[types.NodeID]struct{}),
+	}
+	for _, idx := range []int{0, 1, 2, 3, 4} {
+		expected[0][types.NewNodeID(pubKeys[idx])] = struct{}{}
+	}
+	for _, idx := range []int{1, 2, 3, 4, 5} {
+		expected[1][types.NewNodeID(pubKeys[idx])] = struct{}{}
+	}
+	for _, n := range nodes {
+		req.Equal(expected[0], nodeSetOf(n, 2))
+		req.Equal(expected[1], nodeSetOf(n, 5))
+	}
+}
+
 func (s *ConsensusTestSuite) TestSync() {
 	// The sync test case:
 	// - No configuration change.
@@ -767,6 +940,80 @@ Loop:
 	}
 }
 
+func (s *ConsensusTestSuite) TestRecordReplay() {
+	// Inputs of one node are recorded, and replayed to a fresh consensus
+	// instance, which should confirm the same blocks, including those in
+	// rounds since DKGDelayRound.
+	var (
+		req        = s.Require()
+		peerCount  = 4
+		clock      = test.NewSimulatedClock(time.Now().UTC())
+		dMoment    = clock.Now()
+		untilRound = core.DKGDelayRound + 1
+		buf        bytes.Buffer
+		secrets    bytes.Buffer
+	)
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, 100*time.Millisecond, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	nodes := s.setupNodesWithClock(dMoment, prvKeys, seedGov, clock)
+	recNode := nodes[types.NewNodeID(prvKeys[0].PublicKey())]
+	// DKG secrets are recorded to reproduce the DKG in replay.
+	rec, err := trace.NewRecorderWithConfig(
+		&buf, recNode.ID, dMoment, trace.RecorderConfig{
+			Clock:      clock,
+			DKGSecrets: &secrets,
+		})
+	req.NoError(err)
+	recNode.con = core.NewConsensusWithClock(
+		dMoment,
+		rec.Application(recNode.app),
+		rec.Governance(recNode.gov),
+		rec.Database(recNode.db),
+		rec.Network(recNode.network),
+		prvKeys[0],
+		rec.Clock(),
+		recNode.logger,
+	)
+	defer driveClock(clock, peerCount)()
+	for _, n := range nodes {
+		go n.con.Run(make(chan struct{}))
+	}
+	deadline := time.Now().Add(2 * time.Minute)
+Loop:
+	for {
+		req.True(time.Now().Before(deadline), "timeout")
+		time.Sleep(100 * time.Millisecond)
+		for _, n := range nodes {
+			latestPos := n.app.GetLatestDeliveredPosition()
+			if latestPos.Round < untilRound {
+				continue Loop
+			}
+		}
+		break
+	}
+	for _, n := range nodes {
+		n.con.Stop()
+	}
+	req.NoError(rec.Close())
+	result, err := trace.Replay(&buf, trace.ReplayConfig{
+		PrivateKey: prvKeys[0],
+		Logger:     recNode.logger,
+		DKGSecrets: &secrets,
+	})
+	req.NoError(err)
+	req.NoError(result.Verify())
+	req.NotEmpty(result.Expected)
+	last := result.Expected[len(result.Expected)-1]
+	req.True(last.Position.Round >= untilRound)
+}
+
 func TestConsensus(t *testing.T) {
 	suite.Run(t, new(ConsensusTestSuite))
 }
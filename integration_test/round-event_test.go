@@ -233,6 +233,62 @@ func (s *RoundEventTestSuite) TestLastPeriod() {
 	s.Require().Equal(length, uint64(200))
 }
 
+// crsHidingGovernance pretends the CRS of some round is not ready yet.
+type crsHidingGovernance struct {
+	*test.Governance
+
+	hiddenRound uint64
+	hidden      bool
+}
+
+func (g *crsHidingGovernance) CRS(round uint64) common.Hash {
+	if g.hidden && round == g.hiddenRound {
+		return common.Hash{}
+	}
+	return g.Governance.CRS(round)
+}
+
+func (s *RoundEventTestSuite) TestGovernanceNotReady() {
+	gov := s.prepareGov()
+	s.Require().NoError(gov.State().RequestChange(test.StateChangeRoundLength,
+		uint64(100)))
+	gov.CatchUpWithRound(0)
+	gov.CatchUpWithRound(1)
+	hidingGov := &crsHidingGovernance{Governance: gov, hiddenRound: 1}
+	rEvt, err := utils.NewRoundEvent(context.Background(), hidingGov,
+		s.logger, types.Position{Height: types.GenesisHeight},
+		core.ConfigRoundShift)
+	s.Require().NoError(err)
+	var evts []evtParamToCheck
+	rEvt.Register(func(params []utils.RoundEventParam) {
+		for _, p := range params {
+			evts = append(evts, evtParamToCheck{
+				round:  p.Round,
+				reset:  p.Reset,
+				height: p.BeginHeight,
+				crs:    p.CRS,
+			})
+		}
+	})
+	s.proposeMPK(gov, 1, 0, 3)
+	s.proposeFinalize(gov, 1, 0, 3)
+	// Nothing is triggered when the CRS of next round is not ready.
+	hidingGov.hidden = true
+	s.Require().Equal(uint(0), rEvt.ValidateNextRound(80))
+	s.Require().Empty(evts)
+	// The round is triggered once the CRS is ready.
+	hidingGov.hidden = false
+	s.Require().Equal(uint(1), rEvt.ValidateNextRound(80))
+	s.Require().Len(evts, 1)
+	s.Require().Equal(evts[0], evtParamToCheck{1, 0, 101, gov.CRS(1)})
+	// Creating a RoundEvent fails when the initial CRS is not ready.
+	hidingGov.hiddenRound, hidingGov.hidden = 0, true
+	_, err = utils.NewRoundEvent(context.Background(), hidingGov,
+		s.logger, types.Position{Height: types.GenesisHeight},
+		core.ConfigRoundShift)
+	s.Require().Equal(utils.ErrCRSNotReady, err)
+}
+
 func (s *RoundEventTestSuite) TestTriggerInitEvent() {
 	gov := s.prepareGov()
 	s.Require().NoError(gov.State().RequestChange(test.StateChangeRoundLength,
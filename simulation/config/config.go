@@ -21,10 +21,12 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"time"
 
 	"github.com/naoina/toml"
 	"github.com/tangerine-network/tangerine-consensus/core"
 	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
 )
 
 // Consensus settings.
@@ -51,6 +53,38 @@ type Node struct {
 	Num       uint32
 	MaxBlock  uint64
 	Changes   []Change
+	Byzantine []Byzantine
+	// KeyStorePassphrase encrypts DKG key material of each node in a
+	// keystore, they are kept in plaintext when it's empty.
+	KeyStorePassphrase string
+}
+
+// Byzantine config to make a node misbehave, the node is referred by its
+// index in the list of nodes sorted by node ID.
+type Byzantine struct {
+	Index     int
+	Behaviors []string
+}
+
+// ByzantineBehaviors returns behaviors of the node with 'nID', 'nodeIDs'
+// should be sorted.
+func (n Node) ByzantineBehaviors(nodeIDs types.NodeIDs, nID types.NodeID) (
+	behaviors []test.ByzantineBehavior, err error) {
+	for _, b := range n.Byzantine {
+		if b.Index < 0 || b.Index >= len(nodeIDs) {
+			err = fmt.Errorf("node index out of range: %d", b.Index)
+			return
+		}
+		if nodeIDs[b.Index] != nID {
+			continue
+		}
+		var parsed []test.ByzantineBehavior
+		if parsed, err = test.ParseByzantineBehaviors(b.Behaviors); err != nil {
+			return
+		}
+		behaviors = append(behaviors, parsed...)
+	}
+	return
 }
 
 // LatencyModel for ths simulation.
@@ -65,11 +99,121 @@ type Networking struct {
 	PeerServer string
 	Direct     LatencyModel
 	Gossip     LatencyModel
+	// GossipFanout enables gossiping when positive, messages would be relayed
+	// hop by hop with the latency from Gossip.
+	GossipFanout int
+	GossipTTL    int
+	Faults       Faults
+	// SecureConn authenticates peers and encrypts messages between them,
+	// it's only supported by TCP networks.
+	SecureConn bool
+}
+
+// Partition config, nodes are referred by their indexes in the list of nodes
+// sorted by node ID.
+type Partition struct {
+	Groups [][]int
+	// Begin and End are offsets in milliseconds to dMoment, the partition is
+	// never healed when End is zero.
+	Begin int
+	End   int
+}
+
+// LinkFault config, nodes are referred by their indexes in the list of nodes
+// sorted by node ID. Empty From or To matches all nodes.
+type LinkFault struct {
+	From []int
+	To   []int
+	// Begin and End are offsets in milliseconds to dMoment, the fault is
+	// never healed when End is zero.
+	Begin         int
+	End           int
+	Delay         LatencyModel
+	Loss          float64
+	Duplicate     float64
+	Reorder       float64
+	ReorderWindow int
+}
+
+// Faults config to be injected to the network.
+type Faults struct {
+	Seed       int64
+	Partitions []Partition
+	Links      []LinkFault
+}
+
+// ToFaultPlan converts faults config to test.FaultPlan, 'nodeIDs' should be
+// sorted. A nil plan is returned when there is no fault.
+func (f Faults) ToFaultPlan(nodeIDs types.NodeIDs) (*test.FaultPlan, error) {
+	if len(f.Partitions) == 0 && len(f.Links) == 0 {
+		return nil, nil
+	}
+	toNodeIDs := func(indexes []int) ([]types.NodeID, error) {
+		var IDs []types.NodeID
+		for _, idx := range indexes {
+			if idx < 0 || idx >= len(nodeIDs) {
+				return nil, fmt.Errorf("node index out of range: %d", idx)
+			}
+			IDs = append(IDs, nodeIDs[idx])
+		}
+		return IDs, nil
+	}
+	plan := &test.FaultPlan{Seed: f.Seed}
+	for _, p := range f.Partitions {
+		partition := test.Partition{
+			Begin: time.Duration(p.Begin) * time.Millisecond,
+			End:   time.Duration(p.End) * time.Millisecond,
+		}
+		for _, group := range p.Groups {
+			IDs, err := toNodeIDs(group)
+			if err != nil {
+				return nil, err
+			}
+			partition.Groups = append(partition.Groups, IDs)
+		}
+		plan.Partitions = append(plan.Partitions, partition)
+	}
+	for _, l := range f.Links {
+		from, err := toNodeIDs(l.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := toNodeIDs(l.To)
+		if err != nil {
+			return nil, err
+		}
+		link := test.LinkFault{
+			From:          from,
+			To:            to,
+			Begin:         time.Duration(l.Begin) * time.Millisecond,
+			End:           time.Duration(l.End) * time.Millisecond,
+			Loss:          l.Loss,
+			Duplicate:     l.Duplicate,
+			Reorder:       l.Reorder,
+			ReorderWindow: time.Duration(l.ReorderWindow) * time.Millisecond,
+		}
+		if l.Delay.Mean != 0 || l.Delay.Sigma != 0 {
+			link.Delay = &test.NormalLatencyModel{
+				Mean:  l.Delay.Mean,
+				Sigma: l.Delay.Sigma,
+			}
+		}
+		plan.Links = append(plan.Links, link)
+	}
+	return plan, nil
 }
 
 // Scheduler Settings.
 type Scheduler struct {
-	WorkerNum int
+	// Seed decides the latencies and the order of messages delivered by the
+	// discrete-event scheduler, the same seed yields the same interleaving.
+	Seed int64
+	// UntilRound is the round to stop the scheduler when all nodes reach it.
+	UntilRound uint64
+	// StallTimeout is the simulated time in milliseconds, nodes not
+	// delivering any block within it are reported as stalled. Zero disables
+	// the check.
+	StallTimeout int
 }
 
 // Change represent future configuration changes.
@@ -139,7 +283,9 @@ func GenerateDefault(path string) error {
 			},
 		},
 		Scheduler: Scheduler{
-			WorkerNum: 2,
+			Seed:         1,
+			UntilRound:   5,
+			StallTimeout: 60000,
 		},
 	}
 
@@ -20,12 +20,14 @@ package simulation
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/tangerine-network/tangerine-consensus/common"
 	"github.com/tangerine-network/tangerine-consensus/core"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 	"github.com/tangerine-network/tangerine-consensus/core/db"
+	"github.com/tangerine-network/tangerine-consensus/core/keystore"
 	"github.com/tangerine-network/tangerine-consensus/core/test"
 	"github.com/tangerine-network/tangerine-consensus/core/types"
 	"github.com/tangerine-network/tangerine-consensus/simulation/config"
@@ -70,7 +72,7 @@ type node struct {
 func newNode(prvKey crypto.PrivateKey, logger common.Logger,
 	cfg config.Config) *node {
 	pubKey := prvKey.PublicKey()
-	netModule := test.NewNetwork(pubKey, test.NetworkConfig{
+	netConfig := test.NetworkConfig{
 		Type:       cfg.Networking.Type,
 		PeerServer: cfg.Networking.PeerServer,
 		PeerPort:   peerPort,
@@ -82,12 +84,28 @@ func newNode(prvKey crypto.PrivateKey, logger common.Logger,
 			Mean:  cfg.Networking.Gossip.Mean,
 			Sigma: cfg.Networking.Gossip.Sigma,
 		},
-		Marshaller: test.NewDefaultMarshaller(&jsonMarshaller{})})
+		GossipFanout: cfg.Networking.GossipFanout,
+		GossipTTL:    cfg.Networking.GossipTTL,
+		Marshaller: test.NewBinaryMarshaller(
+			test.NewDefaultMarshaller(&jsonMarshaller{}))}
+	if cfg.Networking.SecureConn {
+		netConfig.SecureConnKey = prvKey
+	}
+	netModule := test.NewNetwork(pubKey, netConfig)
 	id := types.NewNodeID(pubKey)
+	var dbInst db.Database
 	dbInst, err := db.NewMemBackedDB(id.String() + ".db")
 	if err != nil {
 		panic(err)
 	}
+	if cfg.Node.KeyStorePassphrase != "" {
+		ks, err := openKeyStore(
+			id.String()+".keystore", cfg.Node.KeyStorePassphrase)
+		if err != nil {
+			panic(err)
+		}
+		dbInst = keystore.NewDatabase(dbInst, ks)
+	}
 	// Sync config to state in governance.
 	gov, err := test.NewGovernance(
 		test.NewState(core.DKGDelayRound,
@@ -108,6 +126,22 @@ func newNode(prvKey crypto.PrivateKey, logger common.Logger,
 	}
 }
 
+// openKeyStore opens the keystore under 'dir', it's created when not
+// existed.
+func openKeyStore(dir, passphrase string) (*keystore.KeyStore, error) {
+	ks, err := keystore.Create(dir, passphrase, keystore.LightScryptParams)
+	if err != keystore.ErrKeyStoreExists {
+		return ks, err
+	}
+	if ks, err = keystore.Load(dir); err != nil {
+		return nil, err
+	}
+	if err = ks.Unlock(passphrase); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
 // GetID returns the ID of node.
 func (n *node) GetID() types.NodeID {
 	return n.ID
@@ -123,6 +157,12 @@ func (n *node) run(
 	msgChannel := n.netModule.ReceiveChanForNode()
 	peers := n.netModule.Peers()
 	dMoment := n.netModule.DMoment()
+	n.applyFaults(peers)
+	behaviors, err := n.cfg.Node.ByzantineBehaviors(
+		sortedNodeIDs(peers), n.ID)
+	if err != nil {
+		panic(err)
+	}
 	n.logger.Info("Simulation DMoment", "dMoment", dMoment)
 	go n.netModule.Run()
 	// Run consensus.
@@ -164,13 +204,19 @@ readyLoop:
 		}
 	}
 	// Setup Consensus.
+	var network core.Network = n.netModule
+	if len(behaviors) > 0 {
+		n.logger.Info("Misbehave as byzantine node", "behaviors", behaviors)
+		network = test.NewByzantineNetwork(n.netModule, n.prvKey, behaviors...)
+	}
 	n.consensus = core.NewConsensusForSimulation(
 		dMoment,
 		n.app,
 		n.gov,
 		n.db,
-		n.netModule,
+		network,
 		n.prvKey,
+		&common.SystemClock{},
 		n.logger)
 	go n.consensus.Run(make(chan struct{}))
 
@@ -201,6 +247,26 @@ MainLoop:
 	return
 }
 
+// applyFaults injects faults from config to the network module, nodes in
+// config are referred by their indexes in peers sorted by node ID.
+func (n *node) applyFaults(peers []crypto.PublicKey) {
+	plan, err := n.cfg.Networking.Faults.ToFaultPlan(sortedNodeIDs(peers))
+	if err != nil {
+		panic(err)
+	}
+	n.netModule.SetFaultPlan(plan)
+}
+
+// sortedNodeIDs converts peers to their node IDs, sorted.
+func sortedNodeIDs(peers []crypto.PublicKey) types.NodeIDs {
+	nodeIDs := make(types.NodeIDs, 0, len(peers))
+	for _, k := range peers {
+		nodeIDs = append(nodeIDs, types.NewNodeID(k))
+	}
+	sort.Sort(nodeIDs)
+	return nodeIDs
+}
+
 func (n *node) prepareConfigs() {
 	// Prepare configurations.
 	cConfig := n.cfg.Node.Consensus
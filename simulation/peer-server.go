@@ -132,7 +132,13 @@ func (p *PeerServer) Setup(
 	// Setup transport layer.
 	switch cfg.Networking.Type {
 	case "tcp", "tcp-local":
-		p.trans = test.NewTCPTransportServer(&jsonMarshaller{}, peerPort)
+		trans := test.NewTCPTransportServer(&jsonMarshaller{}, peerPort)
+		if cfg.Networking.SecureConn {
+			if err = trans.EnableSecureConn(); err != nil {
+				return
+			}
+		}
+		p.trans = trans
 		dMoment = dMoment.Add(10 * time.Second)
 	case "fake":
 		p.trans = test.NewFakeTransportServer()
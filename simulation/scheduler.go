@@ -0,0 +1,288 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"container/heap"
+	"errors"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	"github.com/tangerine-network/tangerine-consensus/simulation/config"
+)
+
+// Errors for scheduler.
+var (
+	// ErrSchedulerStalled is reported when there is neither pending message
+	// nor pending timer, nodes would never make progress.
+	ErrSchedulerStalled = errors.New("scheduler stalled")
+	// ErrTooManyPeers is reported when more peers than expected join.
+	ErrTooManyPeers = errors.New("too many peers")
+)
+
+// schedulerEpoch is the beginning of virtual time. A fixed epoch keeps
+// timestamps of blocks identical between runs.
+var schedulerEpoch = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// scheduledMessage is a message waiting to be delivered.
+type scheduledMessage struct {
+	time time.Time
+	seq  uint64
+	from types.NodeID
+	to   types.NodeID
+	key  string
+	msg  interface{}
+}
+
+// scheduledMessages is a min-heap of messages ordered by their delivery time,
+// messages with identical delivery time are ordered by their sequence.
+type scheduledMessages []*scheduledMessage
+
+func (m scheduledMessages) Len() int { return len(m) }
+
+func (m scheduledMessages) Less(i, j int) bool {
+	if m[i].time.Equal(m[j].time) {
+		return m[i].seq < m[j].seq
+	}
+	return m[i].time.Before(m[j].time)
+}
+
+func (m scheduledMessages) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+
+func (m *scheduledMessages) Push(x interface{}) {
+	*m = append(*m, x.(*scheduledMessage))
+}
+
+func (m *scheduledMessages) Pop() interface{} {
+	old := *m
+	n := len(old)
+	msg := old[n-1]
+	old[n-1] = nil
+	*m = old[:n-1]
+	return msg
+}
+
+// Scheduler is a seeded discrete-event scheduler, which implements
+// test.TransportRouter interface. Nodes share a simulated clock, messages
+// routed by nodes are collected until all nodes settle, then sorted and
+// assigned latencies drawn from a seeded source. The clock only moves to the
+// moment of the next message delivery or timer, thus the same seed yields
+// the same interleaving of messages.
+//
+// Nodes are considered settled when there is no work in flight tracked by
+// the simulated clock: delivered messages are work in flight until handled by
+// consensus cores, so are ticks of tickers and wake-ups of sleeping routines
+// until they block again. Wall-clock plays no part in deciding the
+// interleaving. Routines woken by others are not tracked, ex. routines
+// waiting for the end of an agreement or doing DKG, their effects would only
+// be seen once the next work in flight is handled.
+type Scheduler struct {
+	clock     *test.SimulatedClock
+	latency   test.LatencyModel
+	numPeers  int
+	lock      sync.Mutex
+	peers     map[types.NodeID]chan<- *test.TransportEnvelope
+	pubKeys   []crypto.PublicKey
+	joined    chan struct{}
+	routed    []*scheduledMessage
+	queue     scheduledMessages
+	seq       uint64
+	digest    common.Hash
+	delivered uint64
+}
+
+// NewScheduler constructs a Scheduler for 'numPeers' peers, the latency of
+// each message is drawn from a normal distribution seeded by 'seed'.
+func NewScheduler(
+	seed int64, numPeers int, latency config.LatencyModel) *Scheduler {
+	clock := test.NewSimulatedClock(schedulerEpoch)
+	clock.TrackWork(true)
+	return &Scheduler{
+		clock: clock,
+		latency: &test.NormalLatencyModel{
+			Mean:  latency.Mean,
+			Sigma: latency.Sigma,
+			Rand:  rand.New(rand.NewSource(seed)),
+		},
+		numPeers: numPeers,
+		peers:    make(map[types.NodeID]chan<- *test.TransportEnvelope),
+		joined:   make(chan struct{}),
+	}
+}
+
+// Clock returns the simulated clock shared by all nodes.
+func (s *Scheduler) Clock() *test.SimulatedClock {
+	return s.clock
+}
+
+// Join implements test.TransportRouter interface.
+func (s *Scheduler) Join(
+	pubKey crypto.PublicKey, recv chan<- *test.TransportEnvelope) (
+	peers []crypto.PublicKey, dMoment time.Time, err error) {
+	if err = func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if len(s.peers) >= s.numPeers {
+			return ErrTooManyPeers
+		}
+		s.peers[types.NewNodeID(pubKey)] = recv
+		s.pubKeys = append(s.pubKeys, pubKey)
+		if len(s.peers) == s.numPeers {
+			close(s.joined)
+		}
+		return nil
+	}(); err != nil {
+		return
+	}
+	<-s.joined
+	peers = append([]crypto.PublicKey{}, s.pubKeys...)
+	dMoment = schedulerEpoch
+	return
+}
+
+// Route implements test.TransportRouter interface.
+func (s *Scheduler) Route(from, to types.NodeID, msg interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.routed = append(s.routed, &scheduledMessage{
+		from: from,
+		to:   to,
+		key:  test.MessageKey(msg),
+		msg:  msg,
+	})
+}
+
+// Step moves the clock to the next moment when something happens: either
+// messages are delivered or timers fire. It returns false when nothing would
+// ever happen.
+func (s *Scheduler) Step() bool {
+	s.settle()
+	deliveries, ok := func() ([]*scheduledMessage, bool) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.scheduleNoLock()
+		timerAt, hasTimer := s.clock.Next()
+		if len(s.queue) == 0 || (hasTimer && timerAt.Before(s.queue[0].time)) {
+			return nil, s.clock.AdvanceToNext()
+		}
+		msgAt := s.queue[0].time
+		s.clock.Advance(msgAt.Sub(s.clock.Now()))
+		// Deliver at most one message to each peer in one step, the handling
+		// order of messages delivered to the same peer at once is decided by
+		// the go runtime.
+		var (
+			deliveries []*scheduledMessage
+			postponed  []*scheduledMessage
+			received   = make(map[types.NodeID]struct{})
+		)
+		for len(s.queue) > 0 && !s.queue[0].time.After(msgAt) {
+			m := heap.Pop(&s.queue).(*scheduledMessage)
+			if _, exists := received[m.to]; exists {
+				postponed = append(postponed, m)
+				continue
+			}
+			received[m.to] = struct{}{}
+			deliveries = append(deliveries, m)
+			s.digest = crypto.Keccak256Hash(s.digest[:], m.from.Hash[:],
+				m.to.Hash[:], []byte(m.key), []byte(m.time.String()))
+			s.delivered++
+		}
+		for _, m := range postponed {
+			heap.Push(&s.queue, m)
+		}
+		return deliveries, true
+	}()
+	s.clock.AddWork(len(deliveries))
+	for _, m := range deliveries {
+		s.peers[m.to] <- &test.TransportEnvelope{
+			PeerType: test.TransportPeer,
+			From:     m.from,
+			Msg:      m.msg,
+		}
+	}
+	return ok
+}
+
+// Digest returns the digest of all delivered messages and their delivery
+// time, runs with identical interleaving have identical digests.
+func (s *Scheduler) Digest() common.Hash {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.digest
+}
+
+// Delivered returns the count of delivered messages.
+func (s *Scheduler) Delivered() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.delivered
+}
+
+// drain keeps stepping until 'done' is closed. Goroutines sleeping on the
+// simulated clock would be blocked forever if the clock stops moving. Work is
+// no longer tracked, stopped nodes won't report their work as done.
+func (s *Scheduler) drain(done <-chan struct{}) {
+	s.clock.TrackWork(false)
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if !s.Step() {
+			runtime.Gosched()
+		}
+	}
+}
+
+// settle waits until handlers of delivered messages and fired timers are
+// completed.
+func (s *Scheduler) settle() {
+	s.clock.WaitIdle()
+}
+
+// scheduleNoLock assigns delivery time to routed messages. Messages are
+// sorted before drawing latencies, thus the order they are routed doesn't
+// matter.
+func (s *Scheduler) scheduleNoLock() {
+	sort.SliceStable(s.routed, func(i, j int) bool {
+		mi, mj := s.routed[i], s.routed[j]
+		if mi.from != mj.from {
+			return mi.from.Less(mj.from.Hash)
+		}
+		if mi.to != mj.to {
+			return mi.to.Less(mj.to.Hash)
+		}
+		return mi.key < mj.key
+	})
+	now := s.clock.Now()
+	for _, m := range s.routed {
+		m.time = now.Add(s.latency.Delay())
+		m.seq = s.seq
+		s.seq++
+		heap.Push(&s.queue, m)
+	}
+	s.routed = nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
+	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	"github.com/tangerine-network/tangerine-consensus/simulation/config"
+)
+
+type SchedulerTestSuite struct {
+	suite.Suite
+}
+
+func (s *SchedulerTestSuite) newScheduler(
+	seed int64, pubKeys []crypto.PublicKey) (
+	*Scheduler, map[types.NodeID]chan *test.TransportEnvelope) {
+	sched := NewScheduler(seed, len(pubKeys),
+		config.LatencyModel{Mean: 100, Sigma: 30})
+	recvs := make(map[types.NodeID]chan *test.TransportEnvelope)
+	var wg sync.WaitGroup
+	for _, k := range pubKeys {
+		ch := make(chan *test.TransportEnvelope, 100)
+		recvs[types.NewNodeID(k)] = ch
+		wg.Add(1)
+		go func(k crypto.PublicKey) {
+			defer wg.Done()
+			peers, dMoment, err := sched.Join(k, ch)
+			s.Require().NoError(err)
+			s.Require().Len(peers, len(pubKeys))
+			s.Require().Equal(schedulerEpoch, dMoment)
+		}(k)
+	}
+	wg.Wait()
+	return sched, recvs
+}
+
+// run routes votes among peers in an order decided by 'routeSeed', and
+// returns the order they are delivered.
+func (s *SchedulerTestSuite) run(seed, routeSeed int64) (
+	order []string, digest common.Hash) {
+	_, pubKeys := newSeededKeys(rand.New(rand.NewSource(1)), 3)
+	sched, recvs := s.newScheduler(seed, pubKeys)
+	type routing struct {
+		from, to types.NodeID
+		vote     *types.Vote
+	}
+	var (
+		routings []routing
+		hashRand = rand.New(rand.NewSource(1))
+	)
+	for _, fromKey := range pubKeys {
+		from := types.NewNodeID(fromKey)
+		for h := uint64(1); h <= 5; h++ {
+			var hash common.Hash
+			hashRand.Read(hash[:])
+			vote := types.NewVote(types.VoteInit, hash, 0)
+			vote.ProposerID = from
+			vote.Position.Height = h
+			for _, toKey := range pubKeys {
+				to := types.NewNodeID(toKey)
+				if to == from {
+					continue
+				}
+				routings = append(routings, routing{from, to, vote})
+			}
+		}
+	}
+	r := rand.New(rand.NewSource(routeSeed))
+	r.Shuffle(len(routings), func(i, j int) {
+		routings[i], routings[j] = routings[j], routings[i]
+	})
+	for _, rt := range routings {
+		sched.Route(rt.from, rt.to, rt.vote)
+	}
+	nodeIDs := types.NodeIDs{}
+	for ID := range recvs {
+		nodeIDs = append(nodeIDs, ID)
+	}
+	sort.Sort(nodeIDs)
+	for sched.Step() {
+		for _, ID := range nodeIDs {
+			select {
+			case e := <-recvs[ID]:
+				order = append(order, fmt.Sprintf("%s<-%s:%s@%s",
+					ID, e.From, test.MessageKey(e.Msg), sched.Clock().Now()))
+				// Delivered messages are work in flight until handled.
+				sched.Clock().WorkDone()
+			default:
+			}
+			s.Require().Len(recvs[ID], 0)
+		}
+	}
+	s.Require().Len(order, len(routings))
+	s.Require().Equal(uint64(len(routings)), sched.Delivered())
+	digest = sched.Digest()
+	return
+}
+
+func (s *SchedulerTestSuite) TestDeterministicInterleaving() {
+	order1, digest1 := s.run(7, 1)
+	order2, digest2 := s.run(7, 2)
+	s.Require().Equal(order1, order2)
+	s.Require().Equal(digest1, digest2)
+	// A different seed leads to different interleaving.
+	order3, digest3 := s.run(8, 1)
+	s.Require().NotEqual(order1, order3)
+	s.Require().NotEqual(digest1, digest3)
+}
+
+func (s *SchedulerTestSuite) TestStepWithTimers() {
+	_, pubKeys := newSeededKeys(rand.New(rand.NewSource(1)), 2)
+	sched, recvs := s.newScheduler(1, pubKeys)
+	from, to := types.NewNodeID(pubKeys[0]), types.NewNodeID(pubKeys[1])
+	// The latency is about 100ms, the timer should fire before the message
+	// is delivered.
+	timer := sched.Clock().After(time.Millisecond)
+	sched.Route(from, to, &types.Block{})
+	s.Require().True(sched.Step())
+	s.Require().Equal(schedulerEpoch.Add(time.Millisecond), <-timer)
+	s.Require().Len(recvs[to], 0)
+	s.Require().True(sched.Step())
+	s.Require().Len(recvs[to], 1)
+	<-recvs[to]
+	sched.Clock().WorkDone()
+	s.Require().False(sched.Step())
+}
+
+func (s *SchedulerTestSuite) TestSettle() {
+	_, pubKeys := newSeededKeys(rand.New(rand.NewSource(1)), 2)
+	sched, recvs := s.newScheduler(1, pubKeys)
+	from, to := types.NewNodeID(pubKeys[0]), types.NewNodeID(pubKeys[1])
+	sched.Route(from, to, &types.Block{})
+	s.Require().True(sched.Step())
+	// The next step should wait until the delivered message is handled, a
+	// message routed by the handler should be scheduled in the next step.
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		e := <-recvs[to]
+		sched.Route(to, from, e.Msg)
+		sched.Clock().WorkDone()
+	}()
+	s.Require().True(sched.Step())
+	<-handled
+	s.Require().Len(recvs[from], 1)
+	<-recvs[from]
+	sched.Clock().WorkDone()
+	s.Require().False(sched.Step())
+}
+
+func (s *SchedulerTestSuite) TestTooManyPeers() {
+	_, pubKeys := newSeededKeys(rand.New(rand.NewSource(1)), 2)
+	sched := NewScheduler(1, 1, config.LatencyModel{})
+	_, _, err := sched.Join(pubKeys[0], make(chan *test.TransportEnvelope))
+	s.Require().NoError(err)
+	_, _, err = sched.Join(pubKeys[1], make(chan *test.TransportEnvelope))
+	s.Require().Equal(ErrTooManyPeers, err)
+}
+
+func (s *SchedulerTestSuite) TestRunWithSchedulerDeterministic() {
+	cfg := &config.Config{
+		Node: config.Node{
+			Consensus: config.Consensus{
+				GenesisCRS:       "In DEXON we trust.",
+				LambdaBA:         250,
+				LambdaDKG:        1000,
+				RoundLength:      100,
+				NotarySetSize:    4,
+				DKGSetSize:       4,
+				MinBlockInterval: 100,
+			},
+			Num:      4,
+			MaxBlock: math.MaxUint64,
+		},
+		Networking: config.Networking{
+			Direct: config.LatencyModel{Mean: 100, Sigma: 30},
+		},
+		Scheduler: config.Scheduler{
+			Seed:       7,
+			UntilRound: core.DKGDelayRound + 1,
+		},
+	}
+	run := func() common.Hash {
+		digest, err := runWithScheduler(cfg, &common.NullLogger{},
+			func(int) common.Logger { return nil })
+		s.Require().NoError(err)
+		return digest
+	}
+	// Runs with the same seed should deliver messages in the same order.
+	s.Require().Equal(run(), run())
+}
+
+func TestScheduler(t *testing.T) {
+	suite.Run(t, new(SchedulerTestSuite))
+}
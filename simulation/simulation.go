@@ -18,19 +18,45 @@
 package simulation
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
+	dexCrypto "github.com/tangerine-network/go-tangerine/crypto"
 	"github.com/tangerine-network/go-tangerine/log"
 
 	"github.com/tangerine-network/tangerine-consensus/common"
+	"github.com/tangerine-network/tangerine-consensus/core"
+	"github.com/tangerine-network/tangerine-consensus/core/crypto"
 	"github.com/tangerine-network/tangerine-consensus/core/crypto/ecdsa"
+	"github.com/tangerine-network/tangerine-consensus/core/db"
 	"github.com/tangerine-network/tangerine-consensus/core/test"
+	"github.com/tangerine-network/tangerine-consensus/core/types"
+	"github.com/tangerine-network/tangerine-consensus/core/utils"
 	"github.com/tangerine-network/tangerine-consensus/simulation/config"
 )
 
+// newLogger creates a logger writing to stderr, and to a file named by
+// 'logPrefix' if any.
+func newLogger(logPrefix string) common.Logger {
+	mw := io.Writer(os.Stderr)
+	if logPrefix != "" {
+		f, err := os.Create(logPrefix + ".log")
+		if err != nil {
+			panic(err)
+		}
+		mw = io.MultiWriter(os.Stderr, f)
+	}
+	logger := log.New()
+	logger.SetHandler(log.StreamHandler(mw, log.TerminalFormat(false)))
+	return logger
+}
+
 // Run starts the simulation.
 func Run(cfg *config.Config, logPrefix string) {
 	var (
@@ -48,20 +74,6 @@ func Run(cfg *config.Config, logPrefix string) {
 		panic(fmt.Errorf("DKGSetSze should not be larger the node num"))
 	}
 
-	newLogger := func(logPrefix string) common.Logger {
-		mw := io.Writer(os.Stderr)
-		if logPrefix != "" {
-			f, err := os.Create(logPrefix + ".log")
-			if err != nil {
-				panic(err)
-			}
-			mw = io.MultiWriter(os.Stderr, f)
-		}
-		logger := log.New()
-		logger.SetHandler(log.StreamHandler(mw, log.TerminalFormat(false)))
-		return logger
-	}
-
 	// init is a function to init a node.
 	init := func(serverEndpoint interface{}, logger common.Logger) {
 		prv, err := ecdsa.NewPrivateKey()
@@ -110,3 +122,272 @@ func Run(cfg *config.Config, logPrefix string) {
 		select {}
 	}
 }
+
+// schedulerNode is a node driven by Scheduler.
+type schedulerNode struct {
+	ID      types.NodeID
+	prvKey  crypto.PrivateKey
+	con     *core.Consensus
+	app     *test.App
+	gov     *test.Governance
+	db      db.Database
+	network *test.Network
+	logger  common.Logger
+	// behaviors makes this node misbehave when not empty.
+	behaviors []test.ByzantineBehavior
+}
+
+// newSeededKeys derives private keys from the random source, thus IDs of
+// nodes are identical between runs with the same seed.
+func newSeededKeys(r *rand.Rand, count int) (
+	prvKeys []crypto.PrivateKey, pubKeys []crypto.PublicKey) {
+	for len(prvKeys) < count {
+		b := make([]byte, 32)
+		// #nosec G104
+		r.Read(b)
+		key, err := dexCrypto.ToECDSA(b)
+		if err != nil {
+			// Not a valid scalar, try again.
+			continue
+		}
+		prvKey := ecdsa.NewPrivateKeyFromECDSA(key)
+		prvKeys = append(prvKeys, prvKey)
+		pubKeys = append(pubKeys, prvKey.PublicKey())
+	}
+	return
+}
+
+// newSchedulerGovernance prepares the governance instance shared by all nodes
+// as the seed.
+func newSchedulerGovernance(cfg *config.Config, pubKeys []crypto.PublicKey,
+	logger common.Logger) (*test.Governance, error) {
+	cConfig := cfg.Node.Consensus
+	gov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound, pubKeys,
+			time.Duration(cConfig.LambdaBA)*time.Millisecond, logger, true),
+		core.ConfigRoundShift)
+	if err != nil {
+		return nil, err
+	}
+	changes := []struct {
+		t test.StateChangeType
+		v interface{}
+	}{
+		{test.StateChangeNotarySetSize, cConfig.NotarySetSize},
+		{test.StateChangeLambdaBA,
+			time.Duration(cConfig.LambdaBA) * time.Millisecond},
+		{test.StateChangeLambdaDKG,
+			time.Duration(cConfig.LambdaDKG) * time.Millisecond},
+		{test.StateChangeRoundLength, uint64(cConfig.RoundLength)},
+		{test.StateChangeMinBlockInterval,
+			time.Duration(cConfig.MinBlockInterval) * time.Millisecond},
+	}
+	for _, c := range changes {
+		if err = gov.State().RequestChange(c.t, c.v); err != nil {
+			return nil, err
+		}
+	}
+	for i := uint64(0); i <= core.ConfigRoundShift+1; i++ {
+		prepareConfigs(i, cfg.Node.Changes, gov)
+	}
+	return gov, nil
+}
+
+// RunWithScheduler runs the simulation with all nodes in this process, which
+// are driven by a seeded discrete-event Scheduler instead of wall-clock and
+// real network. It stops when all nodes deliver blocks in
+// Scheduler.UntilRound, or Node.MaxBlock blocks. The digest of the
+// interleaving of delivered messages is returned, runs with the same seed
+// should return the same digest.
+func RunWithScheduler(cfg *config.Config, logPrefix string) (
+	common.Hash, error) {
+	return runWithScheduler(cfg, newLogger(logPrefix),
+		func(i int) common.Logger {
+			if logPrefix == "" {
+				return nil
+			}
+			return newLogger(fmt.Sprintf("%s.%d", logPrefix, i))
+		})
+}
+
+// runWithScheduler implements RunWithScheduler, the i-th node logs with the
+// logger from 'nodeLogger', or 'logger' if it returns nil.
+func runWithScheduler(cfg *config.Config, logger common.Logger,
+	nodeLogger func(i int) common.Logger) (digest common.Hash, err error) {
+	if cfg.Node.Consensus.NotarySetSize > cfg.Node.Num {
+		err = fmt.Errorf("NotarySetSize should not be larger the node num")
+		return
+	}
+	var (
+		seed  = cfg.Scheduler.Seed
+		sched = NewScheduler(seed, int(cfg.Node.Num), cfg.Networking.Direct)
+		nodes []*schedulerNode
+		// Invariants are checked against the simulated clock.
+		checker = test.NewInvariantChecker(sched.Clock(),
+			time.Duration(cfg.Scheduler.StallTimeout)*time.Millisecond)
+	)
+	prvKeys, pubKeys := newSeededKeys(
+		rand.New(rand.NewSource(seed)), int(cfg.Node.Num))
+	seedGov, err := newSchedulerGovernance(cfg, pubKeys, logger)
+	if err != nil {
+		return
+	}
+	// Sort nodes by their IDs, to make sure the master node to register
+	// configuration changes is always the same one.
+	sort.Slice(prvKeys, func(i, j int) bool {
+		return types.NewNodeID(prvKeys[i].PublicKey()).Less(
+			types.NewNodeID(prvKeys[j].PublicKey()).Hash)
+	})
+	nodeIDs := make(types.NodeIDs, 0, len(prvKeys))
+	for _, k := range prvKeys {
+		nodeIDs = append(nodeIDs, types.NewNodeID(k.PublicKey()))
+	}
+	faults, err := cfg.Networking.Faults.ToFaultPlan(nodeIDs)
+	if err != nil {
+		return
+	}
+	for i, k := range prvKeys {
+		n := &schedulerNode{
+			ID:     types.NewNodeID(k.PublicKey()),
+			prvKey: k,
+			logger: logger,
+		}
+		if l := nodeLogger(i); l != nil {
+			n.logger = l
+		}
+		if n.behaviors, err = cfg.Node.ByzantineBehaviors(
+			nodeIDs, n.ID); err != nil {
+			return
+		}
+		n.network = test.NewNetwork(k.PublicKey(), test.NetworkConfig{
+			Type: test.NetworkTypeRouted,
+			// Latencies are decided by the scheduler.
+			DirectLatency: &test.FixedLatencyModel{},
+			GossipLatency: &test.FixedLatencyModel{},
+			Marshaller:    test.NewDefaultMarshaller(nil),
+			Clock:         sched.Clock(),
+			Faults:        faults,
+		})
+		n.gov = seedGov.Clone()
+		n.gov.SwitchToRemoteMode(n.network)
+		n.gov.NotifyRound(0, types.GenesisHeight)
+		n.network.AttachNodeSetCache(utils.NewNodeSetCache(n.gov))
+		if n.db, err = db.NewMemBackedDB(); err != nil {
+			return
+		}
+		n.network.AttachDatabase(n.db)
+		var rEvt *utils.RoundEvent
+		if rEvt, err = utils.NewRoundEvent(context.Background(), n.gov,
+			n.logger, types.Position{Height: types.GenesisHeight},
+			core.ConfigRoundShift); err != nil {
+			return
+		}
+		n.app = test.NewApp(1, n.gov, rEvt)
+		checker.Watch(n.ID, n.app)
+		if i == 0 {
+			for _, c := range cfg.Node.Changes {
+				if c.Round <= core.ConfigRoundShift+1 {
+					continue
+				}
+				if err = c.RegisterChange(n.gov); err != nil {
+					return
+				}
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	// Join all nodes to the scheduler, Setup would block until all of them
+	// joined.
+	var wg sync.WaitGroup
+	errs := make([]error, len(nodes))
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *schedulerNode) {
+			defer wg.Done()
+			errs[i] = n.network.Setup(sched)
+		}(i, n)
+	}
+	wg.Wait()
+	for _, e := range errs {
+		if e != nil {
+			err = e
+			return
+		}
+	}
+	for _, n := range nodes {
+		go n.network.Run()
+		var network core.Network = n.network
+		if len(n.behaviors) > 0 {
+			network = test.NewByzantineNetwork(
+				n.network, n.prvKey, n.behaviors...)
+		}
+		n.con = core.NewConsensusWithClock(n.network.DMoment(), n.app, n.gov,
+			n.db, network, n.prvKey, sched.Clock(), n.logger)
+		go n.con.Run(make(chan struct{}))
+	}
+	finished := func() bool {
+		for _, n := range nodes {
+			pos := n.app.GetLatestDeliveredPosition()
+			if pos.Round < cfg.Scheduler.UntilRound &&
+				pos.Height < cfg.Node.MaxBlock {
+				return false
+			}
+		}
+		return true
+	}
+	for !finished() {
+		if !sched.Step() {
+			err = ErrSchedulerStalled
+			break
+		}
+		checker.CheckStall()
+		if err = checker.Err(); err != nil {
+			logger.Error("Invariant violated", "report", checker.Report())
+			break
+		}
+	}
+	// Messages delivered while stopping nodes are not settled, they're not
+	// part of the digest.
+	digest = sched.Digest()
+	// Stop all nodes, the scheduler should keep stepping until all of them
+	// are stopped.
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for _, n := range nodes {
+			n.con.Stop()
+		}
+	}()
+	sched.drain(stopped)
+	logger.Info("Scheduler stopped",
+		"digest", digest,
+		"delivered", sched.Delivered(),
+		"elapsed", sched.Clock().Now().Sub(schedulerEpoch))
+	if err != nil {
+		return
+	}
+	err = verifySchedulerNodes(nodes)
+	return
+}
+
+// verifySchedulerNodes makes sure all nodes deliver the same sequence of
+// blocks.
+func verifySchedulerNodes(nodes []*schedulerNode) error {
+	for _, n := range nodes {
+		if err := test.VerifyDB(n.db); err != nil {
+			return err
+		}
+		if err := n.app.Verify(); err != nil {
+			return err
+		}
+		for _, other := range nodes {
+			if n == other {
+				continue
+			}
+			if err := n.app.Compare(other.app); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}